@@ -41,6 +41,8 @@ var astroSiteAssetTemplates = []astroSiteAssetTemplate{
 	{templatePath: "astro_template/src/pages/sitemap.xml.ts", outputPath: "src/pages/sitemap.xml.ts"},
 	{templatePath: "astro_template/src/styles/global.css", outputPath: "src/styles/global.css"},
 	{templatePath: "astro_template/src/scripts/code-copy.js", outputPath: "src/scripts/code-copy.js"},
+	{templatePath: "astro_template/src/scripts/diagrams.js", outputPath: "src/scripts/diagrams.js"},
+	{templatePath: "astro_template/src/scripts/enclosing-range.js", outputPath: "src/scripts/enclosing-range.js"},
 	{templatePath: "astro_template/src/scripts/navigation-rail.js", outputPath: "src/scripts/navigation-rail.js"},
 	{templatePath: "astro_template/src/scripts/theme.js", outputPath: "src/scripts/theme.js"},
 	{templatePath: "astro_template/src/scripts/tooltip.js", outputPath: "src/scripts/tooltip.js"},
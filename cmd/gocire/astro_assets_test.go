@@ -27,6 +27,8 @@ var expectedAstroAssetFiles = []string{
 	"src/components/SidebarItems.astro",
 	"src/styles/global.css",
 	"src/scripts/code-copy.js",
+	"src/scripts/diagrams.js",
+	"src/scripts/enclosing-range.js",
 	"src/scripts/navigation-rail.js",
 	"src/scripts/tooltip.js",
 	"src/scripts/theme.js",
@@ -88,6 +90,11 @@ func TestWriteAstroSiteAssetsWritesExpectedFiles(t *testing.T) {
 	if pkg.Dependencies["lucide-astro"] == "" {
 		t.Fatal("package.json dependencies missing lucide-astro")
 	}
+	for _, dependency := range []string{"mermaid", "@viz-js/viz", "@terrastruct/d2"} {
+		if pkg.Dependencies[dependency] == "" {
+			t.Fatalf("package.json dependencies missing %s", dependency)
+		}
+	}
 
 	astroConfig := readAstroAssetFile(t, outputDir, "astro.config.mjs")
 	for _, want := range []string{
@@ -111,6 +118,8 @@ func TestWriteAstroSiteAssetsWritesExpectedFiles(t *testing.T) {
 		"theme-toggle",
 		"data-theme",
 		"../scripts/code-copy.js",
+		"../scripts/diagrams.js",
+		"../scripts/enclosing-range.js",
 		"../scripts/navigation-rail.js",
 		"../scripts/theme.js",
 	} {
@@ -178,6 +187,31 @@ func TestWriteAstroSiteAssetsWritesExpectedFiles(t *testing.T) {
 		assertAstroAssetContains(t, codeCopy, want)
 	}
 
+	diagrams := readAstroAssetFile(t, outputDir, "src/scripts/diagrams.js")
+	for _, want := range []string{
+		"[data-diagram]:not([data-diagram-rendered])",
+		`import("mermaid")`,
+		`import("@viz-js/viz")`,
+		`import("@terrastruct/d2")`,
+		"data-diagram-error",
+	} {
+		assertAstroAssetContains(t, diagrams, want)
+	}
+
+	enclosingRange := readAstroAssetFile(t, outputDir, "src/scripts/enclosing-range.js")
+	for _, want := range []string{
+		"[data-enclosing]",
+		".cire-page--source",
+		"hashchange",
+		"CSS.highlights",
+		"data-fold-toggle",
+		"data-fold-body",
+		"aria-expanded",
+		"extractContents",
+	} {
+		assertAstroAssetContains(t, enclosingRange, want)
+	}
+
 	tooltip := readAstroAssetFile(t, outputDir, "src/scripts/tooltip.js")
 	for _, want := range []string{
 		"@floating-ui/dom",
@@ -498,6 +532,33 @@ func TestAstroGlobalCSSIncludesProseMarkdownStyles(t *testing.T) {
 	}
 }
 
+func TestAstroGlobalCSSIncludesCalloutStyles(t *testing.T) {
+	outputDir := writeAstroAssetsForTest(t, "Callouts")
+
+	globalCSS := readAstroAssetFile(t, outputDir, "src/styles/global.css")
+	for _, check := range []struct {
+		selector string
+		want     string
+	}{
+		{".cire-admonition", "border-left: 4px solid var(--callout)"},
+		{".cire-admonition-title", "color: var(--callout)"},
+		{".cire-tabs", "flex-wrap: wrap"},
+		{".cire-tab-input", "opacity: 0"},
+		{".cire-tab-panel", "display: none"},
+		{".cire-tab-input:checked + .cire-tab-label + .cire-tab-panel", "display: block"},
+	} {
+		ruleBlock := extractAstroCSSRuleBlock(t, globalCSS, check.selector)
+		if !strings.Contains(ruleBlock, check.want) {
+			t.Fatalf("CSS selector %q missing %q\nGot:\n%s", check.selector, check.want, ruleBlock)
+		}
+	}
+	for _, variable := range []string{"--callout-note:", "--callout-tip:", "--callout-important:", "--callout-warning:", "--callout-caution:"} {
+		if strings.Count(globalCSS, variable) != 2 {
+			t.Fatalf("global.css should define %s for the light and dark themes", variable)
+		}
+	}
+}
+
 func TestAstroSiteLayoutUsesGeneratedNavigation(t *testing.T) {
 	outputDir := writeAstroAssetsForTest(t, "Navigation Docs")
 
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Eric-Song-Nop/gocire/internal"
 	projectconfig "github.com/Eric-Song-Nop/gocire/internal/config"
 )
 
@@ -29,16 +30,55 @@ type astroSiteDataSite struct {
 }
 
 type astroSiteDataPage struct {
-	RouteParam string   `json:"routeParam"`
-	Href       string   `json:"href"`
-	Module     string   `json:"module"`
-	Kind       string   `json:"kind"`
-	Title      string   `json:"title"`
-	SourcePath string   `json:"sourcePath"`
-	Language   string   `json:"language"`
-	Date       string   `json:"date"`
-	Tags       []string `json:"tags"`
-	Author     string   `json:"author"`
+	RouteParam  string             `json:"routeParam"`
+	Href        string             `json:"href"`
+	Module      string             `json:"module"`
+	Kind        string             `json:"kind"`
+	Title       string             `json:"title"`
+	SourcePath  string             `json:"sourcePath"`
+	Language    string             `json:"language"`
+	Date        string             `json:"date"`
+	Tags        []string           `json:"tags"`
+	Author      string             `json:"author"`
+	Description string             `json:"description,omitempty"`
+	Draft       bool               `json:"draft,omitempty"`
+	Order       *int               `json:"order,omitempty"`
+	Docs        []astroSiteDataDoc `json:"docs,omitempty"`
+}
+
+// astroSiteDataDoc is a doc comment of a page with its tags parsed, so
+// templates can list parameters or deprecations without reading the page.
+type astroSiteDataDoc struct {
+	Line            int                       `json:"line"`
+	Description     string                    `json:"description"`
+	Params          []astroSiteDataDocParam   `json:"params,omitempty"`
+	Returns         *astroSiteDataDocTyped    `json:"returns,omitempty"`
+	Throws          []astroSiteDataDocTyped   `json:"throws,omitempty"`
+	Deprecated      bool                      `json:"deprecated,omitempty"`
+	DeprecationNote string                    `json:"deprecationNote,omitempty"`
+	Sections        []astroSiteDataDocSection `json:"sections,omitempty"`
+	Tags            []astroSiteDataDocTag     `json:"tags,omitempty"`
+}
+
+type astroSiteDataDocParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description"`
+}
+
+type astroSiteDataDocTyped struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description"`
+}
+
+type astroSiteDataDocSection struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type astroSiteDataDocTag struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
 }
 
 type astroSiteDataNavigation struct {
@@ -105,21 +145,69 @@ func astroSiteDataPages(pages []astroGeneratedPage) []astroSiteDataPage {
 			href = astroRouteHref("/" + strings.TrimLeft(page.Route, "/"))
 		}
 		sitePages = append(sitePages, astroSiteDataPage{
-			RouteParam: page.Route,
-			Href:       href,
-			Module:     page.Module,
-			Kind:       string(page.Kind),
-			Title:      page.Title,
-			SourcePath: page.SourcePath,
-			Language:   page.Language,
-			Date:       strings.TrimSpace(page.Date),
-			Tags:       cloneAstroSiteDataStrings(page.Tags),
-			Author:     strings.TrimSpace(page.Author),
+			RouteParam:  page.Route,
+			Href:        href,
+			Module:      page.Module,
+			Kind:        string(page.Kind),
+			Title:       page.Title,
+			SourcePath:  page.SourcePath,
+			Language:    page.Language,
+			Date:        strings.TrimSpace(page.Date),
+			Tags:        cloneAstroSiteDataStrings(page.Tags),
+			Author:      strings.TrimSpace(page.Author),
+			Description: page.Description,
+			Draft:       page.Draft,
+			Order:       page.Order,
+			Docs:        astroSiteDataDocs(page.Docs),
 		})
 	}
 	return sitePages
 }
 
+// structuredDocComments keeps the comments whose doc comment tags were parsed.
+func structuredDocComments(comments []internal.CommentInfo) []internal.CommentInfo {
+	var docs []internal.CommentInfo
+	for _, comment := range comments {
+		if comment.Doc != nil {
+			docs = append(docs, comment)
+		}
+	}
+	return docs
+}
+
+func astroSiteDataDocs(comments []internal.CommentInfo) []astroSiteDataDoc {
+	var docs []astroSiteDataDoc
+	for _, comment := range comments {
+		if comment.Doc == nil {
+			continue
+		}
+		doc := comment.Doc
+		siteDoc := astroSiteDataDoc{
+			Line:            int(comment.Span.Start.Line) + 1,
+			Description:     doc.Description,
+			Deprecated:      doc.Deprecated,
+			DeprecationNote: doc.DeprecationNote,
+		}
+		for _, param := range doc.Params {
+			siteDoc.Params = append(siteDoc.Params, astroSiteDataDocParam{Name: param.Name, Type: param.Type, Description: param.Description})
+		}
+		if doc.Returns != nil {
+			siteDoc.Returns = &astroSiteDataDocTyped{Type: doc.Returns.Type, Description: doc.Returns.Description}
+		}
+		for _, throws := range doc.Throws {
+			siteDoc.Throws = append(siteDoc.Throws, astroSiteDataDocTyped{Type: throws.Type, Description: throws.Description})
+		}
+		for _, section := range doc.Sections {
+			siteDoc.Sections = append(siteDoc.Sections, astroSiteDataDocSection{Title: section.Title, Body: section.Body})
+		}
+		for _, tag := range doc.Tags {
+			siteDoc.Tags = append(siteDoc.Tags, astroSiteDataDocTag{Name: tag.Name, Text: tag.Text})
+		}
+		docs = append(docs, siteDoc)
+	}
+	return docs
+}
+
 func astroSiteDataNavigationFromSiteNavigation(navigation SiteNavigation) astroSiteDataNavigation {
 	return astroSiteDataNavigation{
 		Docs: astroSiteDataNavigationSectionFromSiteNavigationSection(navigation.Docs),
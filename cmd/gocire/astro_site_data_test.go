@@ -4,8 +4,10 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/Eric-Song-Nop/gocire/internal"
 	projectconfig "github.com/Eric-Song-Nop/gocire/internal/config"
 	"github.com/Eric-Song-Nop/gocire/internal/project"
+	"github.com/sourcegraph/scip/bindings/go/scip"
 )
 
 func TestNewAstroSiteDataUsesStableContract(t *testing.T) {
@@ -89,3 +91,33 @@ func TestNewAstroSiteDataUsesStableContract(t *testing.T) {
 		t.Fatalf("docs navigation items = %#v, want Intro item", data.Navigation.Docs.Items)
 	}
 }
+
+func TestAstroSiteDataPagesIncludeStructuredDocs(t *testing.T) {
+	pages := astroSiteDataPages([]astroGeneratedPage{
+		{
+			Route: "source/add.js.html",
+			Kind:  project.PageKindSource,
+			Docs: structuredDocComments([]internal.CommentInfo{
+				{Content: "Plain prose."},
+				{
+					Content: "Adds.",
+					Span:    scip.Range{Start: scip.Position{Line: 4}},
+					Doc:     internal.ParseDocComment("Adds.\n@param {number} a first\n@deprecated use sum", "javascript"),
+				},
+			}),
+		},
+	})
+
+	want := []astroSiteDataDoc{
+		{
+			Line:            5,
+			Description:     "Adds.",
+			Params:          []astroSiteDataDocParam{{Name: "a", Type: "number", Description: "first"}},
+			Deprecated:      true,
+			DeprecationNote: "use sum",
+		},
+	}
+	if !reflect.DeepEqual(pages[0].Docs, want) {
+		t.Fatalf("docs = %#v, want %#v", pages[0].Docs, want)
+	}
+}
@@ -0,0 +1,71 @@
+const pendingDiagrams = Array.from(document.querySelectorAll("[data-diagram]:not([data-diagram-rendered])"));
+const renderers = {
+  mermaid: renderMermaid,
+  dot: renderDot,
+  d2: renderD2,
+};
+
+// Each renderer loads only on pages that have a diagram of its kind.
+for (const diagram of pendingDiagrams) {
+  renderDiagram(diagram);
+}
+
+async function renderDiagram(diagram) {
+  if (!(diagram instanceof HTMLElement)) {
+    return;
+  }
+  const render = renderers[diagram.dataset.diagram || ""];
+  const source = diagram.querySelector(".cire-diagram-source");
+  if (!render || !source) {
+    return;
+  }
+
+  try {
+    const svg = await render(source.textContent || "");
+    source.replaceWith(svg);
+    diagram.setAttribute("data-diagram-rendered", "");
+  } catch (error) {
+    // The source stays on the page, which reads better than nothing.
+    diagram.setAttribute("data-diagram-error", "");
+    console.warn("gocire: diagram not rendered", error);
+  }
+}
+
+let mermaidIds = 0;
+
+async function renderMermaid(source) {
+  const { default: mermaid } = await import("mermaid");
+  const dark = document.documentElement.getAttribute("data-theme") === "dark";
+  mermaid.initialize({ startOnLoad: false, theme: dark ? "dark" : "default" });
+  mermaidIds += 1;
+  const { svg } = await mermaid.render("gocire-mermaid-" + mermaidIds, source);
+  return svgElement(svg);
+}
+
+let vizInstance;
+
+async function renderDot(source) {
+  if (!vizInstance) {
+    const { instance } = await import("@viz-js/viz");
+    vizInstance = instance();
+  }
+  const viz = await vizInstance;
+  return viz.renderSVGElement(source);
+}
+
+async function renderD2(source) {
+  const { D2 } = await import("@terrastruct/d2");
+  const d2 = new D2();
+  const result = await d2.compile(source);
+  return svgElement(await d2.render(result.diagram, result.renderOptions));
+}
+
+function svgElement(markup) {
+  const template = document.createElement("template");
+  template.innerHTML = markup.trim();
+  const svg = template.content.firstElementChild;
+  if (!svg) {
+    throw new Error("renderer returned no SVG");
+  }
+  return svg;
+}
@@ -0,0 +1,188 @@
+const enclosingHighlightName = "gocire-enclosing";
+const anchorLinePattern = /^L(\d+)C\d+$/;
+const sourcePage = document.querySelector(".cire-page--source");
+
+if (sourcePage) {
+  for (const definition of sourcePage.querySelectorAll("[data-enclosing]")) {
+    addCollapseToggle(definition);
+  }
+}
+
+highlightEnclosingTarget();
+window.addEventListener("hashchange", highlightEnclosingTarget);
+
+function highlightEnclosingTarget() {
+  clearEnclosingHighlight();
+
+  const id = decodeURIComponent(window.location.hash.slice(1));
+  const target = id ? document.getElementById(id) : null;
+  if (!(target instanceof HTMLElement) || !target.hasAttribute("data-enclosing")) {
+    return;
+  }
+
+  expandCollapsedAncestors(target);
+  const range = enclosingRangeFor(target, 0);
+  if (!range) {
+    return;
+  }
+
+  target.classList.add("cire-enclosing-target");
+  if (typeof CSS !== "undefined" && CSS.highlights && typeof Highlight === "function") {
+    CSS.highlights.set(enclosingHighlightName, new Highlight(range));
+  }
+
+  const rect = range.getBoundingClientRect();
+  const fitsViewport = rect.height > 0 && rect.height < window.innerHeight * 0.8;
+  const top = window.scrollY + rect.top - (fitsViewport ? (window.innerHeight - rect.height) / 2 : 24);
+  window.scrollTo({ top: Math.max(0, top) });
+}
+
+function clearEnclosingHighlight() {
+  if (typeof CSS !== "undefined" && CSS.highlights) {
+    CSS.highlights.delete(enclosingHighlightName);
+  }
+  for (const element of document.querySelectorAll(".cire-enclosing-target")) {
+    element.classList.remove("cire-enclosing-target");
+  }
+}
+
+function addCollapseToggle(definition) {
+  const lines = enclosingLines(definition);
+  if (!lines || lines.end - lines.line < 2) {
+    return;
+  }
+
+  // Keep the definition line and the closing line visible; fold what is between.
+  const range = enclosingRangeFor(definition, 1);
+  if (!range || range.collapsed) {
+    return;
+  }
+
+  const body = document.createElement("span");
+  body.className = "cire-fold-body";
+  body.setAttribute("data-fold-body", "");
+  body.appendChild(range.extractContents());
+  range.insertNode(body);
+
+  const toggle = document.createElement("button");
+  toggle.type = "button";
+  toggle.className = "cire-fold-toggle";
+  toggle.setAttribute("data-fold-toggle", "");
+  toggle.setAttribute("aria-expanded", "true");
+  toggle.setAttribute("aria-label", "Collapse body");
+  toggle.addEventListener("click", () => {
+    setCollapsed(body, toggle, !body.hidden);
+  });
+  body.before(toggle);
+}
+
+function setCollapsed(body, toggle, collapsed) {
+  body.hidden = collapsed;
+  toggle.setAttribute("aria-expanded", collapsed ? "false" : "true");
+  toggle.setAttribute("aria-label", collapsed ? "Expand body" : "Collapse body");
+}
+
+function expandCollapsedAncestors(element) {
+  for (let body = element.closest("[data-fold-body]"); body; body = body.parentElement?.closest("[data-fold-body]")) {
+    const toggle = body.previousElementSibling;
+    if (body.hidden && toggle?.hasAttribute("data-fold-toggle")) {
+      setCollapsed(body, toggle, false);
+    }
+  }
+}
+
+function enclosingLines(element) {
+  const match = anchorLinePattern.exec(element.id || "");
+  const [start, end] = String(element.getAttribute("data-enclosing") || "").split("-").map(Number);
+  if (!match || !Number.isInteger(start) || !Number.isInteger(end) || end < start) {
+    return null;
+  }
+  const line = Number(match[1]);
+  return { start: Math.min(start, line), line, end: Math.max(end, line) };
+}
+
+// enclosingRangeFor maps the one-based data-enclosing lines to a DOM range inside the
+// code element that holds the definition. With mode 1 the range only covers the body
+// between the definition line and the closing line, for folding.
+function enclosingRangeFor(definition, mode) {
+  const lines = enclosingLines(definition);
+  const code = definition.closest("code");
+  if (!lines || !code) {
+    return null;
+  }
+
+  const texts = [];
+  const walker = document.createTreeWalker(code, NodeFilter.SHOW_TEXT, {
+    acceptNode: (node) => node.parentElement?.closest("[data-inlay-hint]") ? NodeFilter.FILTER_REJECT : NodeFilter.FILTER_ACCEPT,
+  });
+  let text = "";
+  for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+    texts.push({ node, offset: text.length });
+    text += node.nodeValue || "";
+  }
+
+  const first = texts.find((entry) => definition.contains(entry.node));
+  if (!first) {
+    return null;
+  }
+
+  let startOffset;
+  let endOffset;
+  if (mode === 1) {
+    startOffset = lineEndAfter(text, first.offset, 0);
+    endOffset = lineStartBefore(text, lineEndAfter(text, first.offset, lines.end - lines.line), 0);
+  } else {
+    startOffset = lineStartBefore(text, first.offset, lines.line - lines.start);
+    endOffset = lineEndAfter(text, first.offset, lines.end - lines.line);
+  }
+  if (endOffset < startOffset) {
+    return null;
+  }
+
+  const range = document.createRange();
+  const start = textPosition(texts, startOffset);
+  const end = textPosition(texts, endOffset);
+  range.setStart(start.node, start.offset);
+  range.setEnd(end.node, end.offset);
+  return range;
+}
+
+// lineStartBefore returns the offset where the line `count` lines above offset begins.
+function lineStartBefore(text, offset, count) {
+  let index = offset;
+  for (let remaining = count; ; remaining--) {
+    const newline = text.lastIndexOf("\n", index - 1);
+    if (newline < 0) {
+      return 0;
+    }
+    if (remaining === 0) {
+      return newline + 1;
+    }
+    index = newline;
+  }
+}
+
+// lineEndAfter returns the offset where the line `count` lines below offset ends.
+function lineEndAfter(text, offset, count) {
+  let index = offset;
+  for (let remaining = count; ; remaining--) {
+    const newline = text.indexOf("\n", index);
+    if (newline < 0) {
+      return text.length;
+    }
+    if (remaining === 0) {
+      return newline;
+    }
+    index = newline + 1;
+  }
+}
+
+function textPosition(texts, offset) {
+  for (let i = texts.length - 1; i >= 0; i--) {
+    if (texts[i].offset <= offset) {
+      const length = texts[i].node.nodeValue?.length || 0;
+      return { node: texts[i].node, offset: Math.min(offset - texts[i].offset, length) };
+    }
+  }
+  return { node: texts[0].node, offset: 0 };
+}
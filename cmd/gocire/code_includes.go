@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Eric-Song-Nop/gocire/internal"
+	"github.com/Eric-Song-Nop/gocire/internal/project"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// projectCodeIncludes resolves the gocire:include directives of project pages
+// against the analyzed code of other project files. Each included file is
+// analyzed once however many pages include it, and its own page export reuses
+// that analysis.
+type projectCodeIncludes struct {
+	runner     *ProjectExportRunner
+	lspFactory LSPAnalyzerFactory
+	tagsIndex  *internal.TagsIndex
+	files      map[string]project.SourceFile
+	// targets holds the absolute paths of the files some page includes, the
+	// only analyses kept for the rest of the run.
+	targets map[string]bool
+
+	mu       sync.Mutex
+	analyses map[string]*projectIncludedAnalysis
+}
+
+type projectIncludedAnalysis struct {
+	once     sync.Once
+	analysis *PipelineAnalysis
+	err      error
+}
+
+func (r *ProjectExportRunner) newProjectCodeIncludes(lspFactory LSPAnalyzerFactory, tagsIndex *internal.TagsIndex) *projectCodeIncludes {
+	files := make(map[string]project.SourceFile, len(r.plan.Files))
+	for _, file := range r.plan.Files {
+		files[path.Clean(file.RelPath)] = file
+	}
+	targets := make(map[string]bool)
+	for _, file := range r.plan.Files {
+		content, err := os.ReadFile(file.AbsPath)
+		if err != nil {
+			continue
+		}
+		for _, include := range internal.FindCodeIncludes(strings.Split(string(content), "\n")) {
+			if target, ok := files[path.Clean(strings.TrimPrefix(include.Path, "/"))]; ok {
+				targets[target.AbsPath] = true
+			}
+		}
+	}
+	return &projectCodeIncludes{
+		runner:     r,
+		lspFactory: lspFactory,
+		tagsIndex:  tagsIndex,
+		files:      files,
+		targets:    targets,
+		analyses:   make(map[string]*projectIncludedAnalysis),
+	}
+}
+
+// Resolve returns the code each include directive of a page names, keyed by
+// directive line. Directives that do not resolve are reported and stay in the
+// code as written.
+func (c *projectCodeIncludes) Resolve(ctx context.Context, file project.SourceFile, sourceLines []string, manifest internal.SourceRouteManifest) map[int]internal.IncludedCode {
+	if c == nil {
+		return nil
+	}
+	includes := internal.FindCodeIncludes(sourceLines)
+	if len(includes) == 0 {
+		return nil
+	}
+
+	resolved := make(map[int]internal.IncludedCode, len(includes))
+	for _, include := range includes {
+		code, err := c.resolve(ctx, include, manifest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s:%d: include not resolved: %v\n", file.RelPath, include.Line+1, err)
+			continue
+		}
+		resolved[include.Line] = code
+	}
+	return resolved
+}
+
+func (c *projectCodeIncludes) resolve(ctx context.Context, include internal.CodeInclude, manifest internal.SourceRouteManifest) (internal.IncludedCode, error) {
+	target, ok := c.files[path.Clean(strings.TrimPrefix(include.Path, "/"))]
+	if !ok {
+		return internal.IncludedCode{}, fmt.Errorf("%s is not a project source file", include.Path)
+	}
+	route, _, ok := manifest.RouteForSourcePath(target.AbsPath)
+	if !ok {
+		return internal.IncludedCode{}, fmt.Errorf("%s has no source page", include.Path)
+	}
+
+	analysis, err := c.Analyze(ctx, target, nil, manifest)
+	if err != nil {
+		return internal.IncludedCode{}, fmt.Errorf("analyze %s: %w", target.RelPath, err)
+	}
+	span, name, err := c.includeSpan(include, target, analysis.SourceLines)
+	if err != nil {
+		return internal.IncludedCode{}, err
+	}
+
+	href := route
+	if name != nil {
+		// A symbol's caption lands on its definition.
+		for _, token := range analysis.Tokens {
+			if token.Anchor != "" && token.Span.Start == name.Start {
+				href = route + "#" + token.Anchor
+				break
+			}
+		}
+	}
+	label := include.Path
+	if include.Fragment != "" {
+		label += "#" + include.Fragment
+	}
+	return internal.IncludedCode{
+		SourceLines: analysis.SourceLines,
+		Tokens:      internal.TranscludeTokens(analysis.Tokens, span, route),
+		Span:        span,
+		Language:    target.Language,
+		Label:       label,
+		Href:        href,
+	}, nil
+}
+
+// includeSpan finds the lines an include names, and the name of the symbol it
+// names, if any.
+func (c *projectCodeIncludes) includeSpan(include internal.CodeInclude, target project.SourceFile, sourceLines []string) (scip.Range, *scip.Range, error) {
+	last := len(sourceLines) - 1
+	for last > 0 && strings.TrimSpace(sourceLines[last]) == "" {
+		last--
+	}
+
+	if include.Fragment == "" {
+		return internal.IncludeLineSpan(sourceLines, 0, last), nil, nil
+	}
+	if start, end, ok := include.LineRange(); ok {
+		if start > last {
+			return scip.Range{}, nil, fmt.Errorf("%s has only %d lines", include.Path, last+1)
+		}
+		return internal.IncludeLineSpan(sourceLines, start, end), nil, nil
+	}
+
+	name := include.Fragment
+	qualifier := ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		qualifier, name = name[:i], name[i+1:]
+	}
+	var candidates []internal.TagDefinition
+	if c.tagsIndex != nil {
+		for _, definition := range c.tagsIndex.Definitions(name) {
+			if definition.Path != target.AbsPath {
+				continue
+			}
+			if qualifier != "" && strings.TrimLeft(definition.Scope, "*&") != qualifier {
+				continue
+			}
+			candidates = append(candidates, definition)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return scip.Range{}, nil, fmt.Errorf("%s defines no %s", include.Path, include.Fragment)
+	case 1:
+		definition := candidates[0]
+		return internal.IncludeLineSpan(sourceLines, int(definition.Body.Start.Line), int(definition.Body.End.Line)), &definition.Range, nil
+	default:
+		return scip.Range{}, nil, fmt.Errorf("%s defines %s %d times; qualify it as Type.%s", include.Path, include.Fragment, len(candidates), name)
+	}
+}
+
+// Analyze analyzes file with pipeline, or with a pipeline of its own when
+// pipeline is nil. Included files are analyzed once for both their page and
+// the pages that include them; other files are not kept.
+func (c *projectCodeIncludes) Analyze(ctx context.Context, file project.SourceFile, pipeline *Pipeline, manifest internal.SourceRouteManifest) (*PipelineAnalysis, error) {
+	if c == nil || !c.targets[file.AbsPath] {
+		return c.analyzeFile(ctx, file, pipeline, manifest)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.analyses[file.AbsPath]
+	if !ok {
+		entry = &projectIncludedAnalysis{}
+		c.analyses[file.AbsPath] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.analysis, entry.err = c.analyzeFile(ctx, file, pipeline, manifest)
+	})
+	return entry.analysis, entry.err
+}
+
+func (c *projectCodeIncludes) analyzeFile(ctx context.Context, file project.SourceFile, pipeline *Pipeline, manifest internal.SourceRouteManifest) (*PipelineAnalysis, error) {
+	if pipeline == nil {
+		var err error
+		pipeline, err = NewPipelineWithOptions(c.runner.pipelineConfigForProjectFile(file, ""), PipelineOptions{
+			Context:            ctx,
+			LSPAnalyzerFactory: c.lspFactory,
+			TagsIndex:          c.tagsIndex,
+			Settings:           c.runner.plan.Settings,
+			Index:              c.runner.index,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pipeline.AnalyzeFile(PipelineRunOptions{
+		Context:  ctx,
+		Manifest: &manifest,
+	})
+}
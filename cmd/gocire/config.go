@@ -25,13 +25,14 @@ type Config struct {
 	PrefixDate       bool
 	CodeWrapperStart string
 	CodeWrapperEnd   string
+	DebugTokens      bool
 }
 
 func ParseConfig() (*Config, error) {
 	cfg := &Config{}
 
 	flag.StringVar(&cfg.SrcPath, "src", "", "source file path")
-	flag.StringVar(&cfg.IndexPath, "index", "./index.scip", "SCIP Index File Path")
+	flag.StringVar(&cfg.IndexPath, "index", "./index.scip", "SCIP index or LSIF dump (.lsif) file path")
 	flag.StringVar(&cfg.OutPath, "output", "", "Output file path for single-file mode, or output directory override for project mode")
 	flag.StringVar(&cfg.Lang, "lang", "", "Language for syntax highlighting (optional)")
 	flag.BoolVar(&cfg.UseLSP, "lsp", false, "Use LSP for analysis (requires language server installed)")
@@ -48,6 +49,8 @@ func ParseConfig() (*Config, error) {
 	flag.StringVar(&cfg.CodeWrapperEnd, "code-wrapper-end", `</code></pre>
 </details>`, "Custom closing HTML/JSX for code blocks")
 
+	flag.BoolVar(&cfg.DebugTokens, "debug-tokens", false, "Write an HTML page of every analyzer's tokens for -src instead of exporting it")
+
 	flag.Parse()
 
 	if cfg.Site {
@@ -63,6 +66,11 @@ func ParseConfig() (*Config, error) {
 		return nil, fmt.Errorf("format 'astro' is only supported with -project")
 	}
 
+	if cfg.DebugTokens && cfg.ProjectMode() {
+		flag.Usage()
+		return nil, fmt.Errorf("-debug-tokens is only supported for a single -src file")
+	}
+
 	if cfg.Jobs < 1 {
 		flag.Usage()
 		return nil, fmt.Errorf("jobs must be greater than 0")
@@ -121,3 +129,12 @@ func (c *Config) ResolveOutputPath() string {
 
 	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, base, ext))
 }
+
+// ResolveDebugTokensPath returns OutPath, or the source path with a
+// .tokens.html extension added.
+func (c *Config) ResolveDebugTokensPath() string {
+	if c.OutPath != "" {
+		return c.OutPath
+	}
+	return c.AbsSrcPath + ".tokens.html"
+}
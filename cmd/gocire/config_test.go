@@ -53,6 +53,33 @@ func TestParseConfigRejectsSingleFileAstroFormat(t *testing.T) {
 	}
 }
 
+func TestParseConfigDebugTokens(t *testing.T) {
+	withCommandLine(t, []string{"gocire", "-src", "main.go", "-debug-tokens"})
+
+	cfg, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("ParseConfig returned error: %v", err)
+	}
+	if !cfg.DebugTokens {
+		t.Fatal("DebugTokens = false, want true")
+	}
+	if want := cfg.AbsSrcPath + ".tokens.html"; cfg.ResolveDebugTokensPath() != want {
+		t.Fatalf("ResolveDebugTokensPath = %q, want %q", cfg.ResolveDebugTokensPath(), want)
+	}
+}
+
+func TestParseConfigRejectsProjectDebugTokens(t *testing.T) {
+	withCommandLine(t, []string{"gocire", "-project", "-debug-tokens"})
+
+	_, err := ParseConfig()
+	if err == nil {
+		t.Fatal("ParseConfig returned nil error")
+	}
+	if !strings.Contains(err.Error(), "single -src file") {
+		t.Fatalf("error = %q, want single-file message", err.Error())
+	}
+}
+
 func withCommandLine(t *testing.T, args []string) {
 	t.Helper()
 
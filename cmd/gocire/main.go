@@ -38,15 +38,22 @@ func main() {
 	if cfg.Lang == "" && cfg.AbsSrcPath != "" {
 		info, err := os.Stat(cfg.AbsSrcPath)
 		if err == nil && !info.IsDir() {
-			lang, err := languages.DetectLanguage(cfg.AbsSrcPath)
+			detection, err := languages.DetectFile(cfg.AbsSrcPath)
 			if err == nil {
-				fmt.Printf("Auto-detected language: %s\n", lang)
-				cfg.Lang = lang
+				fmt.Printf("Auto-detected language: %s (by %s)\n", detection.Language, detection.Method)
+				cfg.Lang = detection.Language
 			}
 		}
 	}
 
-	pipeline, err := NewPipeline(cfg)
+	settings, err := loadSingleFileAnalysisConfig(cfg.ConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer settings.Close()
+
+	pipeline, err := NewPipelineWithOptions(cfg, PipelineOptions{Settings: settings})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -18,6 +18,12 @@ type TokenAnalyzer interface {
 	Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error)
 }
 
+// SourceTreeAnalyzer is implemented by analyzers that can reuse the pipeline's
+// single tree-sitter parse of a file instead of parsing the content again.
+type SourceTreeAnalyzer interface {
+	AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error)
+}
+
 // DocumentGenerator is a common interface for generating output.
 type DocumentGenerator interface {
 	Generate(tokens []internal.TokenInfo, comments []internal.CommentInfo) string
@@ -34,11 +40,55 @@ type LSPAnalyzerFactory func(ctx context.Context, req PipelineLSPRequest) (Token
 type PipelineOptions struct {
 	Context            context.Context
 	LSPAnalyzerFactory LSPAnalyzerFactory
+	// TagsIndex links references that no language server or index resolves.
+	TagsIndex *internal.TagsIndex
+	// Settings carries the analysis and rendering settings of the project
+	// config; the zero value uses the built-in ones.
+	Settings AnalysisSettings
+	// Index is the SCIP index or LSIF dump loaded once for a project run; nil
+	// loads cfg.AbsIndexPath for this pipeline alone.
+	Index *PipelineIndex
+}
+
+// PipelineIndex is a loaded SCIP index or LSIF dump, which answers for every
+// file it covers. Exactly one of SCIP and LSIF is set.
+type PipelineIndex struct {
+	Path string
+	SCIP *internal.SCIPAnalyzer
+	LSIF *internal.LSIFAnalyzer
+}
+
+// LoadPipelineIndex reads the SCIP index or LSIF dump at indexPath. SCIP hover
+// signatures are highlighted with the settings' queries.
+func LoadPipelineIndex(indexPath string, settings AnalysisSettings) (*PipelineIndex, error) {
+	if isLSIFIndexPath(indexPath) {
+		lsifAnalyzer, err := internal.NewLSIFAnalyzer(indexPath)
+		if err != nil {
+			return nil, fmt.Errorf("load LSIF dump failed: %w", err)
+		}
+		return &PipelineIndex{Path: indexPath, LSIF: lsifAnalyzer}, nil
+	}
+	scipAnalyzer, err := internal.NewSCIPAnalyzer(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("load SCIP index file failed: %w", err)
+	}
+	scipAnalyzer.HighlightQueries = settings.HighlightQueries
+	return &PipelineIndex{Path: indexPath, SCIP: scipAnalyzer}, nil
+}
+
+// AnalysisSettings holds what the project config changes about analysis and
+// rendering: highlight.queryDir, source.directives, markdown and links.
+type AnalysisSettings struct {
+	HighlightQueries  *internal.HighlightQueries
+	CommentDirectives *internal.CommentDirectives
+	Markdown          internal.MarkdownOptions
+	LinkPolicy        internal.LinkPolicy
 }
 
 // Pipeline orchestrates the analysis and generation process.
 type Pipeline struct {
 	cfg       *Config
+	settings  AnalysisSettings
 	analyzers []TokenAnalyzer
 	comments  *internal.CommentAnalyzer
 	generator DocumentGenerator
@@ -51,11 +101,25 @@ func NewPipeline(cfg *Config) (*Pipeline, error) {
 
 func NewPipelineWithOptions(cfg *Config, options PipelineOptions) (*Pipeline, error) {
 	p := &Pipeline{
-		cfg: cfg,
+		cfg:      cfg,
+		settings: options.Settings,
+	}
+	newHighlightAnalyzer := func() *internal.HighlightAnalyzer {
+		analyzer := internal.NewHighlightAnalyzer(cfg.Lang)
+		analyzer.Queries = options.Settings.HighlightQueries
+		return analyzer
 	}
 
 	sourceLines := readSourceLines(cfg.AbsSrcPath)
 
+	// Tags are the weakest source of links; every other analyzer outranks
+	// them when tokens are merged, see internal.LinkPolicy.
+	if options.TagsIndex != nil && cfg.Lang != "" {
+		p.analyzers = append(p.analyzers, &TagsWrapper{
+			inner: options.TagsIndex.Analyzer(cfg.Lang, cfg.AbsSrcPath),
+		})
+	}
+
 	// 1. Configure Analyzers
 	if cfg.UseLSP {
 		// LSP Mode: Exclusive
@@ -81,31 +145,49 @@ func NewPipelineWithOptions(cfg *Config, options PipelineOptions) (*Pipeline, er
 			}
 			p.analyzers = append(p.analyzers, analyzer)
 		} else {
+			lspAnalyzer := internal.NewLSPAnalyzer(cfg.Lang, cfg.AbsSrcPath, cfg.LSPRoot)
+			lspAnalyzer.Queries = options.Settings.HighlightQueries
 			p.analyzers = append(p.analyzers, &LSPWrapper{
-				inner: internal.NewLSPAnalyzer(cfg.Lang, cfg.AbsSrcPath, cfg.LSPRoot),
+				inner: lspAnalyzer,
 			})
 		}
 		p.analyzers = append(p.analyzers, &HighlightWrapper{
-			inner: internal.NewHighlightAnalyzer(cfg.Lang),
+			inner: newHighlightAnalyzer(),
 		})
 	} else {
-		// Static Mode: SCIP + Highlight
-		if cfg.AbsIndexPath != "" {
-			scipAnalyzer, err := internal.NewSCIPAnalyzer(cfg.AbsIndexPath)
-			if err == nil {
-				fmt.Printf("Index path: %s\n", cfg.AbsIndexPath)
-				p.analyzers = append(p.analyzers, &SCIPWrapper{
-					inner:      scipAnalyzer,
+		// Static Mode: Locals + SCIP or LSIF + Highlight
+		// Index definitions outrank locals where both link a token.
+		if cfg.Lang != "" {
+			p.analyzers = append(p.analyzers, &LocalsWrapper{
+				inner: internal.NewLocalsAnalyzer(cfg.Lang, cfg.AbsSrcPath),
+			})
+		}
+		index := options.Index
+		if index == nil && cfg.AbsIndexPath != "" {
+			var err error
+			index, err = LoadPipelineIndex(cfg.AbsIndexPath, options.Settings)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v. Index analysis will be skipped.\n", err)
+			}
+		}
+		if index != nil {
+			fmt.Printf("Index path: %s\n", index.Path)
+			if index.LSIF != nil {
+				p.analyzers = append(p.analyzers, &LSIFWrapper{
+					inner:      index.LSIF,
 					sourcePath: cfg.AbsSrcPath,
 				})
 			} else {
-				fmt.Fprintf(os.Stderr, "Warning: Load SCIP index file failed: %v. SCIP analysis will be skipped.\n", err)
+				p.analyzers = append(p.analyzers, &SCIPWrapper{
+					inner:      index.SCIP,
+					sourcePath: cfg.AbsSrcPath,
+				})
 			}
 		}
 
 		if cfg.Lang != "" {
 			p.analyzers = append(p.analyzers, &HighlightWrapper{
-				inner: internal.NewHighlightAnalyzer(cfg.Lang),
+				inner: newHighlightAnalyzer(),
 			})
 		}
 	}
@@ -113,12 +195,14 @@ func NewPipelineWithOptions(cfg *Config, options PipelineOptions) (*Pipeline, er
 	// Comment analysis (if language provided)
 	if cfg.Lang != "" {
 		p.comments = internal.NewCommentAnalyzer(cfg.Lang)
+		p.comments.Directives = options.Settings.CommentDirectives
 	}
 
 	// 2. Configure Generator
 	switch cfg.Format {
 	case "mdx":
 		gen := internal.NewMDXGenerator(sourceLines)
+		gen.Markdown = options.Settings.Markdown
 		if cfg.CodeWrapperStart != "" {
 			gen.CodeWrapperStart = cfg.CodeWrapperStart
 		}
@@ -149,6 +233,9 @@ type PipelineRunOptions struct {
 	Context    context.Context
 	Manifest   *internal.SourceRouteManifest
 	OutputPath string
+	// Includes resolves the gocire:include directives of the analyzed source
+	// for generators that transclude code.
+	Includes func(sourceLines []string) map[int]internal.IncludedCode
 }
 
 type PipelineAnalysis struct {
@@ -166,6 +253,14 @@ func (p *Pipeline) Run() error {
 		manifestPtr = &manifest
 	}
 
+	if p.cfg.DebugTokens {
+		return p.RunDebugTokens(PipelineRunOptions{
+			Context:    context.Background(),
+			Manifest:   manifestPtr,
+			OutputPath: p.cfg.ResolveDebugTokensPath(),
+		})
+	}
+
 	return p.RunFile(PipelineRunOptions{
 		Context:    context.Background(),
 		Manifest:   manifestPtr,
@@ -178,11 +273,19 @@ func (p *Pipeline) RunFile(opts PipelineRunOptions) error {
 	if err != nil {
 		return err
 	}
+	return p.WriteFile(analysis, opts)
+}
 
+// WriteFile generates the document of an analysis made by AnalyzeFile and
+// writes it to opts.OutputPath.
+func (p *Pipeline) WriteFile(analysis *PipelineAnalysis, opts PipelineRunOptions) error {
 	if p.generator == nil {
 		return fmt.Errorf("format %q does not support single-file pipeline generation", p.cfg.Format)
 	}
 
+	if mdx, ok := p.generator.(*MDXWrapper); ok && opts.Includes != nil {
+		mdx.inner.Includes = opts.Includes(analysis.SourceLines)
+	}
 	output := p.generator.Generate(analysis.Tokens, analysis.Comments)
 
 	outPath := opts.OutputPath
@@ -197,6 +300,52 @@ func (p *Pipeline) RunFile(opts PipelineRunOptions) error {
 	return nil
 }
 
+// RunDebugTokens writes the token inspector of the source file: each
+// analyzer's tokens next to the merged tokens the generators would render.
+func (p *Pipeline) RunDebugTokens(opts PipelineRunOptions) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	content, err := os.ReadFile(p.cfg.AbsSrcPath)
+	if err != nil {
+		return fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	streams, comments, err := p.analyzeStreams(ctx, content)
+	if err != nil {
+		return err
+	}
+	// Merging sorts and splits the tokens in place; the streams keep theirs.
+	streams = internal.SortTokenStreams(streams)
+	var allTokens []internal.TokenInfo
+	for _, stream := range streams {
+		allTokens = append(allTokens, stream.Tokens...)
+	}
+	merged, err := p.mergeSortSplit(allTokens, comments)
+	if err != nil {
+		return err
+	}
+	if opts.Manifest != nil {
+		p.resolveTokenLinksWithManifest(merged, *opts.Manifest)
+	}
+
+	sourceLines := strings.Split(string(content), "\n")
+	output := internal.NewTokenDebugGenerator(sourceLines).GenerateTokenDebugHTML(p.cfg.SrcPath, streams, merged)
+
+	outPath := opts.OutputPath
+	if outPath == "" {
+		outPath = p.cfg.ResolveDebugTokensPath()
+	}
+	if err := writeOutputFile(outPath, output); err != nil {
+		return err
+	}
+
+	fmt.Printf("token inspector generated at: %s\n", outPath)
+	return nil
+}
+
 func (p *Pipeline) AnalyzeFile(opts PipelineRunOptions) (*PipelineAnalysis, error) {
 	ctx := opts.Context
 	if ctx == nil {
@@ -232,12 +381,58 @@ func (p *Pipeline) AnalyzeFile(opts PipelineRunOptions) (*PipelineAnalysis, erro
 }
 
 func (p *Pipeline) analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, []internal.CommentInfo, error) {
+	streams, comments, err := p.analyzeStreams(ctx, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Merge Results
+	var allTokens []internal.TokenInfo
+	for _, stream := range streams {
+		allTokens = append(allTokens, stream.Tokens...)
+	}
+
+	return allTokens, comments, nil
+}
+
+// analyzeStreams runs every analyzer over content and returns their tokens
+// apart, named after the analyzer, in the order the analyzers run.
+func (p *Pipeline) analyzeStreams(ctx context.Context, content []byte) ([]internal.TokenStream, []internal.CommentInfo, error) {
+	// Parse once; each analyzer below gets its own clone because trees are not
+	// safe for concurrent use.
+	var tree *internal.SourceTree
+	if p.cfg.Lang != "" {
+		if parsed, err := internal.ParseSource(p.cfg.Lang, content); err == nil {
+			tree = parsed
+			defer tree.Close()
+		}
+	}
+	cloneTree := func() *internal.SourceTree {
+		if tree == nil {
+			return nil
+		}
+		return tree.Clone()
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	// Run Token Analyzers
 	results := make([][]internal.TokenInfo, len(p.analyzers))
 	for i, analyzer := range p.analyzers {
 		i, analyzer := i, analyzer
+		if treeAnalyzer, ok := analyzer.(SourceTreeAnalyzer); ok && tree != nil {
+			analyzerTree := cloneTree()
+			g.Go(func() error {
+				defer analyzerTree.Close()
+				tokens, err := treeAnalyzer.AnalyzeTree(ctx, analyzerTree)
+				if err != nil {
+					return err
+				}
+				results[i] = tokens
+				return nil
+			})
+			continue
+		}
 		g.Go(func() error {
 			tokens, err := analyzer.Analyze(ctx, content)
 			if err != nil {
@@ -251,10 +446,16 @@ func (p *Pipeline) analyze(ctx context.Context, content []byte) ([]internal.Toke
 	// Run Comment Analyzer
 	var comments []internal.CommentInfo
 	if p.comments != nil {
+		commentTree := cloneTree()
 		g.Go(func() error {
 			var err error
 			// CommentAnalyzer doesn't support context cancellation yet, but that's fine for now
-			comments, err = p.comments.Analyze(content)
+			if commentTree != nil {
+				defer commentTree.Close()
+				comments, err = p.comments.AnalyzeTree(commentTree)
+			} else {
+				comments, err = p.comments.Analyze(content)
+			}
 			return err
 		})
 	}
@@ -263,13 +464,36 @@ func (p *Pipeline) analyze(ctx context.Context, content []byte) ([]internal.Toke
 		return nil, nil, fmt.Errorf("analysis failed: %w", err)
 	}
 
-	// Merge Results
-	var allTokens []internal.TokenInfo
-	for _, res := range results {
-		allTokens = append(allTokens, res...)
+	streams := make([]internal.TokenStream, len(results))
+	for i, tokens := range results {
+		streams[i] = internal.TokenStream{Analyzer: analyzerName(p.analyzers[i], tokens, i), Tokens: tokens}
 	}
+	return streams, comments, nil
+}
 
-	return allTokens, comments, nil
+// analyzerName names an analyzer's stream by the source its tokens record,
+// or by its position for analyzers that are neither known nor produced any.
+func analyzerName(analyzer TokenAnalyzer, tokens []internal.TokenInfo, index int) string {
+	switch analyzer.(type) {
+	case *LSPWrapper:
+		return string(internal.TokenSourceLSP)
+	case *HighlightWrapper:
+		return string(internal.TokenSourceSyntax)
+	case *SCIPWrapper:
+		return string(internal.TokenSourceSCIP)
+	case *LSIFWrapper:
+		return string(internal.TokenSourceLSIF)
+	case *LocalsWrapper:
+		return string(internal.TokenSourceLocals)
+	case *TagsWrapper:
+		return string(internal.TokenSourceTags)
+	}
+	for _, token := range tokens {
+		if token.Source != "" {
+			return string(token.Source)
+		}
+	}
+	return fmt.Sprintf("analyzer %d", index+1)
 }
 
 func (p *Pipeline) mergeSortSplit(allTokens []internal.TokenInfo, comments []internal.CommentInfo) ([]internal.TokenInfo, error) {
@@ -277,7 +501,7 @@ func (p *Pipeline) mergeSortSplit(allTokens []internal.TokenInfo, comments []int
 	internal.SortBySpan(comments)
 
 	var err error
-	allTokens, err = internal.MergeSplitTokens(allTokens)
+	allTokens, err = internal.MergeSplitTokensWithPolicy(allTokens, p.settings.LinkPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("merge split tokens failed: %w", err)
 	}
@@ -304,12 +528,85 @@ func (p *Pipeline) sourceRouteManifest() (internal.SourceRouteManifest, error) {
 		return internal.SourceRouteManifest{}, err
 	}
 
-	sourcePaths := make([]string, 0, len(files))
+	// Only the exported file's front matter is read: its slug names the page,
+	// and the other files keep their path routes.
+	var pages []project.SourceFile
 	for _, file := range files {
-		sourcePaths = append(sourcePaths, file.AbsPath)
+		if cleanSiteAbsPath(file.AbsPath) == cleanSiteAbsPath(p.cfg.AbsSrcPath) {
+			pages = append(pages, file)
+		}
 	}
+	routes, _, err := siteRoutesForProject(*cfg, files, pages, p.settings.CommentDirectives)
+	return routes, err
+}
 
-	return internal.NewSourceRouteManifestWithPrefix(cfg.Project.Root, cfg.Source.RoutePrefix, sourcePaths)
+// loadSingleFileAnalysisConfig reads highlight.queryDir, source.directives,
+// markdown and links from the project config for single-file exports. A
+// config that fails to load only warns, as it does for the source route
+// manifest; broken queries are errors.
+func loadSingleFileAnalysisConfig(configPath string) (AnalysisSettings, error) {
+	cfg, err := projectconfig.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: load project config failed: %v. Custom highlight queries, comment directives, Markdown options and link policy will be skipped.\n", err)
+		return AnalysisSettings{}, nil
+	}
+	return loadAnalysisSettings(cfg)
+}
+
+// loadAnalysisSettings compiles the analysis and rendering settings of a
+// project config. Callers close the settings once no pipeline uses them.
+func loadAnalysisSettings(cfg *projectconfig.ProjectConfig) (AnalysisSettings, error) {
+	settings := AnalysisSettings{
+		Markdown:   markdownOptionsFromConfig(cfg.Markdown),
+		LinkPolicy: linkPolicyFromConfig(cfg.Links),
+	}
+	if err := settings.Markdown.Validate(); err != nil {
+		return AnalysisSettings{}, err
+	}
+	if err := settings.LinkPolicy.Validate(); err != nil {
+		return AnalysisSettings{}, err
+	}
+	directives, err := internal.LoadCommentDirectives(cfg.Source.Directives)
+	if err != nil {
+		return AnalysisSettings{}, err
+	}
+	settings.CommentDirectives = directives
+	queries, err := internal.LoadHighlightQueries(cfg.Highlight.QueryDir)
+	if err != nil {
+		return AnalysisSettings{}, err
+	}
+	settings.HighlightQueries = queries
+	return settings, nil
+}
+
+// Close releases the compiled highlight queries.
+func (s AnalysisSettings) Close() {
+	s.HighlightQueries.Close()
+}
+
+// markdownOptionsFromConfig maps the markdown section of the project config
+// onto the renderer's options.
+func markdownOptionsFromConfig(cfg projectconfig.MarkdownConfig) internal.MarkdownOptions {
+	return internal.MarkdownOptions{
+		Footnotes:       cfg.Footnotes,
+		DefinitionLists: cfg.DefinitionLists,
+		Typographer:     cfg.Typographer,
+		Emoji:           cfg.Emoji,
+		Attributes:      cfg.Attributes,
+		HeadingAnchors:  internal.HeadingAnchorStyle(cfg.HeadingAnchors),
+		DisableRawHTML:  !cfg.RawHTML,
+		Sanitize:        internal.SanitizeLevel(cfg.Sanitize),
+	}
+}
+
+// linkPolicyFromConfig maps the links section of the project config onto the
+// policy that picks a merged token's link.
+func linkPolicyFromConfig(cfg projectconfig.LinksConfig) internal.LinkPolicy {
+	policy := internal.LinkPolicy{Strategy: internal.LinkStrategy(cfg.Policy)}
+	for _, analyzer := range cfg.Precedence {
+		policy.Precedence = append(policy.Precedence, internal.TokenSource(analyzer))
+	}
+	return policy
 }
 
 func (p *Pipeline) resolveTokenLinksWithManifest(tokens []internal.TokenInfo, manifest internal.SourceRouteManifest) {
@@ -339,6 +636,10 @@ func (w *LSPWrapper) Analyze(ctx context.Context, content []byte) ([]internal.To
 	return w.inner.Analyze(content)
 }
 
+func (w *LSPWrapper) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	return w.inner.AnalyzeTree(tree)
+}
+
 type HighlightWrapper struct {
 	inner *internal.HighlightAnalyzer
 }
@@ -347,6 +648,10 @@ func (w *HighlightWrapper) Analyze(ctx context.Context, content []byte) ([]inter
 	return w.inner.Analyze(content)
 }
 
+func (w *HighlightWrapper) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	return w.inner.AnalyzeTree(tree)
+}
+
 type SCIPWrapper struct {
 	inner      *internal.SCIPAnalyzer
 	sourcePath string
@@ -357,6 +662,46 @@ func (w *SCIPWrapper) Analyze(ctx context.Context, content []byte) ([]internal.T
 	return w.inner.Analyze(w.sourcePath), nil
 }
 
+type LocalsWrapper struct {
+	inner *internal.LocalsAnalyzer
+}
+
+func (w *LocalsWrapper) Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error) {
+	return w.inner.Analyze(content)
+}
+
+func (w *LocalsWrapper) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	return w.inner.AnalyzeTree(tree)
+}
+
+type TagsWrapper struct {
+	inner *internal.TagsAnalyzer
+}
+
+func (w *TagsWrapper) Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error) {
+	return w.inner.Analyze(content)
+}
+
+func (w *TagsWrapper) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	return w.inner.AnalyzeTree(tree)
+}
+
+type LSIFWrapper struct {
+	inner      *internal.LSIFAnalyzer
+	sourcePath string
+}
+
+func (w *LSIFWrapper) Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error) {
+	// LSIF uses path, not content
+	return w.inner.Analyze(w.sourcePath), nil
+}
+
+// isLSIFIndexPath selects the LSIF analyzer for dumps such as dump.lsif; any other
+// index path is read as SCIP.
+func isLSIFIndexPath(indexPath string) bool {
+	return strings.EqualFold(filepath.Ext(indexPath), ".lsif")
+}
+
 type MarkdownWrapper struct {
 	inner *internal.MarkdownGenerator
 }
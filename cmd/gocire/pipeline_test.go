@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Eric-Song-Nop/gocire/internal"
+	"github.com/sourcegraph/scip/bindings/go/scip"
 )
 
 type testTokenAnalyzer struct{}
@@ -59,3 +62,148 @@ func TestNewPipelinePassesExplicitLSPRequest(t *testing.T) {
 		t.Fatalf("WorkspaceRoot = %q, want %q", gotReq.WorkspaceRoot, root)
 	}
 }
+
+func TestNewPipelineSelectsLSIFAnalyzerByExtension(t *testing.T) {
+	root := t.TempDir()
+	sourcePath := filepath.Join(root, "main.go")
+	indexPath := filepath.Join(root, "dump.lsif")
+	writeProjectTestFile(t, sourcePath, "package main\n")
+	writeProjectTestFile(t, indexPath, `{"id":1,"type":"vertex","label":"metaData","projectRoot":"file://`+filepath.ToSlash(root)+`"}`+"\n")
+
+	pipeline, err := NewPipeline(&Config{
+		SrcPath:      sourcePath,
+		AbsSrcPath:   sourcePath,
+		IndexPath:    indexPath,
+		AbsIndexPath: indexPath,
+		Format:       "markdown",
+	})
+	if err != nil {
+		t.Fatalf("NewPipeline returned error: %v", err)
+	}
+	if len(pipeline.analyzers) != 1 {
+		t.Fatalf("analyzers = %d, want 1", len(pipeline.analyzers))
+	}
+	if _, ok := pipeline.analyzers[0].(*LSIFWrapper); !ok {
+		t.Fatalf("analyzer = %T, want *LSIFWrapper", pipeline.analyzers[0])
+	}
+}
+
+func TestNewPipelineSharesLoadedIndex(t *testing.T) {
+	root := t.TempDir()
+	indexPath := filepath.Join(root, "dump.lsif")
+	writeProjectTestFile(t, indexPath, `{"id":1,"type":"vertex","label":"metaData","projectRoot":"file://`+filepath.ToSlash(root)+`"}`+"\n")
+
+	index, err := LoadPipelineIndex(indexPath, AnalysisSettings{})
+	if err != nil {
+		t.Fatalf("LoadPipelineIndex returned error: %v", err)
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		sourcePath := filepath.Join(root, name)
+		writeProjectTestFile(t, sourcePath, "package main\n")
+		// The configured path does not exist, so only the shared index can answer.
+		pipeline, err := NewPipelineWithOptions(&Config{
+			SrcPath:      sourcePath,
+			AbsSrcPath:   sourcePath,
+			AbsIndexPath: filepath.Join(root, "missing.lsif"),
+			Format:       "markdown",
+		}, PipelineOptions{Index: index})
+		if err != nil {
+			t.Fatalf("NewPipelineWithOptions returned error: %v", err)
+		}
+		wrapper, ok := pipeline.analyzers[0].(*LSIFWrapper)
+		if !ok || wrapper.inner != index.LSIF || wrapper.sourcePath != sourcePath {
+			t.Fatalf("analyzer = %#v, want the shared LSIF analyzer for %s", pipeline.analyzers[0], name)
+		}
+	}
+}
+
+type testTreeAnalyzer struct {
+	trees chan *internal.SourceTree
+}
+
+func (a testTreeAnalyzer) Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error) {
+	return nil, nil
+}
+
+func (a testTreeAnalyzer) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	a.trees <- tree
+	return nil, nil
+}
+
+func TestPipelineAnalyzeSharesOneParseWithTreeAnalyzers(t *testing.T) {
+	trees := make(chan *internal.SourceTree, 2)
+	pipeline := &Pipeline{
+		cfg:       &Config{Lang: "go"},
+		analyzers: []TokenAnalyzer{testTreeAnalyzer{trees: trees}, testTreeAnalyzer{trees: trees}},
+	}
+	if _, _, err := pipeline.analyze(context.Background(), []byte("package main\n")); err != nil {
+		t.Fatalf("analyze returned error: %v", err)
+	}
+	close(trees)
+
+	var got []*internal.SourceTree
+	for tree := range trees {
+		got = append(got, tree)
+	}
+	if len(got) != 2 {
+		t.Fatalf("tree analyzers called %d times, want 2", len(got))
+	}
+	if got[0] == got[1] {
+		t.Fatal("tree analyzers share one tree instead of separate clones")
+	}
+	for _, tree := range got {
+		if tree.Language != "go" || string(tree.Content) != "package main\n" {
+			t.Fatalf("tree = %#v, want parsed go source", tree)
+		}
+	}
+}
+
+type testStreamAnalyzer struct {
+	tokens []internal.TokenInfo
+}
+
+func (a testStreamAnalyzer) Analyze(ctx context.Context, content []byte) ([]internal.TokenInfo, error) {
+	return a.tokens, nil
+}
+
+func TestPipelineRunDebugTokensWritesEveryStream(t *testing.T) {
+	root := t.TempDir()
+	sourcePath := filepath.Join(root, "main.go")
+	outPath := filepath.Join(root, "main.go.tokens.html")
+	writeProjectTestFile(t, sourcePath, "package main\n")
+
+	token := func(source internal.TokenSource, class string, end int32) internal.TokenInfo {
+		return internal.TokenInfo{
+			Span:           scip.Range{End: scip.Position{Character: end}},
+			HighlightClass: class,
+			Source:         source,
+		}
+	}
+	pipeline := &Pipeline{
+		cfg: &Config{SrcPath: "main.go", AbsSrcPath: sourcePath},
+		analyzers: []TokenAnalyzer{
+			testStreamAnalyzer{tokens: []internal.TokenInfo{token(internal.TokenSourceSyntax, "keyword", 7)}},
+			testStreamAnalyzer{tokens: []internal.TokenInfo{token(internal.TokenSourceLSP, "namespace", 12)}},
+			testStreamAnalyzer{},
+		},
+	}
+	if err := pipeline.RunDebugTokens(PipelineRunOptions{OutputPath: outPath}); err != nil {
+		t.Fatalf("RunDebugTokens returned error: %v", err)
+	}
+
+	output, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read token inspector: %v", err)
+	}
+	for _, want := range []string{
+		`data-stream="0" checked> syntax <span class="count">1</span>`,
+		`data-stream="1" checked> lsp <span class="count">1</span>`,
+		`data-stream="2" checked> analyzer 3 <span class="count">0</span>`,
+		`data-stream="3" checked> merged <span class="count">2</span>`,
+		`<span class="analyzer">merged</span> <span class="sources">syntax <strong>lsp</strong></span>`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("token inspector does not contain %q", want)
+		}
+	}
+}
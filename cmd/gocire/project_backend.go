@@ -25,6 +25,17 @@ type ProjectFileExport struct {
 	File     project.SourceFile
 	Page     SitePage
 	Pipeline *Pipeline
+	// Includes resolves the page's gocire:include directives; nil leaves them
+	// in the code as written.
+	Includes *projectCodeIncludes
+	// ProseSymbols holds the project definitions narrative prose may link to.
+	ProseSymbols *internal.ProseSymbolTable
+}
+
+// Analyze analyzes the page's file, sharing the analysis with the pages that
+// include it.
+func (req ProjectFileExport) Analyze(ctx context.Context, manifest internal.SourceRouteManifest) (*PipelineAnalysis, error) {
+	return req.Includes.Analyze(ctx, req.File, req.Pipeline, manifest)
 }
 
 func NewProjectBackend(format string, plan *ProjectExportPlan) (ProjectBackend, error) {
@@ -58,10 +69,17 @@ func (b *documentProjectBackend) ExportFile(ctx context.Context, req ProjectFile
 		return err
 	}
 
-	return req.Pipeline.RunFile(PipelineRunOptions{
+	analysis, err := req.Analyze(ctx, b.plan.Site.Routes)
+	if err != nil {
+		return err
+	}
+	return req.Pipeline.WriteFile(analysis, PipelineRunOptions{
 		Context:    ctx,
 		Manifest:   &b.plan.Site.Routes,
 		OutputPath: outPath,
+		Includes: func(sourceLines []string) map[int]internal.IncludedCode {
+			return req.Includes.Resolve(ctx, req.File, sourceLines, b.plan.Site.Routes)
+		},
 	})
 }
 
@@ -76,16 +94,20 @@ type astroProjectBackend struct {
 }
 
 type astroGeneratedPage struct {
-	Route      string
-	Href       string
-	Module     string
-	Kind       project.PageKind
-	Title      string
-	Date       string
-	Tags       []string
-	Author     string
-	Language   string
-	SourcePath string
+	Route       string
+	Href        string
+	Module      string
+	Kind        project.PageKind
+	Title       string
+	Date        string
+	Tags        []string
+	Author      string
+	Language    string
+	SourcePath  string
+	Description string
+	Draft       bool
+	Order       *int
+	Docs        []internal.CommentInfo
 }
 
 func (b *astroProjectBackend) Prepare(ctx context.Context, plan *ProjectExportPlan) error {
@@ -98,10 +120,7 @@ func (b *astroProjectBackend) Prepare(ctx context.Context, plan *ProjectExportPl
 
 func (b *astroProjectBackend) ExportFile(ctx context.Context, req ProjectFileExport) error {
 	linkManifest := astroLinkManifest(b.plan.Site.Routes)
-	analysis, err := req.Pipeline.AnalyzeFile(PipelineRunOptions{
-		Context:  ctx,
-		Manifest: &linkManifest,
-	})
+	analysis, err := req.Analyze(ctx, linkManifest)
 	if err != nil {
 		return err
 	}
@@ -113,7 +132,16 @@ func (b *astroProjectBackend) ExportFile(ctx context.Context, req ProjectFileExp
 		return err
 	}
 
+	renderMode := astroRenderModeForKind(req.Page.Kind)
+	var includes map[int]internal.IncludedCode
+	var symbols *internal.ProseLinker
+	if renderMode == internal.AstroRenderModeNarrative {
+		includes = req.Includes.Resolve(ctx, req.File, analysis.SourceLines, linkManifest)
+		symbols = astroProseLinker(req, analysis, linkManifest)
+	}
+
 	gen := internal.NewAstroGenerator(analysis.SourceLines)
+	gen.Markdown = b.plan.Settings.Markdown
 	output := gen.GenerateAstro(analysis.Tokens, analysis.Comments, internal.AstroPageOptions{
 		Title:          req.Page.Title,
 		Kind:           string(req.Page.Kind),
@@ -122,9 +150,15 @@ func (b *astroProjectBackend) ExportFile(ctx context.Context, req ProjectFileExp
 		Date:           req.Page.Date,
 		Tags:           req.Page.Tags,
 		Author:         req.Page.Author,
-		RenderMode:     astroRenderModeForKind(req.Page.Kind),
+		Description:    req.Page.Description,
+		RenderMode:     renderMode,
 		CodePageImport: astroCodePageImportForGeneratedRoute(route),
+		Includes:       includes,
+		Symbols:        symbols,
 	})
+	for _, warning := range symbols.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s: prose link not resolved: %s\n", req.File.RelPath, warning.String())
+	}
 
 	if err := writeOutputFile(outPath, output); err != nil {
 		return err
@@ -132,22 +166,37 @@ func (b *astroProjectBackend) ExportFile(ctx context.Context, req ProjectFileExp
 
 	b.mu.Lock()
 	b.pages = append(b.pages, astroGeneratedPage{
-		Route:      strings.TrimLeft(route, "/"),
-		Href:       req.Page.Href,
-		Module:     modulePath,
-		Kind:       req.Page.Kind,
-		Title:      req.Page.Title,
-		Date:       req.Page.Date,
-		Tags:       cloneAstroSiteDataStrings(req.Page.Tags),
-		Author:     req.Page.Author,
-		Language:   req.Page.Language,
-		SourcePath: req.Page.SourcePath,
+		Route:       strings.TrimLeft(route, "/"),
+		Href:        req.Page.Href,
+		Module:      modulePath,
+		Kind:        req.Page.Kind,
+		Title:       req.Page.Title,
+		Date:        req.Page.Date,
+		Tags:        cloneAstroSiteDataStrings(req.Page.Tags),
+		Author:      req.Page.Author,
+		Language:    req.Page.Language,
+		SourcePath:  req.Page.SourcePath,
+		Description: req.Page.Description,
+		Draft:       req.Page.Draft,
+		Order:       req.Page.Order,
+		Docs:        structuredDocComments(analysis.Comments),
 	})
 	b.mu.Unlock()
 
 	return nil
 }
 
+// astroProseLinker collects the symbols a narrative page's prose may link to:
+// the project's tags definitions, then the page's own analyzed code.
+func astroProseLinker(req ProjectFileExport, analysis *PipelineAnalysis, manifest internal.SourceRouteManifest) *internal.ProseLinker {
+	linker := internal.NewProseLinker()
+	if req.ProseSymbols != nil {
+		linker = req.ProseSymbols.Linker(req.File.AbsPath, manifest)
+	}
+	linker.AddTokens(analysis.SourceLines, analysis.Tokens)
+	return linker
+}
+
 func (b *astroProjectBackend) Finish(ctx context.Context) error {
 	b.mu.Lock()
 	pages := append([]astroGeneratedPage(nil), b.pages...)
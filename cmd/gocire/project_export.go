@@ -21,11 +21,18 @@ type ProjectExportPlan struct {
 	Config *projectconfig.ProjectConfig
 	Files  []project.SourceFile
 	Site   SiteModel
+	// Settings are shared by every file's pipeline; Run closes them.
+	Settings AnalysisSettings
 }
 
 type ProjectExportRunner struct {
 	cfg  *Config
 	plan *ProjectExportPlan
+	// proseSymbols is built from the tags index once per Run.
+	proseSymbols *internal.ProseSymbolTable
+	// index is the SCIP index or LSIF dump loaded once per Run and shared by
+	// every file's pipeline, along with its hover cache.
+	index *PipelineIndex
 }
 
 type projectLSPSessionKey struct {
@@ -36,6 +43,7 @@ type projectLSPSessionKey struct {
 type projectLSPAnalyzerProvider struct {
 	ctx           context.Context
 	workspaceRoot string
+	queries       *internal.HighlightQueries
 	sessions      map[projectLSPSessionKey]*internal.LSPSession
 	mu            sync.Mutex
 }
@@ -51,7 +59,7 @@ func NewProjectExportRunner(cfg *Config) (*ProjectExportRunner, error) {
 	}
 	if runnerCfg.AbsIndexPath != "" {
 		if _, err := os.Stat(runnerCfg.AbsIndexPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Load SCIP index file failed: %v. SCIP analysis will be skipped.\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: Load index file failed: %v. Index analysis will be skipped.\n", err)
 			runnerCfg.IndexPath = ""
 			runnerCfg.AbsIndexPath = ""
 		}
@@ -91,15 +99,22 @@ func NewProjectExportPlan(cfg *Config) (*ProjectExportPlan, error) {
 		return nil, err
 	}
 
-	site, err := BuildSiteModel(*projectCfg, files)
+	settings, err := loadAnalysisSettings(projectCfg)
 	if err != nil {
 		return nil, err
 	}
 
+	site, err := BuildSiteModelWithDirectives(*projectCfg, files, settings.CommentDirectives)
+	if err != nil {
+		settings.Close()
+		return nil, err
+	}
+
 	return &ProjectExportPlan{
-		Config: projectCfg,
-		Files:  files,
-		Site:   site,
+		Config:   projectCfg,
+		Files:    files,
+		Site:     site,
+		Settings: settings,
 	}, nil
 }
 
@@ -108,7 +123,12 @@ func SourceRouteManifestForProject(cfg projectconfig.ProjectConfig, files []proj
 	for _, file := range files {
 		sourcePaths = append(sourcePaths, file.AbsPath)
 	}
-	return internal.NewSourceRouteManifestWithPrefix(cfg.Project.Root, cfg.Source.RoutePrefix, sourcePaths)
+	manifest, err := internal.NewSourceRouteManifestWithPrefix(cfg.Project.Root, cfg.Source.RoutePrefix, sourcePaths)
+	if err != nil {
+		return internal.SourceRouteManifest{}, err
+	}
+	manifest.External = internal.NewExternalLinkResolver(cfg.Links.External)
+	return manifest, nil
 }
 
 func (r *ProjectExportRunner) Run(ctx context.Context) error {
@@ -116,6 +136,8 @@ func (r *ProjectExportRunner) Run(ctx context.Context) error {
 		ctx = context.Background()
 	}
 
+	defer r.plan.Settings.Close()
+
 	fmt.Printf("Project root: %s\n", r.plan.Config.Project.Root)
 	fmt.Printf("Project files: %d\n", len(r.plan.Files))
 
@@ -136,13 +158,27 @@ func (r *ProjectExportRunner) Run(ctx context.Context) error {
 		return err
 	}
 
+	if r.cfg.AbsIndexPath != "" {
+		index, err := LoadPipelineIndex(r.cfg.AbsIndexPath, r.plan.Settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v. Index analysis will be skipped.\n", err)
+			r.cfg.IndexPath = ""
+			r.cfg.AbsIndexPath = ""
+		}
+		r.index = index
+	}
+
+	tagsIndex := r.buildTagsIndex()
+	r.proseSymbols = internal.NewProseSymbolTable(tagsIndex)
+	includes := r.newProjectCodeIncludes(lspFactory, tagsIndex)
+
 	g, runCtx := errgroup.WithContext(ctx)
 	g.SetLimit(r.cfg.Jobs)
 
 	for _, file := range r.plan.Files {
 		file := file
 		g.Go(func() error {
-			return r.exportFile(runCtx, file, lspFactory, backend)
+			return r.exportFile(runCtx, file, lspFactory, tagsIndex, includes, backend)
 		})
 	}
 
@@ -163,7 +199,24 @@ func (r *ProjectExportRunner) Run(ctx context.Context) error {
 	return nil
 }
 
-func (r *ProjectExportRunner) exportFile(ctx context.Context, file project.SourceFile, lspFactory LSPAnalyzerFactory, backend ProjectBackend) error {
+// buildTagsIndex collects tree-sitter tags definitions from every project file
+// once, before files are exported in parallel. Files that fail to read or parse
+// only lose their definitions.
+func (r *ProjectExportRunner) buildTagsIndex() *internal.TagsIndex {
+	index := internal.NewTagsIndex()
+	for _, file := range r.plan.Files {
+		content, err := os.ReadFile(file.AbsPath)
+		if err == nil {
+			err = index.AddFile(file.Language, file.AbsPath, content)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: tags index skipped %s: %v\n", file.RelPath, err)
+		}
+	}
+	return index
+}
+
+func (r *ProjectExportRunner) exportFile(ctx context.Context, file project.SourceFile, lspFactory LSPAnalyzerFactory, tagsIndex *internal.TagsIndex, includes *projectCodeIncludes, backend ProjectBackend) error {
 	page, ok := r.plan.Site.PageForFile(file)
 	if !ok {
 		return fmt.Errorf("%s: site page not found", file.RelPath)
@@ -173,15 +226,20 @@ func (r *ProjectExportRunner) exportFile(ctx context.Context, file project.Sourc
 	pipeline, err := NewPipelineWithOptions(fileCfg, PipelineOptions{
 		Context:            ctx,
 		LSPAnalyzerFactory: lspFactory,
+		TagsIndex:          tagsIndex,
+		Settings:           r.plan.Settings,
+		Index:              r.index,
 	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", file.RelPath, err)
 	}
 
 	if err := backend.ExportFile(ctx, ProjectFileExport{
-		File:     file,
-		Page:     page,
-		Pipeline: pipeline,
+		File:         file,
+		Page:         page,
+		Pipeline:     pipeline,
+		Includes:     includes,
+		ProseSymbols: r.proseSymbols,
 	}); err != nil {
 		return fmt.Errorf("%s: %w", file.RelPath, err)
 	}
@@ -205,6 +263,9 @@ func (r *ProjectExportRunner) useLSPForProjectFile(file project.SourceFile) bool
 	if !r.cfg.UseLSP {
 		return false
 	}
+	if cfg, err := languages.GetConfig(file.Language); err != nil || cfg.LSPCommand == "" {
+		return false
+	}
 	if r.cfg.Lang == "" {
 		return true
 	}
@@ -237,6 +298,7 @@ func (r *ProjectExportRunner) projectLSPAnalyzerFactory(ctx context.Context) (LS
 	provider := &projectLSPAnalyzerProvider{
 		ctx:           ctx,
 		workspaceRoot: workspaceRoot,
+		queries:       r.plan.Settings.HighlightQueries,
 		sessions:      make(map[projectLSPSessionKey]*internal.LSPSession),
 	}
 	return provider.AnalyzerFor, provider.Close, nil
@@ -278,6 +340,7 @@ func (p *projectLSPAnalyzerProvider) session(ctx context.Context, language, work
 	if err != nil {
 		return nil, err
 	}
+	session.Queries = p.queries
 	p.sessions[key] = session
 	return session, nil
 }
@@ -313,6 +376,15 @@ func (a *ProjectLSPAnalyzer) Analyze(ctx context.Context, content []byte) ([]int
 	return a.session.AnalyzeFile(a.sourcePath, content)
 }
 
+func (a *ProjectLSPAnalyzer) AnalyzeTree(ctx context.Context, tree *internal.SourceTree) ([]internal.TokenInfo, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return a.session.AnalyzeFileTree(a.sourcePath, tree)
+}
+
 func ProjectOutputPath(outputDir string, manifest internal.SourceRouteManifest, file project.SourceFile, format string) (string, error) {
 	if route, _, ok := manifest.RouteForSourcePath(file.AbsPath); ok {
 		return outputPathForRoute(outputDir, route, format)
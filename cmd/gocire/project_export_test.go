@@ -522,3 +522,228 @@ func writeProjectTestFile(t *testing.T, path string, contents string) {
 		t.Fatalf("WriteFile(%q): %v", path, err)
 	}
 }
+
+func TestProjectExportRunnerLinksReferencesThroughTagsIndex(t *testing.T) {
+	root := t.TempDir()
+	writeProjectTestFile(t, filepath.Join(root, "repo", "main.go"), "package main\n\nfunc main() { pkg.Util() }\n")
+	writeProjectTestFile(t, filepath.Join(root, "repo", "pkg", "util.go"), "package pkg\n\nfunc Util() {}\n")
+
+	configPath := filepath.Join(root, ".gocire.yml")
+	writeProjectTestFile(t, configPath, `
+project:
+  root: repo
+source:
+  include:
+    - "**/*.go"
+output:
+  dir: site
+`)
+
+	runner, err := NewProjectExportRunner(&Config{
+		Project:    true,
+		ConfigPath: configPath,
+		Jobs:       2,
+		Format:     "markdown",
+	})
+	if err != nil {
+		t.Fatalf("NewProjectExportRunner returned error: %v", err)
+	}
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "site", "_source", "main.go.md"))
+	if err != nil {
+		t.Fatalf("read main output: %v", err)
+	}
+	if want := `<a href="/_source/pkg/util.go.html#L3C6"`; !strings.Contains(string(content), want) {
+		t.Fatalf("main output does not link Util to its definition:\n%s", content)
+	}
+}
+
+func TestProjectExportRunnerTranscludesIncludedCode(t *testing.T) {
+	root := t.TempDir()
+	writeProjectTestFile(t, filepath.Join(root, "repo", "docs", "guide.go"), strings.Join([]string{
+		"// Util does nothing.",
+		"// gocire:include pkg/util.go#Util",
+		"//",
+		"// The package clause:",
+		"// gocire:include pkg/util.go#L1",
+		"// gocire:include pkg/util.go#Missing",
+		"package docs",
+		"",
+	}, "\n"))
+	writeProjectTestFile(t, filepath.Join(root, "repo", "pkg", "util.go"), "package pkg\n\nfunc Util() { helper() }\n\nfunc helper() {}\n")
+
+	configPath := filepath.Join(root, ".gocire.yml")
+	writeProjectTestFile(t, configPath, `
+project:
+  root: repo
+source:
+  include:
+    - "**/*.go"
+output:
+  dir: site
+`)
+
+	runner, err := NewProjectExportRunner(&Config{
+		Project:    true,
+		ConfigPath: configPath,
+		Jobs:       2,
+		Format:     "mdx",
+	})
+	if err != nil {
+		t.Fatalf("NewProjectExportRunner returned error: %v", err)
+	}
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "site", "_source", "docs", "guide.go.mdx"))
+	if err != nil {
+		t.Fatalf("read guide output: %v", err)
+	}
+	output := string(content)
+	for _, want := range []string{
+		`<figure className="cire-include"><figcaption><a href="/_source/pkg/util.go.html#L3C6">{` + "`pkg/util.go#Util`" + `}</a></figcaption>`,
+		`<a href="/_source/pkg/util.go.html#L5C6"`,
+		`<figcaption><a href="/_source/pkg/util.go.html">{` + "`pkg/util.go#L1`" + `}</a></figcaption>`,
+		`gocire:include\ pkg/util.go#Missing`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("guide output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "func helper") || strings.Contains(output, `id="L3C6"`) {
+		t.Fatalf("included code should hold only Util and no anchors of its own:\n%s", output)
+	}
+}
+
+func TestProjectCodeIncludesShareIncludedFileAnalysisWithItsPage(t *testing.T) {
+	root := t.TempDir()
+	writeProjectTestFile(t, filepath.Join(root, "repo", "docs", "guide.go"), "// gocire:include pkg/util.go#Util\npackage docs\n")
+	writeProjectTestFile(t, filepath.Join(root, "repo", "pkg", "util.go"), "package pkg\n\nfunc Util() {}\n")
+
+	configPath := filepath.Join(root, ".gocire.yml")
+	writeProjectTestFile(t, configPath, `
+project:
+  root: repo
+source:
+  include:
+    - "**/*.go"
+output:
+  dir: site
+`)
+
+	runner, err := NewProjectExportRunner(&Config{
+		Project:    true,
+		ConfigPath: configPath,
+		Jobs:       1,
+		Format:     "mdx",
+	})
+	if err != nil {
+		t.Fatalf("NewProjectExportRunner returned error: %v", err)
+	}
+	includes := runner.newProjectCodeIncludes(nil, runner.buildTagsIndex())
+	files := make(map[string]project.SourceFile)
+	for _, file := range runner.plan.Files {
+		files[file.RelPath] = file
+	}
+	util, guide := files["pkg/util.go"], files["docs/guide.go"]
+	if !includes.targets[util.AbsPath] || includes.targets[guide.AbsPath] {
+		t.Fatalf("include targets = %v, want only pkg/util.go", includes.targets)
+	}
+
+	ctx := context.Background()
+	manifest := runner.plan.Site.Routes
+	pipeline, err := NewPipelineWithOptions(runner.pipelineConfigForProjectFile(util, ""), PipelineOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("NewPipelineWithOptions returned error: %v", err)
+	}
+	page, err := includes.Analyze(ctx, util, pipeline, manifest)
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	included, err := includes.Analyze(ctx, util, nil, manifest)
+	if err != nil || included != page {
+		t.Fatalf("include analysis = %p, %v, want the page's analysis %p", included, err, page)
+	}
+
+	first, _ := includes.Analyze(ctx, guide, nil, manifest)
+	second, _ := includes.Analyze(ctx, guide, nil, manifest)
+	if first == nil || first == second {
+		t.Fatal("analyses of a file no page includes were kept")
+	}
+}
+
+func TestProjectExportRunnerLinksSymbolsInAstroProse(t *testing.T) {
+	root := t.TempDir()
+	writeProjectTestFile(t, filepath.Join(root, "repo", "docs", "guide.go"), strings.Join([]string{
+		"// Call `pkg.Util` once; [`Missing`] is gone.",
+		"package docs",
+		"",
+	}, "\n"))
+	writeProjectTestFile(t, filepath.Join(root, "repo", "pkg", "util.go"), "package pkg\n\nfunc Util() {}\n")
+
+	configPath := filepath.Join(root, ".gocire.yml")
+	writeProjectTestFile(t, configPath, `
+content:
+  docs: repo/docs
+project:
+  root: repo
+source:
+  include:
+    - "**/*.go"
+output:
+  dir: site
+`)
+
+	runner, err := NewProjectExportRunner(&Config{
+		Project:    true,
+		ConfigPath: configPath,
+		Jobs:       1,
+		Format:     "astro",
+	})
+	if err != nil {
+		t.Fatalf("NewProjectExportRunner returned error: %v", err)
+	}
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "site", "src", "generated", "pages", "_source", "docs", "guide.go.html.astro"))
+	if err != nil {
+		t.Fatalf("read guide output: %v", err)
+	}
+	output := string(content)
+	for _, want := range []string{
+		`<a href="/_source/pkg/util.go.html/#L3C6" class="cire-symbol-link"><code>pkg.Util</code></a>`,
+		"[<code>Missing</code>]",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("guide output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestNewProjectExportPlanRejectsInvalidHighlightQueries(t *testing.T) {
+	root := t.TempDir()
+	writeProjectTestFile(t, filepath.Join(root, "repo", "main.go"), "package main\n")
+	writeProjectTestFile(t, filepath.Join(root, "queries", "go.scm"), "; inherits: go\n(no_such_node) @function.macro\n")
+
+	configPath := filepath.Join(root, ".gocire.yml")
+	writeProjectTestFile(t, configPath, `
+project:
+  root: repo
+highlight:
+  queryDir: queries
+`)
+
+	_, err := NewProjectExportPlan(&Config{ConfigPath: configPath})
+	if err == nil {
+		t.Fatal("NewProjectExportPlan returned nil error for an invalid highlight query")
+	}
+	if want := filepath.Join(root, "queries", "go.scm") + ":2:2: invalid node type no_such_node"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Eric-Song-Nop/gocire/internal"
+	projectconfig "github.com/Eric-Song-Nop/gocire/internal/config"
+	"github.com/Eric-Song-Nop/gocire/internal/project"
+	"gopkg.in/yaml.v3"
+)
+
+// sitePageFrontMatter is the YAML front matter of a page's leading comment:
+//
+//	// ---
+//	// title: Getting started
+//	// tags: [guide]
+//	// ---
+type sitePageFrontMatter struct {
+	Title       string   `yaml:"title"`
+	Date        string   `yaml:"date"`
+	Tags        []string `yaml:"tags"`
+	Author      string   `yaml:"author"`
+	Description string   `yaml:"description"`
+	Draft       bool     `yaml:"draft"`
+	Order       *int     `yaml:"order"`
+	Slug        string   `yaml:"slug"`
+}
+
+var siteSlugPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// siteFrontMatterForFiles reads the front matter of every file that has one,
+// keyed by cleaned absolute path. Each file is read once per site build, and
+// only files with a `---` fence are handed to the comment analyzer, which
+// leaves the project's comment directives out of the front matter comment.
+func siteFrontMatterForFiles(files []project.SourceFile, directives *internal.CommentDirectives) (map[string]sitePageFrontMatter, error) {
+	frontMatter := make(map[string]sitePageFrontMatter)
+	for _, file := range files {
+		matter, ok, err := readSitePageFrontMatter(file, directives)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			frontMatter[cleanSiteAbsPath(file.AbsPath)] = matter
+		}
+	}
+	return frontMatter, nil
+}
+
+func readSitePageFrontMatter(file project.SourceFile, directives *internal.CommentDirectives) (sitePageFrontMatter, bool, error) {
+	if strings.TrimSpace(file.AbsPath) == "" {
+		return sitePageFrontMatter{}, false, nil
+	}
+	data, err := os.ReadFile(file.AbsPath)
+	if err != nil {
+		return sitePageFrontMatter{}, false, nil
+	}
+	const maxFrontMatterScanBytes = 64 * 1024
+	if len(data) > maxFrontMatterScanBytes {
+		data = data[:maxFrontMatterScanBytes]
+	}
+	if !bytes.Contains(data, []byte("---")) {
+		return sitePageFrontMatter{}, false, nil
+	}
+	// Files the comment analyzer cannot read have no front matter to offer.
+	analyzer := internal.NewCommentAnalyzer(file.Language)
+	analyzer.Directives = directives
+	comments, err := analyzer.Analyze(data)
+	if err != nil {
+		return sitePageFrontMatter{}, false, nil
+	}
+	for _, comment := range comments {
+		if comment.FrontMatter != "" {
+			return parseSitePageFrontMatter(file.RelPath, comment.FrontMatter)
+		}
+	}
+	return sitePageFrontMatter{}, false, nil
+}
+
+// parseSitePageFrontMatter decodes and checks a page's front matter. YAML
+// that does not parse only warns, leaving the page its inferred metadata;
+// a bad date or slug is an error.
+func parseSitePageFrontMatter(sourcePath string, frontMatter string) (sitePageFrontMatter, bool, error) {
+	var matter sitePageFrontMatter
+	if err := yaml.Unmarshal([]byte(frontMatter), &matter); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: parse front matter in %s failed: %v. The page keeps its inferred metadata.\n", sourcePath, err)
+		return sitePageFrontMatter{}, false, nil
+	}
+
+	matter.Title = strings.TrimSpace(matter.Title)
+	matter.Date = strings.TrimSpace(matter.Date)
+	matter.Tags = normalizeSiteStrings(matter.Tags)
+	matter.Author = strings.TrimSpace(matter.Author)
+	matter.Description = strings.TrimSpace(matter.Description)
+	matter.Slug = strings.TrimSpace(matter.Slug)
+
+	if matter.Date != "" && validSiteDate(matter.Date) != matter.Date {
+		return sitePageFrontMatter{}, false, fmt.Errorf("front matter in %s: date must be a real YYYY-MM-DD date", sourcePath)
+	}
+	if matter.Slug != "" && !siteSlugPattern.MatchString(matter.Slug) {
+		return sitePageFrontMatter{}, false, fmt.Errorf("front matter in %s: slug %q must be a single path segment of letters, digits, dots, dashes and underscores", sourcePath, matter.Slug)
+	}
+	return matter, true, nil
+}
+
+// siteRoutesForProject builds the routes of files with the front matter slugs
+// of pages applied, and returns the front matter it read for page metadata.
+// Site builds read every file's front matter; a single-file export only needs
+// its own page's.
+func siteRoutesForProject(cfg projectconfig.ProjectConfig, files []project.SourceFile, pages []project.SourceFile, directives *internal.CommentDirectives) (internal.SourceRouteManifest, map[string]sitePageFrontMatter, error) {
+	frontMatter, err := siteFrontMatterForFiles(pages, directives)
+	if err != nil {
+		return internal.SourceRouteManifest{}, nil, err
+	}
+	routes, err := SourceRouteManifestForProject(cfg, files)
+	if err != nil {
+		return internal.SourceRouteManifest{}, nil, err
+	}
+	if err := applySiteFrontMatterSlugs(&routes, files, frontMatter); err != nil {
+		return internal.SourceRouteManifest{}, nil, err
+	}
+	return routes, frontMatter, nil
+}
+
+// applySiteFrontMatterSlugs renames the last segment of the routes whose
+// front matter sets a slug, keeping the directory the source lives in.
+func applySiteFrontMatterSlugs(routes *internal.SourceRouteManifest, files []project.SourceFile, frontMatter map[string]sitePageFrontMatter) error {
+	for _, file := range files {
+		matter, ok := frontMatter[cleanSiteAbsPath(file.AbsPath)]
+		if !ok || matter.Slug == "" {
+			continue
+		}
+		relPath, ok := routes.RelPathForSourcePath(file.AbsPath)
+		if !ok {
+			continue
+		}
+		route, ok := routes.Routes[relPath]
+		if !ok {
+			continue
+		}
+		routes.Routes[relPath] = path.Join(path.Dir(route), matter.Slug+".html")
+	}
+
+	sourceForRoute := make(map[string]string, len(routes.Routes))
+	for relPath, route := range routes.Routes {
+		if existing, ok := sourceForRoute[route]; ok {
+			first, second := existing, relPath
+			if second < first {
+				first, second = second, first
+			}
+			return fmt.Errorf("front matter slug gives %s and %s the same route %s", first, second, route)
+		}
+		sourceForRoute[route] = relPath
+	}
+	return nil
+}
@@ -31,6 +31,11 @@ type SitePage struct {
 	Kind       project.PageKind
 	Language   string
 	SourcePath string
+	// Description, Draft and Order come from front matter only. Drafts are
+	// built but left out of navigation; Order sorts docs navigation.
+	Description string
+	Draft       bool
+	Order       *int
 }
 
 type SiteNavigation struct {
@@ -62,7 +67,13 @@ const (
 )
 
 func BuildSiteModel(cfg projectconfig.ProjectConfig, files []project.SourceFile) (SiteModel, error) {
-	routes, err := SourceRouteManifestForProject(cfg, files)
+	return BuildSiteModelWithDirectives(cfg, files, nil)
+}
+
+// BuildSiteModelWithDirectives is BuildSiteModel for a project with its own
+// comment directives, which front matter comments are read around.
+func BuildSiteModelWithDirectives(cfg projectconfig.ProjectConfig, files []project.SourceFile, directives *internal.CommentDirectives) (SiteModel, error) {
+	routes, frontMatter, err := siteRoutesForProject(cfg, files, files, directives)
 	if err != nil {
 		return SiteModel{}, err
 	}
@@ -71,7 +82,7 @@ func BuildSiteModel(cfg projectconfig.ProjectConfig, files []project.SourceFile)
 	if err != nil {
 		return SiteModel{}, err
 	}
-	pages = mergeSitePageMetadata(cfg, pages)
+	pages = mergeSitePageMetadata(cfg, pages, frontMatter)
 
 	navigation := SiteNavigationForPages(cfg, pages)
 	return SiteModel{
@@ -152,7 +163,9 @@ type sitePageMetadata struct {
 	Author string
 }
 
-func mergeSitePageMetadata(cfg projectconfig.ProjectConfig, pages []SitePage) []SitePage {
+// mergeSitePageMetadata layers page metadata: inferred titles and dates first,
+// then front matter, then content.metadata from the config.
+func mergeSitePageMetadata(cfg projectconfig.ProjectConfig, pages []SitePage, frontMatter map[string]sitePageFrontMatter) []SitePage {
 	docsPrefix := siteContentPrefix(cfg.Project.Root, cfg.Content.Docs, "docs")
 	blogPrefix := siteContentPrefix(cfg.Project.Root, cfg.Content.Blogs, "blogs")
 	metadataByPath := siteConfiguredMetadataByPath(cfg)
@@ -164,6 +177,20 @@ func mergeSitePageMetadata(cfg projectconfig.ProjectConfig, pages []SitePage) []
 		page.Tags = []string{}
 		page.Author = ""
 
+		if matter, ok := frontMatter[cleanSiteAbsPath(page.File.AbsPath)]; ok {
+			if matter.Title != "" {
+				page.Title = matter.Title
+			}
+			if matter.Date != "" {
+				page.Date = matter.Date
+			}
+			page.Tags = cloneSiteStrings(matter.Tags)
+			page.Author = matter.Author
+			page.Description = matter.Description
+			page.Draft = matter.Draft
+			page.Order = matter.Order
+		}
+
 		metadata, ok := siteConfiguredMetadataForPage(metadataByPath, cfg.Project.Root, *page)
 		if !ok {
 			continue
@@ -174,7 +201,9 @@ func mergeSitePageMetadata(cfg projectconfig.ProjectConfig, pages []SitePage) []
 		if metadata.Date != "" {
 			page.Date = metadata.Date
 		}
-		page.Tags = cloneSiteStrings(metadata.Tags)
+		if len(metadata.Tags) > 0 {
+			page.Tags = cloneSiteStrings(metadata.Tags)
+		}
 		if metadata.Author != "" {
 			page.Author = metadata.Author
 		}
@@ -388,7 +417,11 @@ func firstStandaloneCommentH1Title(source string, language string) string {
 			if strings.HasPrefix(line, block.start) {
 				blockLines := []string{line}
 				i++
-				for !strings.Contains(blockLines[len(blockLines)-1], block.end) && i < len(lines) {
+				// The opening line only closes the block after its start delimiter,
+				// which matters when both delimiters are the same.
+				closed := strings.Contains(strings.TrimPrefix(line, block.start), block.end)
+				for !closed && i < len(lines) {
+					closed = strings.Contains(lines[i], block.end)
 					blockLines = append(blockLines, lines[i])
 					i++
 				}
@@ -416,7 +449,10 @@ type siteBlockCommentSyntax struct {
 
 func siteCommentSyntax(language string) ([]string, []siteBlockCommentSyntax) {
 	switch strings.ToLower(strings.TrimSpace(language)) {
-	case "python", "py", "ruby":
+	case "python", "py":
+		// Module docstrings are prose too.
+		return []string{"#"}, []siteBlockCommentSyntax{{start: `"""`, end: `"""`}, {start: "'''", end: "'''"}}
+	case "ruby", "shell", "yaml", "toml", "dockerfile", "makefile":
 		return []string{"#"}, nil
 	case "haskell":
 		return []string{"--"}, []siteBlockCommentSyntax{{start: "{-", end: "-}"}}
@@ -475,6 +511,15 @@ func cleanSiteAbsPath(path string) string {
 func siteDocsNavigation(pages []SitePage, docsPrefix string) SiteNavigationSection {
 	docs := filterSitePagesByKind(pages, project.PageKindDocs)
 	sort.SliceStable(docs, func(i, j int) bool {
+		// Pages with an order come first, lowest first, as Docusaurus orders
+		// sidebar_position.
+		left, right := docs[i].Order, docs[j].Order
+		if (left != nil) != (right != nil) {
+			return left != nil
+		}
+		if left != nil && *left != *right {
+			return *left < *right
+		}
 		return docs[i].SourcePath < docs[j].SourcePath
 	})
 
@@ -529,7 +574,7 @@ func siteBlogNavigation(pages []SitePage, blogPrefix string) SiteNavigationSecti
 func filterSitePagesByKind(pages []SitePage, kind project.PageKind) []SitePage {
 	filtered := make([]SitePage, 0)
 	for _, page := range pages {
-		if page.Kind == kind {
+		if page.Kind == kind && !page.Draft {
 			filtered = append(filtered, page)
 		}
 	}
@@ -4,8 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
+	"github.com/Eric-Song-Nop/gocire/internal"
 	projectconfig "github.com/Eric-Song-Nop/gocire/internal/config"
 	"github.com/Eric-Song-Nop/gocire/internal/project"
 )
@@ -136,6 +138,52 @@ Body.
 	}
 }
 
+func TestBuildSiteModelInfersNarrativeTitleFromHashComments(t *testing.T) {
+	root := t.TempDir()
+	doc := siteModelTestWriteFile(t, root, "docs/deploy.yaml", project.PageKindDocs, `# # Deploying
+# The chart values below.
+replicas: 3
+`)
+	doc.Language = "yaml"
+
+	model, err := BuildSiteModel(siteModelTestConfig(root), []project.SourceFile{doc})
+	if err != nil {
+		t.Fatalf("BuildSiteModel returned error: %v", err)
+	}
+	page, ok := model.PageForFile(doc)
+	if !ok {
+		t.Fatal("PageForFile returned ok=false for docs page")
+	}
+	if page.Title != "Deploying" {
+		t.Fatalf("docs page title = %q, want Deploying", page.Title)
+	}
+}
+
+func TestBuildSiteModelInfersNarrativeTitleFromPythonDocstring(t *testing.T) {
+	root := t.TempDir()
+	doc := siteModelTestWriteFile(t, root, "docs/usage.py", project.PageKindDocs, `#!/usr/bin/env python3
+"""
+# Usage
+
+Run the exporter.
+"""
+import os
+`)
+	doc.Language = "python"
+
+	model, err := BuildSiteModel(siteModelTestConfig(root), []project.SourceFile{doc})
+	if err != nil {
+		t.Fatalf("BuildSiteModel returned error: %v", err)
+	}
+	page, ok := model.PageForFile(doc)
+	if !ok {
+		t.Fatal("PageForFile returned ok=false for docs page")
+	}
+	if page.Title != "Usage" {
+		t.Fatalf("docs page title = %q, want Usage", page.Title)
+	}
+}
+
 func TestBuildSiteModelAppliesConfiguredMetadataOverFallbacks(t *testing.T) {
 	root := t.TempDir()
 	doc := siteModelTestWriteFile(t, root, "docs/intro.go", project.PageKindDocs, `// # Inferred Docs
@@ -253,6 +301,146 @@ package internal
 	}
 }
 
+func TestBuildSiteModelReadsFrontMatter(t *testing.T) {
+	root := t.TempDir()
+	intro := siteModelTestWriteFile(t, root, "docs/intro.go", project.PageKindDocs, `//go:build docs
+
+// ---
+// title: Start here
+// date: 2026-07-01
+// tags: [guide, basics]
+// author: Ada
+// description: How to begin.
+// order: 2
+// slug: start
+// ---
+//
+// # Ignored heading
+package docs
+`)
+	first := siteModelTestWriteFile(t, root, "docs/zeta.go", project.PageKindDocs, `// ---
+// order: 1
+// ---
+package docs
+`)
+	draft := siteModelTestWriteFile(t, root, "docs/alpha.go", project.PageKindDocs, `// ---
+// title: Not yet
+// draft: true
+// ---
+package docs
+`)
+
+	cfg := siteModelTestConfig(root)
+	cfg.Content.Metadata = map[string]projectconfig.ContentMetadata{
+		"docs/intro.go": {Author: "Grace"},
+	}
+	model, err := BuildSiteModel(cfg, []project.SourceFile{intro, first, draft})
+	if err != nil {
+		t.Fatalf("BuildSiteModel returned error: %v", err)
+	}
+
+	page, ok := model.PageForFile(intro)
+	if !ok {
+		t.Fatal("PageForFile returned ok=false for docs page")
+	}
+	if page.Title != "Start here" || page.Date != "2026-07-01" || page.Description != "How to begin." {
+		t.Fatalf("page = %#v, want front matter title, date and description", page)
+	}
+	assertSiteModelStrings(t, page.Tags, []string{"guide", "basics"})
+	if page.Author != "Grace" {
+		t.Fatalf("page author = %q, want configured metadata over front matter", page.Author)
+	}
+	if page.Route != "/_source/docs/start.html" || page.Href != "/_source/docs/start.html/" {
+		t.Fatalf("page route = %q, href = %q, want slug route", page.Route, page.Href)
+	}
+	if route, ok := model.Routes.RouteForRelPath("docs/intro.go"); !ok || route != "/_source/docs/start.html" {
+		t.Fatalf("manifest route = %q, %v, want slug route for links", route, ok)
+	}
+
+	var titles []string
+	for _, item := range model.Navigation.Docs.Items {
+		titles = append(titles, item.Title)
+	}
+	assertSiteModelStrings(t, titles, []string{"Zeta", "Start here"})
+
+	draftPage, ok := model.PageForFile(draft)
+	if !ok || !draftPage.Draft {
+		t.Fatalf("draft page = %#v, %v, want a built draft page", draftPage, ok)
+	}
+}
+
+func TestBuildSiteModelReadsFrontMatterAfterProjectDirectives(t *testing.T) {
+	root := t.TempDir()
+	doc := siteModelTestWriteFile(t, root, "docs/gen.go", project.PageKindDocs, "// @generated by make\n// ---\n// title: Generated\n// ---\npackage docs\n")
+
+	directives, err := internal.LoadCommentDirectives(map[string][]string{"go": {`^//\s*@generated\b`}})
+	if err != nil {
+		t.Fatalf("LoadCommentDirectives returned error: %v", err)
+	}
+	model, err := BuildSiteModelWithDirectives(siteModelTestConfig(root), []project.SourceFile{doc}, directives)
+	if err != nil {
+		t.Fatalf("BuildSiteModelWithDirectives returned error: %v", err)
+	}
+	if page, ok := model.PageForFile(doc); !ok || page.Title != "Generated" {
+		t.Fatalf("page = %#v, %v, want the front matter title after the directive", page, ok)
+	}
+}
+
+func TestBuildSiteModelRejectsInvalidFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "bad date",
+			files:   map[string]string{"docs/a.go": "// ---\n// date: 2026-02-30\n// ---\npackage docs\n"},
+			wantErr: "front matter in docs/a.go: date must be a real YYYY-MM-DD date",
+		},
+		{
+			name:    "bad slug",
+			files:   map[string]string{"docs/a.go": "// ---\n// slug: a/b\n// ---\npackage docs\n"},
+			wantErr: `slug "a/b" must be a single path segment`,
+		},
+		{
+			name: "slug collision",
+			files: map[string]string{
+				"docs/a.go": "// ---\n// slug: b.go\n// ---\npackage docs\n",
+				"docs/b.go": "package docs\n",
+			},
+			wantErr: "docs/a.go and docs/b.go the same route /_source/docs/b.go.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			var files []project.SourceFile
+			for relPath, content := range tt.files {
+				files = append(files, siteModelTestWriteFile(t, root, relPath, project.PageKindDocs, content))
+			}
+			_, err := BuildSiteModel(siteModelTestConfig(root), files)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("BuildSiteModel error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildSiteModelWarnsOnUnparsableFrontMatter(t *testing.T) {
+	root := t.TempDir()
+	doc := siteModelTestWriteFile(t, root, "docs/a.go", project.PageKindDocs, "// ---\n// title: Broken\n// tags: [a\n// ---\npackage docs\n")
+
+	model, err := BuildSiteModel(siteModelTestConfig(root), []project.SourceFile{doc})
+	if err != nil {
+		t.Fatalf("BuildSiteModel returned error: %v", err)
+	}
+	page, ok := model.PageForFile(doc)
+	if !ok || page.Title != "A" || page.Route != "/_source/docs/a.go.html" {
+		t.Fatalf("page = %#v, %v, want inferred metadata", page, ok)
+	}
+}
+
 func TestBuildSiteModelRejectsFileWithoutRelPath(t *testing.T) {
 	root := t.TempDir()
 	_, err := BuildSiteModel(projectconfig.ProjectConfig{
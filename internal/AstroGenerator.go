@@ -27,8 +27,15 @@ type AstroPageOptions struct {
 	Date           string
 	Tags           []string
 	Author         string
+	Description    string
 	RenderMode     AstroRenderMode
 	CodePageImport string
+	// Includes maps the line of each resolved gocire:include directive to the
+	// code it transcludes in narrative mode.
+	Includes map[int]IncludedCode
+	// Symbols links symbol names mentioned in narrative prose; nil leaves
+	// them as code.
+	Symbols *ProseLinker
 }
 
 type AstroTableOfContentsItem struct {
@@ -40,6 +47,8 @@ type AstroTableOfContentsItem struct {
 // AstroGenerator generates complete Astro pages from source analysis data.
 type AstroGenerator struct {
 	sourceLines []string
+	// Markdown turns on optional Markdown features in prose and hover cards.
+	Markdown MarkdownOptions
 }
 
 func NewAstroGenerator(sourceLines []string) *AstroGenerator {
@@ -57,6 +66,7 @@ func (g *AstroGenerator) GenerateAstro(tokens []TokenInfo, comments []CommentInf
 	date := strings.TrimSpace(opts.Date)
 	tags := normalizeAstroStringList(opts.Tags)
 	author := strings.TrimSpace(opts.Author)
+	description := strings.TrimSpace(opts.Description)
 
 	var body string
 	var toc []AstroTableOfContentsItem
@@ -83,6 +93,9 @@ func (g *AstroGenerator) GenerateAstro(tokens []TokenInfo, comments []CommentInf
 	if author != "" {
 		writeAstroAttribute(&sb, "author", author)
 	}
+	if description != "" {
+		writeAstroAttribute(&sb, "description", description)
+	}
 	if len(tags) > 0 {
 		fmt.Fprintf(&sb, " tags={%s}", astroStringArrayLiteral(tags))
 	}
@@ -195,7 +208,14 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 	tokenIdx := 0
 	commentIdx := 0
 	inCodeBlock := false
-	markdownRenderer := NewMarkdownPageRenderer()
+	markdownRenderer := NewMarkdownPageRenderer(g.Markdown)
+	markdownRenderer.LinkSymbols(opts.Symbols)
+	regions := FindCodeRegions(g.sourceLines, comments)
+	comments = proseComments(comments)
+	regionIdx := 0
+	inRegion := false
+	includeLines := codeIncludeLines(opts.Includes)
+	includeIdx := 0
 
 	nextTokenStart := func() scip.Position {
 		if tokenIdx < len(tokens) {
@@ -209,6 +229,31 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 		}
 		return scip.Position{Line: 999999, Character: 999999}
 	}
+	// nextRegionBoundary is the marker line that opens the next region, or
+	// closes the one the walk is inside.
+	nextRegionBoundary := func() scip.Position {
+		if regionIdx >= len(regions) {
+			return scip.Position{Line: 999999, Character: 999999}
+		}
+		if inRegion {
+			return regions[regionIdx].Close.Start
+		}
+		return regions[regionIdx].Open.Start
+	}
+	hiding := func() bool {
+		return inRegion && regions[regionIdx].Kind == CodeRegionHide
+	}
+	nextIncludeStart := func() scip.Position {
+		// A directive swallowed by a comment or token spanning its line is
+		// no longer a directive.
+		for includeIdx < len(includeLines) && scip.Position.Compare(scip.Position{Line: int32(includeLines[includeIdx])}, currentPos) < 0 {
+			includeIdx++
+		}
+		if includeIdx < len(includeLines) {
+			return scip.Position{Line: int32(includeLines[includeIdx]), Character: 0}
+		}
+		return scip.Position{Line: 999999, Character: 999999}
+	}
 
 	for {
 		if scip.Position.Compare(currentPos, fileEndPos) >= 0 && tokenIdx >= len(tokens) && commentIdx >= len(comments) {
@@ -217,6 +262,8 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 
 		tokenStart := nextTokenStart()
 		commentStart := nextCommentStart()
+		regionBoundary := nextRegionBoundary()
+		includeStart := nextIncludeStart()
 		gapEnd := fileEndPos
 		if scip.Position.Compare(tokenStart, gapEnd) < 0 {
 			gapEnd = tokenStart
@@ -224,17 +271,23 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 		if scip.Position.Compare(commentStart, gapEnd) < 0 {
 			gapEnd = commentStart
 		}
+		if scip.Position.Compare(regionBoundary, gapEnd) < 0 {
+			gapEnd = regionBoundary
+		}
+		if scip.Position.Compare(includeStart, gapEnd) < 0 {
+			gapEnd = includeStart
+		}
 
 		if scip.Position.Compare(currentPos, gapEnd) < 0 {
 			gapContent := getSourceFromSpan(g.sourceLines, scip.Range{Start: currentPos, End: gapEnd})
 			if !inCodeBlock {
 				gapContent = strings.TrimLeftFunc(gapContent, unicode.IsSpace)
 			}
-			if scip.Position.Compare(gapEnd, commentStart) == 0 {
+			if scip.Position.Compare(gapEnd, commentStart) == 0 || scip.Position.Compare(gapEnd, regionBoundary) == 0 || scip.Position.Compare(gapEnd, includeStart) == 0 {
 				gapContent = strings.TrimRightFunc(gapContent, unicode.IsSpace)
 			}
 
-			if gapContent != "" {
+			if gapContent != "" && !hiding() {
 				if !inCodeBlock {
 					g.openAstroCodeBlock(&sb, opts)
 					inCodeBlock = true
@@ -244,15 +297,62 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 			currentPos = gapEnd
 		}
 
-		if scip.Position.Compare(currentPos, commentStart) == 0 && scip.Position.Compare(commentStart, tokenStart) <= 0 {
+		if scip.Position.Compare(currentPos, regionBoundary) >= 0 {
+			region := regions[regionIdx]
+			if inCodeBlock {
+				g.closeAstroCodeBlock(&sb)
+				inCodeBlock = false
+			}
+			if !inRegion {
+				if region.Kind == CodeRegionCollapse {
+					sb.WriteString(`<details class="cire-region"><summary>`)
+					sb.WriteString(escapeAstroText(codeRegionSummary(region)))
+					sb.WriteString("</summary>\n")
+				}
+				currentPos = laterPosition(currentPos, region.Open.End)
+				inRegion = true
+			} else {
+				if region.Kind == CodeRegionCollapse {
+					sb.WriteString("</details>\n")
+				}
+				currentPos = laterPosition(currentPos, region.Close.End)
+				inRegion = false
+				regionIdx++
+			}
+
+			for tokenIdx < len(tokens) && scip.Position.Compare(tokens[tokenIdx].Span.End, currentPos) <= 0 {
+				tokenIdx++
+			}
+			for commentIdx < len(comments) && scip.Position.Compare(comments[commentIdx].Span.End, currentPos) <= 0 {
+				commentIdx++
+			}
+		} else if scip.Position.Compare(currentPos, includeStart) == 0 {
+			line := includeLines[includeIdx]
+			if inCodeBlock {
+				g.closeAstroCodeBlock(&sb)
+				inCodeBlock = false
+			}
+			if !hiding() {
+				g.writeAstroInclude(&sb, opts.Includes[line])
+			}
+			currentPos = scip.Position{Line: int32(line), Character: int32(len([]rune(g.sourceLines[line])))}
+			includeIdx++
+
+			for tokenIdx < len(tokens) && scip.Position.Compare(tokens[tokenIdx].Span.End, currentPos) <= 0 {
+				tokenIdx++
+			}
+		} else if scip.Position.Compare(currentPos, commentStart) == 0 && scip.Position.Compare(commentStart, tokenStart) <= 0 {
 			comment := comments[commentIdx]
 			if inCodeBlock {
 				g.closeAstroCodeBlock(&sb)
 				inCodeBlock = false
 			}
-			sb.WriteString(`<div class="cire-prose">`)
-			sb.WriteString(escapeAstroTemplate(markdownRenderer.RenderFragment(comment.Content)))
-			sb.WriteString("</div>\n")
+			// A comment that only held front matter leaves no prose.
+			if prose := comment.Prose("class"); strings.TrimSpace(prose) != "" && !hiding() {
+				sb.WriteString(`<div class="cire-prose">`)
+				sb.WriteString(escapeAstroTemplate(markdownRenderer.RenderFragment(prose)))
+				sb.WriteString("</div>\n")
+			}
 
 			currentPos = comment.Span.End
 			commentIdx++
@@ -262,6 +362,18 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 			}
 		} else if scip.Position.Compare(currentPos, tokenStart) == 0 {
 			token := tokens[tokenIdx]
+			if hiding() {
+				// Hidden code keeps its definitions' anchors so links into it
+				// still land on the page.
+				if token.Anchor != "" && token.InlayHintLabel == "" {
+					sb.WriteString(`<span`)
+					writeAstroAttribute(&sb, "id", token.Anchor)
+					sb.WriteString(` class="cire-hidden-anchor"></span>`)
+				}
+				currentPos = token.Span.End
+				tokenIdx++
+				continue
+			}
 			if !inCodeBlock {
 				g.openAstroCodeBlock(&sb, opts)
 				inCodeBlock = true
@@ -284,8 +396,14 @@ func (g *AstroGenerator) generateNarrativeAstro(tokens []TokenInfo, comments []C
 }
 
 func (g *AstroGenerator) generateAstroCode(tokens []TokenInfo) string {
+	return g.generateAstroCodeSpan(tokens, scip.Range{End: g.fileEndPosition()})
+}
+
+// generateAstroCodeSpan renders the code within span, given the tokens that
+// lie in it.
+func (g *AstroGenerator) generateAstroCodeSpan(tokens []TokenInfo, span scip.Range) string {
 	var sb strings.Builder
-	currentPos := scip.Position{Line: 0, Character: 0}
+	currentPos := span.Start
 
 	for _, token := range tokens {
 		g.outputAstroGap(currentPos, token.Span.Start, &sb)
@@ -293,10 +411,25 @@ func (g *AstroGenerator) generateAstroCode(tokens []TokenInfo) string {
 		currentPos = token.Span.End
 	}
 
-	g.outputAstroRemaining(currentPos, &sb)
+	g.outputAstroGap(currentPos, span.End, &sb)
 	return sb.String()
 }
 
+// writeAstroInclude renders transcluded code as a figure captioned with a
+// link to its source page.
+func (g *AstroGenerator) writeAstroInclude(sb *strings.Builder, include IncludedCode) {
+	sb.WriteString(`<figure class="cire-include"><figcaption><a`)
+	writeAstroAttribute(sb, "href", include.Href)
+	sb.WriteString(">")
+	sb.WriteString(escapeAstroText(include.Label))
+	sb.WriteString("</a></figcaption>")
+	included := NewAstroGenerator(include.SourceLines)
+	included.Markdown = g.Markdown
+	included.openAstroCodeBlock(sb, AstroPageOptions{Language: include.Language})
+	sb.WriteString(included.generateAstroCodeSpan(include.Tokens, include.Span))
+	sb.WriteString("</code></pre></div></figure>\n")
+}
+
 func (g *AstroGenerator) openAstroCodeBlock(sb *strings.Builder, opts AstroPageOptions) {
 	codeClass := "cire"
 	if opts.Language != "" {
@@ -316,7 +449,7 @@ func (g *AstroGenerator) closeAstroCodeBlock(sb *strings.Builder) {
 }
 
 func (g *AstroGenerator) outputAstroGap(start, end scip.Position, sb *strings.Builder) {
-	if scip.Position.Compare(start, end) == 0 {
+	if scip.Position.Compare(start, end) >= 0 {
 		return
 	}
 
@@ -324,16 +457,6 @@ func (g *AstroGenerator) outputAstroGap(start, end scip.Position, sb *strings.Bu
 	sb.WriteString(escapeAstroText(content))
 }
 
-func (g *AstroGenerator) outputAstroRemaining(startPos scip.Position, sb *strings.Builder) {
-	fileEndPos := g.fileEndPosition()
-	if scip.Position.Compare(startPos, fileEndPos) >= 0 {
-		return
-	}
-
-	content := getSourceFromSpan(g.sourceLines, scip.Range{Start: startPos, End: fileEndPos})
-	sb.WriteString(escapeAstroText(content))
-}
-
 func (g *AstroGenerator) outputAstroToken(token TokenInfo, sb *strings.Builder) {
 	if token.InlayHintLabel != "" {
 		sb.WriteString(`<span class="inlay-hint" data-inlay-hint aria-hidden="true">`)
@@ -345,10 +468,10 @@ func (g *AstroGenerator) outputAstroToken(token TokenInfo, sb *strings.Builder)
 	content := getSourceFromSpan(g.sourceLines, token.Span)
 	escapedContent := escapeAstroText(content)
 
-	cssClass := token.HighlightClass
+	cssClass := strings.Join(token.Classes(), " ")
 	id := token.Anchor
 	href := token.Href
-	encodedHover, encodedHoverHTML, hasHover := encodeAstroHover(token.Document)
+	encodedHover, encodedHoverHTML, hasHover := encodeAstroHover(token.Document, g.Markdown)
 
 	switch {
 	case href != "":
@@ -374,6 +497,9 @@ func (g *AstroGenerator) outputAstroToken(token TokenInfo, sb *strings.Builder)
 		if definitionClass != "" {
 			writeAstroAttribute(sb, "class", definitionClass)
 		}
+		if enclosing, ok := astroEnclosingLines(token.EnclosingRange); ok {
+			writeAstroAttribute(sb, "data-enclosing", enclosing)
+		}
 		if hasHover {
 			writeAstroHoverAttributes(sb, encodedHover, encodedHoverHTML)
 		}
@@ -396,13 +522,22 @@ func (g *AstroGenerator) outputAstroToken(token TokenInfo, sb *strings.Builder)
 	}
 }
 
-func encodeAstroHover(document []string) (encodedRaw string, encodedHTML string, ok bool) {
+// astroEnclosingLines formats a definition body as a one-based, inclusive
+// "start-end" line range for the source page scripts.
+func astroEnclosingLines(enclosing *scip.Range) (string, bool) {
+	if enclosing == nil || enclosing.End.Line < enclosing.Start.Line {
+		return "", false
+	}
+	return fmt.Sprintf("%d-%d", oneBased(enclosing.Start.Line), oneBased(enclosing.End.Line)), true
+}
+
+func encodeAstroHover(document []string, options MarkdownOptions) (encodedRaw string, encodedHTML string, ok bool) {
 	hover := strings.Join(document, "\n")
 	if hover == "" {
 		return "", "", false
 	}
 
-	renderedHover := RenderMarkdown(hover)
+	renderedHover := RenderMarkdownWithOptions(hover, options)
 	return base64.StdEncoding.EncodeToString([]byte(hover)),
 		base64.StdEncoding.EncodeToString([]byte(renderedHover)),
 		true
@@ -114,6 +114,199 @@ func TestGenerateAstroNarrativeModeInterleavesProseAndCode(t *testing.T) {
 	}
 }
 
+func TestGenerateAstroNarrativeModeRendersDocCommentTags(t *testing.T) {
+	sourceLines := []string{
+		"/**",
+		" * Adds two numbers.",
+		" * @param a first",
+		" * @return the sum",
+		" */",
+		"function add(a, b) { return a + b; }",
+	}
+	comments, err := NewCommentAnalyzer("javascript").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Doc == nil {
+		t.Fatalf("comments = %#v, want one comment with a parsed doc", comments)
+	}
+
+	output := NewAstroGenerator(sourceLines).GenerateAstro(nil, comments, AstroPageOptions{
+		Title:      "Add",
+		Language:   "javascript",
+		RenderMode: AstroRenderModeNarrative,
+	})
+
+	expectedParts := []string{
+		`<p>Adds two numbers.</p>`,
+		`<div class="cire-doc-params">`,
+		`<td><code>a</code></td>`,
+		`<div class="cire-doc-returns">`,
+		`<strong>Returns</strong> the sum`,
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Fatalf("output missing %q\nGot:\n%s", part, output)
+		}
+	}
+	if strings.Contains(output, "@param") {
+		t.Fatalf("narrative prose should not keep raw tags\nGot:\n%s", output)
+	}
+}
+
+func TestGenerateAstroNarrativeModeKeepsDirectivesInCode(t *testing.T) {
+	sourceLines := []string{
+		"// Kinds of things.",
+		"//go:generate stringer -type=Kind",
+		"type Kind int",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	output := NewAstroGenerator(sourceLines).GenerateAstro(nil, comments, AstroPageOptions{
+		Title:      "Kinds",
+		Language:   "go",
+		RenderMode: AstroRenderModeNarrative,
+	})
+
+	if !strings.Contains(output, `<div class="cire-prose"><p>Kinds of things.</p>`) {
+		t.Fatalf("output missing prose\nGot:\n%s", output)
+	}
+	codeStart := strings.Index(output, `<pre class="cire-code">`)
+	directive := strings.Index(output, "//go:generate stringer -type=Kind")
+	if codeStart < 0 || directive < codeStart {
+		t.Fatalf("directive should render inside the code block\nGot:\n%s", output)
+	}
+}
+
+func TestGenerateAstroNarrativeModeHidesAndCollapsesRegions(t *testing.T) {
+	sourceLines := []string{
+		"// Intro.",
+		"package main",
+		"",
+		"// gocire:collapse \"Imports\"",
+		"import \"fmt\"",
+		"// gocire:end",
+		"",
+		"// gocire:hide",
+		"// Plumbing nobody reads.",
+		"func helper() {}",
+		"// gocire:end",
+		"",
+		"func main() { helper() }",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tokens := []TokenInfo{
+		{
+			Span:           scip.Range{Start: scip.Position{Line: 9, Character: 5}, End: scip.Position{Line: 9, Character: 11}},
+			HighlightClass: "function",
+			Anchor:         "helper",
+		},
+		{
+			Span:           scip.Range{Start: scip.Position{Line: 12, Character: 14}, End: scip.Position{Line: 12, Character: 20}},
+			HighlightClass: "function",
+			Href:           "#helper",
+		},
+	}
+
+	output := NewAstroGenerator(sourceLines).GenerateAstro(tokens, comments, AstroPageOptions{
+		Title:      "Main",
+		Language:   "go",
+		RenderMode: AstroRenderModeNarrative,
+	})
+
+	for _, part := range []string{
+		`<details class="cire-region"><summary>Imports</summary>`,
+		`import &quot;fmt&quot;</code></pre></div>` + "\n</details>",
+		`<span id="helper" class="cire-hidden-anchor"></span>`,
+		`<a href="#helper" class="function reference">helper</a>`,
+	} {
+		if !strings.Contains(output, part) {
+			t.Fatalf("output missing %q\nGot:\n%s", part, output)
+		}
+	}
+	for _, hidden := range []string{"gocire:", "func helper", "Plumbing"} {
+		if strings.Contains(output, hidden) {
+			t.Fatalf("output should not contain %q\nGot:\n%s", hidden, output)
+		}
+	}
+}
+
+func TestGenerateAstroNarrativeModeRendersIncludes(t *testing.T) {
+	sourceLines := []string{
+		"// See Util:",
+		"// gocire:include pkg/util.go#Util",
+		"package docs",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	includedLines := []string{"package pkg", "", "func Util() {}"}
+
+	output := NewAstroGenerator(sourceLines).GenerateAstro(nil, comments, AstroPageOptions{
+		Title:      "Guide",
+		Language:   "go",
+		RenderMode: AstroRenderModeNarrative,
+		Includes: map[int]IncludedCode{
+			1: {
+				SourceLines: includedLines,
+				Tokens: []TokenInfo{{
+					Span:           scip.Range{Start: scip.Position{Line: 2, Character: 5}, End: scip.Position{Line: 2, Character: 9}},
+					HighlightClass: "function",
+					Href:           "/_source/pkg/util.go.html/#L3C6",
+				}},
+				Span:     IncludeLineSpan(includedLines, 2, 2),
+				Language: "go",
+				Label:    "pkg/util.go#Util",
+				Href:     "/_source/pkg/util.go.html/#L3C6",
+			},
+		},
+	})
+
+	want := `<figure class="cire-include"><figcaption><a href="/_source/pkg/util.go.html/#L3C6">pkg/util.go#Util</a></figcaption>` +
+		`<div class="cire-code-block" data-code-block><pre class="cire-code"><code class="cire language-go" data-language="go">` +
+		`func <a href="/_source/pkg/util.go.html/#L3C6" class="function reference">Util</a>() &#123;&#125;</code></pre></div></figure>`
+	if !strings.Contains(output, want) {
+		t.Fatalf("output missing included code %q\nGot:\n%s", want, output)
+	}
+	if strings.Contains(output, "gocire:include") {
+		t.Fatalf("resolved include directive should not render\nGot:\n%s", output)
+	}
+}
+
+func TestGenerateAstroNarrativeModeDropsFrontMatter(t *testing.T) {
+	sourceLines := []string{
+		"// ---",
+		"// title: Main",
+		"// ---",
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	output := NewAstroGenerator(sourceLines).GenerateAstro(nil, comments, AstroPageOptions{
+		Title:       "Main",
+		Language:    "go",
+		Description: "The CLI entry point.",
+		RenderMode:  AstroRenderModeNarrative,
+	})
+
+	if strings.Contains(output, "title: Main") || strings.Contains(output, "cire-prose") {
+		t.Fatalf("front matter should leave neither prose nor code\nGot:\n%s", output)
+	}
+	if !strings.Contains(output, `description="The CLI entry point."`) {
+		t.Fatalf("output missing page description\nGot:\n%s", output)
+	}
+}
+
 func TestGenerateAstroNarrativeModePassesTableOfContents(t *testing.T) {
 	sourceLines := []string{
 		"// # Page title",
@@ -355,6 +548,46 @@ func TestGenerateAstroEscapesLinkAnchorAndHoverAttributes(t *testing.T) {
 	}
 }
 
+func TestGenerateAstroEmitsEnclosingRangeOnDefinitionAnchors(t *testing.T) {
+	sourceLines := []string{"func main() {", "\tprintln()", "}"}
+	gen := NewAstroGenerator(sourceLines)
+
+	output := gen.GenerateAstro([]TokenInfo{
+		{
+			Anchor:       "L1C6",
+			IsDefinition: true,
+			Span: scip.Range{
+				Start: scip.Position{Line: 0, Character: 5},
+				End:   scip.Position{Line: 0, Character: 9},
+			},
+			EnclosingRange: &scip.Range{
+				Start: scip.Position{Line: 0, Character: 0},
+				End:   scip.Position{Line: 2, Character: 1},
+			},
+		},
+		{
+			Href: "#L1C6",
+			Span: scip.Range{
+				Start: scip.Position{Line: 1, Character: 1},
+				End:   scip.Position{Line: 1, Character: 8},
+			},
+			EnclosingRange: &scip.Range{
+				Start: scip.Position{Line: 0, Character: 0},
+				End:   scip.Position{Line: 2, Character: 1},
+			},
+		},
+	}, nil, AstroPageOptions{
+		RenderMode: AstroRenderModeSource,
+	})
+
+	if !strings.Contains(output, `<span id="L1C6" class="definition" data-enclosing="1-3">main</span>`) {
+		t.Fatalf("definition anchor missing data-enclosing\nGot:\n%s", output)
+	}
+	if strings.Count(output, "data-enclosing") != 1 {
+		t.Fatalf("only definition anchors should carry data-enclosing\nGot:\n%s", output)
+	}
+}
+
 func TestGenerateAstroOutputsRenderedHoverHTMLAttribute(t *testing.T) {
 	sourceLines := []string{`main`}
 	gen := NewAstroGenerator(sourceLines)
@@ -458,3 +691,78 @@ func decodeAstroAttributeBase64(t *testing.T, value string) string {
 	}
 	return string(decoded)
 }
+
+func TestGenerateAstroSanitizesHoverAndProseHTML(t *testing.T) {
+	sourceLines := []string{
+		`// Run <img src=x onerror=alert(1)> and <a href="javascript:alert(2)">this</a>.`,
+		"// <script>alert(3)</script>",
+		"main",
+	}
+	gen := NewAstroGenerator(sourceLines)
+
+	output := gen.GenerateAstro([]TokenInfo{
+		{
+			Document: []string{"Docs <svg onload=alert(4)></svg> and [a link](javascript:alert(5))."},
+			Span: scip.Range{
+				Start: scip.Position{Line: 2, Character: 0},
+				End:   scip.Position{Line: 2, Character: 4},
+			},
+		},
+	}, []CommentInfo{
+		{
+			Content: strings.Join([]string{
+				`Run <img src=x onerror=alert(1)> and <a href="javascript:alert(2)">this</a>.`,
+				"",
+				"<script>alert(3)</script>",
+			}, "\n"),
+			Span: scip.Range{
+				Start: scip.Position{Line: 0, Character: 0},
+				End:   scip.Position{Line: 1, Character: 29},
+			},
+		},
+	}, AstroPageOptions{
+		Language:   "go",
+		RenderMode: AstroRenderModeNarrative,
+	})
+
+	renderedHover := decodeAstroAttributeBase64(t, extractAstroAttribute(t, output, "data-hover-html"))
+	for _, page := range []string{output, renderedHover} {
+		for _, unwanted := range []string{"onerror", "onload", "javascript:", "<script", "alert(3)"} {
+			if strings.Contains(page, unwanted) {
+				t.Fatalf("output should not contain %q\nGot:\n%s", unwanted, page)
+			}
+		}
+	}
+	if !strings.Contains(output, `<img src="x">`) || !strings.Contains(output, "<a>this</a>") {
+		t.Fatalf("prose should keep sanitized elements\nGot:\n%s", output)
+	}
+	if !strings.Contains(renderedHover, "<a>a link</a>") {
+		t.Fatalf("hover should keep the link text\nGot:\n%s", renderedHover)
+	}
+}
+
+func TestGenerateAstroEmitsEveryHighlightClass(t *testing.T) {
+	sourceLines := []string{"value.method()"}
+	gen := NewAstroGenerator(sourceLines)
+
+	output := gen.GenerateAstro([]TokenInfo{
+		{
+			HighlightClass:   "function.method",
+			HighlightClasses: []string{"type", "function.method"},
+			Symbol:           "method",
+			IsReference:      true,
+			Href:             "#method",
+			Span: scip.Range{
+				Start: scip.Position{Line: 0, Character: 6},
+				End:   scip.Position{Line: 0, Character: 12},
+			},
+		},
+	}, nil, AstroPageOptions{
+		RenderMode: AstroRenderModeSource,
+		Language:   "go",
+	})
+
+	if !strings.Contains(output, `class="type function.method reference"`) {
+		t.Fatalf("output should carry every class\nGot:\n%s", output)
+	}
+}
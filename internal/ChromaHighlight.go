@@ -0,0 +1,264 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/cockroachdb/errors"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// chromaToken is one lexed token with its position in the source.
+type chromaToken struct {
+	tokenType chroma.TokenType
+	value     string
+	start     int // Byte offset
+	span      scip.Range
+}
+
+// chromaTokens lexes sourceContent with the Chroma lexer of a language that has
+// no tree-sitter grammar. Trailing line endings are dropped from token values
+// so spans end on the line the token does.
+func chromaTokens(language string, sourceContent []byte) ([]chromaToken, error) {
+	cfg, err := languages.GetConfig(language)
+	if err != nil {
+		return nil, err
+	}
+	lexer := lexers.Get(cfg.ChromaLexer)
+	if lexer == nil {
+		return nil, errors.Newf("no chroma lexer %q for %s", cfg.ChromaLexer, language)
+	}
+	return lexChromaTokens(lexer, string(sourceContent))
+}
+
+// lexChromaTokens lexes source with lexer, which may be one no registry
+// language uses, such as SQL injected into a string. Line endings are left
+// as they are so token offsets match the source's bytes.
+func lexChromaTokens(lexer chroma.Lexer, source string) ([]chromaToken, error) {
+	iterator, err := lexer.Tokenise(&chroma.TokeniseOptions{State: "root", EnsureLF: false}, source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "lex %s source", lexer.Config().Name)
+	}
+
+	var tokens []chromaToken
+	offset := 0
+	pos := scip.Position{}
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		// Lexers may append a final newline the source does not have.
+		if offset >= len(source) {
+			break
+		}
+		value := token.Value
+		if offset+len(value) > len(source) {
+			value = source[offset:]
+		}
+		start := pos
+		end := advancePosition(pos, value)
+
+		trimmed := strings.TrimRight(value, "\r\n")
+		if trimmed != "" && strings.TrimSpace(trimmed) != "" {
+			tokens = append(tokens, chromaToken{
+				tokenType: token.Type,
+				value:     trimmed,
+				start:     offset,
+				span:      scip.Range{Start: start, End: advancePosition(start, trimmed)},
+			})
+		}
+		offset += len(value)
+		pos = end
+	}
+	return tokens, nil
+}
+
+func advancePosition(pos scip.Position, text string) scip.Position {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return scip.Position{
+			Line:      pos.Line + int32(strings.Count(text, "\n")),
+			Character: int32(len(text) - i - 1),
+		}
+	}
+	return scip.Position{Line: pos.Line, Character: pos.Character + int32(len(text))}
+}
+
+// chromaHighlightTokens highlights a file with its Chroma lexer, naming
+// classes after the tree-sitter captures so themes style both alike.
+func chromaHighlightTokens(language string, sourceContent []byte) ([]TokenInfo, error) {
+	lexed, err := chromaTokens(language, sourceContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []TokenInfo
+	for _, token := range lexed {
+		class := chromaHighlightClass(token.tokenType, token.value)
+		if class == "" {
+			continue
+		}
+		tokens = append(tokens, TokenInfo{
+			HighlightClass: class,
+			Document:       []string{},
+			Span:           token.span,
+			Source:         TokenSourceSyntax,
+		})
+	}
+	return tokens, nil
+}
+
+func chromaHighlightClass(tokenType chroma.TokenType, value string) string {
+	switch tokenType {
+	case chroma.CommentPreproc, chroma.CommentHashbang:
+		return "keyword.directive"
+	case chroma.KeywordConstant:
+		return "constant.builtin"
+	case chroma.KeywordType:
+		return "type.builtin"
+	case chroma.NameTag, chroma.NameProperty, chroma.NameOther:
+		// YAML and JSON keys are tags to Chroma, TOML keys are other names.
+		return "property"
+	case chroma.NameAttribute, chroma.NameDecorator:
+		return "attribute"
+	case chroma.NameBuiltin:
+		return "function.builtin"
+	case chroma.NameFunction:
+		return "function"
+	case chroma.NameVariable:
+		return "variable"
+	case chroma.NameClass:
+		return "type"
+	case chroma.NameConstant, chroma.NameEntity:
+		return "constant"
+	case chroma.NameLabel:
+		return "label"
+	case chroma.NameNamespace:
+		return "module"
+	case chroma.LiteralStringEscape:
+		return "string.escape"
+	case chroma.LiteralStringSymbol:
+		return "string.special.symbol"
+	case chroma.Literal:
+		// Plain YAML scalars.
+		return "string"
+	case chroma.Punctuation:
+		if strings.Trim(value, "()[]{}") == "" {
+			return "punctuation.bracket"
+		}
+		return "punctuation.delimiter"
+	}
+
+	switch tokenType.Category() {
+	case chroma.Comment:
+		return "comment"
+	case chroma.Keyword:
+		return "keyword"
+	case chroma.Operator:
+		return "operator"
+	}
+	switch tokenType.SubCategory() {
+	case chroma.LiteralString:
+		return "string"
+	case chroma.LiteralNumber:
+		return "number"
+	}
+	return ""
+}
+
+// chromaComments collects the standalone comments of a file lexed with
+// Chroma, joining comments separated only by whitespace the way the
+// tree-sitter comment queries do. Shebangs, other preprocessor lines and
+// comment directives are not prose.
+func chromaComments(directives *CommentDirectives, language string, sourceContent []byte) ([]CommentInfo, error) {
+	lexed, err := chromaTokens(language, sourceContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []CommentInfo
+	var parts []string
+	var span scip.Range
+	lastEnd := -1
+	flush := func() {
+		if len(parts) > 0 {
+			comments = append(comments, CommentInfo{
+				Content: strings.Join(parts, "\n"),
+				Span:    span,
+			})
+		}
+		parts = nil
+		lastEnd = -1
+	}
+
+	for _, token := range lexed {
+		if token.tokenType.Category() != chroma.Comment ||
+			token.tokenType == chroma.CommentPreproc ||
+			token.tokenType == chroma.CommentHashbang {
+			flush()
+			continue
+		}
+		if lastEnd >= 0 && len(bytes.TrimSpace(sourceContent[lastEnd:token.start])) != 0 {
+			flush()
+		}
+		if len(parts) == 0 && !isCommentStandalone(sourceContent, token.start) {
+			continue
+		}
+
+		for _, line := range chromaCommentLines(token) {
+			if directives.isDirective(language, line.value) {
+				flush()
+				continue
+			}
+			if len(parts) == 0 {
+				span.Start = line.span.Start
+			}
+			parts = append(parts, cleanNodeContent(line.value, language))
+			span.End = line.span.End
+		}
+		lastEnd = token.start + len(token.value)
+	}
+	flush()
+
+	markers := regionMarkerComments(sourceContent, func(offset int, _ scip.Position) bool {
+		for _, token := range lexed {
+			if offset >= token.start && offset < token.start+len(token.value) {
+				return token.tokenType.Category() == chroma.Comment
+			}
+		}
+		return false
+	})
+	if len(markers) > 0 {
+		comments = append(comments, markers...)
+		SortBySpan(comments)
+	}
+	return comments, nil
+}
+
+// chromaCommentLines splits a token that lexers return for several line
+// comments at once. Block comments stay whole for cleanNodeContent to dedent.
+func chromaCommentLines(token chromaToken) []chromaToken {
+	if strings.HasPrefix(token.value, "/*") || !strings.Contains(token.value, "\n") {
+		return []chromaToken{token}
+	}
+
+	var lines []chromaToken
+	offset := 0
+	pos := token.span.Start
+	for _, line := range strings.SplitAfter(token.value, "\n") {
+		text := strings.TrimRightFunc(line, unicode.IsSpace)
+		indented := strings.TrimLeftFunc(text, unicode.IsSpace)
+		start := advancePosition(pos, text[:len(text)-len(indented)])
+		if indented != "" {
+			lines = append(lines, chromaToken{
+				tokenType: token.tokenType,
+				value:     indented,
+				start:     token.start + offset + len(text) - len(indented),
+				span:      scip.Range{Start: start, End: advancePosition(start, indented)},
+			})
+		}
+		offset += len(line)
+		pos = advancePosition(pos, line)
+	}
+	return lines
+}
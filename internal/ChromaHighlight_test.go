@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestHighlightAnalyzerLexesLanguagesWithoutGrammars(t *testing.T) {
+	tests := []struct {
+		language string
+		source   string
+		want     map[string]string
+	}{
+		{
+			language: "yaml",
+			source:   "# values\nreplicas: 3\nimage: nginx\ndebug: true\n",
+			want: map[string]string{
+				"# values@0": "comment",
+				"replicas@1": "property",
+				"3@1":        "number",
+				"nginx@2":    "string",
+				"true@3":     "constant.builtin",
+			},
+		},
+		{
+			language: "dockerfile",
+			source:   "FROM golang:1.25 AS build\nRUN echo \"$HOME\"\n",
+			want: map[string]string{
+				"FROM@0":  "keyword",
+				"AS@0":    "keyword",
+				"echo@1":  "function.builtin",
+				"$HOME@1": "variable",
+			},
+		},
+		{
+			language: "toml",
+			source:   "[package]\nname = \"gocire\"\n",
+			want: map[string]string{
+				"[@0":          "punctuation.bracket",
+				"package@0":    "property",
+				"\"gocire\"@1": "string",
+			},
+		},
+		{
+			language: "protobuf",
+			source:   "message User {\n  string name = 1;\n}\n",
+			want: map[string]string{
+				"message@0": "keyword",
+				"User@0":    "type",
+				"string@1":  "type.builtin",
+				";@1":       "punctuation.delimiter",
+			},
+		},
+		{
+			language: "sh",
+			source:   "#!/bin/sh\nexport PATH=\"$HOME/bin\"\n",
+			want: map[string]string{
+				"#!/bin/sh@0": "keyword.directive",
+				"export@1":    "function.builtin",
+				"PATH@1":      "variable",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			classes := highlightClassesOf(t, tt.language, tt.source)
+			for text, want := range tt.want {
+				if got := classes[text]; got != want {
+					t.Errorf("%s class = %q, want %q in %v", text, got, want, classes)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentAnalyzerCollectsHashCommentsWithoutGrammars(t *testing.T) {
+	source := "#!/usr/bin/env bash\n" +
+		"# Deploy the site.\n" +
+		"#\n" +
+		"#   Runs on every push.\n" +
+		"set -e # strict\n" +
+		"\n" +
+		"  # Build first.\n" +
+		"make build\n"
+
+	comments, err := NewCommentAnalyzer("shell").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	want := []CommentInfo{
+		{
+			Content: "Deploy the site.\n\n  Runs on every push.",
+			Span:    scip.Range{Start: scip.Position{Line: 1}, End: scip.Position{Line: 3, Character: 23}},
+		},
+		{
+			Content: "Build first.",
+			Span:    scip.Range{Start: scip.Position{Line: 6, Character: 2}, End: scip.Position{Line: 6, Character: 16}},
+		},
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Fatalf("comments = %#v, want %#v", comments, want)
+	}
+}
+
+func TestCommentAnalyzerKeepsCRLFCommentsWithoutGrammars(t *testing.T) {
+	source := "# Service settings.\r\nname: web # inline\r\n\r\n# Ports.\r\nport: 80\r\n"
+
+	comments, err := NewCommentAnalyzer("yaml").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	want := []CommentInfo{
+		{
+			Content: "Service settings.",
+			Span:    scip.Range{Start: scip.Position{Line: 0}, End: scip.Position{Line: 0, Character: 19}},
+		},
+		{
+			Content: "Ports.",
+			Span:    scip.Range{Start: scip.Position{Line: 3}, End: scip.Position{Line: 3, Character: 8}},
+		},
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Fatalf("comments = %#v, want %#v", comments, want)
+	}
+}
+
+func TestCommentAnalyzerCleansProtobufBlockComments(t *testing.T) {
+	source := "/*\n * A user account.\n */\nmessage User {}\n"
+
+	comments, err := NewCommentAnalyzer("protobuf").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Content != "A user account." {
+		t.Fatalf("comments = %#v, want one cleaned block comment", comments)
+	}
+}
+
+func TestLanguagesWithoutGrammarsHaveNoLocalsOrTags(t *testing.T) {
+	locals, err := NewLocalsAnalyzer("yaml", "values.yaml").Analyze([]byte("a: 1\n"))
+	if err != nil || len(locals) != 0 {
+		t.Fatalf("locals = %#v, %v, want none", locals, err)
+	}
+
+	index := NewTagsIndex()
+	if err := index.AddFile("makefile", "Makefile", []byte("all:\n\ttrue\n")); err != nil {
+		t.Fatalf("AddFile returned error: %v", err)
+	}
+	if _, err := ParseSource("json", []byte("{}")); err == nil {
+		t.Fatal("ParseSource(json) succeeded, want an error for a language without a grammar")
+	}
+}
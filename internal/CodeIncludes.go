@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// CodeInclude is a directive that transcludes code from another file:
+//
+//	// gocire:include internal/TokenInfo.go#MergeSplitTokens
+//	// gocire:include internal/TokenInfo.go#L60-L95
+//
+// Path is relative to the project root. Fragment names a symbol, optionally
+// qualified by its receiver or class as Type.Method, or a line range; without
+// one the whole file is included.
+type CodeInclude struct {
+	Line     int
+	Path     string
+	Fragment string
+}
+
+// IncludedCode is the analyzed code a CodeInclude resolved to.
+type IncludedCode struct {
+	SourceLines []string
+	// Tokens lie within Span and link back to the source page, see
+	// TranscludeTokens.
+	Tokens   []TokenInfo
+	Span     scip.Range
+	Language string
+	// Label names the included code, and Href is its place on the source page.
+	Label string
+	Href  string
+}
+
+var codeIncludeLinesPattern = regexp.MustCompile(`^L([1-9][0-9]*)(?:-L([1-9][0-9]*))?$`)
+
+// FindCodeIncludes returns the include directives of a file in line order.
+func FindCodeIncludes(sourceLines []string) []CodeInclude {
+	var includes []CodeInclude
+	for i, line := range sourceLines {
+		if include, ok := parseCodeInclude(line); ok {
+			include.Line = i
+			includes = append(includes, include)
+		}
+	}
+	return includes
+}
+
+// codeIncludeLines returns the directive lines of includes in order.
+func codeIncludeLines(includes map[int]IncludedCode) []int {
+	lines := make([]int, 0, len(includes))
+	for line := range includes {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// isCodeInclude reports whether a comment, as written in the source, is an
+// include directive rather than prose.
+func isCodeInclude(comment string) bool {
+	_, ok := parseCodeInclude(comment)
+	return ok
+}
+
+func parseCodeInclude(line string) (CodeInclude, bool) {
+	text, _ := codeMarkerText(line)
+	rest, ok := strings.CutPrefix(text, "gocire:include ")
+	if !ok {
+		return CodeInclude{}, false
+	}
+	target := strings.TrimSpace(rest)
+	if target == "" || strings.ContainsAny(target, " \t") {
+		return CodeInclude{}, false
+	}
+	path, fragment, _ := strings.Cut(target, "#")
+	if path == "" {
+		return CodeInclude{}, false
+	}
+	return CodeInclude{Path: path, Fragment: fragment}, true
+}
+
+// LineRange reads a fragment such as L60-L95 or L60 as zero-based, inclusive
+// start and end lines.
+func (c CodeInclude) LineRange() (start int, end int, ok bool) {
+	match := codeIncludeLinesPattern.FindStringSubmatch(c.Fragment)
+	if match == nil {
+		return 0, 0, false
+	}
+	start, _ = strconv.Atoi(match[1])
+	end = start
+	if match[2] != "" {
+		end, _ = strconv.Atoi(match[2])
+	}
+	if end < start {
+		return 0, 0, false
+	}
+	return start - 1, end - 1, true
+}
+
+// IncludeLineSpan spans whole lines start through end of sourceLines. end is
+// clamped to the file; start must lie within it.
+func IncludeLineSpan(sourceLines []string, start int, end int) scip.Range {
+	end = min(end, len(sourceLines)-1)
+	return scip.Range{
+		Start: scip.Position{Line: int32(start), Character: 0},
+		End:   scip.Position{Line: int32(end), Character: int32(len([]rune(sourceLines[end])))},
+	}
+}
+
+// TranscludeTokens keeps the tokens within span and points them at the source
+// page, pageHref: links within that file become links into the page, and
+// definitions, whose anchors belong to the page, link to themselves there.
+func TranscludeTokens(tokens []TokenInfo, span scip.Range, pageHref string) []TokenInfo {
+	var included []TokenInfo
+	for _, token := range tokens {
+		if scip.Position.Compare(token.Span.Start, span.Start) < 0 || scip.Position.Compare(token.Span.End, span.End) > 0 {
+			continue
+		}
+		if strings.HasPrefix(token.Href, "#") {
+			token.Href = pageHref + token.Href
+		}
+		if token.Anchor != "" {
+			if token.Href == "" {
+				token.Href = pageHref + "#" + token.Anchor
+			}
+			token.Anchor = ""
+		}
+		// The page's own anchors are the only ones links may land on.
+		token.IsDefinition = false
+		token.IsReference = false
+		included = append(included, token)
+	}
+	return included
+}
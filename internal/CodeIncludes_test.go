@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestFindCodeIncludes(t *testing.T) {
+	got := FindCodeIncludes([]string{
+		"// gocire:include internal/TokenInfo.go#MergeSplitTokens",
+		"# gocire:include scripts/run.sh#L3-L9",
+		"/* gocire:include go.mod */",
+		"// see gocire:include for details",
+		"// gocire:include a.go b.go",
+	})
+	want := []CodeInclude{
+		{Line: 0, Path: "internal/TokenInfo.go", Fragment: "MergeSplitTokens"},
+		{Line: 1, Path: "scripts/run.sh", Fragment: "L3-L9"},
+		{Line: 2, Path: "go.mod"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindCodeIncludes() = %#v, want %#v", got, want)
+	}
+}
+
+func TestCodeIncludeLineRange(t *testing.T) {
+	tests := []struct {
+		fragment   string
+		start, end int
+		ok         bool
+	}{
+		{"L60-L95", 59, 94, true},
+		{"L7", 6, 6, true},
+		{"L9-L3", 0, 0, false},
+		{"L0", 0, 0, false},
+		{"Token", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := CodeInclude{Fragment: tt.fragment}.LineRange()
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Fatalf("LineRange(%q) = %d, %d, %v, want %d, %d, %v", tt.fragment, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestTranscludeTokensLinkBackToSourcePage(t *testing.T) {
+	span := scip.Range{Start: scip.Position{Line: 2}, End: scip.Position{Line: 4, Character: 1}}
+	tokens := []TokenInfo{
+		{Span: scip.Range{Start: scip.Position{Line: 0, Character: 5}, End: scip.Position{Line: 0, Character: 8}}, Anchor: "L1C6"},
+		{Span: scip.Range{Start: scip.Position{Line: 2, Character: 5}, End: scip.Position{Line: 2, Character: 8}}, Anchor: "L3C6", IsDefinition: true},
+		{Span: scip.Range{Start: scip.Position{Line: 3, Character: 1}, End: scip.Position{Line: 3, Character: 4}}, Href: "#L1C6"},
+		{Span: scip.Range{Start: scip.Position{Line: 3, Character: 6}, End: scip.Position{Line: 3, Character: 9}}, Href: "/_source/b.go.html#L2C1"},
+	}
+
+	got := TranscludeTokens(tokens, span, "/_source/a.go.html")
+
+	want := []string{"/_source/a.go.html#L3C6", "/_source/a.go.html#L1C6", "/_source/b.go.html#L2C1"}
+	if len(got) != len(want) {
+		t.Fatalf("TranscludeTokens() kept %d tokens, want %d: %#v", len(got), len(want), got)
+	}
+	for i, token := range got {
+		if token.Href != want[i] || token.Anchor != "" || token.IsDefinition {
+			t.Fatalf("token %d = %#v, want href %q and no anchor", i, token, want[i])
+		}
+	}
+}
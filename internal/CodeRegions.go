@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// CodeRegionKind says how narrative output shows the code of a region.
+type CodeRegionKind string
+
+const (
+	// CodeRegionHide omits the code, keeping only its definition anchors.
+	CodeRegionHide CodeRegionKind = "hide"
+	// CodeRegionCollapse folds the code into a closed details block.
+	CodeRegionCollapse CodeRegionKind = "collapse"
+)
+
+// CodeRegion is a span of source between an opening and a closing marker line:
+//
+//	// gocire:hide                 // gocire:collapse "Imports"     // #region Helpers
+//	...                            ...                              ...
+//	// gocire:end                  // gocire:end                    // #endregion
+//
+// Open and Close cover the marker lines, which never render.
+type CodeRegion struct {
+	Kind  CodeRegionKind
+	Title string
+	Open  scip.Range
+	Close scip.Range
+}
+
+type codeRegionMarker struct {
+	open   bool
+	region bool // #region / #endregion rather than gocire:
+	kind   CodeRegionKind
+	title  string
+}
+
+// FindCodeRegions pairs the region markers among the comments of a file,
+// which the comment analyzer reports as RegionMarker comments. Markers may
+// nest, but only the outermost region of a nest is returned; markers left
+// unpaired are ignored.
+func FindCodeRegions(sourceLines []string, comments []CommentInfo) []CodeRegion {
+	type openMarker struct {
+		marker codeRegionMarker
+		line   int
+	}
+	var regions []CodeRegion
+	var stack []openMarker
+	for _, comment := range comments {
+		i := int(comment.Span.Start.Line)
+		if !comment.RegionMarker || i >= len(sourceLines) {
+			continue
+		}
+		marker, ok := parseCodeRegionMarker(sourceLines[i])
+		if !ok {
+			continue
+		}
+		if marker.open {
+			stack = append(stack, openMarker{marker: marker, line: i})
+			continue
+		}
+		// A close pairs with the nearest open of its own family.
+		match := -1
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j].marker.region == marker.region {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			continue
+		}
+		open := stack[match]
+		stack = stack[:match]
+		regions = append(regions, CodeRegion{
+			Kind:  open.marker.kind,
+			Title: open.marker.title,
+			Open:  codeRegionLineRange(sourceLines, open.line),
+			Close: codeRegionLineRange(sourceLines, i),
+		})
+	}
+
+	// Pairs nest or stand apart, so the outermost are those that start after
+	// the last kept region closed.
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Open.Start.Line < regions[j].Open.Start.Line
+	})
+	kept := regions[:0]
+	for _, region := range regions {
+		if len(kept) > 0 && region.Open.Start.Line <= kept[len(kept)-1].Close.Start.Line {
+			continue
+		}
+		kept = append(kept, region)
+	}
+	return kept
+}
+
+// proseComments returns comments without the region markers among them.
+func proseComments(comments []CommentInfo) []CommentInfo {
+	prose := make([]CommentInfo, 0, len(comments))
+	for _, comment := range comments {
+		if !comment.RegionMarker {
+			prose = append(prose, comment)
+		}
+	}
+	return prose
+}
+
+// regionMarkerComments returns a RegionMarker comment for each line of source
+// that holds only a region marker and opens a comment or preprocessor line,
+// as isMarkerNode reports from the line's first byte. A marker written inside
+// a string is not one.
+func regionMarkerComments(sourceContent []byte, isMarkerNode func(offset int, pos scip.Position) bool) []CommentInfo {
+	sourceLines := strings.Split(string(sourceContent), "\n")
+	var markers []CommentInfo
+	offset := 0
+	for i, line := range sourceLines {
+		lineStart := offset
+		offset += len(line) + 1
+		if _, ok := parseCodeRegionMarker(line); !ok {
+			continue
+		}
+		column := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		if !isMarkerNode(lineStart+column, scip.Position{Line: int32(i), Character: int32(column)}) {
+			continue
+		}
+		markers = append(markers, CommentInfo{
+			Content:      strings.TrimSpace(line),
+			Span:         codeRegionLineRange(sourceLines, i),
+			RegionMarker: true,
+		})
+	}
+	return markers
+}
+
+// isCodeRegionMarker reports whether a comment, as written in the source, is
+// a region marker line rather than prose.
+func isCodeRegionMarker(comment string) bool {
+	_, ok := parseCodeRegionMarker(comment)
+	return ok
+}
+
+// parseCodeRegionMarker reads a line holding only a region marker, written
+// behind a line or block comment marker. #region and #endregion may also stand
+// bare, as in C#, or follow #pragma, as in C and C++.
+func parseCodeRegionMarker(line string) (codeRegionMarker, bool) {
+	text, hashed := codeMarkerText(line)
+	if rest, ok := strings.CutPrefix(text, "pragma "); ok && hashed {
+		text = strings.TrimSpace(rest)
+	}
+
+	word, rest, _ := strings.Cut(text, " ")
+	rest = strings.TrimSpace(rest)
+	switch {
+	case word == "gocire:hide" && rest == "":
+		return codeRegionMarker{open: true, kind: CodeRegionHide}, true
+	case word == "gocire:collapse":
+		return codeRegionMarker{open: true, kind: CodeRegionCollapse, title: codeRegionTitle(rest)}, true
+	case word == "gocire:end" && rest == "":
+		return codeRegionMarker{}, true
+	case word == "region" && hashed:
+		return codeRegionMarker{open: true, region: true, kind: CodeRegionCollapse, title: codeRegionTitle(rest)}, true
+	case word == "endregion" && hashed:
+		return codeRegionMarker{region: true}, true
+	}
+	return codeRegionMarker{}, false
+}
+
+// codeMarkerText strips the comment markers around a line that holds only a
+// comment, and reports whether the text began with #.
+func codeMarkerText(line string) (text string, hashed bool) {
+	text = strings.TrimSpace(line)
+	for _, pair := range [][2]string{{"/*", "*/"}, {"<!--", "-->"}, {"{-", "-}"}, {"(*", "*)"}} {
+		if strings.HasPrefix(text, pair[0]) && strings.HasSuffix(text, pair[1]) && len(text) >= len(pair[0])+len(pair[1]) {
+			text = strings.TrimSpace(text[len(pair[0]) : len(text)-len(pair[1])])
+			break
+		}
+	}
+	for _, prefix := range []string{"//", "--", ";", "%"} {
+		if strings.HasPrefix(text, prefix) {
+			text = strings.TrimSpace(strings.TrimLeft(text, prefix[:1]))
+			break
+		}
+	}
+	hashed = strings.HasPrefix(text, "#")
+	return strings.TrimSpace(strings.TrimPrefix(text, "#")), hashed
+}
+
+func codeRegionTitle(text string) string {
+	text = strings.TrimSpace(text)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		return strings.TrimSpace(unquoted)
+	}
+	return text
+}
+
+func codeRegionLineRange(sourceLines []string, line int) scip.Range {
+	return scip.Range{
+		Start: scip.Position{Line: int32(line), Character: 0},
+		End:   scip.Position{Line: int32(line), Character: int32(len([]rune(sourceLines[line])))},
+	}
+}
+
+// laterPosition returns the later of the walk's position and a marker line's
+// end, so skipping a marker never walks back over output already written.
+func laterPosition(current, end scip.Position) scip.Position {
+	if scip.Position.Compare(end, current) > 0 {
+		return end
+	}
+	return current
+}
+
+// codeRegionSummary is the summary line of a collapsed region.
+func codeRegionSummary(region CodeRegion) string {
+	if region.Title != "" {
+		return region.Title
+	}
+	return "Code"
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindCodeRegions(t *testing.T) {
+	sourceLines := []string{
+		"package main",
+		"",
+		"// gocire:collapse \"Imports\"",
+		"import \"fmt\"",
+		"// gocire:end",
+		"",
+		"    // gocire:hide",
+		"    // #region inner",
+		"    var x = 1",
+		"    // #endregion",
+		"    // gocire:end",
+		"# region Python helpers",
+		"# endregion",
+		"#pragma region",
+		"#pragma endregion",
+		"// gocire:hide",
+		"// a region with no end",
+	}
+
+	got := FindCodeRegions(sourceLines, markerCommentsForTest(sourceLines))
+	want := []struct {
+		kind      CodeRegionKind
+		title     string
+		open      int32
+		close     int32
+		closeChar int32
+	}{
+		{CodeRegionCollapse, "Imports", 2, 4, 13},
+		{CodeRegionHide, "", 6, 10, 17},
+		{CodeRegionCollapse, "Python helpers", 11, 12, 11},
+		{CodeRegionCollapse, "", 13, 14, 17},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FindCodeRegions() = %#v, want %d regions", got, len(want))
+	}
+	for i, w := range want {
+		region := got[i]
+		gotFields := []any{region.Kind, region.Title, region.Open.Start.Line, region.Close.Start.Line, region.Close.End.Character}
+		wantFields := []any{w.kind, w.title, w.open, w.close, w.closeChar}
+		if !reflect.DeepEqual(gotFields, wantFields) {
+			t.Fatalf("region %d = %v, want %v", i, gotFields, wantFields)
+		}
+	}
+}
+
+func TestFindCodeRegionsSkipsMarkersOutsideComments(t *testing.T) {
+	source := strings.Join([]string{
+		"package main",
+		"",
+		"var usage = `",
+		"// gocire:hide",
+		"`",
+		"",
+		"// gocire:end",
+		"func main() {}",
+	}, "\n")
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	if regions := FindCodeRegions(strings.Split(source, "\n"), comments); len(regions) != 0 {
+		t.Fatalf("FindCodeRegions() = %#v, want no region from a marker inside a string", regions)
+	}
+}
+
+func TestFindCodeRegionsReadsPreprocessorMarkers(t *testing.T) {
+	source := "class C {\n    #region Helpers\n    void F() {}\n    #endregion\n}\n"
+	comments, err := NewCommentAnalyzer("csharp").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	regions := FindCodeRegions(strings.Split(source, "\n"), comments)
+	if len(regions) != 1 || regions[0].Title != "Helpers" || regions[0].Open.Start.Line != 1 || regions[0].Close.Start.Line != 3 {
+		t.Fatalf("FindCodeRegions() = %#v, want the Helpers region", regions)
+	}
+}
+
+// markerCommentsForTest reports every line as a region marker comment, as the
+// comment analyzer would for lines that hold one.
+func markerCommentsForTest(sourceLines []string) []CommentInfo {
+	comments := make([]CommentInfo, len(sourceLines))
+	for i := range sourceLines {
+		comments[i] = CommentInfo{Span: codeRegionLineRange(sourceLines, i), RegionMarker: true}
+	}
+	return comments
+}
+
+func TestParseCodeRegionMarkerIgnoresProse(t *testing.T) {
+	for _, line := range []string{
+		"// the region we deploy to",
+		"// gocire:hide the rest",
+		"x := 1 // gocire:hide",
+		"# regional settings",
+	} {
+		if _, ok := parseCodeRegionMarker(line); ok {
+			t.Fatalf("parseCodeRegionMarker(%q) should not be a marker", line)
+		}
+	}
+}
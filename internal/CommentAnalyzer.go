@@ -5,7 +5,6 @@ import (
 	"strings"
 	"unicode"
 
-	"github.com/Eric-Song-Nop/gocire/internal/languages"
 	"github.com/cockroachdb/errors"
 	"github.com/sourcegraph/scip/bindings/go/scip"
 	sitter "github.com/tree-sitter/go-tree-sitter"
@@ -13,6 +12,9 @@ import (
 
 type CommentAnalyzer struct {
 	language string
+	// Directives holds the project's own comment directives, which stay in
+	// the code instead of becoming prose; nil has only the registry's.
+	Directives *CommentDirectives
 }
 
 func NewCommentAnalyzer(language string) *CommentAnalyzer {
@@ -43,47 +45,11 @@ func cleanNodeContent(content string, language string) string {
 		return strings.TrimRightFunc(text, unicode.IsSpace)
 	}
 
-	// Helper to dedent block comments
-	dedent := func(lines []string) []string {
-		if len(lines) == 0 {
-			return lines
-		}
-		// Find common indent
-		commonIndent := -1
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			indent := 0
-			for _, r := range line {
-				if unicode.IsSpace(r) {
-					indent++
-				} else {
-					break
-				}
-			}
-			if commonIndent == -1 || indent < commonIndent {
-				commonIndent = indent
-			}
-		}
-
-		if commonIndent <= 0 {
-			return lines
-		}
-
-		var result []string
-		for _, line := range lines {
-			if len(line) >= commonIndent {
-				result = append(result, line[commonIndent:])
-			} else {
-				result = append(result, line)
-			}
-		}
-		return result
-	}
-
 	switch strings.ToLower(language) {
-	case "go", "golang", "java", "js", "javascript", "ts", "typescript", "rust", "c", "cpp", "c++", "csharp", "c#", "cs", "php", "dart":
+	case "go", "golang", "java", "js", "javascript", "ts", "typescript", "rust", "c", "cpp", "c++", "csharp", "c#", "cs", "php", "dart", "protobuf", "json":
+		if strings.EqualFold(language, "rust") {
+			content = trimRustDocMarker(content)
+		}
 		if strings.HasPrefix(content, "//") {
 			return cleanLine(content, "//")
 		}
@@ -147,11 +113,11 @@ func cleanNodeContent(content string, language string) string {
 				for i, l := range rawLines {
 					rawLines[i] = strings.TrimRightFunc(l, unicode.IsSpace)
 				}
-				cleaned = dedent(rawLines)
+				cleaned = dedentLines(rawLines)
 			}
 			return strings.Join(cleaned, "\n")
 		}
-	case "python", "py", "ruby":
+	case "python", "py", "ruby", "shell", "yaml", "toml", "dockerfile", "makefile":
 		if strings.HasPrefix(content, "#") {
 			return cleanLine(content, "#")
 		}
@@ -180,12 +146,96 @@ func cleanNodeContent(content string, language string) string {
 			for i, l := range rawLines {
 				rawLines[i] = strings.TrimRightFunc(l, unicode.IsSpace)
 			}
-			return strings.Join(dedent(rawLines), "\n")
+			return strings.Join(dedentLines(rawLines), "\n")
 		}
 	}
 	return strings.TrimSpace(content)
 }
 
+// dedentLines removes the indentation that all non-blank lines share.
+func dedentLines(lines []string) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	// Find common indent
+	commonIndent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := 0
+		for _, r := range line {
+			if unicode.IsSpace(r) {
+				indent++
+			} else {
+				break
+			}
+		}
+		if commonIndent == -1 || indent < commonIndent {
+			commonIndent = indent
+		}
+	}
+
+	if commonIndent <= 0 {
+		return lines
+	}
+
+	var result []string
+	for _, line := range lines {
+		if len(line) >= commonIndent {
+			result = append(result, line[commonIndent:])
+		} else {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// trimRustDocMarker turns Rust doc comments (//!, ///, /*!) into plain ones.
+// Four or more slashes make an ordinary comment again.
+func trimRustDocMarker(content string) string {
+	switch {
+	case strings.HasPrefix(content, "////"):
+		return content
+	case strings.HasPrefix(content, "//!"), strings.HasPrefix(content, "///"):
+		return "//" + content[3:]
+	case strings.HasPrefix(content, "/*!"):
+		return "/*" + content[3:]
+	}
+	return content
+}
+
+// cleanDocString strips the quotes of a docstring and dedents it the way
+// Python's inspect.cleandoc does: the first line loses its leading whitespace
+// and the others lose the indentation they share.
+func cleanDocString(content string, language string) string {
+	switch strings.ToLower(language) {
+	case "python", "py":
+		content = strings.TrimLeft(content, "rRuUbBfF")
+		for _, quote := range []string{`"""`, "'''", `"`, "'"} {
+			if len(content) >= 2*len(quote) && strings.HasPrefix(content, quote) && strings.HasSuffix(content, quote) {
+				content = content[len(quote) : len(content)-len(quote)]
+				break
+			}
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	lines[0] = strings.TrimLeftFunc(lines[0], unicode.IsSpace)
+	lines = append(lines[:1], dedentLines(lines[1:])...)
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func isCommentStandalone(sourceContent []byte, startByte int) bool {
 	// Find the start of the current line
 	lineStart := bytes.LastIndexByte(sourceContent[:startByte], '\n') + 1
@@ -195,26 +245,28 @@ func isCommentStandalone(sourceContent []byte, startByte int) bool {
 }
 
 func (h *CommentAnalyzer) Analyze(sourceContent []byte) ([]CommentInfo, error) {
-	cfg, err := languages.GetConfig(h.language)
+	if !hasGrammar(h.language) {
+		comments, err := chromaComments(h.Directives, h.language, sourceContent)
+		if err != nil {
+			return nil, err
+		}
+		return h.finishComments(comments, sourceContent, nil), nil
+	}
+	tree, err := ParseSource(h.language, sourceContent)
 	if err != nil {
 		return nil, err
 	}
+	defer tree.Close()
+	return h.AnalyzeTree(tree)
+}
 
-	q, err := getCommentQuery(h.language)
+// AnalyzeTree collects the standalone comments of an already parsed file.
+func (h *CommentAnalyzer) AnalyzeTree(tree *SourceTree) ([]CommentInfo, error) {
+	query, err := cachedQuery(h.language, commentQueryKind)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get comment query for %s", h.language)
-	}
-	query, queryErr := sitter.NewQuery(cfg.SitterLanguage, q)
-	if queryErr != nil {
-		return nil, errors.Wrapf(queryErr, "comment analyzer failed to create query for %s", h.language)
+		return nil, err
 	}
-
-	parser := sitter.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(cfg.SitterLanguage)
-
-	tree := parser.Parse(sourceContent, nil)
-	defer tree.Close()
+	sourceContent := tree.Content
 
 	qc := sitter.NewQueryCursor()
 	defer qc.Close()
@@ -222,6 +274,14 @@ func (h *CommentAnalyzer) Analyze(sourceContent []byte) ([]CommentInfo, error) {
 	matches := qc.Matches(query, tree.RootNode(), sourceContent)
 
 	var tokens []CommentInfo
+	// docComments holds the start of each doc comment, whose tags are parsed.
+	docComments := make(map[scip.Position]bool)
+	// lineStarts is only needed to look past comments that may document the
+	// next line, and is computed once for the whole file.
+	var lineStarts []int
+	if documentsNextLine(h.language) {
+		lineStarts = sourceLineStarts(sourceContent)
+	}
 
 	for match := matches.Next(); match != nil; match = matches.Next() {
 		if len(match.Captures) == 0 {
@@ -238,10 +298,42 @@ func (h *CommentAnalyzer) Analyze(sourceContent []byte) ([]CommentInfo, error) {
 		var contentParts []string
 		var start scip.Position
 		var end scip.Position
+		var opening string
 		first := true
+		flush := func() {
+			if !first {
+				tokens = append(tokens, CommentInfo{
+					Content: strings.Join(contentParts, "\n"),
+					Span: scip.Range{
+						Start: start,
+						End:   end,
+					},
+				})
+				if isDocCommentMarker(opening) || (lineStarts != nil && precedesCode(sourceContent, lineStarts, end)) {
+					docComments[start] = true
+				}
+			}
+			contentParts = nil
+			first = true
+		}
 
 		for _, capture := range match.Captures {
 			node := capture.Node
+			// A shebang is a comment to the grammar but not prose.
+			if node.StartByte() == 0 && bytes.HasPrefix(sourceContent, []byte("#!")) {
+				continue
+			}
+			nodeContent := string(sourceContent[node.StartByte():node.EndByte()])
+			// Directives stay in the code and split the prose around them.
+			if h.Directives.isDirective(h.language, nodeContent) {
+				flush()
+				continue
+			}
+			// A doc comment starts its own prose, so its tags are parsed apart
+			// from the comments above it.
+			if !first && isDocCommentMarker(nodeContent) && !isDocCommentMarker(opening) {
+				flush()
+			}
 
 			// Capture range
 			s := scip.Position{
@@ -255,28 +347,196 @@ func (h *CommentAnalyzer) Analyze(sourceContent []byte) ([]CommentInfo, error) {
 
 			if first {
 				start = s
+				opening = nodeContent
 				first = false
 			}
 			end = e
 
-			startByte := node.StartByte()
-			endByte := node.EndByte()
-			nodeContent := string(sourceContent[startByte:endByte])
 			cleaned := cleanNodeContent(nodeContent, h.language)
 			contentParts = append(contentParts, cleaned)
 		}
+		flush()
+	}
 
-		if !first {
-			token := CommentInfo{
-				Content: strings.Join(contentParts, "\n"),
-				Span: scip.Range{
-					Start: start,
-					End:   end,
-				},
+	docs, err := h.docStrings(tree)
+	if err != nil {
+		return nil, err
+	}
+	markers := regionMarkerComments(sourceContent, func(_ int, pos scip.Position) bool {
+		return startsCommentNode(tree, pos)
+	})
+	for _, doc := range docs {
+		docComments[doc.Span.Start] = true
+	}
+	if len(docs) > 0 || len(markers) > 0 {
+		tokens = append(tokens, docs...)
+		tokens = append(tokens, markers...)
+		SortBySpan(tokens)
+	}
+
+	return h.finishComments(tokens, sourceContent, docComments), nil
+}
+
+// startsCommentNode reports whether a comment or preprocessor node of tree
+// starts at pos.
+func startsCommentNode(tree *SourceTree, pos scip.Position) bool {
+	point := sitter.NewPoint(uint(pos.Line), uint(pos.Character))
+	for node := tree.RootNode().DescendantForPointRange(point, point); node != nil; node = node.Parent() {
+		if node.StartPosition() != point {
+			return false
+		}
+		kind := node.Kind()
+		if strings.Contains(kind, "comment") || strings.HasPrefix(kind, "preproc") {
+			return true
+		}
+	}
+	return false
+}
+
+// finishComments moves the front matter of the leading comment out of its
+// prose and parses the tags of the doc comments, which docComments holds by
+// their start.
+func (h *CommentAnalyzer) finishComments(comments []CommentInfo, sourceContent []byte, docComments map[scip.Position]bool) []CommentInfo {
+	leading := true
+	for i := range comments {
+		if comments[i].RegionMarker {
+			continue
+		}
+		if leading && h.isLeadingComment(sourceContent, comments[i].Span) {
+			if frontMatter, rest, ok := splitFrontMatter(comments[i].Content); ok {
+				comments[i].FrontMatter = frontMatter
+				comments[i].Content = rest
 			}
-			tokens = append(tokens, token)
+		}
+		leading = false
+		if docComments[comments[i].Span.Start] {
+			comments[i].Doc = ParseDocComment(comments[i].Content, h.language)
 		}
 	}
+	return comments
+}
 
-	return tokens, nil
+// isDocCommentMarker reports whether a comment, as written, opens with a doc
+// comment marker: /** or /*! blocks, /// or //! lines, or Haddock's -- |.
+func isDocCommentMarker(comment string) bool {
+	switch {
+	case strings.HasPrefix(comment, "/**"):
+		return comment != "/**/" && !strings.HasPrefix(comment, "/***")
+	case strings.HasPrefix(comment, "///"):
+		return !strings.HasPrefix(comment, "////")
+	case strings.HasPrefix(comment, "/*!"), strings.HasPrefix(comment, "//!"),
+		strings.HasPrefix(comment, "-- |"), strings.HasPrefix(comment, "{- |"):
+		return true
+	}
+	return false
+}
+
+// documentsNextLine reports whether language writes doc comments as plain
+// comments directly above what they document, as GoDoc and YARD do.
+func documentsNextLine(language string) bool {
+	switch strings.ToLower(language) {
+	case "go", "golang", "ruby":
+		return true
+	}
+	return false
+}
+
+// sourceLineStarts returns the byte offset at which each line of sourceContent starts.
+func sourceLineStarts(sourceContent []byte) []int {
+	starts := []int{0}
+	for i, b := range sourceContent {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// precedesCode reports whether the line after end holds code. lineStarts are
+// the line offsets of sourceContent, as returned by sourceLineStarts.
+func precedesCode(sourceContent []byte, lineStarts []int, end scip.Position) bool {
+	next := int(end.Line) + 1
+	if next >= len(lineStarts) {
+		return false
+	}
+	stop := len(sourceContent)
+	if next+1 < len(lineStarts) {
+		stop = lineStarts[next+1]
+	}
+	return len(bytes.TrimSpace(sourceContent[lineStarts[next]:stop])) > 0
+}
+
+// isLeadingComment reports whether only blank lines, a shebang and comment
+// directives come before span, as in a file that opens with front matter after
+// its //go:build line.
+func (h *CommentAnalyzer) isLeadingComment(sourceContent []byte, span scip.Range) bool {
+	lines := strings.SplitN(string(sourceContent), "\n", int(span.Start.Line)+1)
+	for i, line := range lines[:len(lines)-1] {
+		line = strings.TrimSpace(line)
+		if line == "" || (i == 0 && strings.HasPrefix(line, "#!")) || h.Directives.isDirective(h.language, line) {
+			continue
+		}
+		return false
+	}
+	before := lines[len(lines)-1]
+	return int(span.Start.Character) <= len(before) && strings.TrimSpace(before[:span.Start.Character]) == ""
+}
+
+// splitFrontMatter splits a comment that opens with a YAML front matter block,
+// delimited by --- lines, into the YAML and the prose after it.
+func splitFrontMatter(content string) (frontMatter string, rest string, ok bool) {
+	lines := strings.Split(content, "\n")
+	if len(lines) < 2 || strings.TrimSpace(lines[0]) != "---" {
+		return "", content, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == "---" || trimmed == "..." {
+			rest := lines[i+1:]
+			for len(rest) > 0 && strings.TrimSpace(rest[0]) == "" {
+				rest = rest[1:]
+			}
+			return strings.Join(lines[1:i], "\n"), strings.Join(rest, "\n"), true
+		}
+	}
+	return "", content, false
+}
+
+// docStrings collects the strings the language's docs query marks as
+// documentation, such as Python docstrings, as prose.
+func (h *CommentAnalyzer) docStrings(tree *SourceTree) ([]CommentInfo, error) {
+	query, err := cachedQuery(h.language, docsQueryKind)
+	if err != nil || query == nil {
+		return nil, err
+	}
+	sourceContent := tree.Content
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	var docs []CommentInfo
+	captures := qc.Captures(query, tree.RootNode(), sourceContent)
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		capture := match.Captures[index]
+		if query.CaptureNames()[capture.Index] != "doc" {
+			continue
+		}
+		node := capture.Node
+		if !isCommentStandalone(sourceContent, int(node.StartByte())) {
+			continue
+		}
+		docs = append(docs, CommentInfo{
+			Content: cleanDocString(string(sourceContent[node.StartByte():node.EndByte()]), h.language),
+			Span: scip.Range{
+				Start: scip.Position{
+					Line:      int32(node.StartPosition().Row),
+					Character: int32(node.StartPosition().Column),
+				},
+				End: scip.Position{
+					Line:      int32(node.EndPosition().Row),
+					Character: int32(node.EndPosition().Column),
+				},
+			},
+		})
+	}
+	return docs, nil
 }
@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/sourcegraph/scip/bindings/go/scip"
 )
 
 func TestCleanNodeContent(t *testing.T) {
@@ -138,6 +144,34 @@ func TestCleanNodeContent(t *testing.T) {
 			language: "haskell",
 			want:     "Line 1\nLine 2",
 		},
+
+		// ---------------------------------------------------------------------
+		// Rust doc comments
+		// ---------------------------------------------------------------------
+		{
+			name:     "Rust inner line doc",
+			content:  "//! Crate docs.",
+			language: "rust",
+			want:     "Crate docs.",
+		},
+		{
+			name:     "Rust outer line doc",
+			content:  "/// Adds two numbers.\n",
+			language: "rust",
+			want:     "Adds two numbers.",
+		},
+		{
+			name:     "Rust four slashes are a plain comment",
+			content:  "//// divider",
+			language: "rust",
+			want:     "// divider",
+		},
+		{
+			name:     "Rust inner block doc",
+			content:  "/*!\n * Crate docs.\n */",
+			language: "rust",
+			want:     "Crate docs.",
+		},
 	}
 
 	for _, tt := range tests {
@@ -219,3 +253,229 @@ func TestIsCommentStandalone(t *testing.T) {
 
 // Ensure the function is exported for testing (if it wasn't already in the same package)
 // Since this test is in package 'internal', it can access unexported 'cleanNodeContent'.
+
+func TestCleanDocString(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "one line",
+			content: `"""Return the answer."""`,
+			want:    "Return the answer.",
+		},
+		{
+			name:    "indented body",
+			content: "\"\"\"Summary.\n\n        Details that\n          continue.\n        \"\"\"",
+			want:    "Summary.\n\nDetails that\n  continue.",
+		},
+		{
+			name:    "summary on the second line",
+			content: "'''\n    # Usage\n\n    Call it.\n    '''",
+			want:    "# Usage\n\nCall it.",
+		},
+		{
+			name:    "raw prefix and single quotes",
+			content: `r'Match \d+.'`,
+			want:    `Match \d+.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanDocString(tt.content, "python"); got != tt.want {
+				t.Errorf("cleanDocString(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentAnalyzerCollectsPythonDocstrings(t *testing.T) {
+	source := `#!/usr/bin/env python3
+"""# Deploying
+
+Steps for a release.
+"""
+import os
+
+x = "not a docstring"
+
+
+class Site:
+    """A generated site."""
+
+    def build(self):
+        """Build every page.
+
+        Pages are written in parallel.
+        """
+        value = "also not a docstring"
+        return value
+`
+	comments, err := NewCommentAnalyzer("python").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	var got []string
+	for _, comment := range comments {
+		got = append(got, comment.Content)
+	}
+	want := []string{
+		"# Deploying\n\nSteps for a release.",
+		"A generated site.",
+		"Build every page.\n\nPages are written in parallel.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("comments = %#v, want %#v", got, want)
+	}
+	if span := comments[2].Span; span.Start.Line != 14 || span.Start.Character != 8 || span.End.Line != 17 {
+		t.Fatalf("method docstring span = %+v, want lines 14 to 17 from column 8", span)
+	}
+}
+
+func TestCommentAnalyzerCleansRustDocComments(t *testing.T) {
+	source := "//! Crate docs.\n//! More.\n\n/// Adds.\nfn add() {}\n"
+	comments, err := NewCommentAnalyzer("rust").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) == 0 || comments[0].Content != "Crate docs.\nMore.\nAdds." {
+		t.Fatalf("comments = %#v, want doc markers stripped", comments)
+	}
+}
+
+func TestCommentAnalyzerLeavesDirectivesInCode(t *testing.T) {
+	source := "//go:build linux\n\n// Package x does things.\n//nolint:all\n// It is small.\npackage x\n\n//go:generate stringer -type=Kind\n"
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	want := []CommentInfo{
+		{
+			Content: "Package x does things.",
+			Span:    scip.Range{Start: scip.Position{Line: 2}, End: scip.Position{Line: 2, Character: 25}},
+		},
+		{
+			Content: "It is small.",
+			Span:    scip.Range{Start: scip.Position{Line: 4}, End: scip.Position{Line: 4, Character: 15}},
+		},
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Fatalf("comments = %#v, want %#v", comments, want)
+	}
+}
+
+func TestLoadCommentDirectivesExtendsRegistry(t *testing.T) {
+	directives, err := LoadCommentDirectives(map[string][]string{"shell": {`^#\s*@generated\b`}})
+	if err != nil {
+		t.Fatalf("LoadCommentDirectives returned error: %v", err)
+	}
+
+	source := "# shellcheck disable=SC2086\n# @generated by make\n# Deploys the site.\n"
+	analyzer := NewCommentAnalyzer("shell")
+	analyzer.Directives = directives
+	comments, err := analyzer.Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Content != "Deploys the site." || comments[0].Span.Start.Line != 2 {
+		t.Fatalf("comments = %#v, want only the prose comment", comments)
+	}
+
+	// Analyzers without the project's directives keep the registry's alone.
+	comments, err = NewCommentAnalyzer("shell").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 1 || !strings.HasPrefix(comments[0].Content, "@generated by make") {
+		t.Fatalf("comments = %#v, want the @generated line as prose", comments)
+	}
+
+	if _, err := LoadCommentDirectives(map[string][]string{"go": {"(unclosed"}}); err == nil {
+		t.Fatal("LoadCommentDirectives accepted an invalid pattern")
+	}
+}
+
+func TestBuiltinCommentDirectivesCompile(t *testing.T) {
+	for _, language := range languages.Names() {
+		cfg, err := languages.GetConfig(language)
+		if err != nil {
+			t.Fatalf("GetConfig(%s) returned error: %v", language, err)
+		}
+		for _, pattern := range cfg.CommentDirectives {
+			if _, err := regexp.Compile(pattern); err != nil {
+				t.Errorf("%s comment directive %q does not compile: %v", language, pattern, err)
+			}
+		}
+	}
+}
+
+func TestCommentAnalyzerSplitsLeadingFrontMatter(t *testing.T) {
+	source := "//go:build tools\n\n// ---\n// title: Tools\n// ---\n//\n// Pins tool versions.\npackage tools\n\n// ---\n// not: front matter\n// ---\nvar x = 1\n"
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("comments = %#v, want two", comments)
+	}
+	if comments[0].FrontMatter != "title: Tools" || comments[0].Content != "Pins tool versions." {
+		t.Fatalf("leading comment = %#v, want front matter split from prose", comments[0])
+	}
+	if comments[1].FrontMatter != "" || comments[1].Content != "---\nnot: front matter\n---" {
+		t.Fatalf("later comment = %#v, want it left alone", comments[1])
+	}
+
+	comments, err = NewCommentAnalyzer("go").Analyze([]byte("package x\n\n// ---\n// title: Late\n// ---\n"))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].FrontMatter != "" {
+		t.Fatalf("comments = %#v, want no front matter after code", comments)
+	}
+}
+
+func TestCommentAnalyzerParsesTagsOfDocCommentsOnly(t *testing.T) {
+	source := strings.Join([]string{
+		"// Setup notes, see below.",
+		"// @param nothing here is a tag",
+		"",
+		"/**",
+		" * Adds two numbers.",
+		" * @param a first",
+		" */",
+		"function add(a, b) { return a + b }",
+	}, "\n")
+	comments, err := NewCommentAnalyzer("javascript").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("comments = %#v, want two", comments)
+	}
+	if comments[0].Doc != nil {
+		t.Fatalf("plain comment Doc = %#v, want nil", comments[0].Doc)
+	}
+	if doc := comments[1].Doc; doc == nil || len(doc.Params) != 1 || doc.Params[0].Name != "a" {
+		t.Fatalf("doc comment Doc = %#v, want the param a", doc)
+	}
+
+	for _, tt := range []struct {
+		source string
+		doc    bool
+	}{
+		{"package x\n\nvar y = 1\n\n// Old notes.\n//\n// Deprecated: kept for history.\n\nfunc F() {}\n", false},
+		{"package x\n\nvar y = 1\n\n// F does it.\n//\n// Deprecated: use G.\nfunc F() {}\n", true},
+	} {
+		comments, err := NewCommentAnalyzer("go").Analyze([]byte(tt.source))
+		if err != nil {
+			t.Fatalf("Analyze returned error: %v", err)
+		}
+		if len(comments) != 1 || (comments[0].Doc != nil) != tt.doc {
+			t.Fatalf("comments = %#v, want one with parsed tags %v", comments, tt.doc)
+		}
+	}
+}
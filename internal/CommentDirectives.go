@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/cockroachdb/errors"
+)
+
+var (
+	registryCommentDirectivesMu sync.Mutex
+	// registryCommentDirectives caches the compiled patterns of the language
+	// registry per language.
+	registryCommentDirectives = map[string][]*regexp.Regexp{}
+)
+
+// CommentDirectives holds the patterns, keyed by canonical language, that a
+// project adds to the comment directives of the language registry. A nil
+// *CommentDirectives has only the registry's.
+type CommentDirectives struct {
+	patterns map[string][]*regexp.Regexp
+}
+
+// LoadCommentDirectives compiles patterns, keyed by language, into comment
+// directives added to those of the language registry.
+func LoadCommentDirectives(patterns map[string][]string) (*CommentDirectives, error) {
+	compiled := make(map[string][]*regexp.Regexp, len(patterns))
+	for language, sources := range patterns {
+		canonical, err := languages.CanonicalName(strings.TrimSpace(language))
+		if err != nil {
+			return nil, errors.Wrapf(err, "comment directives for %s", language)
+		}
+		for _, source := range sources {
+			re, err := regexp.Compile(source)
+			if err != nil {
+				return nil, errors.Wrapf(err, "comment directive %q for %s", source, language)
+			}
+			compiled[canonical] = append(compiled[canonical], re)
+		}
+	}
+	return &CommentDirectives{patterns: compiled}, nil
+}
+
+// isDirective reports whether a comment, as written in the source with its
+// markers, is a directive of language rather than prose. Region markers and
+// include directives are directives in every language.
+func (d *CommentDirectives) isDirective(language string, comment string) bool {
+	comment = strings.TrimSpace(comment)
+	if isCodeRegionMarker(comment) || isCodeInclude(comment) {
+		return true
+	}
+	canonical, err := languages.CanonicalName(language)
+	if err != nil {
+		return false
+	}
+	for _, re := range registryDirectives(canonical) {
+		if re.MatchString(comment) {
+			return true
+		}
+	}
+	if d != nil {
+		for _, re := range d.patterns[canonical] {
+			if re.MatchString(comment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func registryDirectives(canonical string) []*regexp.Regexp {
+	registryCommentDirectivesMu.Lock()
+	defer registryCommentDirectivesMu.Unlock()
+	if patterns, ok := registryCommentDirectives[canonical]; ok {
+		return patterns
+	}
+
+	var patterns []*regexp.Regexp
+	if cfg, err := languages.GetConfig(canonical); err == nil {
+		for _, source := range cfg.CommentDirectives {
+			patterns = append(patterns, regexp.MustCompile(source))
+		}
+	}
+	registryCommentDirectives[canonical] = patterns
+	return patterns
+}
@@ -0,0 +1,483 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DocComment is a doc comment split into its description and the parts that
+// Javadoc, JSDoc, PHPDoc, YARD, Sphinx, GoDoc and rustdoc conventions mark.
+type DocComment struct {
+	Description     string
+	Params          []DocParam
+	Returns         *DocReturn
+	Throws          []DocThrows
+	Deprecated      bool
+	DeprecationNote string
+	Sections        []DocSection // rustdoc sections such as # Errors and # Panics
+	Tags            []DocTag     // Other block tags, such as @since and @see
+}
+
+type DocParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+type DocReturn struct {
+	Type        string
+	Description string
+}
+
+type DocThrows struct {
+	Type        string
+	Description string
+}
+
+type DocSection struct {
+	Title string
+	Body  string
+}
+
+type DocTag struct {
+	Name string
+	Text string
+}
+
+var (
+	docBlockTagPattern    = regexp.MustCompile(`(?i)^@(param|arg|argument|returns?|throws?|exception|raises?|deprecated|since|see|author|version|example|yields?)\b\s*(.*)$`)
+	docInlineTagPattern   = regexp.MustCompile(`\{@(?:link|linkcode|linkplain|code|literal)\s+([^}]*)\}`)
+	docSphinxFieldPattern = regexp.MustCompile(`^:(param|parameter|arg|argument|key|keyword|type|returns?|rtype|raises?|except|exception)\b\s*([^:]*):\s*(.*)$`)
+	docSphinxDeprecated   = regexp.MustCompile(`^\.\. deprecated::\s*(.*)$`)
+	rustDocSectionPattern = regexp.MustCompile(`^(#{1,3})\s+(Errors|Panics|Safety)\s*$`)
+)
+
+// ParseDocComment reads the tag conventions of language out of a cleaned
+// comment. It returns nil when the comment has none, so plain prose keeps
+// rendering as written.
+func ParseDocComment(content string, language string) *DocComment {
+	doc := &DocComment{}
+	lines := strings.Split(content, "\n")
+
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		lines = doc.takeGoDeprecation(lines)
+	case "rust":
+		lines = doc.takeRustSections(lines)
+	case "python", "py":
+		lines = doc.takeSphinxFields(lines)
+		lines = doc.takeBlockTags(lines, language)
+	default:
+		lines = doc.takeBlockTags(lines, language)
+	}
+
+	if !doc.HasTags() {
+		return nil
+	}
+	doc.Description = replaceDocInlineTags(trimDocLines(lines))
+	return doc
+}
+
+// HasTags reports whether the comment had anything besides its description.
+func (d *DocComment) HasTags() bool {
+	return d != nil && (len(d.Params) > 0 || d.Returns != nil || len(d.Throws) > 0 ||
+		d.Deprecated || len(d.Sections) > 0 || len(d.Tags) > 0)
+}
+
+// takeBlockTags moves the @tags docBlockTagPattern knows and the lines that
+// continue them out of lines. Like Javadoc, everything after the first block
+// tag belongs to some tag; other @words, such as a mention, stay prose.
+func (d *DocComment) takeBlockTags(lines []string, language string) []string {
+	var description []string
+	var tagName string
+	var tagLines []string
+	flush := func() {
+		if tagName != "" {
+			d.addBlockTag(tagName, strings.TrimSpace(strings.Join(tagLines, "\n")), language)
+		}
+		tagName, tagLines = "", nil
+	}
+
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isDocFence(trimmed) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if match := docBlockTagPattern.FindStringSubmatch(trimmed); match != nil {
+				flush()
+				tagName, tagLines = strings.ToLower(match[1]), []string{match[2]}
+				continue
+			}
+		}
+		if tagName != "" {
+			tagLines = append(tagLines, line)
+		} else {
+			description = append(description, line)
+		}
+	}
+	flush()
+	return description
+}
+
+func (d *DocComment) addBlockTag(name, text, language string) {
+	switch name {
+	case "param", "arg", "argument":
+		typ, rest := splitDocType(text, language)
+		name, rest := splitDocWord(rest)
+		// PHPDoc writes the type before a $name without braces.
+		if typ == "" && !strings.HasPrefix(name, "$") {
+			if next, after := splitDocWord(rest); strings.HasPrefix(next, "$") {
+				typ, name, rest = name, next, after
+			}
+		}
+		// JSDoc marks optional parameters as [name] or [name=default].
+		if strings.HasPrefix(name, "[") {
+			name, _, _ = strings.Cut(strings.Trim(name, "[]"), "=")
+		}
+		d.Params = append(d.Params, DocParam{Name: name, Type: typ, Description: trimDocDash(rest)})
+	case "return", "returns":
+		typ, rest := splitDocType(text, language)
+		if typ == "" && strings.EqualFold(language, "php") {
+			typ, rest = splitDocWord(rest)
+		}
+		d.Returns = &DocReturn{Type: typ, Description: trimDocDash(rest)}
+	case "throws", "throw", "exception", "raise", "raises":
+		typ, rest := splitDocType(text, language)
+		if typ == "" {
+			typ, rest = splitDocWord(rest)
+		}
+		d.Throws = append(d.Throws, DocThrows{Type: typ, Description: trimDocDash(rest)})
+	case "deprecated":
+		d.Deprecated = true
+		d.DeprecationNote = text
+	default:
+		d.Tags = append(d.Tags, DocTag{Name: name, Text: text})
+	}
+}
+
+// takeGoDeprecation moves a "Deprecated: " paragraph, the GoDoc convention,
+// out of lines.
+func (d *DocComment) takeGoDeprecation(lines []string) []string {
+	var kept []string
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if isDocFence(trimmed) {
+			inFence = !inFence
+		}
+		if inFence || d.Deprecated || !strings.HasPrefix(trimmed, "Deprecated: ") || (i > 0 && strings.TrimSpace(lines[i-1]) != "") {
+			kept = append(kept, lines[i])
+			continue
+		}
+		paragraph := []string{strings.TrimPrefix(trimmed, "Deprecated: ")}
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			i++
+			paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+		}
+		// Drop the blank line after the paragraph so the prose around it
+		// keeps a single paragraph break.
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "" {
+			i++
+		}
+		d.Deprecated = true
+		d.DeprecationNote = strings.Join(paragraph, "\n")
+	}
+	return kept
+}
+
+// takeRustSections moves the # Errors, # Panics and # Safety sections of a
+// rustdoc comment out of lines. Code fences are skipped, since hidden lines in
+// examples also start with #.
+func (d *DocComment) takeRustSections(lines []string) []string {
+	var kept []string
+	var section *DocSection
+	var body []string
+	level := 0
+	flush := func() {
+		if section != nil {
+			section.Body = trimDocLines(body)
+			d.Sections = append(d.Sections, *section)
+		}
+		section, body = nil, nil
+	}
+
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isDocFence(trimmed) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if match := rustDocSectionPattern.FindStringSubmatch(trimmed); match != nil {
+				flush()
+				section = &DocSection{Title: match[2]}
+				level = len(match[1])
+				continue
+			}
+			if section != nil && isMarkdownHeadingAtOrAbove(trimmed, level) {
+				flush()
+			}
+		}
+		if section != nil {
+			body = append(body, line)
+		} else {
+			kept = append(kept, line)
+		}
+	}
+	flush()
+	return kept
+}
+
+// takeSphinxFields moves reStructuredText field lists such as :param x: and
+// :raises ValueError: and the deprecated directive out of lines.
+func (d *DocComment) takeSphinxFields(lines []string) []string {
+	var kept []string
+	types := make(map[string]string)
+	var current *string
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isDocFence(trimmed) {
+			inFence = !inFence
+		}
+		if inFence {
+			current = nil
+			kept = append(kept, line)
+			continue
+		}
+		if match := docSphinxFieldPattern.FindStringSubmatch(trimmed); match != nil {
+			current = d.addSphinxField(match[1], strings.Fields(match[2]), match[3], types)
+			continue
+		}
+		if match := docSphinxDeprecated.FindStringSubmatch(trimmed); match != nil {
+			d.Deprecated = true
+			d.DeprecationNote = match[1]
+			current = &d.DeprecationNote
+			continue
+		}
+		// Indented lines continue the field above them.
+		if current != nil && trimmed != "" && line != trimmed {
+			*current = strings.TrimSpace(*current + " " + trimmed)
+			continue
+		}
+		current = nil
+		kept = append(kept, line)
+	}
+
+	for i := range d.Params {
+		if typ, ok := types[d.Params[i].Name]; ok && d.Params[i].Type == "" {
+			d.Params[i].Type = typ
+		}
+	}
+	return kept
+}
+
+func (d *DocComment) addSphinxField(field string, args []string, text string, types map[string]string) *string {
+	switch field {
+	case "param", "parameter", "arg", "argument", "key", "keyword":
+		if len(args) == 0 {
+			return nil
+		}
+		d.Params = append(d.Params, DocParam{
+			Name:        args[len(args)-1],
+			Type:        strings.Join(args[:len(args)-1], " "),
+			Description: text,
+		})
+		return &d.Params[len(d.Params)-1].Description
+	case "type":
+		if len(args) > 0 {
+			types[args[0]] = text
+		}
+		return nil
+	case "return", "returns":
+		if d.Returns == nil {
+			d.Returns = &DocReturn{}
+		}
+		d.Returns.Description = text
+		return &d.Returns.Description
+	case "rtype":
+		if d.Returns == nil {
+			d.Returns = &DocReturn{}
+		}
+		d.Returns.Type = text
+		return nil
+	default:
+		d.Throws = append(d.Throws, DocThrows{Type: strings.Join(args, " "), Description: text})
+		return &d.Throws[len(d.Throws)-1].Description
+	}
+}
+
+// Markdown renders the comment as Markdown, wrapping each part in a raw HTML
+// block whose classes themes can style. classAttr is "class" for HTML and
+// "className" for MDX.
+func (d *DocComment) Markdown(classAttr string) string {
+	var sb strings.Builder
+	block := func(class string, body string) {
+		fmt.Fprintf(&sb, "<div %s=\"%s\">\n\n%s\n\n</div>\n\n", classAttr, class, body)
+	}
+
+	if d.Deprecated {
+		note := "**Deprecated.**"
+		if d.DeprecationNote != "" {
+			note += " " + replaceDocInlineTags(d.DeprecationNote)
+		}
+		block("cire-doc-deprecated", note)
+	}
+	if d.Description != "" {
+		sb.WriteString(d.Description)
+		sb.WriteString("\n\n")
+	}
+	if len(d.Params) > 0 {
+		block("cire-doc-params", d.paramsTable())
+	}
+	if d.Returns != nil {
+		block("cire-doc-returns", "**Returns** "+docTypedText(d.Returns.Type, d.Returns.Description))
+	}
+	if len(d.Throws) > 0 {
+		var items []string
+		for _, throws := range d.Throws {
+			items = append(items, "- "+docTypedText(throws.Type, throws.Description))
+		}
+		block("cire-doc-throws", "**Throws**\n\n"+strings.Join(items, "\n"))
+	}
+	for _, section := range d.Sections {
+		block("cire-doc-section", "**"+section.Title+"**\n\n"+section.Body)
+	}
+	if len(d.Tags) > 0 {
+		var items []string
+		for _, tag := range d.Tags {
+			items = append(items, strings.TrimSpace("- **@"+tag.Name+"** "+replaceDocInlineTags(tag.Text)))
+		}
+		block("cire-doc-tags", strings.Join(items, "\n"))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func (d *DocComment) paramsTable() string {
+	typed := false
+	for _, param := range d.Params {
+		typed = typed || param.Type != ""
+	}
+
+	var sb strings.Builder
+	if typed {
+		sb.WriteString("| Parameter | Type | Description |\n| --- | --- | --- |\n")
+	} else {
+		sb.WriteString("| Parameter | Description |\n| --- | --- |\n")
+	}
+	for _, param := range d.Params {
+		fmt.Fprintf(&sb, "| %s |", docCode(param.Name))
+		if typed {
+			fmt.Fprintf(&sb, " %s |", docCode(param.Type))
+		}
+		fmt.Fprintf(&sb, " %s |\n", docTableCell(replaceDocInlineTags(param.Description)))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// structureHoverDocument renders the tags of a hover's documentation the way
+// prose renders them, leaving documentation without tags untouched.
+func structureHoverDocument(document string, language string) string {
+	doc := ParseDocComment(document, language)
+	if doc == nil {
+		return document
+	}
+	return doc.Markdown("class")
+}
+
+func docTypedText(typ, description string) string {
+	description = replaceDocInlineTags(description)
+	switch {
+	case typ == "":
+		return description
+	case description == "":
+		return docCode(typ)
+	default:
+		return docCode(typ) + " — " + description
+	}
+}
+
+func docCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(text, "`", "'") + "`"
+}
+
+func docTableCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// splitDocType takes a {Type} (or, for YARD, a [Type]) off the front of text.
+func splitDocType(text string, language string) (string, string) {
+	text = strings.TrimSpace(text)
+	open, close := byte('{'), byte('}')
+	if strings.EqualFold(language, "ruby") {
+		open, close = '[', ']'
+	}
+	if text == "" || text[0] != open {
+		return "", text
+	}
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(text[1:i]), strings.TrimSpace(text[i+1:])
+			}
+		}
+	}
+	return "", text
+}
+
+func splitDocWord(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	i := strings.IndexFunc(text, unicode.IsSpace)
+	if i < 0 {
+		return text, ""
+	}
+	return text[:i], strings.TrimSpace(text[i:])
+}
+
+func trimDocDash(text string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "- "))
+}
+
+// replaceDocInlineTags turns {@link Foo} and {@code x} into inline code.
+func replaceDocInlineTags(text string) string {
+	return docInlineTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		match := docInlineTagPattern.FindStringSubmatch(tag)
+		target, label, _ := strings.Cut(strings.TrimSpace(match[1]), " ")
+		if label = strings.TrimSpace(label); label != "" && !strings.HasPrefix(tag, "{@code") && !strings.HasPrefix(tag, "{@literal") {
+			return label + " (" + docCode(target) + ")"
+		}
+		return docCode(strings.TrimSpace(match[1]))
+	})
+}
+
+func trimDocLines(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(dedentLines(lines), "\n")
+}
+
+func isDocFence(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+func isMarkdownHeadingAtOrAbove(trimmed string, level int) bool {
+	hashes := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+	return hashes > 0 && hashes <= level && len(trimmed) > hashes && trimmed[hashes] == ' '
+}
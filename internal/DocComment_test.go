@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDocComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		want     *DocComment
+	}{
+		{
+			name:     "plain prose",
+			language: "java",
+			content:  "Adds two numbers.\n\nMail me at a@b.c.",
+			want:     nil,
+		},
+		{
+			name:     "javadoc",
+			language: "java",
+			content: "Parses a port.\n\n@param text the text to parse,\n  without spaces\n@return the port\n" +
+				"@throws NumberFormatException if {@code text} is not a number\n@deprecated use {@link Ports#parse}\n@since 1.2",
+			want: &DocComment{
+				Description:     "Parses a port.",
+				Params:          []DocParam{{Name: "text", Description: "the text to parse,\n  without spaces"}},
+				Returns:         &DocReturn{Description: "the port"},
+				Throws:          []DocThrows{{Type: "NumberFormatException", Description: "if {@code text} is not a number"}},
+				Deprecated:      true,
+				DeprecationNote: "use {@link Ports#parse}",
+				Tags:            []DocTag{{Name: "since", Text: "1.2"}},
+			},
+		},
+		{
+			name:     "jsdoc",
+			language: "javascript",
+			content:  "Adds.\n@param {number} a - first\n@param {number} [b=0] second\n@returns {number} the sum\n@throws {RangeError} on overflow",
+			want: &DocComment{
+				Description: "Adds.",
+				Params: []DocParam{
+					{Name: "a", Type: "number", Description: "first"},
+					{Name: "b", Type: "number", Description: "second"},
+				},
+				Returns: &DocReturn{Type: "number", Description: "the sum"},
+				Throws:  []DocThrows{{Type: "RangeError", Description: "on overflow"}},
+			},
+		},
+		{
+			name:     "phpdoc",
+			language: "php",
+			content:  "Finds a user.\n@param int $id The id\n@return User|null",
+			want: &DocComment{
+				Description: "Finds a user.",
+				Params:      []DocParam{{Name: "$id", Type: "int", Description: "The id"}},
+				Returns:     &DocReturn{Type: "User|null"},
+			},
+		},
+		{
+			name:     "yard",
+			language: "ruby",
+			content:  "Greets.\n@param name [String] who to greet\n@return [String]",
+			want: &DocComment{
+				Description: "Greets.",
+				Params:      []DocParam{{Name: "name", Description: "[String] who to greet"}},
+				Returns:     &DocReturn{Type: "String"},
+			},
+		},
+		{
+			name:     "tags inside fences stay prose",
+			language: "typescript",
+			content:  "Example:\n```ts\n@Component()\nclass A {}\n```",
+			want:     nil,
+		},
+		{
+			name:     "godoc deprecation",
+			language: "go",
+			content:  "Dial connects.\n\nDeprecated: Use DialContext,\nwhich can be canceled.\n\nIt retries once.",
+			want: &DocComment{
+				Description:     "Dial connects.\n\nIt retries once.",
+				Deprecated:      true,
+				DeprecationNote: "Use DialContext,\nwhich can be canceled.",
+			},
+		},
+		{
+			name:     "rustdoc sections",
+			language: "rust",
+			content:  "Opens a file.\n\n# Errors\n\nFails when missing.\n\n# Examples\n\n```\n# use std::fs;\n```\n\n# Panics\nNever.",
+			want: &DocComment{
+				Description: "Opens a file.\n\n# Examples\n\n```\n# use std::fs;\n```",
+				Sections: []DocSection{
+					{Title: "Errors", Body: "Fails when missing."},
+					{Title: "Panics", Body: "Never."},
+				},
+			},
+		},
+		{
+			name:     "sphinx fields",
+			language: "python",
+			content:  "Load a file.\n\n:param str path: where to read\n:param mode: how to open\n   the file\n:type mode: str\n:returns: the data\n:rtype: bytes\n:raises OSError: when unreadable\n\n.. deprecated:: 2.0",
+			want: &DocComment{
+				Description: "Load a file.",
+				Params: []DocParam{
+					{Name: "path", Type: "str", Description: "where to read"},
+					{Name: "mode", Type: "str", Description: "how to open the file"},
+				},
+				Returns:         &DocReturn{Type: "bytes", Description: "the data"},
+				Throws:          []DocThrows{{Type: "OSError", Description: "when unreadable"}},
+				Deprecated:      true,
+				DeprecationNote: "2.0",
+			},
+		},
+		{
+			name:     "unknown tags stay prose",
+			language: "javascript",
+			content:  "Thanks @alice for the fix.\n@Override\nmethod body follows\n@returns nothing",
+			want: &DocComment{
+				Description: "Thanks @alice for the fix.\n@Override\nmethod body follows",
+				Returns:     &DocReturn{Description: "nothing"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDocComment(tt.content, tt.language)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseDocComment() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocCommentMarkdown(t *testing.T) {
+	doc := ParseDocComment("Adds.\n@param {number} a first | left\n@param b second\n@returns {number} the sum\n@throws {RangeError} on overflow\n@deprecated use {@link sum}\n@see add", "javascript")
+
+	got := doc.Markdown("className")
+	wantParts := []string{
+		"<div className=\"cire-doc-deprecated\">\n\n**Deprecated.** use `sum`\n\n</div>",
+		"Adds.",
+		"| Parameter | Type | Description |",
+		"| `a` | `number` | first \\| left |",
+		"| `b` |  | second |",
+		"<div className=\"cire-doc-returns\">\n\n**Returns** `number` — the sum",
+		"<div className=\"cire-doc-throws\">\n\n**Throws**\n\n- `RangeError` — on overflow",
+		"- **@see** add",
+	}
+	for _, part := range wantParts {
+		if !strings.Contains(got, part) {
+			t.Fatalf("Markdown() missing %q\nGot:\n%s", part, got)
+		}
+	}
+	if strings.Index(got, "cire-doc-deprecated") > strings.Index(got, "Adds.") {
+		t.Fatalf("deprecation banner should come before the description\nGot:\n%s", got)
+	}
+}
+
+func TestStructureHoverDocumentKeepsUntaggedDocumentation(t *testing.T) {
+	if got := structureHoverDocument("Just prose.", "java"); got != "Just prose." {
+		t.Fatalf("structureHoverDocument() = %q, want documentation unchanged", got)
+	}
+	got := structureHoverDocument("Sums.\n@param a first", "java")
+	if !strings.Contains(got, `<div class="cire-doc-params">`) {
+		t.Fatalf("structureHoverDocument() = %q, want a parameter table", got)
+	}
+}
@@ -0,0 +1,418 @@
+package internal
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// ExternalPackage identifies the dependency that owns a definition outside the project root.
+type ExternalPackage struct {
+	Manager string // Normalized package manager: go, npm, cargo, pip
+	Module  string // Module or package name as published, e.g. github.com/cockroachdb/errors
+	Version string // Published version, e.g. v1.11.1
+	Package string // Import path of the defining package, e.g. github.com/cockroachdb/errors/withstack
+	Symbol  string // Symbol path inside the package, e.g. Wrap or Client.Do
+}
+
+// ExternalLinkResolver builds documentation URLs for definitions that live in dependencies.
+//
+// Templates are keyed either by a package manager name (go, npm, cargo, pip) or by a
+// module path prefix. Module prefixes win over managers, and longer prefixes win over
+// shorter ones. Resolution only expands templates, so it never touches the network.
+type ExternalLinkResolver struct {
+	Templates map[string]string
+}
+
+var externalLinkTemplateVariables = []string{"manager", "module", "version", "package", "symbol"}
+
+func NewExternalLinkResolver(templates map[string]string) ExternalLinkResolver {
+	if len(templates) == 0 {
+		return ExternalLinkResolver{}
+	}
+	cloned := make(map[string]string, len(templates))
+	for key, template := range templates {
+		key = strings.TrimSpace(key)
+		template = strings.TrimSpace(template)
+		if key == "" || template == "" {
+			continue
+		}
+		cloned[key] = template
+	}
+	return ExternalLinkResolver{Templates: cloned}
+}
+
+// Resolve returns the documentation URL for an external definition.
+func (r ExternalLinkResolver) Resolve(definition SourceLocation) (string, bool) {
+	if len(r.Templates) == 0 {
+		return "", false
+	}
+
+	pkg := definition.Package
+	if pkg == nil {
+		if targetPath, ok := definitionSourcePath(definition); ok {
+			pkg = ExternalPackageFromPath(targetPath, "")
+		}
+	}
+	if pkg == nil {
+		return "", false
+	}
+
+	template, ok := r.templateFor(*pkg)
+	if !ok {
+		return "", false
+	}
+	return expandExternalLinkTemplate(template, *pkg)
+}
+
+func (r ExternalLinkResolver) templateFor(pkg ExternalPackage) (string, bool) {
+	keys := make([]string, 0, len(r.Templates))
+	for key := range r.Templates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		if isExternalModulePrefix(key, pkg.Module) || isExternalModulePrefix(key, pkg.Package) {
+			return r.Templates[key], true
+		}
+	}
+	if template, ok := r.Templates[pkg.Manager]; ok {
+		return template, true
+	}
+	return "", false
+}
+
+func isExternalModulePrefix(prefix, module string) bool {
+	if prefix == "" || module == "" || IsExternalPackageManager(prefix) {
+		return false
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	return module == prefix || strings.HasPrefix(module, prefix+"/")
+}
+
+// IsExternalPackageManager reports whether key names a package manager rather than a module prefix.
+func IsExternalPackageManager(key string) bool {
+	switch key {
+	case "go", "npm", "cargo", "pip":
+		return true
+	default:
+		return false
+	}
+}
+
+// ExternalLinkTemplateVariables lists the placeholders accepted in external link templates.
+func ExternalLinkTemplateVariables() []string {
+	return append([]string(nil), externalLinkTemplateVariables...)
+}
+
+// expandExternalLinkTemplate substitutes {manager}, {module}, {version}, {package} and
+// {symbol}. A template that references a value the definition does not carry does not
+// produce a link, since a half-filled URL is worse than no link. Values are escaped for
+// where they appear: in the query as query values, elsewhere segment by segment, so the
+// slashes of module and package paths stay path separators.
+func expandExternalLinkTemplate(template string, pkg ExternalPackage) (string, bool) {
+	values := map[string]string{
+		"manager": pkg.Manager,
+		"module":  pkg.Module,
+		"version": pkg.Version,
+		"package": pkg.Package,
+		"symbol":  pkg.Symbol,
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			sb.WriteString(template)
+			break
+		}
+		end += start
+
+		name := template[start+1 : end]
+		value, known := values[name]
+		if !known {
+			sb.WriteString(template[:end+1])
+			template = template[end+1:]
+			continue
+		}
+		if value == "" {
+			return "", false
+		}
+		sb.WriteString(template[:start])
+		sb.WriteString(escapeExternalLinkValue(value, sb.String()))
+		template = template[end+1:]
+	}
+	return sb.String(), true
+}
+
+// escapeExternalLinkValue escapes a template value for the URL written so far.
+func escapeExternalLinkValue(value string, prefix string) string {
+	if query := strings.IndexByte(prefix, '?'); query >= 0 && !strings.Contains(prefix[query:], "#") {
+		return url.QueryEscape(value)
+	}
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// ExternalPackageFromSCIPSymbol extracts package information from a global SCIP symbol.
+func ExternalPackageFromSCIPSymbol(symbol string) *ExternalPackage {
+	if symbol == "" || scip.IsLocalSymbol(symbol) {
+		return nil
+	}
+	parsed, err := scip.ParseSymbol(symbol)
+	if err != nil || parsed.Package == nil || parsed.Package.Name == "" || parsed.Package.Name == "." {
+		return nil
+	}
+
+	pkg := &ExternalPackage{
+		Manager: normalizeExternalPackageManager(parsed.Package.Manager),
+		Module:  parsed.Package.Name,
+		Version: parsed.Package.Version,
+	}
+
+	var namespaces []string
+	var names []string
+	for _, descriptor := range parsed.Descriptors {
+		switch descriptor.Suffix {
+		case scip.Descriptor_Namespace:
+			namespaces = append(namespaces, descriptor.Name)
+		case scip.Descriptor_Type, scip.Descriptor_Term, scip.Descriptor_Method, scip.Descriptor_Macro:
+			names = append(names, descriptor.Name)
+		}
+	}
+	pkg.Package = strings.Join(namespaces, "/")
+	if pkg.Package == "" {
+		pkg.Package = pkg.Module
+	}
+	pkg.Symbol = strings.Join(names, ".")
+	return pkg
+}
+
+func normalizeExternalPackageManager(manager string) string {
+	switch strings.ToLower(strings.TrimSpace(manager)) {
+	case "go", "gomod":
+		return "go"
+	case "npm", "yarn", "pnpm":
+		return "npm"
+	case "cargo", "crates":
+		return "cargo"
+	case "pip", "python", "pypi":
+		return "pip"
+	default:
+		return strings.ToLower(strings.TrimSpace(manager))
+	}
+}
+
+// ExternalPackageFromPath recognizes Go module cache and GOROOT source paths, as returned
+// by gopls definitions, and turns them into package information.
+func ExternalPackageFromPath(sourcePath string, symbol string) *ExternalPackage {
+	if sourcePath == "" {
+		return nil
+	}
+	slashPath := filepath.ToSlash(filepath.Clean(sourcePath))
+
+	if rest, ok := trimGoModuleCacheRoot(slashPath); ok {
+		return goModuleCachePackage(rest, symbol)
+	}
+	if goroot := goRootDir(); goroot != "" {
+		srcRoot := strings.TrimRight(filepath.ToSlash(filepath.Join(goroot, "src")), "/") + "/"
+		if strings.HasPrefix(slashPath, srcRoot) {
+			pkgPath := path.Dir(strings.TrimPrefix(slashPath, srcRoot))
+			if pkgPath == "." || pkgPath == "" {
+				return nil
+			}
+			return &ExternalPackage{
+				Manager: "go",
+				Module:  "std",
+				Version: goRootVersion(goroot),
+				Package: pkgPath,
+				Symbol:  symbol,
+			}
+		}
+	}
+	return nil
+}
+
+// ExternalPackageFromDefinition is ExternalPackageFromPath for a definition location. A Go
+// method's symbol is qualified by its receiver type, as in Client.Do, which is read from
+// the dependency's source since the name at the reference does not carry it.
+func ExternalPackageFromDefinition(definition SourceLocation, name string) *ExternalPackage {
+	pkg := ExternalPackageFromPath(definition.Path, name)
+	if pkg == nil || pkg.Manager != "go" || name == "" {
+		return pkg
+	}
+	if receiver := goMethodReceiver(definition.Path, definition.Range.Start); receiver != "" {
+		pkg.Symbol = receiver + "." + name
+	}
+	return pkg
+}
+
+// Receivers of a dependency file's methods, keyed by the position of the method name.
+// Dependency sources do not change during a run, so each file is parsed once.
+var (
+	goMethodReceiversMu sync.Mutex
+	goMethodReceivers   = make(map[string]map[scip.Position]string)
+)
+
+// goMethodReceiver returns the receiver type of the Go method named at position.
+func goMethodReceiver(sourcePath string, position scip.Position) string {
+	goMethodReceiversMu.Lock()
+	defer goMethodReceiversMu.Unlock()
+
+	receivers, ok := goMethodReceivers[sourcePath]
+	if !ok {
+		receivers = readGoMethodReceivers(sourcePath)
+		goMethodReceivers[sourcePath] = receivers
+	}
+	return receivers[scip.Position{Line: position.Line, Character: position.Character}]
+}
+
+func readGoMethodReceivers(sourcePath string) map[scip.Position]string {
+	sourceContent, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil
+	}
+	tree, err := ParseSource("go", sourceContent)
+	if err != nil {
+		return nil
+	}
+	defer tree.Close()
+
+	receivers := make(map[scip.Position]string)
+	root := tree.RootNode()
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		node := root.NamedChild(i)
+		if node.Kind() != "method_declaration" {
+			continue
+		}
+		name := node.ChildByFieldName("name")
+		receiver := tagReceiverType(node, sourceContent)
+		if name == nil || receiver == "" {
+			continue
+		}
+		start := name.StartPosition()
+		receivers[scip.Position{Line: int32(start.Row), Character: int32(start.Column)}] = receiver
+	}
+	return receivers
+}
+
+func trimGoModuleCacheRoot(slashPath string) (string, bool) {
+	for _, root := range goModuleCacheRoots() {
+		root = strings.TrimRight(filepath.ToSlash(filepath.Clean(root)), "/") + "/"
+		if strings.HasPrefix(slashPath, root) {
+			return strings.TrimPrefix(slashPath, root), true
+		}
+	}
+	const marker = "/pkg/mod/"
+	if idx := strings.Index(slashPath, marker); idx >= 0 {
+		return slashPath[idx+len(marker):], true
+	}
+	return "", false
+}
+
+func goModuleCacheRoots() []string {
+	var roots []string
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		roots = append(roots, modCache)
+	}
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		if gopath != "" {
+			roots = append(roots, filepath.Join(gopath, "pkg", "mod"))
+		}
+	}
+	return roots
+}
+
+func goModuleCachePackage(rest string, symbol string) *ExternalPackage {
+	if strings.HasPrefix(rest, "cache/") {
+		return nil
+	}
+	parts := strings.Split(rest, "/")
+	for i, part := range parts {
+		at := strings.LastIndexByte(part, '@')
+		if at < 0 {
+			continue
+		}
+		moduleParts := append(append([]string(nil), parts[:i]...), part[:at])
+		module := unescapeGoModulePath(strings.Join(moduleParts, "/"))
+		pkgPath := module
+		if dir := path.Dir(strings.Join(parts[i+1:], "/")); dir != "." && dir != "" {
+			pkgPath = module + "/" + unescapeGoModulePath(dir)
+		}
+		return &ExternalPackage{
+			Manager: "go",
+			Module:  module,
+			Version: unescapeGoModulePath(part[at+1:]),
+			Package: pkgPath,
+			Symbol:  symbol,
+		}
+	}
+	return nil
+}
+
+// unescapeGoModulePath reverses the module cache case encoding, where "!a" stands for "A".
+func unescapeGoModulePath(value string) string {
+	if !strings.Contains(value, "!") {
+		return value
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '!' && i+1 < len(value) && value[i+1] >= 'a' && value[i+1] <= 'z' {
+			sb.WriteByte(value[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		sb.WriteByte(value[i])
+	}
+	return sb.String()
+}
+
+func goRootDir() string {
+	if goroot := os.Getenv("GOROOT"); goroot != "" {
+		return goroot
+	}
+	return runtime.GOROOT()
+}
+
+// GOROOT versions by directory, read once instead of for every standard library definition.
+var (
+	goRootVersionsMu sync.Mutex
+	goRootVersions   = make(map[string]string)
+)
+
+func goRootVersion(goroot string) string {
+	goRootVersionsMu.Lock()
+	defer goRootVersionsMu.Unlock()
+
+	if version, ok := goRootVersions[goroot]; ok {
+		return version
+	}
+	version := ""
+	if data, err := os.ReadFile(filepath.Join(goroot, "VERSION")); err == nil {
+		version, _, _ = strings.Cut(string(data), "\n")
+		version = strings.TrimSpace(version)
+	}
+	goRootVersions[goroot] = version
+	return version
+}
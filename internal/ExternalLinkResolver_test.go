@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestExternalPackageFromSCIPSymbol(t *testing.T) {
+	pkg := ExternalPackageFromSCIPSymbol("scip-go gomod github.com/cockroachdb/errors v1.11.1 `github.com/cockroachdb/errors/withstack`/Client#Do().")
+	if pkg == nil {
+		t.Fatal("ExternalPackageFromSCIPSymbol returned nil")
+	}
+	want := ExternalPackage{
+		Manager: "go",
+		Module:  "github.com/cockroachdb/errors",
+		Version: "v1.11.1",
+		Package: "github.com/cockroachdb/errors/withstack",
+		Symbol:  "Client.Do",
+	}
+	if *pkg != want {
+		t.Fatalf("package = %#v, want %#v", *pkg, want)
+	}
+
+	pkg = ExternalPackageFromSCIPSymbol("scip-typescript npm lodash 4.17.21 `debounce.d.ts`/debounce().")
+	if pkg == nil || pkg.Manager != "npm" || pkg.Module != "lodash" || pkg.Version != "4.17.21" || pkg.Symbol != "debounce" {
+		t.Fatalf("npm package = %#v", pkg)
+	}
+
+	if pkg := ExternalPackageFromSCIPSymbol("local 12"); pkg != nil {
+		t.Fatalf("local symbol package = %#v, want nil", pkg)
+	}
+}
+
+func TestExternalPackageFromGoModuleCachePath(t *testing.T) {
+	modCache := filepath.Join(t.TempDir(), "mod")
+	t.Setenv("GOMODCACHE", modCache)
+
+	sourcePath := filepath.Join(modCache, "github.com", "!burnt!sushi", "toml@v1.3.2", "internal", "decode.go")
+	pkg := ExternalPackageFromPath(sourcePath, "Decode")
+	if pkg == nil {
+		t.Fatal("ExternalPackageFromPath returned nil for module cache path")
+	}
+	want := ExternalPackage{
+		Manager: "go",
+		Module:  "github.com/BurntSushi/toml",
+		Version: "v1.3.2",
+		Package: "github.com/BurntSushi/toml/internal",
+		Symbol:  "Decode",
+	}
+	if *pkg != want {
+		t.Fatalf("package = %#v, want %#v", *pkg, want)
+	}
+
+	if pkg := ExternalPackageFromPath(filepath.Join(t.TempDir(), "repo", "main.go"), "main"); pkg != nil {
+		t.Fatalf("project path package = %#v, want nil", pkg)
+	}
+}
+
+func TestExternalPackageFromGoRootPath(t *testing.T) {
+	goroot := t.TempDir()
+	t.Setenv("GOROOT", goroot)
+	writeExternalLinkTestFile(t, filepath.Join(goroot, "VERSION"), "go1.25.4\ntime 2025-01-01\n")
+
+	pkg := ExternalPackageFromPath(filepath.Join(goroot, "src", "net", "http", "client.go"), "Get")
+	if pkg == nil {
+		t.Fatal("ExternalPackageFromPath returned nil for GOROOT path")
+	}
+	want := ExternalPackage{Manager: "go", Module: "std", Version: "go1.25.4", Package: "net/http", Symbol: "Get"}
+	if *pkg != want {
+		t.Fatalf("package = %#v, want %#v", *pkg, want)
+	}
+}
+
+func TestExternalPackageFromDefinitionQualifiesGoMethods(t *testing.T) {
+	modCache := filepath.Join(t.TempDir(), "mod")
+	t.Setenv("GOMODCACHE", modCache)
+
+	sourcePath := filepath.Join(modCache, "example.com", "web@v1.0.0", "client.go")
+	writeExternalLinkTestFile(t, sourcePath, "package web\n\ntype Client struct{}\n\nfunc (c *Client) Do() {}\n\nfunc Do() {}\n")
+
+	method := SourceLocation{Path: sourcePath, Range: scip.Range{Start: scip.Position{Line: 4, Character: 17}}}
+	if pkg := ExternalPackageFromDefinition(method, "Do"); pkg == nil || pkg.Symbol != "Client.Do" {
+		t.Fatalf("method package = %#v, want symbol Client.Do", pkg)
+	}
+
+	function := SourceLocation{Path: sourcePath, Range: scip.Range{Start: scip.Position{Line: 6, Character: 5}}}
+	if pkg := ExternalPackageFromDefinition(function, "Do"); pkg == nil || pkg.Symbol != "Do" {
+		t.Fatalf("function package = %#v, want symbol Do", pkg)
+	}
+}
+
+func TestExternalLinkResolverPrefersLongestModulePrefix(t *testing.T) {
+	resolver := NewExternalLinkResolver(map[string]string{
+		"go":                            "https://pkg.go.dev/{package}@{version}#{symbol}",
+		"github.com/cockroachdb":        "https://docs.example.com/{module}",
+		"github.com/cockroachdb/errors": "https://errors.example.com/{version}/{symbol}",
+	})
+
+	definition := SourceLocation{Package: &ExternalPackage{
+		Manager: "go",
+		Module:  "github.com/cockroachdb/errors",
+		Version: "v1.11.1",
+		Package: "github.com/cockroachdb/errors",
+		Symbol:  "Wrap",
+	}}
+	href, ok := resolver.Resolve(definition)
+	if !ok || href != "https://errors.example.com/v1.11.1/Wrap" {
+		t.Fatalf("Resolve = %q, %v", href, ok)
+	}
+
+	definition.Package.Module = "golang.org/x/sync"
+	definition.Package.Package = "golang.org/x/sync/errgroup"
+	definition.Package.Symbol = "Group"
+	href, ok = resolver.Resolve(definition)
+	if !ok || href != "https://pkg.go.dev/golang.org/x/sync/errgroup@v1.11.1#Group" {
+		t.Fatalf("manager fallback Resolve = %q, %v", href, ok)
+	}
+}
+
+func TestExternalLinkResolverSkipsTemplatesWithMissingValues(t *testing.T) {
+	resolver := NewExternalLinkResolver(map[string]string{
+		"npm": "https://www.npmjs.com/package/{module}/v/{version}",
+	})
+
+	_, ok := resolver.Resolve(SourceLocation{Package: &ExternalPackage{Manager: "npm", Module: "lodash"}})
+	if ok {
+		t.Fatal("Resolve returned ok=true for template with missing version")
+	}
+	_, ok = resolver.Resolve(SourceLocation{Package: &ExternalPackage{Manager: "cargo", Module: "serde", Version: "1.0.0"}})
+	if ok {
+		t.Fatal("Resolve returned ok=true for manager without template")
+	}
+}
+
+func TestExternalLinkResolverEscapesValues(t *testing.T) {
+	resolver := NewExternalLinkResolver(map[string]string{
+		"npm": "https://example.com/{module}/{version}#{symbol}",
+		"pip": "https://example.com/search?q={symbol}&v={version}",
+	})
+
+	href, ok := resolver.Resolve(SourceLocation{Package: &ExternalPackage{
+		Manager: "npm",
+		Module:  "@scope/name",
+		Version: "1.0.0 beta?x=<y>",
+		Symbol:  "a b",
+	}})
+	if !ok || href != "https://example.com/@scope/name/1.0.0%20beta%3Fx=%3Cy%3E#a%20b" {
+		t.Fatalf("Resolve = %q, %v", href, ok)
+	}
+
+	href, ok = resolver.Resolve(SourceLocation{Package: &ExternalPackage{
+		Manager: "pip",
+		Module:  "requests",
+		Version: "2.0",
+		Symbol:  "a&b=c/d",
+	}})
+	if !ok || href != "https://example.com/search?q=a%26b%3Dc%2Fd&v=2.0" {
+		t.Fatalf("query Resolve = %q, %v", href, ok)
+	}
+}
+
+func TestResolveDefinitionHrefUsesExternalTemplatesOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	manifest := newTestSourceRouteManifest(t, root, []string{"main.go"})
+	manifest.External = NewExternalLinkResolver(map[string]string{
+		"go": "https://pkg.go.dev/{package}@{version}#{symbol}",
+	})
+
+	modCache := filepath.Join(t.TempDir(), "mod")
+	t.Setenv("GOMODCACHE", modCache)
+	definitionPath := filepath.Join(modCache, "golang.org", "x", "sync@v0.17.0", "errgroup", "errgroup.go")
+	definition := SourceLocation{
+		Path:    definitionPath,
+		Range:   scip.Range{Start: scip.Position{Line: 10, Character: 5}},
+		Package: ExternalPackageFromPath(definitionPath, "Group"),
+	}
+
+	href, ok, warning := ResolveDefinitionHref(filepath.Join(root, "main.go"), definition, manifest)
+	if !ok {
+		t.Fatalf("ResolveDefinitionHref returned ok=false, warning=%v", warning)
+	}
+	if href != "https://pkg.go.dev/golang.org/x/sync/errgroup@v0.17.0#Group" {
+		t.Fatalf("href = %q", href)
+	}
+
+	manifest.External = ExternalLinkResolver{}
+	_, ok, warning = ResolveDefinitionHref(filepath.Join(root, "main.go"), definition, manifest)
+	if ok || warning == nil || warning.Code != LinkResolutionWarningOutsideRoot {
+		t.Fatalf("without templates ok=%v warning=%v, want outside_root", ok, warning)
+	}
+}
+
+func writeExternalLinkTestFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("create %s: %v", filepath.Dir(filePath), err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", filePath, err)
+	}
+}
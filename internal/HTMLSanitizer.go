@@ -0,0 +1,308 @@
+package internal
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SanitizeLevel says how much HTML survives in rendered Markdown: the prose
+// of narrative pages and the hover text that language servers and indexes
+// supply, either of which may come from someone the site's author does not
+// trust.
+type SanitizeLevel string
+
+const (
+	// SanitizeOff passes HTML through as written.
+	SanitizeOff SanitizeLevel = "off"
+	// SanitizeStandard keeps the HTML Markdown, math, callouts and diagrams
+	// render to, and the formatting authors commonly write by hand, but drops
+	// scripts, event handlers, frames, forms and unsafe URLs.
+	SanitizeStandard SanitizeLevel = "standard"
+	// SanitizeStrict also drops images, inline SVG and inline styles, so
+	// nothing on the page loads from elsewhere or changes its layout.
+	SanitizeStrict SanitizeLevel = "strict"
+)
+
+// htmlElements lists the HTML elements the sanitizer keeps: those Markdown,
+// callouts and KaTeX render to and the formatting authors commonly write by
+// hand.
+var htmlElements = setOf(
+	"a", "abbr", "aside", "b", "blockquote", "br", "caption", "cite", "code",
+	"col", "colgroup", "dd", "del", "details", "dfn", "div", "dl", "dt", "em",
+	"figcaption", "figure", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i",
+	"img", "input", "ins", "kbd", "label", "li", "mark", "ol", "p", "pre",
+	"q", "rp", "rt", "ruby", "s", "samp", "section", "small", "span",
+	"strong", "sub", "summary", "sup", "table", "tbody", "td", "tfoot", "th",
+	"thead", "time", "tr", "u", "ul", "var", "wbr",
+)
+
+// mathElements lists the MathML elements KaTeX renders to.
+var mathElements = setOf(
+	"math", "annotation", "menclose", "mfrac", "mi", "mn", "mo", "mover",
+	"mpadded", "mphantom", "mroot", "mrow", "ms", "mspace", "msqrt", "mstyle",
+	"msub", "msubsup", "msup", "mtable", "mtd", "mtext", "mtr", "munder",
+	"munderover", "semantics",
+)
+
+// svgElements lists the SVG elements KaTeX and Graphviz render to, which
+// the strict level drops.
+var svgElements = setOf(
+	"svg", "circle", "clippath", "defs", "ellipse", "g", "line",
+	"lineargradient", "path", "polygon", "polyline", "radialgradient", "rect",
+	"stop", "text", "title", "tspan", "use",
+)
+
+// droppedWithContent lists the elements whose content goes with them, because
+// it is code, or markup the browser would not show as written.
+var droppedWithContent = setOf(
+	"script", "style", "iframe", "frame", "frameset", "object", "embed",
+	"applet", "noscript", "noembed", "noframes", "template", "textarea",
+	"select", "xmp", "plaintext", "button",
+)
+
+// sanitizedAttributes lists the attributes elements keep besides the global
+// ones of sanitizedAttribute.
+var sanitizedAttributes = map[string]map[string]bool{
+	"a":          setOf("href", "xlink:href", "name", "rel", "target"),
+	"col":        setOf("span"),
+	"colgroup":   setOf("span"),
+	"details":    setOf("open"),
+	"img":        setOf("src", "alt", "width", "height", "loading"),
+	"input":      setOf("type", "checked", "disabled", "name"),
+	"label":      setOf("for"),
+	"li":         setOf("value"),
+	"ol":         setOf("start", "reversed", "type"),
+	"q":          setOf("cite"),
+	"blockquote": setOf("cite"),
+	"del":        setOf("cite", "datetime"),
+	"ins":        setOf("cite", "datetime"),
+	"td":         setOf("align", "colspan", "rowspan"),
+	"th":         setOf("align", "colspan", "rowspan", "scope"),
+	"time":       setOf("datetime"),
+}
+
+// urlAttributes lists the attributes that hold a URL, which must pass
+// sanitizedURL.
+var urlAttributes = setOf("href", "xlink:href", "src", "cite")
+
+var (
+	// unsafeStylePattern matches declarations that load or run anything.
+	unsafeStylePattern = regexp.MustCompile(`(?i)url\s*\(|expression\s*\(|javascript:|@import|behavior\s*:|-moz-binding|\\|<`)
+	// dataImagePattern matches the inline images the standard level allows.
+	dataImagePattern = regexp.MustCompile(`(?i)^data:image/(?:png|gif|jpeg|webp);base64,[a-z0-9+/=\s]*$`)
+)
+
+// sanitizeHTML keeps the elements and attributes level allows in an HTML
+// fragment. Elements it does not allow are dropped, keeping their text unless
+// it is code; attributes it does not allow, such as event handlers and
+// javascript: URLs, are dropped from the elements it keeps.
+func sanitizeHTML(fragment string, level SanitizeLevel) string {
+	if level == SanitizeOff || !strings.Contains(fragment, "<") {
+		return fragment
+	}
+
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	// dropping names the element being dropped with its content, and depth
+	// counts how many of it are open.
+	dropping, depth := "", 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return sb.String()
+		}
+		raw := string(tokenizer.Raw())
+		token := tokenizer.Token()
+
+		if dropping != "" {
+			switch {
+			case tokenType == html.StartTagToken && token.Data == dropping:
+				depth++
+			case tokenType == html.EndTagToken && token.Data == dropping:
+				depth--
+				if depth == 0 {
+					dropping = ""
+				}
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.TextToken:
+			// Text never opens a tag, whatever the browser's parser state.
+			sb.WriteString(strings.ReplaceAll(raw, "<", "&lt;"))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedWithContent[token.Data] {
+				if tokenType == html.StartTagToken {
+					dropping, depth = token.Data, 1
+				}
+				continue
+			}
+			if !sanitizedElement(token.Data, level) {
+				continue
+			}
+			attributes, changed := sanitizeAttributes(token, level)
+			// MDX reads a brace in a tag as code; quoted, it is a string.
+			if !changed && !strings.Contains(raw, "{") {
+				sb.WriteString(raw)
+				continue
+			}
+			sb.WriteString("<" + writtenTagName(raw[1:], token.Data))
+			for _, attribute := range attributes {
+				sb.WriteString(" " + attribute.Key)
+				if attribute.Val != "" {
+					sb.WriteString(`="` + html.EscapeString(attribute.Val) + `"`)
+				}
+			}
+			if tokenType == html.SelfClosingTagToken {
+				sb.WriteString(" /")
+			}
+			sb.WriteString(">")
+		case html.EndTagToken:
+			if sanitizedElement(token.Data, level) {
+				sb.WriteString("</" + writtenTagName(raw[2:], token.Data) + ">")
+			}
+		}
+		// Comments and doctypes go.
+	}
+}
+
+// writtenTagName returns a tag's name as written at the start of tag, which
+// the tokenizer lowercases but MDX tells components apart by.
+func writtenTagName(tag string, name string) string {
+	if len(tag) >= len(name) && strings.EqualFold(tag[:len(name)], name) {
+		return tag[:len(name)]
+	}
+	return name
+}
+
+func sanitizedElement(name string, level SanitizeLevel) bool {
+	switch {
+	case htmlElements[name]:
+		return name != "img" || level != SanitizeStrict
+	case mathElements[name]:
+		return true
+	case svgElements[name]:
+		return level != SanitizeStrict
+	}
+	return false
+}
+
+// sanitizeAttributes returns the attributes of token that level keeps, and
+// whether any were dropped or rewritten.
+func sanitizeAttributes(token html.Token, level SanitizeLevel) ([]html.Attribute, bool) {
+	kept := make([]html.Attribute, 0, len(token.Attr))
+	changed := false
+	for _, attribute := range token.Attr {
+		value, ok := sanitizedAttribute(token.Data, attribute, level)
+		if !ok {
+			changed = true
+			continue
+		}
+		if value != attribute.Val {
+			changed = true
+			attribute.Val = value
+		}
+		kept = append(kept, attribute)
+	}
+	return kept, changed
+}
+
+// sanitizedAttribute returns the value an element's attribute keeps, if level
+// allows it.
+func sanitizedAttribute(element string, attribute html.Attribute, level SanitizeLevel) (string, bool) {
+	key, value := attribute.Key, attribute.Val
+	foreign := mathElements[element] || svgElements[element]
+	switch {
+	case strings.HasPrefix(key, "on"):
+		return "", false
+	case urlAttributes[key]:
+		switch {
+		case element == "use":
+			// SVG may only reuse shapes of its own.
+			return value, strings.HasPrefix(value, "#")
+		case !foreign && !sanitizedAttributes[element][key]:
+			return "", false
+		}
+		return value, sanitizedURL(value, element == "img" && level != SanitizeStrict)
+	case key == "style":
+		return sanitizeStyle(value, level)
+	case key == "data-hover-html":
+		// The site's tooltips show this as HTML.
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", false
+		}
+		return base64.StdEncoding.EncodeToString([]byte(sanitizeHTML(string(decoded), level))), true
+	case key == "target" && level == SanitizeStrict:
+		return "", false
+	case key == "type" && element == "input":
+		return value, value == "checkbox" || value == "radio"
+	case key == "class" || key == "id" || key == "title" || key == "lang" || key == "dir" || key == "role",
+		strings.HasPrefix(key, "aria-"), strings.HasPrefix(key, "data-"):
+		return value, true
+	case foreign:
+		// Presentation attributes such as viewBox, fill or mathvariant. The
+		// animation elements that could turn one into a script are not kept.
+		return value, true
+	}
+	return value, sanitizedAttributes[element][key]
+}
+
+// sanitizedURL reports whether a link or image may point at url: a relative
+// URL or an http, https or mailto one, and for images, if dataImages is set,
+// an inline raster image.
+func sanitizedURL(url string, dataImages bool) bool {
+	// Browsers ignore control characters and whitespace within schemes.
+	compact := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, url)
+	colon := strings.IndexByte(compact, ':')
+	if colon < 0 || strings.ContainsAny(compact[:colon], "/?#") {
+		return true
+	}
+	switch strings.ToLower(compact[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	case "data":
+		return dataImages && dataImagePattern.MatchString(strings.TrimSpace(url))
+	}
+	return false
+}
+
+// sanitizeStyle keeps inline styles at the standard level unless they load
+// or run anything, and only text alignment, which Markdown tables use, at the
+// strict level.
+func sanitizeStyle(style string, level SanitizeLevel) (string, bool) {
+	if unsafeStylePattern.MatchString(style) {
+		return "", false
+	}
+	if level != SanitizeStrict {
+		return style, true
+	}
+	var kept []string
+	for _, declaration := range strings.Split(style, ";") {
+		property, _, _ := strings.Cut(declaration, ":")
+		if strings.EqualFold(strings.TrimSpace(property), "text-align") {
+			kept = append(kept, strings.TrimSpace(declaration))
+		}
+	}
+	if len(kept) == 0 {
+		return "", false
+	}
+	return strings.Join(kept, ";"), true
+}
+
+func setOf(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
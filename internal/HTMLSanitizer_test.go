@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestSanitizeHTMLRemovesXSSVectors(t *testing.T) {
+	hover := base64.StdEncoding.EncodeToString([]byte(`<img src=x onerror=alert(1)>`))
+	cleanHover := base64.StdEncoding.EncodeToString([]byte(`<img src="x">`))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"script", `a<script>alert(1)</script>b`, `ab`},
+		{"event handler", `<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{"javascript URL", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"encoded javascript URL", `<a href="JaVaScRiPt&colon;alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript URL with control characters", `<a href=" jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"data URL link", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a>x</a>`},
+		{"script in SVG", `<svg><script>alert(1)</script></svg>`, `<svg></svg>`},
+		{"SVG onload", `<svg onload=alert(1)>`, `<svg>`},
+		{"SVG use of another document", `<svg><use href="https://example.com/sprite.svg#x"/></svg>`, `<svg><use /></svg>`},
+		{"markup in SVG title", `<svg><title><img src=x onerror=alert(1)></title></svg>`, `<svg><title>&lt;img src=x onerror=alert(1)></title></svg>`},
+		{"MathML link", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>`, `<math><mi>x</mi></math>`},
+		{"iframe", `<iframe src="https://example.com"></iframe>`, ``},
+		{"form", `<form action="javascript:alert(1)"><button>Go</button></form>`, ``},
+		{"style element", `<style>body{background:url(https://example.com)}</style>`, ``},
+		{"style URL", `<div style="background:url(javascript:alert(1))">x</div>`, `<div>x</div>`},
+		{"comment", `<!-- <img src=x onerror=alert(1)> -->`, ``},
+		{"details ontoggle", `<details open ontoggle=alert(1)>`, `<details open>`},
+		{"text input", `<input type="text" autofocus onfocus=alert(1)>`, `<input>`},
+		{"hover HTML", `<a data-hover-html="` + hover + `">x</a>`, `<a data-hover-html="` + cleanHover + `">x</a>`},
+		{"JSX attribute", `<a href={alert(1)}>x</a>`, `<a href="{alert(1)}">x</a>`},
+		{"safe HTML", `<p class="note"><kbd>Ctrl</kbd> &amp; <a href="https://example.com/?a=1&amp;b=2" title="t">x</a></p>`, `<p class="note"><kbd>Ctrl</kbd> &amp; <a href="https://example.com/?a=1&amp;b=2" title="t">x</a></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.input, SanitizeStandard); got != tt.want {
+				t.Fatalf("sanitizeHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLLevels(t *testing.T) {
+	input := `<p style="text-align: center; color: red">x</p><img src="data:image/png;base64,AAAA" alt=""><svg viewBox="0 0 1 1"><path d="M0 0"/></svg><a href="/x" target="_blank">y</a>`
+
+	if got := sanitizeHTML(input, SanitizeOff); got != input {
+		t.Fatalf("off level changed HTML: %q", got)
+	}
+	if got := sanitizeHTML(input, SanitizeStandard); got != input {
+		t.Fatalf("standard level changed safe HTML: %q", got)
+	}
+	want := `<p style="text-align: center">x</p><a href="/x">y</a>`
+	if got := sanitizeHTML(input, SanitizeStrict); got != want {
+		t.Fatalf("strict level = %q, want %q", got, want)
+	}
+}
@@ -3,17 +3,18 @@ package internal
 import (
 	"embed"
 
-	"github.com/Eric-Song-Nop/gocire/internal/languages"
-	"github.com/cockroachdb/errors"
 	"github.com/sourcegraph/scip/bindings/go/scip"
 	sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
-//go:embed queries/*.scm
+//go:embed queries/*.scm queries/locals/*.scm queries/tags/*.scm queries/injections/*.scm queries/docs/*.scm
 var queryFS embed.FS
 
 type HighlightAnalyzer struct {
 	language string
+	// Queries holds the project's own highlight queries; nil uses the
+	// built-in ones.
+	Queries *HighlightQueries
 }
 
 func NewHighlightAnalyzer(language string) *HighlightAnalyzer {
@@ -22,59 +23,71 @@ func NewHighlightAnalyzer(language string) *HighlightAnalyzer {
 	}
 }
 
+// Analyze highlights a file, with its Chroma lexer when the language has no
+// tree-sitter grammar.
 func (h *HighlightAnalyzer) Analyze(sourceContent []byte) ([]TokenInfo, error) {
-	cfg, err := languages.GetConfig(h.language)
+	if !hasGrammar(h.language) {
+		return chromaHighlightTokens(h.language, sourceContent)
+	}
+	tree, err := ParseSource(h.language, sourceContent)
 	if err != nil {
 		return nil, err
 	}
-
-	parser := sitter.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(cfg.SitterLanguage)
-
-	tree := parser.Parse(sourceContent, nil)
 	defer tree.Close()
+	return h.AnalyzeTree(tree)
+}
 
-	queryContent, err := queryFS.ReadFile("queries/" + cfg.QueryFileName)
+// AnalyzeTree highlights an already parsed file, including the regions its
+// injections query hands to other grammars.
+func (h *HighlightAnalyzer) AnalyzeTree(tree *SourceTree) ([]TokenInfo, error) {
+	tokens, err := highlightTreeTokens(h.Queries, h.language, tree)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read query file %s", cfg.QueryFileName)
+		return nil, err
 	}
+	// Injected tokens come after the host tokens so they win over the string
+	// or comment that contains them once tokens are merged.
+	return append(tokens, injectedHighlightTokens(h.Queries, tree, 1)...), nil
+}
 
-	query, queryErr := sitter.NewQuery(cfg.SitterLanguage, string(queryContent))
-	if queryErr != nil {
-		return nil, errors.Wrapf(queryErr, "failed to create query for %s", h.language)
+func highlightTreeTokens(queries *HighlightQueries, language string, tree *SourceTree) ([]TokenInfo, error) {
+	query, err := queries.query(language)
+	if err != nil {
+		return nil, err
 	}
-	defer query.Close()
+	sourceContent := tree.Content
 
 	qc := sitter.NewQueryCursor()
 	defer qc.Close()
 
-	matches := qc.Matches(query, tree.RootNode(), sourceContent)
+	// Captures arrive in document order, and captures of one node in pattern
+	// order, so later patterns (such as user queries that inherit the built-in
+	// one) take precedence once tokens are merged.
+	captures := qc.Captures(query, tree.RootNode(), sourceContent)
 
 	var tokens []TokenInfo
 
-	for match := matches.Next(); match != nil; match = matches.Next() {
-		for _, capture := range match.Captures {
-			node := capture.Node
-			token := TokenInfo{
-				Symbol:         "",
-				IsReference:    false,
-				IsDefinition:   false,
-				HighlightClass: query.CaptureNames()[capture.Index],
-				Document:       []string{},
-				Span: scip.Range{
-					Start: scip.Position{
-						Line:      int32(node.StartPosition().Row),
-						Character: int32(node.StartPosition().Column),
-					},
-					End: scip.Position{
-						Line:      int32(node.EndPosition().Row),
-						Character: int32(node.EndPosition().Column),
-					},
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		capture := match.Captures[index]
+		node := capture.Node
+		token := TokenInfo{
+			Symbol:         "",
+			IsReference:    false,
+			IsDefinition:   false,
+			HighlightClass: query.CaptureNames()[capture.Index],
+			Document:       []string{},
+			Span: scip.Range{
+				Start: scip.Position{
+					Line:      int32(node.StartPosition().Row),
+					Character: int32(node.StartPosition().Column),
+				},
+				End: scip.Position{
+					Line:      int32(node.EndPosition().Row),
+					Character: int32(node.EndPosition().Column),
 				},
-			}
-			tokens = append(tokens, token)
+			},
+			Source: TokenSourceSyntax,
 		}
+		tokens = append(tokens, token)
 	}
 
 	return tokens, nil
@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxInjectionDepth bounds nested injections, such as a template literal
+// inside a script inside a PHP file.
+const maxInjectionDepth = 3
+
+// combinedInjectionKey groups the matches of one combined pattern by language.
+type combinedInjectionKey struct {
+	pattern  uint
+	language string
+}
+
+// injectionRegion is the part of a host file handed to another grammar.
+type injectionRegion struct {
+	language string
+	ranges   []sitter.Range
+	// lexer highlights a language with no tree-sitter grammar, such as SQL
+	// or HTML, in place of a grammar.
+	lexer chroma.Lexer
+}
+
+// injectedHighlightTokens highlights the regions that the injections query of
+// the tree's language hands to another grammar. The regions are parsed in
+// place with included ranges, so the tokens are already in host coordinates.
+// Regions in a language with no grammar are highlighted with its Chroma
+// lexer, and those Chroma has no lexer for either are skipped.
+func injectedHighlightTokens(queries *HighlightQueries, tree *SourceTree, depth int) []TokenInfo {
+	if depth > maxInjectionDepth {
+		return nil
+	}
+	query, err := cachedQuery(tree.Language, injectionsQueryKind)
+	if err != nil || query == nil {
+		return nil
+	}
+
+	var tokens []TokenInfo
+	for _, region := range findInjectionRegions(query, tree) {
+		if region.lexer != nil {
+			tokens = append(tokens, chromaInjectionTokens(region, tree.Content)...)
+			continue
+		}
+		injected, err := parseInjectionRegion(region, tree.Content)
+		if err != nil || injected == nil {
+			continue
+		}
+		highlighted, err := highlightTreeTokens(queries, region.language, injected)
+		if err == nil {
+			tokens = append(tokens, highlighted...)
+			tokens = append(tokens, injectedHighlightTokens(queries, injected, depth+1)...)
+		}
+		injected.Close()
+	}
+	return tokens
+}
+
+func findInjectionRegions(query *sitter.Query, tree *SourceTree) []*injectionRegion {
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	var regions []*injectionRegion
+	// Combined patterns gather every match into one region per language, so
+	// template fragments split by ${} interpolations parse as one document.
+	combined := make(map[combinedInjectionKey]*injectionRegion)
+
+	matches := qc.Matches(query, tree.RootNode(), tree.Content)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var language string
+		isCombined, includeChildren := false, false
+		for _, property := range query.PropertySettings(match.PatternIndex) {
+			switch property.Key {
+			case "injection.language":
+				if property.Value != nil {
+					language = *property.Value
+				}
+			case "injection.combined":
+				isCombined = true
+			case "injection.include-children":
+				includeChildren = true
+			}
+		}
+
+		var ranges []sitter.Range
+		for _, capture := range match.Captures {
+			switch query.CaptureNames()[capture.Index] {
+			case "injection.language":
+				language = capture.Node.Utf8Text(tree.Content)
+			case "injection.content":
+				ranges = append(ranges, injectionContentRanges(&capture.Node, includeChildren)...)
+			}
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		canonical, lexer, ok := injectionLanguage(language)
+		if !ok {
+			continue
+		}
+
+		if !isCombined {
+			regions = append(regions, &injectionRegion{language: canonical, ranges: ranges, lexer: lexer})
+			continue
+		}
+		key := combinedInjectionKey{pattern: match.PatternIndex, language: canonical}
+		region, ok := combined[key]
+		if !ok {
+			region = &injectionRegion{language: canonical, lexer: lexer}
+			combined[key] = region
+			regions = append(regions, region)
+		}
+		region.ranges = append(region.ranges, ranges...)
+	}
+	return regions
+}
+
+// injectionContentRanges returns the byte ranges of node to re-parse. Unless
+// include-children is set, the node's children stay with the host grammar.
+func injectionContentRanges(node *sitter.Node, includeChildren bool) []sitter.Range {
+	if includeChildren || node.ChildCount() == 0 {
+		return []sitter.Range{node.Range()}
+	}
+
+	var ranges []sitter.Range
+	current := node.Range()
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child == nil {
+			continue
+		}
+		if child.StartByte() > current.StartByte {
+			ranges = append(ranges, sitter.Range{
+				StartByte:  current.StartByte,
+				EndByte:    child.StartByte(),
+				StartPoint: current.StartPoint,
+				EndPoint:   child.StartPosition(),
+			})
+		}
+		current.StartByte = child.EndByte()
+		current.StartPoint = child.EndPosition()
+	}
+	if current.EndByte > current.StartByte {
+		ranges = append(ranges, current)
+	}
+	return ranges
+}
+
+// chromaInjectionLexers lists the Chroma lexers that highlight embedded
+// languages gocire has no grammar for. Only their names and aliases select
+// them, not the filename patterns or catch-all aliases such as "text" that
+// lexers.Get also accepts.
+var chromaInjectionLexers = []string{
+	"SQL", "MySQL", "PostgreSQL SQL dialect", "Transact-SQL",
+	"HTML", "XML", "CSS", "SCSS", "GraphQL",
+	"JSON", "YAML", "TOML", "markdown", "Bash",
+}
+
+var (
+	chromaInjectionNamesOnce sync.Once
+	chromaInjectionNames     map[string]chroma.Lexer
+)
+
+// chromaInjectionLexer returns the allowed Chroma lexer called name by its
+// name or an alias.
+func chromaInjectionLexer(name string) chroma.Lexer {
+	chromaInjectionNamesOnce.Do(func() {
+		chromaInjectionNames = make(map[string]chroma.Lexer)
+		for _, lexerName := range chromaInjectionLexers {
+			lexer := lexers.Get(lexerName)
+			if lexer == nil {
+				continue
+			}
+			chromaInjectionNames[strings.ToLower(lexer.Config().Name)] = lexer
+			for _, alias := range lexer.Config().Aliases {
+				chromaInjectionNames[strings.ToLower(alias)] = lexer
+			}
+		}
+	})
+	return chromaInjectionNames[name]
+}
+
+// injectionLanguage resolves a captured language name to a registry
+// language with a grammar or, failing that, to a Chroma lexer: the registry
+// language's own, or an allowed one named by the name, as SQL and HTML are.
+func injectionLanguage(name string) (string, chroma.Lexer, bool) {
+	name = normalizeInjectionLanguage(name)
+	if canonical, err := languages.CanonicalName(name); err == nil {
+		cfg, err := languages.GetConfig(canonical)
+		if err != nil {
+			return "", nil, false
+		}
+		if cfg.HasGrammar() {
+			return canonical, nil, true
+		}
+		if lexer := lexers.Get(cfg.ChromaLexer); lexer != nil {
+			return canonical, lexer, true
+		}
+		return "", nil, false
+	}
+	if name == "" {
+		return "", nil, false
+	}
+	if lexer := chromaInjectionLexer(name); lexer != nil {
+		return name, lexer, true
+	}
+	return "", nil, false
+}
+
+// chromaInjectionTokens highlights a region with its Chroma lexer. The
+// ranges are lexed as one text, as a grammar parses them, and each token is
+// mapped back to the host's coordinates range by range.
+func chromaInjectionTokens(region *injectionRegion, content []byte) []TokenInfo {
+	ranges := sortedInjectionRanges(region.ranges)
+	var text strings.Builder
+	starts := make([]int, len(ranges)) // Offset of each range in text
+	for i, r := range ranges {
+		starts[i] = text.Len()
+		text.Write(content[r.StartByte:r.EndByte])
+	}
+	lexed, err := lexChromaTokens(region.lexer, text.String())
+	if err != nil {
+		return nil
+	}
+
+	lineStarts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	position := func(offset int) scip.Position {
+		line := sort.SearchInts(lineStarts, offset+1) - 1
+		return scip.Position{Line: int32(line), Character: int32(offset - lineStarts[line])}
+	}
+
+	markup := region.lexer.Config().Name == "HTML" || region.lexer.Config().Name == "XML"
+	var tokens []TokenInfo
+	for _, token := range lexed {
+		class := chromaHighlightClass(token.tokenType, token.value)
+		if token.tokenType == chroma.NameTag && markup {
+			class = "tag"
+		}
+		if class == "" {
+			continue
+		}
+		for i, r := range ranges {
+			start := max(token.start, starts[i])
+			end := min(token.start+len(token.value), starts[i]+int(r.EndByte-r.StartByte))
+			if start >= end {
+				continue
+			}
+			tokens = append(tokens, TokenInfo{
+				HighlightClass: class,
+				Document:       []string{},
+				Span: scip.Range{
+					Start: position(int(r.StartByte) + start - starts[i]),
+					End:   position(int(r.StartByte) + end - starts[i]),
+				},
+				Source: TokenSourceSyntax,
+			})
+		}
+	}
+	return tokens
+}
+
+// sortedInjectionRanges returns the non-empty ranges in source order.
+func sortedInjectionRanges(ranges []sitter.Range) []sitter.Range {
+	sorted := make([]sitter.Range, 0, len(ranges))
+	for _, r := range ranges {
+		if r.EndByte > r.StartByte {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartByte < sorted[j].StartByte
+	})
+	return sorted
+}
+
+// normalizeInjectionLanguage turns a captured language name, such as a
+// `/* sql */` comment or a `<<~SQL` heredoc tag, into a registry lookup key.
+func normalizeInjectionLanguage(name string) string {
+	name = strings.TrimSpace(name)
+	name = strings.TrimPrefix(name, "/*")
+	name = strings.TrimSuffix(name, "*/")
+	name = strings.TrimPrefix(name, "//")
+	name = strings.TrimPrefix(name, "#")
+	name = strings.Trim(strings.TrimSpace(name), "\"'")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// parseInjectionRegion parses the region's ranges of content with its
+// language. It uses a parser of its own because included ranges outlive a
+// Reset and would leak into the next file parsed with a cached parser.
+func parseInjectionRegion(region *injectionRegion, content []byte) (*SourceTree, error) {
+	cfg, err := languages.GetConfig(region.language)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.HasGrammar() {
+		return nil, nil
+	}
+
+	ranges := sortedInjectionRanges(region.ranges)
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(cfg.SitterLanguage); err != nil {
+		return nil, err
+	}
+	if err := parser.SetIncludedRanges(ranges); err != nil {
+		return nil, err
+	}
+	tree := parser.Parse(content, nil)
+	if tree == nil {
+		return nil, nil
+	}
+	return &SourceTree{
+		Language: region.language,
+		Content:  content,
+		Tree:     tree,
+	}, nil
+}
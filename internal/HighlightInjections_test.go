@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+)
+
+func TestInjectionQueriesCompile(t *testing.T) {
+	for _, language := range languages.Names() {
+		if _, err := cachedQuery(language, injectionsQueryKind); err != nil {
+			t.Errorf("%s injections query: %v", language, err)
+		}
+	}
+}
+
+func TestHighlightAnalyzerHighlightsTaggedTemplates(t *testing.T) {
+	source := "const q = ts`function add(a: number) { return a }`;\n"
+	classes := highlightClassesOf(t, "javascript", source)
+
+	if got := classes["a@0"]; got != "variable.parameter" {
+		t.Fatalf("a class = %q, want variable.parameter from the injected grammar", got)
+	}
+	if got := classes["number@0"]; got != "type.builtin" {
+		t.Fatalf("number class = %q, want type.builtin", got)
+	}
+}
+
+func TestHighlightAnalyzerHighlightsRubyHeredocs(t *testing.T) {
+	source := "script = <<~JS\n  const answer = 42;\nJS\n"
+	classes := highlightClassesOf(t, "ruby", source)
+
+	if got := classes["const@1"]; got == "" || got == "string" {
+		t.Fatalf("const class = %q, want a JavaScript keyword class", got)
+	}
+	if got := classes["42@1"]; got != "number" {
+		t.Fatalf("42 class = %q, want number", got)
+	}
+}
+
+func TestHighlightAnalyzerLexesInjectedLanguagesWithoutGrammar(t *testing.T) {
+	source := "package p\n\nvar q = /* sql */ `SELECT id\nFROM users`\n"
+	classes := highlightClassesOf(t, "go", source)
+
+	if got := classes["SELECT@2"]; got != "keyword" {
+		t.Fatalf("SELECT class = %q, want keyword from the SQL lexer", got)
+	}
+	if got := classes["FROM@3"]; got != "keyword" {
+		t.Fatalf("FROM class = %q, want keyword on the string's second line", got)
+	}
+}
+
+func TestHighlightAnalyzerHighlightsHTMLAroundPHP(t *testing.T) {
+	source := "<div class=\"box\"><?php echo 1; ?></div>\n"
+	classes := highlightClassesOf(t, "php", source)
+
+	if got := classes["div@0"]; got != "tag" {
+		t.Fatalf("div class = %q, want tag from the HTML lexer", got)
+	}
+	if got := classes["class@0"]; got != "attribute" {
+		t.Fatalf("class attribute class = %q, want attribute", got)
+	}
+	if got := classes["echo@0"]; got != "keyword" {
+		t.Fatalf("echo class = %q, want keyword from the PHP grammar", got)
+	}
+}
+
+func TestHighlightAnalyzerSkipsUnknownInjectionLanguages(t *testing.T) {
+	source := "package p\n\nvar q = /* cobolish */ `SELECT id FROM users`\n"
+	tokens, err := NewHighlightAnalyzer("go").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tree, err := ParseSource("go", []byte(source))
+	if err != nil {
+		t.Fatalf("ParseSource returned error: %v", err)
+	}
+	defer tree.Close()
+	host, err := highlightTreeTokens(nil, "go", tree)
+	if err != nil {
+		t.Fatalf("highlightTreeTokens returned error: %v", err)
+	}
+	if len(tokens) != len(host) {
+		t.Fatalf("got %d tokens, want the %d host tokens", len(tokens), len(host))
+	}
+
+	classes := highlightClassesOf(t, "go", source)
+	if got := classes["`SELECT id FROM users`@2"]; got != "string" {
+		t.Fatalf("string class = %q, want string", got)
+	}
+}
+
+func TestHighlightAnalyzerInjectsKnownLanguageNamedByComment(t *testing.T) {
+	source := "package p\n\nvar q = /* javascript */ `let x = 1`\n"
+	classes := highlightClassesOf(t, "go", source)
+
+	if got := classes["let@2"]; got == "" || got == "string" {
+		t.Fatalf("let class = %q, want a JavaScript keyword class", got)
+	}
+}
+
+func TestHighlightAnalyzerIgnoresCommentsThatAreNotLanguageMarkers(t *testing.T) {
+	tests := map[string]string{
+		"prose block comment": "package p\n\nvar q = /* the user query */ `SELECT id FROM users`\n",
+		"line comment":        "package p\n\nvar q = // sql\n\t`SELECT id FROM users`\n",
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			classes := highlightClassesOf(t, "go", source)
+			for key, class := range classes {
+				if strings.HasPrefix(key, "SELECT@") {
+					t.Fatalf("SELECT class = %q, want it left inside the host string", class)
+				}
+			}
+		})
+	}
+}
+
+func TestInjectionLanguageAllowsOnlyListedLexerNames(t *testing.T) {
+	tests := map[string]bool{
+		"go":       true,
+		"sql":      true,
+		"postgres": true,
+		"gql":      true,
+		"text":     false,
+		"svg":      false,
+		"htm":      false,
+		"eof":      false,
+	}
+	for name, want := range tests {
+		if _, _, ok := injectionLanguage(name); ok != want {
+			t.Errorf("injectionLanguage(%q) ok = %v, want %v", name, ok, want)
+		}
+	}
+}
+
+func TestNormalizeInjectionLanguage(t *testing.T) {
+	tests := map[string]string{
+		"/* sql */":     "sql",
+		"// HTML":       "html",
+		"# language=js": "language=js",
+		"JS":            "js",
+		`"python"`:      "python",
+	}
+	for input, want := range tests {
+		if got := normalizeInjectionLanguage(input); got != want {
+			t.Errorf("normalizeInjectionLanguage(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/cockroachdb/errors"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+var (
+	inheritsDirectivePattern = regexp.MustCompile(`^;+\s*inherits\s*:\s*(.*)$`)
+	highlightCapturePattern  = regexp.MustCompile(`^_?[a-z][a-z0-9_-]*(\.[a-z0-9_-]+)*$`)
+)
+
+// userHighlightQuery is one .scm file from the configured highlight query directory.
+type userHighlightQuery struct {
+	language string
+	path     string
+	source   string
+	inherits []string
+}
+
+// HighlightQueries holds the highlight queries a project writes for itself,
+// which take the place of the built-in queries of their languages. A nil
+// *HighlightQueries uses the built-in queries.
+type HighlightQueries struct {
+	queries map[string]*sitter.Query
+}
+
+// LoadHighlightQueries replaces or extends the built-in highlight queries with
+// the .scm files in dir. Files are named like the built-in queries (go.scm,
+// c_sharp.scm) or after a language (csharp.scm). A file replaces the built-in
+// query of its language, unless its leading comments hold a directive such as
+// `; inherits: go`, in which case the listed queries come first and the file
+// extends them; a language inheriting itself gets the built-in query. An empty
+// dir returns nil, the built-in queries. The caller closes the queries once no
+// analyzer uses them.
+func LoadHighlightQueries(dir string) (*HighlightQueries, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read highlight query directory %s", dir)
+	}
+
+	userQueries := make(map[string]*userHighlightQuery)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".scm" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		language, ok := highlightQueryLanguage(entry.Name())
+		if !ok {
+			return nil, errors.Newf("highlight query %s: no supported language uses this file name", path)
+		}
+		if existing, ok := userQueries[language]; ok {
+			return nil, errors.Newf("highlight query %s: %s already defines the %s query", path, existing.path, language)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read highlight query %s", path)
+		}
+		query := &userHighlightQuery{
+			language: language,
+			path:     path,
+			source:   string(content),
+		}
+		if query.inherits, err = parseInheritsDirective(query.source); err != nil {
+			return nil, errors.Wrapf(err, "highlight query %s", path)
+		}
+		userQueries[language] = query
+	}
+
+	languageNames := make([]string, 0, len(userQueries))
+	for language := range userQueries {
+		languageNames = append(languageNames, language)
+	}
+	sort.Strings(languageNames)
+
+	compiled := make(map[string]*sitter.Query, len(userQueries))
+	for _, language := range languageNames {
+		query, err := compileUserHighlightQuery(userQueries, userQueries[language])
+		if err != nil {
+			(&HighlightQueries{queries: compiled}).Close()
+			return nil, err
+		}
+		compiled[language] = query
+	}
+	return &HighlightQueries{queries: compiled}, nil
+}
+
+// query returns the highlight query of language: the project's own if it
+// wrote one, the built-in one otherwise.
+func (q *HighlightQueries) query(language string) (*sitter.Query, error) {
+	if q != nil {
+		if canonical, err := languages.CanonicalName(language); err == nil {
+			if query, ok := q.queries[canonical]; ok {
+				return query, nil
+			}
+		}
+	}
+	return cachedQuery(language, highlightQueryKind)
+}
+
+// Close frees the compiled queries.
+func (q *HighlightQueries) Close() {
+	if q == nil {
+		return
+	}
+	for _, query := range q.queries {
+		query.Close()
+	}
+	q.queries = nil
+}
+
+func highlightQueryLanguage(fileName string) (string, bool) {
+	for _, language := range languages.Names() {
+		cfg, err := languages.GetConfig(language)
+		if err == nil && cfg.QueryFileName == fileName {
+			return language, true
+		}
+	}
+	language, err := languages.CanonicalName(strings.TrimSuffix(fileName, ".scm"))
+	if err != nil {
+		return "", false
+	}
+	return language, true
+}
+
+// parseInheritsDirective reads `; inherits: a,b` from the comment lines at the
+// top of a query.
+func parseInheritsDirective(source string) ([]string, error) {
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, ";") {
+			return nil, nil
+		}
+		match := inheritsDirectivePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		var inherits []string
+		for _, name := range strings.Split(match[1], ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			language, err := languages.CanonicalName(name)
+			if err != nil {
+				return nil, errors.Newf("inherits unknown language %q", name)
+			}
+			inherits = append(inherits, language)
+		}
+		if len(inherits) == 0 {
+			return nil, errors.New("inherits directive names no language")
+		}
+		return inherits, nil
+	}
+	return nil, nil
+}
+
+// highlightQuerySection records which query a span of composed source came
+// from, so compile errors point at the right file and line.
+type highlightQuerySection struct {
+	name      string
+	startLine uint
+}
+
+func compileUserHighlightQuery(userQueries map[string]*userHighlightQuery, query *userHighlightQuery) (*sitter.Query, error) {
+	var sb strings.Builder
+	var sections []highlightQuerySection
+	if err := composeHighlightQuery(userQueries, query, &sb, &sections, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	cfg, err := languages.GetConfig(query.language)
+	if err != nil {
+		return nil, err
+	}
+	compiled, queryErr := sitter.NewQuery(cfg.SitterLanguage, sb.String())
+	if queryErr != nil {
+		section := sections[0]
+		for _, candidate := range sections {
+			if candidate.startLine <= queryErr.Row {
+				section = candidate
+			}
+		}
+		return nil, errors.Newf("highlight query %s:%d:%d: %s", section.name,
+			queryErr.Row-section.startLine+1, queryErr.Column+1, queryErrorMessage(queryErr))
+	}
+
+	for _, name := range compiled.CaptureNames() {
+		if !highlightCapturePattern.MatchString(name) {
+			compiled.Close()
+			return nil, errors.Newf("highlight query %s: capture @%s cannot be used as a highlight class; "+
+				"use lowercase dotted names such as @function.macro", query.path, name)
+		}
+	}
+	return compiled, nil
+}
+
+// composeHighlightQuery writes the inherited queries followed by the query itself.
+func composeHighlightQuery(userQueries map[string]*userHighlightQuery, query *userHighlightQuery, sb *strings.Builder, sections *[]highlightQuerySection, visiting map[string]bool) error {
+	if visiting[query.language] {
+		return errors.Newf("highlight query %s: inherits %s in a cycle", query.path, query.language)
+	}
+	visiting[query.language] = true
+	defer delete(visiting, query.language)
+
+	for _, language := range query.inherits {
+		parent, ok := userQueries[language]
+		if ok && language != query.language {
+			if err := composeHighlightQuery(userQueries, parent, sb, sections, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cfg, err := languages.GetConfig(language)
+		if err != nil {
+			return err
+		}
+		content, err := queryFS.ReadFile("queries/" + cfg.QueryFileName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read query file %s", cfg.QueryFileName)
+		}
+		appendHighlightQuerySection(sb, sections, "built-in "+cfg.QueryFileName, string(content))
+	}
+	appendHighlightQuerySection(sb, sections, query.path, query.source)
+	return nil
+}
+
+func appendHighlightQuerySection(sb *strings.Builder, sections *[]highlightQuerySection, name, source string) {
+	*sections = append(*sections, highlightQuerySection{
+		name:      name,
+		startLine: uint(strings.Count(sb.String(), "\n")),
+	})
+	sb.WriteString(source)
+	if !strings.HasSuffix(source, "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+func queryErrorMessage(err *sitter.QueryError) string {
+	switch err.Kind {
+	case sitter.QueryErrorCapture:
+		return "invalid capture name @" + err.Message
+	case sitter.QueryErrorNodeType:
+		return "invalid node type " + err.Message
+	case sitter.QueryErrorField:
+		return "invalid field name " + err.Message
+	case sitter.QueryErrorPredicate:
+		return "invalid predicate: " + err.Message
+	case sitter.QueryErrorStructure:
+		return "impossible pattern: " + strings.TrimSpace(err.Message)
+	case sitter.QueryErrorSyntax:
+		return "invalid syntax: " + strings.TrimSpace(err.Message)
+	default:
+		return err.Message
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadHighlightQueriesExtendsInheritedQuery(t *testing.T) {
+	dir := t.TempDir()
+	writeHighlightQuery(t, dir, "go.scm", `; inherits: go
+((call_expression
+  function: (selector_expression
+    operand: (identifier) @_pkg
+    field: (field_identifier) @function.macro.log))
+  (#eq? @_pkg "log"))
+`)
+
+	queries := loadHighlightQueriesForTest(t, dir)
+
+	classes := highlightClassesWithQueries(t, queries, "go", "package p\n\nfunc f() {\n\tlog.Printf(\"x\")\n\tfmt.Printf(\"x\")\n}\n")
+	if got := classes["Printf@3"]; got != "function.macro.log" {
+		t.Fatalf("log.Printf class = %q, want function.macro.log", got)
+	}
+	if got := classes["Printf@4"]; got == "function.macro.log" || got == "" {
+		t.Fatalf("fmt.Printf class = %q, want the built-in class", got)
+	}
+	if got := classes["func@2"]; got != "keyword.function" && got != "keyword" {
+		t.Fatalf("func class = %q, want the inherited keyword class", got)
+	}
+}
+
+func TestLoadHighlightQueriesReplacesBuiltInQuery(t *testing.T) {
+	dir := t.TempDir()
+	writeHighlightQuery(t, dir, "python.scm", "(identifier) @variable\n")
+
+	queries := loadHighlightQueriesForTest(t, dir)
+
+	classes := highlightClassesWithQueries(t, queries, "python", "def f():\n    pass\n")
+	if got := classes["def@0"]; got != "" {
+		t.Fatalf("def class = %q, want no class once the built-in query is replaced", got)
+	}
+	if got := classes["f@0"]; got != "variable" {
+		t.Fatalf("f class = %q, want variable", got)
+	}
+
+	// Analyzers without the project's queries keep the built-in ones.
+	if got := highlightClassesOf(t, "python", "def f():\n    pass\n")["def@0"]; got == "" {
+		t.Fatal("built-in query lost its def class")
+	}
+}
+
+func TestLoadHighlightQueriesReportsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown node type",
+			file:    "go.scm",
+			content: "; inherits: go\n\n(not_a_node) @function\n",
+			want:    "go.scm:3:2: invalid node type not_a_node",
+		},
+		{
+			name:    "capture not usable as a class",
+			file:    "go.scm",
+			content: "(identifier) @Log.Macro\n",
+			want:    "capture @Log.Macro cannot be used as a highlight class",
+		},
+		{
+			name:    "undefined capture in predicate",
+			file:    "go.scm",
+			content: "((identifier) @variable\n  (#eq? @missing \"x\"))\n",
+			want:    "go.scm:2:10: invalid capture name @missing",
+		},
+		{
+			name:    "unknown inherited language",
+			file:    "go.scm",
+			content: "; inherits: cobol\n(identifier) @variable\n",
+			want:    `inherits unknown language "cobol"`,
+		},
+		{
+			name:    "unknown language file",
+			file:    "cobol.scm",
+			content: "(identifier) @variable\n",
+			want:    "no supported language uses this file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeHighlightQuery(t, dir, tt.file, tt.content)
+
+			queries, err := LoadHighlightQueries(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadHighlightQueries error = %v, want %q", err, tt.want)
+			}
+			if queries != nil {
+				t.Fatal("LoadHighlightQueries returned queries with an error")
+			}
+
+			query, err := cachedQuery("go", highlightQueryKind)
+			if err != nil {
+				t.Fatalf("cachedQuery returned error: %v", err)
+			}
+			for _, name := range query.CaptureNames() {
+				if name == "Log.Macro" {
+					t.Fatal("invalid query replaced the built-in query")
+				}
+			}
+		})
+	}
+}
+
+func loadHighlightQueriesForTest(t *testing.T, dir string) *HighlightQueries {
+	t.Helper()
+	queries, err := LoadHighlightQueries(dir)
+	if err != nil {
+		t.Fatalf("LoadHighlightQueries returned error: %v", err)
+	}
+	t.Cleanup(queries.Close)
+	return queries
+}
+
+func writeHighlightQuery(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+// highlightClassesOf returns the merged class of each token keyed by "text@line".
+func highlightClassesOf(t *testing.T, language, source string) map[string]string {
+	t.Helper()
+	return highlightClassesWithQueries(t, nil, language, source)
+}
+
+func highlightClassesWithQueries(t *testing.T, queries *HighlightQueries, language, source string) map[string]string {
+	t.Helper()
+	analyzer := NewHighlightAnalyzer(language)
+	analyzer.Queries = queries
+	tokens, err := analyzer.Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	SortBySpan(tokens)
+	tokens, err = MergeSplitTokens(tokens)
+	if err != nil {
+		t.Fatalf("MergeSplitTokens returned error: %v", err)
+	}
+
+	lines := strings.Split(source, "\n")
+	classes := make(map[string]string)
+	for _, token := range tokens {
+		if token.Span.Start.Line != token.Span.End.Line {
+			continue
+		}
+		line := lines[token.Span.Start.Line]
+		text := line[token.Span.Start.Character:token.Span.End.Character]
+		classes[fmt.Sprintf("%s@%d", text, token.Span.Start.Line)] = token.HighlightClass
+	}
+	return classes
+}
@@ -0,0 +1,399 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// LSIFAnalyzer Analyze with an LSIF dump
+//
+// Used to load line-delimited LSIF graphs produced by older indexers
+// and turn the ranges of one document into the same token stream the
+// SCIP analyzer produces, including hover docs and cross-file definitions.
+type LSIFAnalyzer struct {
+	projectRoot   string
+	documents     map[string]string   // document id -> normalized absolute path
+	documentURIs  map[string]string   // document id -> URI as written in the dump
+	contains      map[string][]string // document id -> range ids
+	rangeDocument map[string]string   // range id -> document id
+	ranges        map[string]scip.Range
+	next          map[string]string // range/resultSet id -> resultSet id
+	hovers        map[string]string // range/resultSet id -> hoverResult id
+	hoverResults  map[string][]string
+	definitions   map[string]string // range/resultSet id -> definitionResult id
+	definitionIDs map[string]bool
+	items         map[string][]string // definitionResult id -> range ids
+	monikers      map[string][]string // range/resultSet id -> moniker ids
+	monikerInfo   map[string]lsifMoniker
+	packages      map[string]string // moniker id -> packageInformation id
+	packageInfo   map[string]lsifPackageInformation
+}
+
+type lsifElement struct {
+	ID    json.RawMessage   `json:"id"`
+	Type  string            `json:"type"`
+	Label string            `json:"label"`
+	OutV  json.RawMessage   `json:"outV"`
+	InV   json.RawMessage   `json:"inV"`
+	InVs  []json.RawMessage `json:"inVs"`
+
+	// Vertex payloads. Only the fields used by the analyzer are decoded.
+	URI         string          `json:"uri"`
+	ProjectRoot string          `json:"projectRoot"`
+	Start       *lsifPosition   `json:"start"`
+	End         *lsifPosition   `json:"end"`
+	Result      json.RawMessage `json:"result"`
+	Scheme      string          `json:"scheme"`
+	Identifier  string          `json:"identifier"`
+	Kind        string          `json:"kind"`
+	Name        string          `json:"name"`
+	Manager     string          `json:"manager"`
+	Version     string          `json:"version"`
+}
+
+type lsifPosition struct {
+	Line      int32 `json:"line"`
+	Character int32 `json:"character"`
+}
+
+type lsifMoniker struct {
+	Scheme     string
+	Identifier string
+	Kind       string
+}
+
+type lsifPackageInformation struct {
+	Name    string
+	Manager string
+	Version string
+}
+
+func NewLSIFAnalyzer(indexPath string) (*LSIFAnalyzer, error) {
+	lsifFile, err := os.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lsifFile.Close()
+
+	l := &LSIFAnalyzer{
+		documents:     make(map[string]string),
+		documentURIs:  make(map[string]string),
+		contains:      make(map[string][]string),
+		rangeDocument: make(map[string]string),
+		ranges:        make(map[string]scip.Range),
+		next:          make(map[string]string),
+		hovers:        make(map[string]string),
+		hoverResults:  make(map[string][]string),
+		definitions:   make(map[string]string),
+		definitionIDs: make(map[string]bool),
+		items:         make(map[string][]string),
+		monikers:      make(map[string][]string),
+		monikerInfo:   make(map[string]lsifMoniker),
+		packages:      make(map[string]string),
+		packageInfo:   make(map[string]lsifPackageInformation),
+	}
+
+	scanner := bufio.NewScanner(lsifFile)
+	// Hover results for large symbols easily exceed the default 64KiB line limit.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var element lsifElement
+		if err := json.Unmarshal(line, &element); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse LSIF dump at path %s line %d", indexPath, lineNumber)
+		}
+		l.add(element)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read LSIF dump at path %s", indexPath)
+	}
+
+	for documentID, uri := range l.documentURIs {
+		l.documents[documentID] = l.documentPath(uri)
+	}
+	for documentID, rangeIDs := range l.contains {
+		if _, ok := l.documentURIs[documentID]; !ok {
+			continue
+		}
+		for _, rangeID := range rangeIDs {
+			l.rangeDocument[rangeID] = documentID
+		}
+	}
+
+	return l, nil
+}
+
+func (l *LSIFAnalyzer) add(element lsifElement) {
+	id := lsifID(element.ID)
+	switch element.Type {
+	case "vertex":
+		switch element.Label {
+		case "metaData":
+			l.projectRoot = element.ProjectRoot
+		case "document":
+			l.documentURIs[id] = element.URI
+		case "range":
+			if element.Start != nil && element.End != nil {
+				l.ranges[id] = scip.Range{
+					Start: scip.Position{Line: element.Start.Line, Character: element.Start.Character},
+					End:   scip.Position{Line: element.End.Line, Character: element.End.Character},
+				}
+			}
+		case "definitionResult":
+			l.definitionIDs[id] = true
+		case "hoverResult":
+			l.hoverResults[id] = lsifHoverContents(element.Result)
+		case "moniker":
+			l.monikerInfo[id] = lsifMoniker{Scheme: element.Scheme, Identifier: element.Identifier, Kind: element.Kind}
+		case "packageInformation":
+			l.packageInfo[id] = lsifPackageInformation{Name: element.Name, Manager: element.Manager, Version: element.Version}
+		}
+	case "edge":
+		outV := lsifID(element.OutV)
+		inV := lsifID(element.InV)
+		inVs := make([]string, 0, len(element.InVs))
+		for _, raw := range element.InVs {
+			inVs = append(inVs, lsifID(raw))
+		}
+		switch element.Label {
+		case "contains":
+			l.contains[outV] = append(l.contains[outV], inVs...)
+		case "next":
+			l.next[outV] = inV
+		case "textDocument/hover":
+			l.hovers[outV] = inV
+		case "textDocument/definition":
+			l.definitions[outV] = inV
+		case "item":
+			// Only definition result items are kept: references are derived from the
+			// definitions each range points at, the same way the SCIP analyzer links them.
+			if l.definitionIDs[outV] {
+				l.items[outV] = append(l.items[outV], inVs...)
+			}
+		case "moniker":
+			l.monikers[outV] = append(l.monikers[outV], inV)
+		case "packageInformation":
+			l.packages[outV] = inV
+		}
+	}
+}
+
+// lsifID normalizes vertex ids, which the LSIF spec allows to be numbers or strings.
+func lsifID(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func (l *LSIFAnalyzer) documentPath(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		return normalizePath(u.Path)
+	}
+	if filepath.IsAbs(uri) {
+		return normalizePath(uri)
+	}
+	root := l.projectRoot
+	if u, err := url.Parse(root); err == nil && u.Scheme == "file" {
+		root = u.Path
+	}
+	return normalizePath(filepath.Join(root, filepath.FromSlash(uri)))
+}
+
+func (l *LSIFAnalyzer) Analyze(sourcePath string) []TokenInfo {
+	documentID := ""
+	for id, documentPath := range l.documents {
+		if samePath(documentPath, sourcePath) {
+			documentID = id
+			break
+		}
+	}
+	if documentID == "" {
+		return []TokenInfo{}
+	}
+
+	var tokens []TokenInfo
+	for _, rangeID := range l.contains[documentID] {
+		span, ok := l.ranges[rangeID]
+		if !ok {
+			continue
+		}
+
+		var documents []string
+		if hoverID, ok := l.resolve(rangeID, l.hovers); ok {
+			documents = append(documents, l.hoverResults[hoverID]...)
+		}
+
+		token := TokenInfo{
+			HighlightClass: "",
+			Document:       documents,
+			Span:           span,
+			Source:         TokenSourceLSIF,
+		}
+		if definition, definitionRangeID, ok := l.definition(rangeID); ok {
+			token.Definition = definition
+			token.Symbol = getSymbolID(definition.URI, int(definition.Range.Start.Line), int(definition.Range.Start.Character))
+			token.IsDefinition = definitionRangeID == rangeID
+			token.IsReference = !token.IsDefinition
+		} else if pkg := l.importedPackage(rangeID); pkg != nil {
+			token.Definition = &SourceLocation{Package: pkg}
+			token.IsReference = true
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+// resolve follows the next chain from a range through its result sets until edges has an entry.
+func (l *LSIFAnalyzer) resolve(id string, edges map[string]string) (string, bool) {
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		seen[id] = true
+		if target, ok := edges[id]; ok {
+			return target, true
+		}
+		id = l.next[id]
+	}
+	return "", false
+}
+
+func (l *LSIFAnalyzer) definition(rangeID string) (*SourceLocation, string, bool) {
+	resultID, ok := l.resolve(rangeID, l.definitions)
+	if !ok {
+		return nil, "", false
+	}
+
+	// Prefer the range itself when the result lists it, then the earliest location
+	// so that the chosen definition does not depend on map iteration order.
+	candidates := append([]string(nil), l.items[resultID]...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i] == rangeID || candidates[j] == rangeID {
+			return candidates[i] == rangeID
+		}
+		left, right := l.documents[l.rangeDocument[candidates[i]]], l.documents[l.rangeDocument[candidates[j]]]
+		if left != right {
+			return left < right
+		}
+		return scip.Position.Less(l.ranges[candidates[i]].Start, l.ranges[candidates[j]].Start)
+	})
+	for _, candidate := range candidates {
+		documentID, ok := l.rangeDocument[candidate]
+		if !ok {
+			continue
+		}
+		span, ok := l.ranges[candidate]
+		if !ok {
+			continue
+		}
+		documentPath := l.documents[documentID]
+		definition := &SourceLocation{
+			URI:   fileURIForPath(documentPath),
+			Path:  documentPath,
+			Range: span,
+		}
+		definition.Package = ExternalPackageFromPath(definition.Path, "")
+		return definition, candidate, true
+	}
+	return nil, "", false
+}
+
+// importedPackage describes ranges whose definition lives outside the dump, using the
+// import moniker and its package information when the indexer emitted them.
+func (l *LSIFAnalyzer) importedPackage(rangeID string) *ExternalPackage {
+	seen := make(map[string]bool)
+	for id := rangeID; id != "" && !seen[id]; id = l.next[id] {
+		seen[id] = true
+		for _, monikerID := range l.monikers[id] {
+			moniker := l.monikerInfo[monikerID]
+			if moniker.Kind != "import" {
+				continue
+			}
+			info, ok := l.packageInfo[l.packages[monikerID]]
+			if !ok || info.Name == "" {
+				continue
+			}
+			pkg := &ExternalPackage{
+				Manager: normalizeExternalPackageManager(info.Manager),
+				Module:  info.Name,
+				Version: info.Version,
+				Package: info.Name,
+			}
+			// lsif-go and lsif-node write identifiers as "<package path>:<symbol>".
+			if pkgPath, symbol, ok := strings.Cut(moniker.Identifier, ":"); ok {
+				if pkgPath != "" {
+					pkg.Package = pkgPath
+				}
+				pkg.Symbol = symbol
+			}
+			return pkg
+		}
+	}
+	return nil
+}
+
+func fileURIForPath(sourcePath string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(sourcePath)}).String()
+}
+
+// lsifHoverContents converts hover result contents into markdown strings. LSIF hovers
+// may hold MarkupContent, a MarkedString, or an array of MarkedStrings.
+func lsifHoverContents(raw json.RawMessage) []string {
+	var result struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil || len(result.Contents) == 0 {
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(result.Contents, &list); err != nil {
+		list = []json.RawMessage{result.Contents}
+	}
+
+	var documents []string
+	for _, item := range list {
+		if text := lsifMarkedString(item); strings.TrimSpace(text) != "" {
+			documents = append(documents, text)
+		}
+	}
+	return documents
+}
+
+func lsifMarkedString(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var marked struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &marked); err != nil {
+		return ""
+	}
+	if marked.Language != "" {
+		return fmt.Sprintf("```%s\n%s\n```", marked.Language, strings.TrimRight(marked.Value, "\n"))
+	}
+	return marked.Value
+}
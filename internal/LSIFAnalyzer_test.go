@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLSIFAnalyzerHoversAndCrossFileDefinitions(t *testing.T) {
+	root := t.TempDir()
+	indexPath := filepath.Join(root, "dump.lsif")
+	dump := strings.Join([]string{
+		`{"id":1,"type":"vertex","label":"metaData","version":"0.4.3","projectRoot":"` + fileURI(root) + `"}`,
+		`{"id":2,"type":"vertex","label":"document","uri":"` + fileURI(filepath.Join(root, "lib.go")) + `","languageId":"go"}`,
+		`{"id":3,"type":"vertex","label":"document","uri":"main.go","languageId":"go"}`,
+		`{"id":4,"type":"vertex","label":"resultSet"}`,
+		`{"id":5,"type":"vertex","label":"range","start":{"line":2,"character":5},"end":{"line":2,"character":10}}`,
+		`{"id":6,"type":"edge","label":"next","outV":5,"inV":4}`,
+		`{"id":7,"type":"vertex","label":"hoverResult","result":{"contents":[{"language":"go","value":"func Greet()"},"Greet says hello."]}}`,
+		`{"id":8,"type":"edge","label":"textDocument/hover","outV":4,"inV":7}`,
+		`{"id":9,"type":"vertex","label":"definitionResult"}`,
+		`{"id":10,"type":"edge","label":"textDocument/definition","outV":4,"inV":9}`,
+		`{"id":11,"type":"edge","label":"item","outV":9,"inVs":[5],"document":2}`,
+		`{"id":12,"type":"vertex","label":"range","start":{"line":4,"character":1},"end":{"line":4,"character":6}}`,
+		`{"id":13,"type":"edge","label":"next","outV":12,"inV":4}`,
+		`{"id":14,"type":"vertex","label":"range","start":{"line":5,"character":1},"end":{"line":5,"character":8}}`,
+		`{"id":15,"type":"vertex","label":"moniker","scheme":"gomod","identifier":"golang.org/x/sync/errgroup:Group","kind":"import"}`,
+		`{"id":16,"type":"vertex","label":"packageInformation","name":"golang.org/x/sync","manager":"gomod","version":"v0.17.0"}`,
+		`{"id":17,"type":"edge","label":"moniker","outV":14,"inV":15}`,
+		`{"id":18,"type":"edge","label":"packageInformation","outV":15,"inV":16}`,
+		`{"id":19,"type":"edge","label":"contains","outV":2,"inVs":[5]}`,
+		`{"id":20,"type":"edge","label":"contains","outV":3,"inVs":[12,14]}`,
+	}, "\n")
+	if err := os.WriteFile(indexPath, []byte(dump), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+
+	analyzer, err := NewLSIFAnalyzer(indexPath)
+	if err != nil {
+		t.Fatalf("NewLSIFAnalyzer returned error: %v", err)
+	}
+
+	libTokens := analyzer.Analyze(filepath.Join(root, "lib.go"))
+	if len(libTokens) != 1 {
+		t.Fatalf("lib.go tokens = %d, want 1", len(libTokens))
+	}
+	definition := libTokens[0]
+	if !definition.IsDefinition || definition.IsReference {
+		t.Fatalf("lib.go token roles = def %v ref %v, want definition", definition.IsDefinition, definition.IsReference)
+	}
+	if len(definition.Document) != 2 || definition.Document[0] != "```go\nfunc Greet()\n```" || definition.Document[1] != "Greet says hello." {
+		t.Fatalf("hover documents = %#v", definition.Document)
+	}
+
+	mainTokens := analyzer.Analyze(filepath.Join(root, "main.go"))
+	if len(mainTokens) != 2 {
+		t.Fatalf("main.go tokens = %d, want 2", len(mainTokens))
+	}
+	reference := mainTokens[0]
+	if !reference.IsReference || reference.IsDefinition {
+		t.Fatalf("main.go token roles = def %v ref %v, want reference", reference.IsDefinition, reference.IsReference)
+	}
+	if reference.Symbol == "" || reference.Symbol != definition.Symbol {
+		t.Fatalf("reference symbol = %q, want definition symbol %q", reference.Symbol, definition.Symbol)
+	}
+	if reference.Definition == nil || !samePath(reference.Definition.Path, filepath.Join(root, "lib.go")) {
+		t.Fatalf("reference definition = %#v, want lib.go", reference.Definition)
+	}
+	if reference.Definition.Range.Start.Line != 2 || reference.Definition.Range.Start.Character != 5 {
+		t.Fatalf("reference definition range = %#v", reference.Definition.Range)
+	}
+	if len(reference.Document) != 2 {
+		t.Fatalf("reference hover documents = %#v, want result set hover", reference.Document)
+	}
+
+	imported := mainTokens[1]
+	if imported.Definition == nil || imported.Definition.Package == nil {
+		t.Fatalf("imported token definition = %#v, want package information", imported.Definition)
+	}
+	want := ExternalPackage{Manager: "go", Module: "golang.org/x/sync", Version: "v0.17.0", Package: "golang.org/x/sync/errgroup", Symbol: "Group"}
+	if *imported.Definition.Package != want {
+		t.Fatalf("imported package = %#v, want %#v", *imported.Definition.Package, want)
+	}
+
+	if tokens := analyzer.Analyze(filepath.Join(root, "missing.go")); len(tokens) != 0 {
+		t.Fatalf("missing document tokens = %d, want 0", len(tokens))
+	}
+}
+
+func TestNewLSIFAnalyzerReportsMalformedLine(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "dump.lsif")
+	if err := os.WriteFile(indexPath, []byte("{\"id\":1,\"type\":\"vertex\",\"label\":\"metaData\"}\n{not json\n"), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+
+	_, err := NewLSIFAnalyzer(indexPath)
+	if err == nil {
+		t.Fatal("NewLSIFAnalyzer returned nil error for malformed dump")
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("error = %q, want line number", err.Error())
+	}
+}
@@ -2,9 +2,12 @@ package internal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -21,13 +24,20 @@ type LSPAnalyzer struct {
 	language      string
 	sourcePath    string
 	workspaceRoot string
+	// Queries holds the project's own highlight queries, whose captures the
+	// language server is asked about; nil uses the built-in ones.
+	Queries *HighlightQueries
 }
 
 // LSPSession owns one initialized language-server process and reuses it across files.
 type LSPSession struct {
 	language string
+	rootDir  string // Absolute workspace root; definitions outside it are dependencies
 	cfg      *languages.LanguageConfig
 	client   *lsp.Client
+	// Queries holds the project's own highlight queries; nil uses the
+	// built-in ones.
+	Queries *HighlightQueries
 
 	requestMu sync.Mutex
 	closeMu   sync.Mutex
@@ -43,6 +53,16 @@ func NewLSPAnalyzer(language, sourcePath string, workspaceRoot string) *LSPAnaly
 }
 
 func (l *LSPAnalyzer) Analyze(sourceContent []byte) ([]TokenInfo, error) {
+	tree, err := ParseSource(l.language, sourceContent)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	return l.AnalyzeTree(tree)
+}
+
+// AnalyzeTree queries the language server at the captures of an already parsed file.
+func (l *LSPAnalyzer) AnalyzeTree(tree *SourceTree) ([]TokenInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
@@ -51,8 +71,9 @@ func (l *LSPAnalyzer) Analyze(sourceContent []byte) ([]TokenInfo, error) {
 		return nil, err
 	}
 	defer session.Close()
+	session.Queries = l.Queries
 
-	return session.AnalyzeFile(l.sourcePath, sourceContent)
+	return session.AnalyzeFileTree(l.sourcePath, tree)
 }
 
 // NewLSPSession starts and initializes one language server for a workspace.
@@ -69,15 +90,28 @@ func NewLSPSession(ctx context.Context, language, workspaceRoot string) (*LSPSes
 		ctx = context.Background()
 	}
 	rootDir := resolveLSPWorkspaceRoot(workspaceRoot, "")
+	if absRoot, err := filepath.Abs(rootDir); err == nil {
+		rootDir = absRoot
+	}
+	server := selectLSPServer(cfg)
+
+	args := server.Args
+	if server.WorkspaceDataArg != "" {
+		dataDir, err := lspWorkspaceDataDir(server.Command, rootDir)
+		if err != nil {
+			return nil, err
+		}
+		args = append(append([]string(nil), server.Args...), server.WorkspaceDataArg, dataDir)
+	}
 
-	lspDebugf("start session language=%s command=%s args=%v root=%s", language, cfg.LSPCommand, cfg.LSPArgs, rootDir)
-	client, err := lsp.NewClient(ctx, cfg.LSPCommand, cfg.LSPArgs)
+	lspDebugf("start session language=%s command=%s args=%v root=%s", language, server.Command, args, rootDir)
+	client, err := lsp.NewClient(ctx, server.Command, args)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to start lsp client")
 	}
 
-	lspDebugf("initialize language=%s initOptions=%#v workspaceConfig=%#v", language, cfg.LSPInitializationOptions, cfg.LSPWorkspaceConfiguration)
-	if err := client.InitializeWithOptions(rootDir, cfg.LSPInitializationOptions, cfg.LSPWorkspaceConfiguration); err != nil {
+	lspDebugf("initialize language=%s initOptions=%#v workspaceConfig=%#v", language, server.InitializationOptions, server.WorkspaceConfiguration)
+	if err := client.InitializeWithOptions(rootDir, server.InitializationOptions, server.WorkspaceConfiguration); err != nil {
 		_ = client.Shutdown()
 		return nil, errors.Wrap(err, "lsp initialize failed")
 	}
@@ -92,11 +126,41 @@ func NewLSPSession(ctx context.Context, language, workspaceRoot string) (*LSPSes
 
 	return &LSPSession{
 		language: language,
+		rootDir:  rootDir,
 		cfg:      cfg,
 		client:   client,
 	}, nil
 }
 
+// selectLSPServer returns the first of the language's servers that is on PATH.
+// When none is installed it returns the primary server, so the start error
+// names the one users are most likely to install.
+func selectLSPServer(cfg *languages.LanguageConfig) languages.LSPServer {
+	servers := cfg.LSPServers()
+	for _, server := range servers {
+		if _, err := exec.LookPath(server.Command); err == nil {
+			return server
+		}
+	}
+	return servers[0]
+}
+
+// lspWorkspaceDataDir returns the data directory a server such as jdtls keeps
+// for one workspace, under the user cache directory. Each workspace root gets
+// its own, so projects never share or clobber each other's server state.
+func lspWorkspaceDataDir(command, rootDir string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(rootDir))
+	dataDir := filepath.Join(cacheDir, "gocire", "lsp", command, filepath.Base(rootDir)+"-"+hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return "", errors.Wrapf(err, "create %s workspace data directory", command)
+	}
+	return dataDir, nil
+}
+
 func resolveLSPWorkspaceRoot(workspaceRoot, sourcePath string) string {
 	if workspaceRoot != "" {
 		return workspaceRoot
@@ -109,6 +173,17 @@ func resolveLSPWorkspaceRoot(workspaceRoot, sourcePath string) string {
 
 // AnalyzeFile analyzes one file through the session's language server.
 func (s *LSPSession) AnalyzeFile(sourcePath string, sourceContent []byte) ([]TokenInfo, error) {
+	tree, err := ParseSource(s.language, sourceContent)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	return s.AnalyzeFileTree(sourcePath, tree)
+}
+
+// AnalyzeFileTree is AnalyzeFile for a file the caller has already parsed.
+func (s *LSPSession) AnalyzeFileTree(sourcePath string, tree *SourceTree) ([]TokenInfo, error) {
+	sourceContent := tree.Content
 	lspDebugf("didOpen language=%s source=%s bytes=%d", s.language, sourcePath, len(sourceContent))
 	if err := s.withLSPClient(func(client *lsp.Client) error {
 		return client.DidOpen(sourcePath, s.language, string(sourceContent))
@@ -121,7 +196,7 @@ func (s *LSPSession) AnalyzeFile(sourcePath string, sourceContent []byte) ([]Tok
 
 	inlayHintTokens := s.fetchInlayHintTokens(sourcePath, sourceContent)
 
-	tokens, err := analyzeLSPTokens(s.language, sourcePath, sourceContent, s.cfg, func(line, char int) (*lsp.Hover, []lsp.Location) {
+	tokens, err := analyzeLSPTokens(s.Queries, s.language, s.rootDir, sourcePath, tree, s.cfg, func(line, char int) (*lsp.Hover, []lsp.Location) {
 		var hover *lsp.Hover
 		var defs []lsp.Location
 		if err := s.withLSPClient(func(client *lsp.Client) error {
@@ -262,6 +337,7 @@ func tokenInfosFromInlayHints(hints []lsp.InlayHint) []TokenInfo {
 				Start: pos,
 				End:   pos,
 			},
+			Source: TokenSourceLSP,
 		})
 	}
 	return tokens
@@ -317,24 +393,12 @@ func isContentModifiedError(err error) bool {
 	return strings.Contains(msg, "content modified") || strings.Contains(msg, "-32801")
 }
 
-func analyzeLSPTokens(language, sourcePath string, sourceContent []byte, cfg *languages.LanguageConfig, queryLSP lspTokenQuery) ([]TokenInfo, error) {
-	parser := sitter.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(cfg.SitterLanguage)
-
-	tree := parser.Parse(sourceContent, nil)
-	defer tree.Close()
-
-	queryContent, err := queryFS.ReadFile("queries/" + cfg.QueryFileName)
+func analyzeLSPTokens(queries *HighlightQueries, language, rootDir, sourcePath string, tree *SourceTree, cfg *languages.LanguageConfig, queryLSP lspTokenQuery) ([]TokenInfo, error) {
+	query, err := queries.query(language)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read query file %s", cfg.QueryFileName)
-	}
-
-	query, qErr := sitter.NewQuery(cfg.SitterLanguage, string(queryContent))
-	if qErr != nil {
-		return nil, errors.Wrapf(qErr, "failed to create query for %s", language)
+		return nil, err
 	}
-	defer query.Close()
+	sourceContent := tree.Content
 
 	qc := sitter.NewQueryCursor()
 	defer qc.Close()
@@ -393,7 +457,7 @@ func analyzeLSPTokens(language, sourcePath string, sourceContent []byte, cfg *la
 				// Process hover results
 				if hover != nil && hover.Contents.Value != "" {
 					hoverResultCount++
-					docs = append(docs, hover.Contents.Value)
+					docs = append(docs, structureHoverDocument(hover.Contents.Value, language))
 				}
 
 				// Process definition results
@@ -401,6 +465,9 @@ func analyzeLSPTokens(language, sourcePath string, sourceContent []byte, cfg *la
 					definitionResultCount++
 					d := defs[0]
 					definition = sourceLocationFromLSP(d)
+					if !withinRoot(rootDir, definition.Path) {
+						definition.Package = ExternalPackageFromDefinition(*definition, node.Utf8Text(sourceContent))
+					}
 					// Generate a unique symbol ID based on the definition location
 					// This allows references to link to this specific definition
 					// We use the first definition if multiple are returned
@@ -430,6 +497,7 @@ func analyzeLSPTokens(language, sourcePath string, sourceContent []byte, cfg *la
 				Document:       docs,
 				Span:           span,
 				Definition:     definition,
+				Source:         TokenSourceLSP,
 			}
 			tokens = append(tokens, token)
 		}
@@ -476,6 +544,19 @@ func isDefinitionLocation(definition *SourceLocation, sourcePath string, span sc
 	return scip.Position.Compare(definition.Range.Start, span.Start) == 0
 }
 
+// withinRoot reports whether sourcePath lies under rootDir. Without a root every
+// path counts as outside, so dependencies are still recognized by their path.
+func withinRoot(rootDir, sourcePath string) bool {
+	if rootDir == "" || sourcePath == "" {
+		return false
+	}
+	rel, err := filepath.Rel(rootDir, sourcePath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func samePath(left, right string) bool {
 	if left == "" || right == "" {
 		return false
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -69,3 +70,16 @@ func TestFullDocumentInlayHintRangeTrimsTrailingSplitLine(t *testing.T) {
 		t.Fatalf("multi-line range end = %+v, want 1:%d", r.End, len([]rune("second")))
 	}
 }
+
+func TestWithinRootSeparatesProjectFromDependencyPaths(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo")
+	if !withinRoot(root, filepath.Join(root, "pkg", "mod", "main.go")) {
+		t.Fatal("project file under a pkg/mod directory counted as outside the root")
+	}
+	if withinRoot(root, filepath.Join(filepath.Dir(root), "repo2", "main.go")) {
+		t.Fatal("sibling directory counted as inside the root")
+	}
+	if withinRoot("", filepath.Join(root, "main.go")) {
+		t.Fatal("path counted as inside an empty root")
+	}
+}
@@ -0,0 +1,333 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// fakeLSPRecord is what the scripted server saw of the client.
+type fakeLSPRecord struct {
+	Command               string          `json:"command"`
+	Args                  []string        `json:"args"`
+	InitializationOptions json.RawMessage `json:"initializationOptions"`
+	Configuration         json.RawMessage `json:"configuration"`
+}
+
+func TestLSPSessionStartsConfiguredServers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake servers are shell scripts")
+	}
+
+	tests := []struct {
+		name        string
+		language    string
+		installed   []string
+		wantCommand string
+		wantArgs    []string
+		// wantFlags are arguments that must be among the server's, checked in
+		// place of wantArgs for servers with long registry command lines.
+		wantFlags   []string
+		optionPath  []string
+		wantOption  interface{}
+		section     string
+		wantSection interface{}
+	}{
+		{
+			name:        "jdtls",
+			language:    "java",
+			installed:   []string{"jdtls"},
+			wantCommand: "jdtls",
+			wantFlags:   []string{"-data"},
+			optionPath:  []string{"settings", "java", "inlayHints", "parameterNames", "enabled"},
+			wantOption:  "all",
+			section:     "java.inlayHints.variableTypes.enabled",
+			wantSection: true,
+		},
+		{
+			name:        "solargraph",
+			language:    "ruby",
+			installed:   []string{"solargraph", "ruby-lsp"},
+			wantCommand: "solargraph",
+			wantArgs:    []string{"stdio"},
+			optionPath:  []string{"definitions"},
+			wantOption:  true,
+			section:     "solargraph.diagnostics",
+			wantSection: false,
+		},
+		{
+			name:        "ruby-lsp when solargraph is missing",
+			language:    "ruby",
+			installed:   []string{"ruby-lsp"},
+			wantCommand: "ruby-lsp",
+			wantArgs:    []string{},
+			optionPath:  []string{"enabledFeatures", "inlayHint"},
+			wantOption:  true,
+		},
+		{
+			name:        "csharp-ls",
+			language:    "csharp",
+			installed:   []string{"csharp-ls", "OmniSharp"},
+			wantCommand: "csharp-ls",
+			wantArgs:    []string{},
+			section:     "csharp.applyFormattingOptions",
+			wantSection: false,
+		},
+		{
+			name:        "OmniSharp when csharp-ls is missing",
+			language:    "cs",
+			installed:   []string{"OmniSharp"},
+			wantCommand: "OmniSharp",
+			wantFlags: []string{
+				"-lsp",
+				"RoslynExtensionsOptions:InlayHintsOptions:EnableForParameters=true",
+				"RoslynExtensionsOptions:InlayHintsOptions:EnableForTypes=true",
+			},
+		},
+		{
+			name:        "intelephense",
+			language:    "php",
+			installed:   []string{"intelephense", "phpactor"},
+			wantCommand: "intelephense",
+			wantArgs:    []string{"--stdio"},
+			optionPath:  []string{"clearCache"},
+			wantOption:  false,
+			section:     "intelephense.telemetry.enabled",
+			wantSection: false,
+		},
+		{
+			name:        "phpactor when intelephense is missing",
+			language:    "php",
+			installed:   []string{"phpactor"},
+			wantCommand: "phpactor",
+			wantArgs:    []string{"language-server"},
+			optionPath:  []string{"language_server_worse_reflection.inlay_hints.enable"},
+			wantOption:  true,
+		},
+		{
+			name:        "dart",
+			language:    "dart",
+			installed:   []string{"dart"},
+			wantCommand: "dart",
+			wantArgs:    []string{"language-server", "--protocol=lsp"},
+			optionPath:  []string{"onlyAnalyzeProjectsWithOpenFiles"},
+			wantOption:  false,
+			section:     "dart.inlayHints.parameterNames.enabled",
+			wantSection: "all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := runFakeLSPSession(t, tt.language, tt.installed, tt.section)
+
+			if record.Command != tt.wantCommand {
+				t.Fatalf("started %q, want %q", record.Command, tt.wantCommand)
+			}
+			if tt.wantFlags != nil {
+				for _, flag := range tt.wantFlags {
+					if !slices.Contains(record.Args, flag) {
+						t.Fatalf("args = %#v, want %q among them", record.Args, flag)
+					}
+				}
+			} else if !reflect.DeepEqual(record.Args, tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", record.Args, tt.wantArgs)
+			}
+			if tt.optionPath != nil {
+				var options interface{}
+				if err := json.Unmarshal(record.InitializationOptions, &options); err != nil {
+					t.Fatalf("decode initializationOptions %s: %v", record.InitializationOptions, err)
+				}
+				if got := jsonPath(options, tt.optionPath...); got != tt.wantOption {
+					t.Fatalf("initializationOptions %v = %#v, want %#v in %s", tt.optionPath, got, tt.wantOption, record.InitializationOptions)
+				}
+			}
+			if tt.section != "" {
+				var configuration []interface{}
+				if err := json.Unmarshal(record.Configuration, &configuration); err != nil {
+					t.Fatalf("decode configuration %s: %v", record.Configuration, err)
+				}
+				if len(configuration) != 1 || configuration[0] != tt.wantSection {
+					t.Fatalf("workspace/configuration %s = %s, want [%v]", tt.section, record.Configuration, tt.wantSection)
+				}
+			}
+		})
+	}
+}
+
+func TestLSPWorkspaceDataDirIsPerWorkspace(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	first, err := lspWorkspaceDataDir("jdtls", "/work/a/app")
+	if err != nil {
+		t.Fatalf("lspWorkspaceDataDir returned error: %v", err)
+	}
+	again, _ := lspWorkspaceDataDir("jdtls", "/work/a/app")
+	other, _ := lspWorkspaceDataDir("jdtls", "/work/b/app")
+	if first != again || first == other {
+		t.Fatalf("data dirs = %q, %q, %q, want one per workspace root", first, again, other)
+	}
+	if info, err := os.Stat(first); err != nil || !info.IsDir() {
+		t.Fatalf("data dir %q was not created: %v", first, err)
+	}
+}
+
+func TestLSPSessionNamesPrimaryServerWhenNoneIsInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := NewLSPSession(context.Background(), "ruby", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "solargraph") {
+		t.Fatalf("NewLSPSession error = %v, want one naming solargraph", err)
+	}
+}
+
+// runFakeLSPSession puts scripts named after the installed servers on PATH,
+// each re-running this test binary as a scripted server, then opens and
+// closes a session for language.
+func runFakeLSPSession(t *testing.T, language string, installed []string, section string) fakeLSPRecord {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("find test binary: %v", err)
+	}
+
+	binDir := t.TempDir()
+	for _, command := range installed {
+		script := fmt.Sprintf("#!/bin/sh\nexec '%s' -test.run='^TestFakeLSPServerProcess$' -- '%s' \"$@\"\n", executable, command)
+		if err := os.WriteFile(filepath.Join(binDir, command), []byte(script), 0o755); err != nil {
+			t.Fatalf("write fake %s: %v", command, err)
+		}
+	}
+	logPath := filepath.Join(t.TempDir(), "record.json")
+	t.Setenv("PATH", binDir)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GOCIRE_FAKE_LSP_LOG", logPath)
+	t.Setenv("GOCIRE_FAKE_LSP_SECTION", section)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	session, err := NewLSPSession(ctx, language, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLSPSession returned error: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("fake server left no record: %v", err)
+	}
+	var record fakeLSPRecord
+	if err := json.Unmarshal(content, &record); err != nil {
+		t.Fatalf("decode record %s: %v", content, err)
+	}
+	return record
+}
+
+// TestFakeLSPServerProcess is not a real test: runFakeLSPSession runs the test
+// binary through it as a language server that records what it was sent.
+func TestFakeLSPServerProcess(t *testing.T) {
+	logPath := os.Getenv("GOCIRE_FAKE_LSP_LOG")
+	if logPath == "" {
+		return
+	}
+
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	record := fakeLSPRecord{Command: args[0], Args: append([]string{}, args[1:]...)}
+	section := os.Getenv("GOCIRE_FAKE_LSP_SECTION")
+
+	var (
+		mu       sync.Mutex
+		recorded = make(chan struct{})
+		exited   = make(chan struct{})
+		once     sync.Once
+	)
+	writeRecord := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		content, _ := json.Marshal(record)
+		_ = os.WriteFile(logPath, content, 0o644)
+		close(recorded)
+	}
+
+	handler := jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+		switch req.Method {
+		case "initialize":
+			var params struct {
+				InitializationOptions json.RawMessage `json:"initializationOptions"`
+			}
+			if req.Params != nil {
+				_ = json.Unmarshal(*req.Params, &params)
+			}
+			mu.Lock()
+			record.InitializationOptions = params.InitializationOptions
+			mu.Unlock()
+			return map[string]interface{}{"capabilities": map[string]interface{}{}}, nil
+		case "initialized":
+			if section == "" {
+				writeRecord()
+				return nil, nil
+			}
+			var result json.RawMessage
+			params := map[string]interface{}{"items": []map[string]string{{"section": section}}}
+			if err := conn.Call(ctx, "workspace/configuration", params, &result); err == nil {
+				mu.Lock()
+				record.Configuration = result
+				mu.Unlock()
+			}
+			writeRecord()
+			return nil, nil
+		case "exit":
+			once.Do(func() { close(exited) })
+		}
+		return nil, nil
+	})
+
+	stream := jsonrpc2.NewBufferedStream(fakeLSPStdio{}, jsonrpc2.VSCodeObjectCodec{})
+	conn := jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.AsyncHandler(handler))
+
+	select {
+	case <-exited:
+	case <-conn.DisconnectNotify():
+	}
+	select {
+	case <-recorded:
+	case <-time.After(5 * time.Second):
+	}
+	os.Exit(0)
+}
+
+type fakeLSPStdio struct{}
+
+func (fakeLSPStdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (fakeLSPStdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (fakeLSPStdio) Close() error                { return nil }
+
+func jsonPath(value interface{}, path ...string) interface{} {
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
@@ -40,6 +40,7 @@ type SourceRouteManifest struct {
 	Root        string
 	RoutePrefix string
 	Routes      map[string]string
+	External    ExternalLinkResolver
 }
 
 func NewSourceRouteManifest(root string, sourcePaths []string) (SourceRouteManifest, error) {
@@ -135,6 +136,9 @@ func ResolveTokenLinks(currentSourcePath string, tokens []TokenInfo, manifest So
 func ResolveDefinitionHref(currentSourcePath string, definition SourceLocation, manifest SourceRouteManifest) (href string, ok bool, warning *LinkResolutionWarning) {
 	targetPath, ok := definitionSourcePath(definition)
 	if !ok {
+		if href, ok := manifest.External.Resolve(definition); ok {
+			return href, true, nil
+		}
 		return "", false, &LinkResolutionWarning{
 			Code:    LinkResolutionWarningInvalidLocation,
 			Message: "definition location does not include a file path or file URI",
@@ -143,6 +147,9 @@ func ResolveDefinitionHref(currentSourcePath string, definition SourceLocation,
 
 	targetRelPath, ok := manifest.RelPathForSourcePath(targetPath)
 	if !ok {
+		if href, ok := manifest.External.Resolve(definition); ok {
+			return href, true, nil
+		}
 		return "", false, &LinkResolutionWarning{
 			Code:    LinkResolutionWarningOutsideRoot,
 			Message: "definition target is outside the source route manifest root",
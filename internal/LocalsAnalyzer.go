@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// LocalsAnalyzer Analyze with tree-sitter locals queries
+//
+// Used to link local variables and parameters to their definitions inside one
+// file when no language server or index is available. Queries use the
+// tree-sitter conventions: @local.scope, @local.definition and
+// @local.reference, with `(#set! local.scope-inherits false)` for scopes that
+// cannot see their parents. Captures starting with an underscore claim a node
+// without linking it, e.g. member names that share the identifier node type.
+type LocalsAnalyzer struct {
+	language   string
+	sourcePath string
+}
+
+type localScope struct {
+	endByte     uint
+	inherits    bool
+	definitions map[string]localDefinition
+}
+
+type localDefinition struct {
+	symbol string
+	span   scip.Range
+}
+
+func NewLocalsAnalyzer(language string, sourcePath string) *LocalsAnalyzer {
+	return &LocalsAnalyzer{
+		language:   language,
+		sourcePath: sourcePath,
+	}
+}
+
+func (l *LocalsAnalyzer) Analyze(sourceContent []byte) ([]TokenInfo, error) {
+	if !hasGrammar(l.language) {
+		return []TokenInfo{}, nil
+	}
+	tree, err := ParseSource(l.language, sourceContent)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+	return l.AnalyzeTree(tree)
+}
+
+// AnalyzeTree links the locals of an already parsed file.
+func (l *LocalsAnalyzer) AnalyzeTree(tree *SourceTree) ([]TokenInfo, error) {
+	query, err := cachedQuery(l.language, localsQueryKind)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return []TokenInfo{}, nil
+	}
+	sourceContent := tree.Content
+
+	root := tree.RootNode()
+	scopes := []*localScope{{
+		endByte:     root.EndByte(),
+		inherits:    false,
+		definitions: map[string]localDefinition{},
+	}}
+	claimed := make(map[uintptr]bool)
+	var tokens []TokenInfo
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	captures := qc.Captures(query, root, sourceContent)
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		capture := match.Captures[index]
+		node := capture.Node
+		captureName := query.CaptureNames()[capture.Index]
+
+		// Captures arrive in document order, so scopes that ended before this
+		// node no longer apply.
+		for len(scopes) > 1 && scopes[len(scopes)-1].endByte <= node.StartByte() {
+			scopes = scopes[:len(scopes)-1]
+		}
+
+		switch {
+		case captureName == "local.scope":
+			scopes = append(scopes, &localScope{
+				endByte:     node.EndByte(),
+				inherits:    localScopeInherits(query, match.PatternIndex),
+				definitions: map[string]localDefinition{},
+			})
+		case captureName == "local.definition" || strings.HasPrefix(captureName, "local.definition."):
+			if claimed[node.Id()] {
+				continue
+			}
+			claimed[node.Id()] = true
+
+			span := localNodeSpan(&node)
+			definition := localDefinition{
+				symbol: fmt.Sprintf("local_%d_%d", span.Start.Line, span.Start.Character),
+				span:   span,
+			}
+			scopes[len(scopes)-1].definitions[node.Utf8Text(sourceContent)] = definition
+			tokens = append(tokens, TokenInfo{
+				Symbol:       definition.symbol,
+				IsDefinition: true,
+				Span:         span,
+				Definition:   &SourceLocation{Path: l.sourcePath, Range: span},
+				Source:       TokenSourceLocals,
+			})
+		case captureName == "local.reference":
+			if claimed[node.Id()] {
+				continue
+			}
+			claimed[node.Id()] = true
+
+			definition, ok := lookupLocalDefinition(scopes, node.Utf8Text(sourceContent))
+			if !ok {
+				continue
+			}
+			tokens = append(tokens, TokenInfo{
+				Symbol:      definition.symbol,
+				IsReference: true,
+				Span:        localNodeSpan(&node),
+				Definition:  &SourceLocation{Path: l.sourcePath, Range: definition.span},
+				Source:      TokenSourceLocals,
+			})
+		case strings.HasPrefix(captureName, "_"):
+			claimed[node.Id()] = true
+		}
+	}
+
+	return tokens, nil
+}
+
+func localScopeInherits(query *sitter.Query, patternIndex uint) bool {
+	for _, property := range query.PropertySettings(patternIndex) {
+		if property.Key == "local.scope-inherits" && property.Value != nil && *property.Value == "false" {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupLocalDefinition searches the innermost scope outward, stopping at scopes
+// that do not inherit definitions from their parents.
+func lookupLocalDefinition(scopes []*localScope, name string) (localDefinition, bool) {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if definition, ok := scopes[i].definitions[name]; ok {
+			return definition, true
+		}
+		if !scopes[i].inherits {
+			break
+		}
+	}
+	return localDefinition{}, false
+}
+
+func localNodeSpan(node *sitter.Node) scip.Range {
+	return scip.Range{
+		Start: scip.Position{
+			Line:      int32(node.StartPosition().Row),
+			Character: int32(node.StartPosition().Column),
+		},
+		End: scip.Position{
+			Line:      int32(node.EndPosition().Row),
+			Character: int32(node.EndPosition().Column),
+		},
+	}
+}
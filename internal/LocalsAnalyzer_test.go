@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var localsTestLanguages = []string{
+	"go", "python", "typescript", "javascript", "rust", "cpp", "c",
+	"haskell", "java", "ruby", "csharp", "php", "dart",
+}
+
+func TestLocalsQueriesCompileForEveryLanguage(t *testing.T) {
+	for _, language := range localsTestLanguages {
+		t.Run(language, func(t *testing.T) {
+			if _, err := NewLocalsAnalyzer(language, "/repo/file").Analyze([]byte("")); err != nil {
+				t.Fatalf("Analyze returned error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalsAnalyzerLinksReferencesToDefinitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		source   string
+		// Each link is "reference line:col -> definition line:col", zero-based.
+		want []string
+	}{
+		{
+			name:     "go parameters and short declarations",
+			language: "go",
+			source: "package p\n" +
+				"func add(a int, b int) int {\n" +
+				"\tc := a + b\n" +
+				"\treturn c\n" +
+				"}\n",
+			want: []string{"2:6 -> 1:9", "2:10 -> 1:16", "3:8 -> 2:1"},
+		},
+		{
+			name:     "go shadowing in nested block",
+			language: "go",
+			source: "package p\n" +
+				"func f(x int) int {\n" +
+				"\tif true {\n" +
+				"\t\tx := 2\n" +
+				"\t\treturn x\n" +
+				"\t}\n" +
+				"\treturn x\n" +
+				"}\n",
+			want: []string{"4:9 -> 3:2", "6:8 -> 1:7"},
+		},
+		{
+			name:     "python ignores attributes",
+			language: "python",
+			source: "def f(name):\n" +
+				"    self.name = name\n",
+			want: []string{"1:16 -> 0:6"},
+		},
+		{
+			name:     "ruby methods do not inherit outer locals",
+			language: "ruby",
+			source: "x = 1\n" +
+				"def m(y)\n" +
+				"  x + y\n" +
+				"end\n",
+			want: []string{"2:6 -> 1:6"},
+		},
+		{
+			name:     "php closures import variables with use",
+			language: "php",
+			source: "<?php\n" +
+				"function f($a) {\n" +
+				"  $g = function () use ($a) { return $a; };\n" +
+				"  return $g;\n" +
+				"}\n",
+			want: []string{"2:37 -> 2:24", "3:9 -> 2:2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := NewLocalsAnalyzer(tt.language, "/repo/file").Analyze([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("Analyze returned error: %v", err)
+			}
+
+			var got []string
+			for _, token := range tokens {
+				if !token.IsReference {
+					continue
+				}
+				got = append(got, formatLocalLink(token))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Fatalf("links = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalsAnalyzerDefinitionsCarrySourceLocation(t *testing.T) {
+	tokens, err := NewLocalsAnalyzer("go", "/repo/main.go").Analyze([]byte("package p\nfunc f(a int) int { return a }\n"))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("tokens = %#v, want definition and reference", tokens)
+	}
+	definition, reference := tokens[0], tokens[1]
+	if !definition.IsDefinition || definition.Definition == nil || definition.Definition.Path != "/repo/main.go" {
+		t.Fatalf("definition = %#v", definition)
+	}
+	if reference.Symbol != definition.Symbol {
+		t.Fatalf("reference symbol = %q, want %q", reference.Symbol, definition.Symbol)
+	}
+}
+
+func formatLocalLink(token TokenInfo) string {
+	definition := token.Definition.Range.Start
+	return fmt.Sprintf("%d:%d -> %d:%d", token.Span.Start.Line, token.Span.Start.Character, definition.Line, definition.Character)
+}
@@ -16,6 +16,12 @@ type MDXGenerator struct {
 	comments         []CommentInfo // Comments to interleave
 	CodeWrapperStart string        // Custom opening HTML/JSX for code blocks
 	CodeWrapperEnd   string        // Custom closing HTML/JSX for code blocks
+	// Includes maps the line of each resolved gocire:include directive to the
+	// code it transcludes
+	Includes map[int]IncludedCode
+	// Markdown turns on optional Markdown features in hover cards and decides
+	// how prose HTML is sanitized.
+	Markdown MarkdownOptions
 }
 
 // NewMDXGenerator creates a new MDXGenerator instance from the given source lines.
@@ -30,6 +36,8 @@ func NewMDXGenerator(sourceLines []string) *MDXGenerator {
 
 // GenerateMDX generates MDX JSX code with proper escaping for JSX
 func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) string {
+	regions := FindCodeRegions(m.sourceLines, comments)
+	comments = proseComments(comments)
 	m.comments = comments
 	var sb strings.Builder
 
@@ -40,10 +48,22 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 		fileEndPos = scip.Position{Line: int32(lastLineIdx), Character: int32(len([]rune(m.sourceLines[lastLineIdx])))}
 	}
 
+	// Front matter from the leading comment opens the document, where MDX
+	// tooling reads it.
+	if len(comments) > 0 && comments[0].FrontMatter != "" {
+		sb.WriteString("---\n")
+		sb.WriteString(comments[0].FrontMatter)
+		sb.WriteString("\n---\n\n")
+	}
+
 	currentPos := scip.Position{Line: 0, Character: 0}
 	tokenIdx := 0
 	commentIdx := 0
 	inCodeBlock := false
+	regionIdx := 0
+	inRegion := false
+	includeLines := codeIncludeLines(m.Includes)
+	includeIdx := 0
 
 	// Helper to get the start position of the next token or "infinity"
 	getNextTokenStart := func() scip.Position {
@@ -61,6 +81,33 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 		return scip.Position{Line: 999999, Character: 999999} // "Infinity"
 	}
 
+	// Helper to get the marker line that opens the next region, or closes the
+	// one we are inside
+	getNextRegionBoundary := func() scip.Position {
+		if regionIdx >= len(regions) {
+			return scip.Position{Line: 999999, Character: 999999} // "Infinity"
+		}
+		if inRegion {
+			return regions[regionIdx].Close.Start
+		}
+		return regions[regionIdx].Open.Start
+	}
+	hiding := func() bool {
+		return inRegion && regions[regionIdx].Kind == CodeRegionHide
+	}
+
+	// Helper to get the line start of the next include directive, dropping
+	// directives a comment or token spanning their line swallowed
+	getNextIncludeStart := func() scip.Position {
+		for includeIdx < len(includeLines) && scip.Position.Compare(scip.Position{Line: int32(includeLines[includeIdx])}, currentPos) < 0 {
+			includeIdx++
+		}
+		if includeIdx < len(includeLines) {
+			return scip.Position{Line: int32(includeLines[includeIdx]), Character: 0}
+		}
+		return scip.Position{Line: 999999, Character: 999999} // "Infinity"
+	}
+
 	for {
 		// Break condition: if currentPos reached fileEndPos AND no more tokens/comments
 		if scip.Position.Compare(currentPos, fileEndPos) >= 0 && tokenIdx >= len(tokens) && commentIdx >= len(m.comments) {
@@ -69,6 +116,8 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 
 		nextTokenStart := getNextTokenStart()
 		nextCommentStart := getNextCommentStart()
+		nextRegionBoundary := getNextRegionBoundary()
+		nextIncludeStart := getNextIncludeStart()
 
 		// Determine the end of the current gap (code or text)
 		gapEnd := fileEndPos
@@ -78,6 +127,12 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 		if scip.Position.Compare(nextCommentStart, gapEnd) < 0 {
 			gapEnd = nextCommentStart
 		}
+		if scip.Position.Compare(nextRegionBoundary, gapEnd) < 0 {
+			gapEnd = nextRegionBoundary
+		}
+		if scip.Position.Compare(nextIncludeStart, gapEnd) < 0 {
+			gapEnd = nextIncludeStart
+		}
 
 		// Process gap text (code/plain text)
 		if scip.Position.Compare(currentPos, gapEnd) < 0 {
@@ -88,12 +143,12 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 				gapContent = strings.TrimLeftFunc(gapContent, unicode.IsSpace)
 			}
 
-			// If this gap is immediately before a comment, trim trailing whitespace
-			if scip.Position.Compare(gapEnd, nextCommentStart) == 0 {
+			// If this gap is immediately before a comment or directive line, trim trailing whitespace
+			if scip.Position.Compare(gapEnd, nextCommentStart) == 0 || scip.Position.Compare(gapEnd, nextRegionBoundary) == 0 || scip.Position.Compare(gapEnd, nextIncludeStart) == 0 {
 				gapContent = strings.TrimRightFunc(gapContent, unicode.IsSpace)
 			}
 
-			if gapContent != "" {
+			if gapContent != "" && !hiding() {
 				if !inCodeBlock {
 					sb.WriteString(m.CodeWrapperStart)
 					sb.WriteString("\n")
@@ -107,7 +162,63 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 		}
 
 		// Process next event
-		if scip.Position.Compare(currentPos, nextCommentStart) == 0 && scip.Position.Compare(nextCommentStart, nextTokenStart) <= 0 {
+		if scip.Position.Compare(currentPos, nextRegionBoundary) >= 0 {
+			// Current event is a region marker line, which never renders
+			region := regions[regionIdx]
+
+			// Close code block if open
+			if inCodeBlock {
+				sb.WriteString(m.CodeWrapperEnd)
+				sb.WriteString("\n")
+				inCodeBlock = false
+			}
+
+			if !inRegion {
+				if region.Kind == CodeRegionCollapse {
+					sb.WriteString("<details className=\"cire-region\"><summary>{`")
+					sb.WriteString(escapeMDXForTemplateLiteral(codeRegionSummary(region)))
+					sb.WriteString("`}</summary>\n\n")
+				}
+				currentPos = laterPosition(currentPos, region.Open.End)
+				inRegion = true
+			} else {
+				if region.Kind == CodeRegionCollapse {
+					sb.WriteString("</details>\n")
+				}
+				currentPos = laterPosition(currentPos, region.Close.End)
+				inRegion = false
+				regionIdx++
+			}
+
+			// Skip any tokens or comments on the marker line
+			for tokenIdx < len(tokens) && scip.Position.Compare(tokens[tokenIdx].Span.End, currentPos) <= 0 {
+				tokenIdx++
+			}
+			for commentIdx < len(m.comments) && scip.Position.Compare(m.comments[commentIdx].Span.End, currentPos) <= 0 {
+				commentIdx++
+			}
+		} else if scip.Position.Compare(currentPos, nextIncludeStart) == 0 {
+			// Current event is an include directive, replaced by the code it names
+			line := includeLines[includeIdx]
+
+			// Close code block if open
+			if inCodeBlock {
+				sb.WriteString(m.CodeWrapperEnd)
+				sb.WriteString("\n")
+				inCodeBlock = false
+			}
+
+			if !hiding() {
+				m.outputIncludeJSX(m.Includes[line], &sb)
+			}
+			currentPos = scip.Position{Line: int32(line), Character: int32(len([]rune(m.sourceLines[line])))}
+			includeIdx++
+
+			// Skip any tokens on the directive line
+			for tokenIdx < len(tokens) && scip.Position.Compare(tokens[tokenIdx].Span.End, currentPos) <= 0 {
+				tokenIdx++
+			}
+		} else if scip.Position.Compare(currentPos, nextCommentStart) == 0 && scip.Position.Compare(nextCommentStart, nextTokenStart) <= 0 {
 			// Current event is a comment (or comment and token start at same pos, prefer comment)
 			comment := m.comments[commentIdx]
 
@@ -119,8 +230,12 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 			}
 
 			// Output comment content (prose)
-			sb.WriteString(comment.Content)
-			sb.WriteString("\n") // Add a newline after the comment content
+			prose := comment.Prose("className")
+			prose = sanitizeMDXProse(prose, m.Markdown)
+			if strings.TrimSpace(prose) != "" && !hiding() {
+				sb.WriteString(prose)
+				sb.WriteString("\n") // Add a newline after the comment content
+			}
 
 			currentPos = comment.Span.End
 			commentIdx++
@@ -133,6 +248,20 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 			// Current event is a token
 			token := tokens[tokenIdx]
 
+			// Hidden code keeps only its anchors, so links into it still resolve
+			if hiding() {
+				id := token.Anchor
+				if id == "" && token.IsDefinition {
+					id = token.Symbol
+				}
+				if id != "" && token.InlayHintLabel == "" {
+					fmt.Fprintf(&sb, `<span id="%s" className="cire-hidden-anchor" />`, escapeMDXAttribute(id))
+				}
+				currentPos = token.Span.End
+				tokenIdx++
+				continue
+			}
+
 			// Open code block if not already in one
 			if !inCodeBlock {
 				sb.WriteString(m.CodeWrapperStart)
@@ -168,6 +297,37 @@ func (m *MDXGenerator) GenerateMDX(tokens []TokenInfo, comments []CommentInfo) s
 	return sb.String()
 }
 
+// outputIncludeJSX renders transcluded code as a figure captioned with a link
+// to its source page
+func (m *MDXGenerator) outputIncludeJSX(include IncludedCode, sb *strings.Builder) {
+	fmt.Fprintf(sb, `<figure className="cire-include"><figcaption><a href="%s">{`+"`%s`"+`}</a></figcaption>`,
+		escapeMDXAttribute(include.Href), escapeMDXForTemplateLiteral(include.Label))
+	sb.WriteString("\n\n")
+	sb.WriteString(m.CodeWrapperStart)
+	sb.WriteString("\n")
+
+	included := &MDXGenerator{sourceLines: include.SourceLines}
+	currentPos := include.Span.Start
+	for _, token := range include.Tokens {
+		included.outputGapJSX(currentPos, token.Span.Start, sb)
+		included.outputTokenJSX(token, sb)
+		currentPos = token.Span.End
+	}
+	included.outputGapJSX(currentPos, include.Span.End, sb)
+
+	sb.WriteString(m.CodeWrapperEnd)
+	sb.WriteString("\n</figure>\n")
+}
+
+func (m *MDXGenerator) outputGapJSX(start, end scip.Position, sb *strings.Builder) {
+	if scip.Position.Compare(start, end) >= 0 {
+		return
+	}
+	sb.WriteString("<span className=\"cire_text\">{`")
+	sb.WriteString(escapeMDXForTemplateLiteral(getSourceFromSpan(m.sourceLines, scip.Range{Start: start, End: end})))
+	sb.WriteString("`}</span>")
+}
+
 func (m *MDXGenerator) outputTokenJSX(token TokenInfo, sb *strings.Builder) {
 	if token.InlayHintLabel != "" {
 		fmt.Fprintf(sb, `<span className="inlay-hint">{`+"`%s`"+`}</span>`, escapeMDXForTemplateLiteral(token.InlayHintLabel))
@@ -177,10 +337,7 @@ func (m *MDXGenerator) outputTokenJSX(token TokenInfo, sb *strings.Builder) {
 	content := getSourceFromSpan(m.sourceLines, token.Span)
 	escapedContent := escapeMDXForTemplateLiteral(content) // Use template literal escaping
 
-	var cssClass string
-	if token.HighlightClass != "" {
-		cssClass = token.HighlightClass
-	}
+	cssClass := strings.Join(token.Classes(), " ")
 
 	// Build template literal content
 	templateContent := "{`" + escapedContent + "`}"
@@ -222,7 +379,7 @@ func (m *MDXGenerator) outputTokenJSX(token TokenInfo, sb *strings.Builder) {
 
 	if len(token.Document) > 0 {
 		doc := strings.Join(token.Document, "\n")
-		htmlDoc := RenderMarkdown(doc)
+		htmlDoc := RenderMarkdownWithOptions(doc, m.Markdown)
 		escapedHTML := escapeForJSTemplateLiteral(htmlDoc)
 		// Use rc-tooltip API with dangerouslySetInnerHTML to render HTML from Markdown
 		fmt.Fprintf(sb, `<Tooltip overlay={<div className="cire-markdown" dangerouslySetInnerHTML={{ __html: `+"`"+`%s`+"`"+` }} />} placement="top" trigger={['hover']}>%s</Tooltip>`,
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestGenerateMDXOpensWithFrontMatter(t *testing.T) {
+	sourceLines := []string{
+		"// ---",
+		"// title: Main",
+		"// ---",
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	output := NewMDXGenerator(sourceLines).GenerateMDX(nil, comments)
+
+	if !strings.HasPrefix(output, "---\ntitle: Main\n---\n\n<pre><code className=\"cire\">") {
+		t.Fatalf("output should open with front matter and go straight to code\nGot:\n%s", output)
+	}
+	if strings.Count(output, "title: Main") != 1 {
+		t.Fatalf("front matter should appear once\nGot:\n%s", output)
+	}
+}
+
+func TestGenerateMDXHidesAndCollapsesRegions(t *testing.T) {
+	sourceLines := []string{
+		"package main",
+		"// #region Imports",
+		"import \"fmt\"",
+		"// #endregion",
+		"// gocire:hide",
+		"func helper() {}",
+		"// gocire:end",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tokens := []TokenInfo{{
+		Span:         scip.Range{Start: scip.Position{Line: 5, Character: 5}, End: scip.Position{Line: 5, Character: 11}},
+		Symbol:       "helper",
+		IsDefinition: true,
+	}}
+
+	output := NewMDXGenerator(sourceLines).GenerateMDX(tokens, comments)
+
+	for _, part := range []string{
+		"<details className=\"cire-region\"><summary>{`Imports`}</summary>\n\n<pre><code className=\"cire\">",
+		"</code></pre>\n</details>\n",
+		`<span id="helper" className="cire-hidden-anchor" />`,
+	} {
+		if !strings.Contains(output, part) {
+			t.Fatalf("output missing %q\nGot:\n%s", part, output)
+		}
+	}
+	for _, hidden := range []string{"#region", "#endregion", "gocire:", "func helper"} {
+		if strings.Contains(output, hidden) {
+			t.Fatalf("output should not contain %q\nGot:\n%s", hidden, output)
+		}
+	}
+}
+
+func TestGenerateMDXKeepsMarkersInsideStrings(t *testing.T) {
+	sourceLines := []string{
+		"package main",
+		"",
+		"var usage = `",
+		"// gocire:hide",
+		"`",
+		"",
+		"// gocire:end",
+		"func main() {}",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tokens := []TokenInfo{{
+		Span:           scip.Range{Start: scip.Position{Line: 2, Character: 12}, End: scip.Position{Line: 4, Character: 1}},
+		HighlightClass: "string",
+	}}
+
+	output := NewMDXGenerator(sourceLines).GenerateMDX(tokens, comments)
+
+	if strings.Contains(output, "<details") || !strings.Contains(output, "gocire:hide") {
+		t.Fatalf("a marker inside a string should stay code\nGot:\n%s", output)
+	}
+	if got := strings.Count(output, "\\`"); got != 2 {
+		t.Fatalf("output has %d escaped backticks, want the string's 2\nGot:\n%s", got, output)
+	}
+}
+
+func TestGenerateMDXOmitsProseHTMLWhenRawHTMLIsOff(t *testing.T) {
+	sourceLines := []string{
+		"// Press <Kbd>Ctrl</Kbd> to copy.",
+		"//",
+		"// <Widget onLoad={steal} />",
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tokens := []TokenInfo{{
+		Span:     scip.Range{Start: scip.Position{Line: 3, Character: 8}, End: scip.Position{Line: 3, Character: 12}},
+		Document: []string{"Package <b>main</b>."},
+	}}
+
+	generator := NewMDXGenerator(sourceLines)
+	generator.Markdown = MarkdownOptions{DisableRawHTML: true}
+	output := generator.GenerateMDX(tokens, comments)
+
+	if !strings.Contains(output, "Press Ctrl to copy.") {
+		t.Fatalf("prose text should stay\nGot:\n%s", output)
+	}
+	for _, unwanted := range []string{"<Kbd>", "<Widget", "<b>"} {
+		if strings.Contains(output, unwanted) {
+			t.Fatalf("output should omit %q\nGot:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestGenerateMDXSanitizesProseAndHoverHTML(t *testing.T) {
+	sourceLines := []string{
+		"// Press <Kbd onClick={steal}>Ctrl</Kbd> to copy {alert(document.cookie)}.",
+		"//",
+		`// <img src="x" onerror="alert(1)">`,
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	tokens := []TokenInfo{{
+		Span:     scip.Range{Start: scip.Position{Line: 3, Character: 8}, End: scip.Position{Line: 3, Character: 12}},
+		Document: []string{"Package <script>alert(2)</script>main and `{code}`."},
+	}}
+
+	output := NewMDXGenerator(sourceLines).GenerateMDX(tokens, comments)
+
+	for _, want := range []string{
+		"Press <Kbd>Ctrl</Kbd> to copy &#123;alert(document.cookie)&#125;.",
+		`<img src="x">`,
+		"<code>{code}</code>",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"onClick", "{steal}", "onerror", "<script", "alert(2)", "{alert(document.cookie)}"} {
+		if strings.Contains(output, unwanted) {
+			t.Fatalf("output should not contain %q\nGot:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestGenerateMDXKeepsProseExpressions(t *testing.T) {
+	sourceLines := []string{
+		"// The main file entry point for the CLI.",
+		"// {/* truncate */}",
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	for _, options := range []MarkdownOptions{{}, {Sanitize: SanitizeStrict}, {DisableRawHTML: true}} {
+		generator := NewMDXGenerator(sourceLines)
+		generator.Markdown = options
+		output := generator.GenerateMDX(nil, comments)
+		if !strings.Contains(output, "The main file entry point for the CLI.\n{/* truncate */}") {
+			t.Fatalf("options %+v: output should keep the truncate marker\nGot:\n%s", options, output)
+		}
+	}
+}
+
+func TestGenerateMDXSanitizesMultiLineTagsAndExpressions(t *testing.T) {
+	sourceLines := []string{
+		"// <img",
+		"//   src=x onerror={alert(1)}>",
+		"//",
+		"// <div",
+		"//  onclick=\"steal()\">",
+		"// {fetch('//evil')}",
+		"// </div>",
+		"//",
+		"// Call {fetch('//evil')} or `{code}`, $x^{2}$ and \\{x\\}.",
+		"// {/* note */ fetch('//evil') /* end */}",
+		"package main",
+	}
+	comments, err := NewCommentAnalyzer("go").Analyze([]byte(strings.Join(sourceLines, "\n")))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	output := NewMDXGenerator(sourceLines).GenerateMDX(nil, comments)
+
+	for _, want := range []string{
+		`<img src="x">`,
+		"<div>\n&#123;fetch('//evil')&#125;\n</div>",
+		"Call &#123;fetch('//evil')&#125; or `{code}`, $x^{2}$ and \\{x\\}.",
+		"&#123;/* note */ fetch('//evil') /* end */&#125;",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"onerror", "onclick", "{alert", "{fetch"} {
+		if strings.Contains(output, unwanted) {
+			t.Fatalf("output should not contain %q\nGot:\n%s", unwanted, output)
+		}
+	}
+}
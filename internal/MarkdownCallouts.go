@@ -0,0 +1,315 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// markdownCallouts extends narrative Markdown with callout blocks:
+//
+//   - GitHub alerts, a blockquote opening with [!NOTE], [!TIP], [!IMPORTANT],
+//     [!WARNING] or [!CAUTION];
+//   - MkDocs admonitions, a !!! type "Title" line over an indented body, or
+//     ??? for a collapsed one and ???+ for one that starts open;
+//   - content tabs, consecutive === "Title" lines over indented bodies, which
+//     show one body at a time. ===! starts a new set of tabs.
+type markdownCallouts struct{}
+
+func (markdownCallouts) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(admonitionParser{}, 550),
+			util.Prioritized(contentTabParser{}, 550),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(githubAlertTransformer{}, 100),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&calloutRenderer{}, 500),
+	))
+}
+
+var kindAdmonition = ast.NewNodeKind("Admonition")
+
+// admonition is a callout block of a kind such as note or warning.
+type admonition struct {
+	ast.BaseBlock
+	kind  string
+	title string
+	// collapsible admonitions render as <details>, closed unless open.
+	collapsible bool
+	open        bool
+}
+
+func (n *admonition) Kind() ast.NodeKind {
+	return kindAdmonition
+}
+
+func (n *admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Kind": n.kind, "Title": n.title}, nil)
+}
+
+var kindContentTab = ast.NewNodeKind("ContentTab")
+
+// contentTab is one tab of a set; consecutive tabs form the set.
+type contentTab struct {
+	ast.BaseBlock
+	title string
+	// newSet starts a set even when a tab precedes this one.
+	newSet bool
+}
+
+func (n *contentTab) Kind() ast.NodeKind {
+	return kindContentTab
+}
+
+func (n *contentTab) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Title": n.title}, nil)
+}
+
+var (
+	admonitionPattern  = regexp.MustCompile(`^(!!!|\?\?\?\+?)[ \t]+([A-Za-z][A-Za-z0-9_-]*)(?:[ \t]+"([^"]*)")?[ \t]*$`)
+	contentTabPattern  = regexp.MustCompile(`^===(!?)[ \t]+"([^"]*)"[ \t]*$`)
+	githubAlertPattern = regexp.MustCompile(`^\[!([A-Za-z]+)\]$`)
+)
+
+var githubAlertKinds = map[string]bool{
+	"note":      true,
+	"tip":       true,
+	"important": true,
+	"warning":   true,
+	"caution":   true,
+}
+
+type admonitionParser struct{}
+
+func (admonitionParser) Trigger() []byte {
+	return []byte{'!', '?'}
+}
+
+func (admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	match := admonitionPattern.FindSubmatchIndex(util.TrimRightSpace(line))
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+	marker := string(line[match[2]:match[3]])
+	node := &admonition{
+		kind:        strings.ToLower(string(line[match[4]:match[5]])),
+		collapsible: marker != "!!!",
+		open:        marker == "???+",
+	}
+	node.title = calloutTitle(node.kind)
+	if match[6] >= 0 {
+		node.title = strings.TrimSpace(string(line[match[6]:match[7]]))
+		if node.title == "" && node.collapsible {
+			// A collapsed admonition needs a summary to open it by.
+			node.title = calloutTitle(node.kind)
+		}
+	}
+	reader.AdvanceToEOL()
+	return node, parser.HasChildren
+}
+
+func (admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return continueIndentedCallout(reader)
+}
+
+func (admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (admonitionParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type contentTabParser struct{}
+
+func (contentTabParser) Trigger() []byte {
+	return []byte{'='}
+}
+
+func (contentTabParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	match := contentTabPattern.FindSubmatch(util.TrimRightSpace(line))
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+	reader.AdvanceToEOL()
+	return &contentTab{
+		title:  strings.TrimSpace(string(match[2])),
+		newSet: len(match[1]) > 0,
+	}, parser.HasChildren
+}
+
+func (contentTabParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return continueIndentedCallout(reader)
+}
+
+func (contentTabParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (contentTabParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (contentTabParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// continueIndentedCallout keeps a callout open over blank lines and lines
+// indented by four columns, which make up its body.
+func continueIndentedCallout(reader text.Reader) parser.State {
+	line, _ := reader.PeekLine()
+	if util.IsBlank(line) {
+		reader.AdvanceToEOL()
+		return parser.Continue | parser.HasChildren
+	}
+	if indent, _ := util.IndentWidth(line, reader.LineOffset()); indent < 4 {
+		return parser.Close
+	}
+	pos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	reader.AdvanceAndSetPadding(pos, padding)
+	return parser.Continue | parser.HasChildren
+}
+
+// githubAlertTransformer turns blockquotes whose first line is an alert
+// marker such as [!NOTE] into admonitions.
+type githubAlertTransformer struct{}
+
+func (githubAlertTransformer) Transform(document *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if quote, ok := node.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, quote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, quote := range quotes {
+		paragraph, ok := quote.FirstChild().(*ast.Paragraph)
+		if !ok || paragraph.Lines().Len() == 0 {
+			continue
+		}
+		firstLine := paragraph.Lines().At(0)
+		match := githubAlertPattern.FindSubmatch(util.TrimRightSpace(util.TrimLeftSpace(firstLine.Value(source))))
+		if match == nil || !githubAlertKinds[strings.ToLower(string(match[1]))] {
+			continue
+		}
+
+		// The marker line parses as text; the rest of the paragraph stays.
+		for child := paragraph.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if text, ok := child.(*ast.Text); !ok || text.Segment.Start >= firstLine.Stop {
+				break
+			}
+			paragraph.RemoveChild(paragraph, child)
+			child = next
+		}
+		if paragraph.ChildCount() == 0 {
+			quote.RemoveChild(quote, paragraph)
+		}
+
+		kind := strings.ToLower(string(match[1]))
+		node := &admonition{kind: kind, title: calloutTitle(kind)}
+		for child := quote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			node.AppendChild(node, child)
+			child = next
+		}
+		quote.Parent().ReplaceChild(quote.Parent(), quote, node)
+	}
+}
+
+func calloutTitle(kind string) string {
+	if kind == "" {
+		return ""
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+type calloutRenderer struct {
+	// tabSets numbers the tab sets rendered so far, so each set's radio
+	// buttons get a name of their own on the page.
+	tabSets int
+}
+
+func (r *calloutRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindAdmonition, r.renderAdmonition)
+	reg.Register(kindContentTab, r.renderContentTab)
+}
+
+func (r *calloutRenderer) renderAdmonition(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*admonition)
+	element := "aside"
+	if n.collapsible {
+		element = "details"
+	}
+	if !entering {
+		_, _ = fmt.Fprintf(w, "</%s>\n", element)
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = fmt.Fprintf(w, `<%s class="cire-admonition cire-admonition--%s"`, element, n.kind)
+	if n.open {
+		_, _ = w.WriteString(" open")
+	}
+	_, _ = w.WriteString(">\n")
+	switch {
+	case n.collapsible:
+		_, _ = fmt.Fprintf(w, "<summary class=\"cire-admonition-title\">%s</summary>\n", util.EscapeHTML([]byte(n.title)))
+	case n.title != "":
+		_, _ = fmt.Fprintf(w, "<p class=\"cire-admonition-title\">%s</p>\n", util.EscapeHTML([]byte(n.title)))
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderContentTab renders a set of tabs as radio buttons, each followed by
+// its label and panel, so the stylesheet alone can switch between them.
+func (r *calloutRenderer) renderContentTab(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*contentTab)
+	previous, afterTab := n.PreviousSibling().(*contentTab)
+	startsSet := !afterTab || n.newSet
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		if next, ok := n.NextSibling().(*contentTab); !ok || next.newSet {
+			_, _ = w.WriteString("</div>\n")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	index := 1
+	if startsSet {
+		r.tabSets++
+		_, _ = w.WriteString("<div class=\"cire-tabs\">\n")
+	} else {
+		for tab := previous; tab != nil; {
+			index++
+			if tab.newSet {
+				break
+			}
+			tab, _ = tab.PreviousSibling().(*contentTab)
+		}
+	}
+
+	id := fmt.Sprintf("cire-tabs-%d-%d", r.tabSets, index)
+	_, _ = fmt.Fprintf(w, `<input type="radio" class="cire-tab-input" name="cire-tabs-%d" id="%s"`, r.tabSets, id)
+	if startsSet {
+		_, _ = w.WriteString(" checked")
+	}
+	_, _ = w.WriteString(">\n")
+	_, _ = fmt.Fprintf(w, "<label class=\"cire-tab-label\" for=\"%s\">%s</label>\n", id, util.EscapeHTML([]byte(n.title)))
+	_, _ = w.WriteString("<div class=\"cire-tab-panel\">\n")
+	return ast.WalkContinue, nil
+}
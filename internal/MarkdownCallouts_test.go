@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownRendersGitHubAlerts(t *testing.T) {
+	html := RenderMarkdown("> [!WARNING]\n> Mind the `gap`.\n\n> [!TODO]\n> Not an alert.\n\n> Plain quote.")
+
+	for _, want := range []string{
+		"<aside class=\"cire-admonition cire-admonition--warning\">\n<p class=\"cire-admonition-title\">Warning</p>\n<p>Mind the <code>gap</code>.</p>\n</aside>",
+		"<blockquote>\n<p>[!TODO]\nNot an alert.</p>\n</blockquote>",
+		"<blockquote>\n<p>Plain quote.</p>\n</blockquote>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered alerts missing %q\nGot:\n%s", want, html)
+		}
+	}
+}
+
+func TestRenderMarkdownRendersMkDocsAdmonitions(t *testing.T) {
+	html := RenderMarkdown(strings.Join([]string{
+		`!!! tip "Try this"`,
+		"    Body *one*.",
+		"",
+		"    - item",
+		"",
+		"After.",
+		"",
+		"??? danger",
+		"    Hidden.",
+		"",
+		`???+ note "Open <now>"`,
+		"    Shown.",
+		"",
+		`!!! info ""`,
+		"    Untitled.",
+	}, "\n"))
+
+	for _, want := range []string{
+		"<aside class=\"cire-admonition cire-admonition--tip\">\n<p class=\"cire-admonition-title\">Try this</p>\n<p>Body <em>one</em>.</p>\n<ul>\n<li>item</li>\n</ul>\n</aside>\n<p>After.</p>",
+		"<details class=\"cire-admonition cire-admonition--danger\">\n<summary class=\"cire-admonition-title\">Danger</summary>\n<p>Hidden.</p>\n</details>",
+		"<details class=\"cire-admonition cire-admonition--note\" open>\n<summary class=\"cire-admonition-title\">Open &lt;now&gt;</summary>",
+		"<aside class=\"cire-admonition cire-admonition--info\">\n<p>Untitled.</p>\n</aside>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered admonitions missing %q\nGot:\n%s", want, html)
+		}
+	}
+}
+
+func TestMarkdownPageRendererNumbersContentTabSets(t *testing.T) {
+	renderer := NewMarkdownPageRenderer(MarkdownOptions{})
+	first := renderer.RenderFragment(strings.Join([]string{
+		`=== "Go"`,
+		"    ```go",
+		"    x := 1",
+		"    ```",
+		"",
+		`=== "Rust"`,
+		"    Rust text.",
+		"",
+		`===! "Python"`,
+		"    Python text.",
+	}, "\n"))
+	second := renderer.RenderFragment("=== \"Shell\"\n    Shell text.")
+	html := first + second
+
+	for _, want := range []string{
+		"<div class=\"cire-tabs\">\n<input type=\"radio\" class=\"cire-tab-input\" name=\"cire-tabs-1\" id=\"cire-tabs-1-1\" checked>\n<label class=\"cire-tab-label\" for=\"cire-tabs-1-1\">Go</label>\n<div class=\"cire-tab-panel\">\n<pre class=\"chroma\" data-language=\"go\">",
+		"<input type=\"radio\" class=\"cire-tab-input\" name=\"cire-tabs-1\" id=\"cire-tabs-1-2\">\n<label class=\"cire-tab-label\" for=\"cire-tabs-1-2\">Rust</label>\n<div class=\"cire-tab-panel\">\n<p>Rust text.</p>\n</div>\n</div>\n<div class=\"cire-tabs\">",
+		`id="cire-tabs-2-1" checked>` + "\n" + `<label class="cire-tab-label" for="cire-tabs-2-1">Python</label>`,
+		`id="cire-tabs-3-1" checked>` + "\n" + `<label class="cire-tab-label" for="cire-tabs-3-1">Shell</label>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered tabs missing %q\nGot:\n%s", want, html)
+		}
+	}
+}
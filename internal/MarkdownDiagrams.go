@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// markdownDiagrams renders ```mermaid, ```dot and ```d2 fences as diagram
+// containers instead of highlighted code. The site's diagram script draws
+// them when the page loads; Graphviz DOT is drawn to inline SVG here instead
+// when a dot binary is on PATH. A diagram that cannot be drawn shows its
+// source.
+type markdownDiagrams struct{}
+
+func (markdownDiagrams) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(diagramTransformer{}, 100),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(diagramRenderer{}, 500),
+	))
+}
+
+// diagramLanguages maps fence languages to the diagram language they hold.
+var diagramLanguages = map[string]string{
+	"mermaid":  "mermaid",
+	"dot":      "dot",
+	"graphviz": "dot",
+	"d2":       "d2",
+}
+
+// dotRenderTimeout bounds how long one diagram may keep dot busy.
+const dotRenderTimeout = 10 * time.Second
+
+var kindDiagram = ast.NewNodeKind("Diagram")
+
+type diagramBlock struct {
+	ast.BaseBlock
+	language string
+	source   string
+}
+
+func (n *diagramBlock) Kind() ast.NodeKind {
+	return kindDiagram
+}
+
+func (n *diagramBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Language": n.language}, nil)
+}
+
+// diagramTransformer replaces diagram fences before the highlighter sees
+// them.
+type diagramTransformer struct{}
+
+func (diagramTransformer) Transform(document *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var fences []*ast.FencedCodeBlock
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fence, ok := node.(*ast.FencedCodeBlock); ok && entering {
+			fences = append(fences, fence)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, fence := range fences {
+		language, ok := diagramLanguages[strings.ToLower(string(fence.Language(source)))]
+		if !ok {
+			continue
+		}
+		var body strings.Builder
+		lines := fence.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			body.Write(line.Value(source))
+		}
+		diagram := &diagramBlock{language: language, source: body.String()}
+		fence.Parent().ReplaceChild(fence.Parent(), fence, diagram)
+	}
+}
+
+type diagramRenderer struct{}
+
+func (r diagramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindDiagram, r.renderDiagram)
+}
+
+func (diagramRenderer) renderDiagram(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*diagramBlock)
+	if n.language == "dot" {
+		if svg, ok := renderDOTToSVG(n.source); ok {
+			_, _ = fmt.Fprintf(w, "<div class=\"cire-diagram\" data-diagram=\"dot\" data-diagram-rendered>\n%s\n</div>\n", svg)
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	_, _ = fmt.Fprintf(w, "<div class=\"cire-diagram\" data-diagram=\"%s\">\n", n.language)
+	_, _ = fmt.Fprintf(w, "<pre class=\"cire-diagram-source\"><code>%s</code></pre>\n</div>\n", util.EscapeHTML([]byte(n.source)))
+	return ast.WalkSkipChildren, nil
+}
+
+// renderDOTToSVG draws a Graphviz graph with the dot binary on PATH. It
+// reports false when dot is missing or rejects the graph.
+func renderDOTToSVG(graph string) (string, bool) {
+	dot, err := exec.LookPath("dot")
+	if err != nil {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dotRenderTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, dot, "-Tsvg")
+	cmd.Stdin = strings.NewReader(graph)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", false
+	}
+
+	// Inline SVG drops the XML prolog, doctype and comments dot writes first.
+	output := stdout.String()
+	start := strings.Index(output, "<svg")
+	if start < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(output[start:]), true
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownPageRendererEmitsDiagramContainers(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	html := NewMarkdownPageRenderer(MarkdownOptions{}).RenderFragment(strings.Join([]string{
+		"```mermaid",
+		"graph LR",
+		"  A --> B",
+		"```",
+		"",
+		"```graphviz",
+		"digraph { a -> b }",
+		"```",
+		"",
+		"```d2",
+		"x -> y: <ok>",
+		"```",
+		"",
+		"```go",
+		"x := 1",
+		"```",
+	}, "\n"))
+
+	for _, want := range []string{
+		"<div class=\"cire-diagram\" data-diagram=\"mermaid\">\n<pre class=\"cire-diagram-source\"><code>graph LR\n  A --&gt; B\n</code></pre>\n</div>",
+		"<div class=\"cire-diagram\" data-diagram=\"dot\">\n<pre class=\"cire-diagram-source\"><code>digraph { a -&gt; b }\n</code></pre>\n</div>",
+		"<div class=\"cire-diagram\" data-diagram=\"d2\">\n<pre class=\"cire-diagram-source\"><code>x -&gt; y: &lt;ok&gt;\n</code></pre>\n</div>",
+		`<pre class="chroma" data-language="go">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered diagrams missing %q\nGot:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, `class="chroma" data-language="mermaid"`) {
+		t.Fatalf("diagram fences should skip highlighting:\n%s", html)
+	}
+}
+
+func TestMarkdownPageRendererDrawsDOTWithLocalGraphviz(t *testing.T) {
+	binDir := t.TempDir()
+	// A stand-in for dot that draws every graph as the same SVG and rejects
+	// graphs that mention "broken".
+	script := strings.Join([]string{
+		"#!/bin/sh",
+		"while read -r line; do",
+		`  case "$line" in *broken*) exit 1 ;; esac`,
+		"done",
+		`printf '<?xml version="1.0"?>\n<!-- Generated by graphviz -->\n<svg width="8pt"><g class="graph"></g></svg>\n'`,
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(binDir, "dot"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake dot: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+
+	html := NewMarkdownPageRenderer(MarkdownOptions{}).RenderFragment("```dot\ndigraph { a -> b }\n```\n\n```dot\ndigraph { broken }\n```")
+
+	for _, want := range []string{
+		"<div class=\"cire-diagram\" data-diagram=\"dot\" data-diagram-rendered>\n<svg width=\"8pt\"><g class=\"graph\"></g></svg>\n</div>",
+		"<div class=\"cire-diagram\" data-diagram=\"dot\">\n<pre class=\"cire-diagram-source\"><code>digraph { broken }\n</code></pre>\n</div>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered DOT missing %q\nGot:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<?xml") || strings.Contains(html, "Generated by") {
+		t.Fatalf("inline SVG should drop the XML prolog and comments:\n%s", html)
+	}
+}
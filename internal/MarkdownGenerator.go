@@ -90,10 +90,7 @@ func (m *MarkdownGenerator) outputTokenHTML(token TokenInfo, sb *strings.Builder
 	var escapedContent string
 	escapedContent = escapeHTML(content)
 
-	var cssClass string
-	if token.HighlightClass != "" {
-		cssClass = token.HighlightClass
-	}
+	cssClass := strings.Join(token.Classes(), " ")
 
 	id := token.Anchor
 	if id == "" && token.IsDefinition {
@@ -209,6 +209,31 @@ func TestGenerateMarkdownOutputsInlayHint(t *testing.T) {
 	}
 }
 
+func TestGenerateMarkdownEscapesMarkupInSourceAndSymbols(t *testing.T) {
+	sourceLines := []string{`const page = "<script>alert(1)</script>"`}
+	gen := NewMarkdownGenerator(sourceLines)
+
+	output := gen.GenerateMarkdown([]TokenInfo{
+		{
+			Symbol:       `x" onmouseover="alert(2)`,
+			IsDefinition: true,
+			Span: scip.Range{
+				Start: scip.Position{Line: 0, Character: 6},
+				End:   scip.Position{Line: 0, Character: 10},
+			},
+		},
+	})
+
+	for _, unwanted := range []string{"<script>", `" onmouseover="`} {
+		if strings.Contains(output, unwanted) {
+			t.Fatalf("output should escape %q\nGot:\n%s", unwanted, output)
+		}
+	}
+	if !strings.Contains(output, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Fatalf("output should keep the escaped source\nGot:\n%s", output)
+	}
+}
+
 func TestGetSourceFromSpan(t *testing.T) {
 	lines := []string{
 		"line0",
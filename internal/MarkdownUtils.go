@@ -3,20 +3,72 @@ package internal
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
 	katex "github.com/FurqanSoftware/goldmark-katex"
 	formatter "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/cockroachdb/errors"
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
 	highlight "github.com/yuin/goldmark-highlighting/v2"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
+// MarkdownOptions turns on Markdown features beyond GitHub Flavored Markdown,
+// math, callouts and diagrams, which are always on. The zero value renders
+// Markdown as gocire always has, sanitizing its HTML at the standard level.
+type MarkdownOptions struct {
+	Footnotes       bool
+	DefinitionLists bool
+	// Typographer turns straight quotes, dashes and ellipses into their
+	// typographic forms.
+	Typographer bool
+	// Emoji turns shortcodes such as :tada: into emoji.
+	Emoji bool
+	// Attributes enables {#id .class} attribute lists on headings and blocks.
+	Attributes bool
+	// HeadingAnchors places a "#" permalink by each page heading.
+	HeadingAnchors HeadingAnchorStyle
+	// DisableRawHTML omits HTML written in prose instead of passing it
+	// through, for sites built from content that is not trusted.
+	DisableRawHTML bool
+	// Sanitize sets how much of the rendered HTML survives; empty means
+	// SanitizeStandard.
+	Sanitize SanitizeLevel
+}
+
+type HeadingAnchorStyle string
+
+const (
+	HeadingAnchorNone   HeadingAnchorStyle = "none"
+	HeadingAnchorBefore HeadingAnchorStyle = "before"
+	HeadingAnchorAfter  HeadingAnchorStyle = "after"
+)
+
+// Validate reports options that name an unknown heading anchor style or
+// sanitize level.
+func (options MarkdownOptions) Validate() error {
+	switch options.HeadingAnchors {
+	case "", HeadingAnchorNone, HeadingAnchorBefore, HeadingAnchorAfter:
+	default:
+		return errors.Newf("unknown heading anchor style %q", options.HeadingAnchors)
+	}
+	switch options.Sanitize {
+	case "", SanitizeOff, SanitizeStandard, SanitizeStrict:
+	default:
+		return errors.Newf("unknown sanitize level %q", options.Sanitize)
+	}
+	return nil
+}
+
 type MarkdownHeading struct {
 	Level int
 	ID    string
@@ -25,27 +77,48 @@ type MarkdownHeading struct {
 
 type MarkdownPageRenderer struct {
 	gm       goldmark.Markdown
+	options  MarkdownOptions
 	slugs    *headingSlugger
 	headings []MarkdownHeading
+	links    *ProseLinker
+	// fragments counts the fragments rendered so far, which keeps the
+	// footnote IDs of each apart on the page.
+	fragments int
 }
 
 // RenderMarkdown converts a CommonMark string to HTML using Goldmark,
-// with CommonMark and GFM extensions enabled.
+// with CommonMark and GFM extensions enabled, and sanitizes the result.
 func RenderMarkdown(input string) string {
-	gm := newMarkdownRenderer()
+	return RenderMarkdownWithOptions(input, MarkdownOptions{})
+}
+
+// RenderMarkdownWithOptions renders Markdown like RenderMarkdown with the
+// optional features of options.
+func RenderMarkdownWithOptions(input string, options MarkdownOptions) string {
+	gm := newMarkdownRenderer(options)
 
 	var buf bytes.Buffer
 	if err := gm.Convert([]byte(input), &buf); err != nil {
-		return input
+		return escapeHTML(input)
 	}
-	return buf.String()
+	return sanitizeHTML(buf.String(), options.Sanitize)
 }
 
-func NewMarkdownPageRenderer() *MarkdownPageRenderer {
-	return &MarkdownPageRenderer{
-		gm:    newMarkdownPageRenderer(),
-		slugs: newHeadingSlugger(),
+func NewMarkdownPageRenderer(options MarkdownOptions) *MarkdownPageRenderer {
+	r := &MarkdownPageRenderer{
+		options: options,
+		slugs:   newHeadingSlugger(),
 	}
+	r.gm = newMarkdownPageRenderer(r.options, func(ast.Node) []byte {
+		return fmt.Appendf(nil, "f%d-", r.fragments)
+	})
+	return r
+}
+
+// LinkSymbols makes inline code that names a symbol of linker link to its
+// definition in the fragments rendered after it.
+func (r *MarkdownPageRenderer) LinkSymbols(linker *ProseLinker) {
+	r.links = linker
 }
 
 func (r *MarkdownPageRenderer) RenderFragment(input string) string {
@@ -53,15 +126,19 @@ func (r *MarkdownPageRenderer) RenderFragment(input string) string {
 		return RenderMarkdown(input)
 	}
 
+	r.fragments++
 	source := []byte(input)
 	document := r.gm.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext()))
 	r.assignHeadingIDs(document, source)
+	if r.links != nil {
+		r.links.linkCodeSpans(document, source, r.options)
+	}
 
 	var buf bytes.Buffer
 	if err := r.gm.Renderer().Render(&buf, source, document); err != nil {
-		return input
+		return escapeHTML(input)
 	}
-	return buf.String()
+	return sanitizeHTML(buf.String(), r.options.Sanitize)
 }
 
 func (r *MarkdownPageRenderer) Headings() []MarkdownHeading {
@@ -91,6 +168,7 @@ func (r *MarkdownPageRenderer) assignHeadingIDs(document ast.Node, source []byte
 			id = r.slugs.Unique(id)
 		}
 		heading.SetAttribute([]byte("id"), []byte(id))
+		addHeadingAnchor(heading, id, r.options.HeadingAnchors)
 
 		if title != "" {
 			r.headings = append(r.headings, MarkdownHeading{
@@ -103,8 +181,26 @@ func (r *MarkdownPageRenderer) assignHeadingIDs(document ast.Node, source []byte
 	})
 }
 
+// addHeadingAnchor adds a permalink to the heading's own ID before or after
+// its text, as style asks.
+func addHeadingAnchor(heading *ast.Heading, id string, style HeadingAnchorStyle) {
+	if style != HeadingAnchorBefore && style != HeadingAnchorAfter {
+		return
+	}
+	link := ast.NewLink()
+	link.Destination = []byte("#" + id)
+	link.Title = []byte("Link to this heading")
+	link.SetAttributeString("class", []byte("cire-heading-anchor"))
+	link.AppendChild(link, ast.NewString([]byte("#")))
+	if style == HeadingAnchorBefore && heading.FirstChild() != nil {
+		heading.InsertBefore(heading, heading.FirstChild(), link)
+		return
+	}
+	heading.AppendChild(heading, link)
+}
+
 func ExtractMarkdownHeadings(input string) []MarkdownHeading {
-	gm := newMarkdownRenderer()
+	gm := newMarkdownRenderer(MarkdownOptions{})
 	source := []byte(input)
 	document := gm.Parser().Parse(text.NewReader(source), parser.WithContext(parser.NewContext()))
 
@@ -132,20 +228,46 @@ func ExtractMarkdownHeadings(input string) []MarkdownHeading {
 	return headings
 }
 
-func newMarkdownRenderer() goldmark.Markdown {
-	return newMarkdownRendererWithParserOptions(parser.WithAutoHeadingID())
+func newMarkdownRenderer(options MarkdownOptions) goldmark.Markdown {
+	return newMarkdownRendererWithParserOptions(options, nil, parser.WithAutoHeadingID())
 }
 
-func newMarkdownPageRenderer() goldmark.Markdown {
-	return newMarkdownRendererWithParserOptions(parser.WithHeadingAttribute())
+func newMarkdownPageRenderer(options MarkdownOptions, footnotePrefix func(ast.Node) []byte) goldmark.Markdown {
+	return newMarkdownRendererWithParserOptions(options, footnotePrefix, parser.WithHeadingAttribute())
 }
 
-func newMarkdownRendererWithParserOptions(opts ...parser.Option) goldmark.Markdown {
+func newMarkdownRendererWithParserOptions(options MarkdownOptions, footnotePrefix func(ast.Node) []byte, opts ...parser.Option) goldmark.Markdown {
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown for tables, task lists, etc.
+		&katex.Extender{},
+		markdownCallouts{},
+		markdownDiagrams{},
+	}
+	if options.Footnotes {
+		var footnoteOptions []extension.FootnoteOption
+		if footnotePrefix != nil {
+			footnoteOptions = append(footnoteOptions, extension.WithFootnoteIDPrefixFunction(footnotePrefix))
+		}
+		extensions = append(extensions, extension.NewFootnote(footnoteOptions...))
+	}
+	if options.DefinitionLists {
+		extensions = append(extensions, extension.DefinitionList)
+	}
+	if options.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+	if options.Emoji {
+		extensions = append(extensions, emoji.Emoji)
+	}
+	if options.Attributes {
+		opts = append(opts, parser.WithAttribute())
+	}
+	if options.DisableRawHTML {
+		opts = append(opts, parser.WithASTTransformers(util.Prioritized(rawHTMLFilter{}, 1000)))
+	}
+
 	gm := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM, // GitHub Flavored Markdown for tables, task lists, etc.
-			&katex.Extender{},
-		),
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithExtensions(
 			highlight.NewHighlighting(highlight.WithFormatOptions(
 				formatter.WithClasses(true),
@@ -153,12 +275,215 @@ func newMarkdownRendererWithParserOptions(opts ...parser.Option) goldmark.Markdo
 		),
 		goldmark.WithParserOptions(opts...),
 		goldmark.WithRendererOptions(
-			html.WithUnsafe(), // Allow embedding of raw HTML
+			// Raw HTML passes through unless rawHTMLFilter drops it first.
+			html.WithUnsafe(),
 		),
 	)
 	return gm
 }
 
+// generatedHTMLPattern matches the HTML blocks that stay when raw HTML is
+// off: the wrappers DocComment.Markdown puts around doc sections, and SCIP
+// signature blocks, which hold nothing but text and classed spans.
+var generatedHTMLPattern = regexp.MustCompile(`^(?:<div (?:class|className)="cire-[a-z-]+">|</div>|` +
+	`<pre class="cire cire-signature"(?: data-language="[a-z0-9_+#-]+")?><code>(?:[^<]|<span class="[a-z0-9_. -]+">[^<]*</span>)*</code></pre>)$`)
+
+// generatedBadgePattern matches the opening tag of a SCIP kind badge, which
+// stays with its closing tag when raw HTML is off.
+var generatedBadgePattern = regexp.MustCompile(`^<span class="cire-kind-badge" data-kind="[a-z-]+">$`)
+
+// rawHTMLFilter drops the raw HTML of a parsed document.
+type rawHTMLFilter struct{}
+
+func (rawHTMLFilter) Transform(document *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	for _, node := range rawHTMLNodes(document, source) {
+		node.Parent().RemoveChild(node.Parent(), node)
+	}
+}
+
+// rawHTMLNodes finds the inline HTML and HTML blocks of a document, leaving
+// out generated doc section wrappers.
+func rawHTMLNodes(document ast.Node, source []byte) []ast.Node {
+	var nodes []ast.Node
+	generated := make(map[ast.Node]bool)
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.RawHTML:
+			if generated[n] {
+				break
+			}
+			if closing := generatedBadgeClosing(n, source); closing != nil {
+				generated[closing] = true
+				break
+			}
+			nodes = append(nodes, n)
+		case *ast.HTMLBlock:
+			if !generatedHTMLPattern.Match(bytes.TrimSpace(n.Lines().Value(source))) {
+				nodes = append(nodes, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return nodes
+}
+
+// generatedBadgeClosing returns the closing tag of the SCIP kind badge that
+// node opens, or nil if node opens none.
+func generatedBadgeClosing(node *ast.RawHTML, source []byte) ast.Node {
+	if !generatedBadgePattern.Match(node.Segments.Value(source)) {
+		return nil
+	}
+	label, ok := node.NextSibling().(*ast.Text)
+	if !ok {
+		return nil
+	}
+	closing, ok := label.NextSibling().(*ast.RawHTML)
+	if !ok || string(closing.Segments.Value(source)) != "</span>" {
+		return nil
+	}
+	return closing
+}
+
+// omitRawHTML removes the raw HTML of Markdown from its source, for output
+// that another tool renders, such as MDX.
+func omitRawHTML(markdown string, options MarkdownOptions) string {
+	return rewriteMarkdownSource(markdown, options, func(string) string { return "" }, false)
+}
+
+// sanitizeMDXProse prepares prose for MDX, which would run its HTML as JSX
+// and its braces as JavaScript. It omits the raw HTML if options disable it
+// and sanitizes it otherwise, and unless sanitizing is off turns braces into
+// character references, sparing MDX comments such as {/* truncate */}.
+func sanitizeMDXProse(markdown string, options MarkdownOptions) string {
+	if options.Sanitize == SanitizeOff {
+		if options.DisableRawHTML {
+			return omitRawHTML(markdown, options)
+		}
+		return markdown
+	}
+	rawHTML := func(fragment string) string {
+		return escapeMDXBraces(sanitizeHTML(fragment, options.Sanitize))
+	}
+	if options.DisableRawHTML {
+		rawHTML = func(string) string { return "" }
+	}
+	return rewriteMarkdownSource(markdown, options, rawHTML, true)
+}
+
+// mdxCommentPattern matches an MDX comment: an expression holding nothing but
+// a block comment, which runs nothing.
+var mdxCommentPattern = regexp.MustCompile(`\{/\*(?:[^*]|\*+[^*/])*\*+/\}`)
+
+// escapeMDXBraces turns the braces of text into character references, except
+// those of MDX comments.
+func escapeMDXBraces(text string) string {
+	return string(escapeMDXBracesIn([]byte(text), 0, len(text), mdxCommentPattern.FindAllIndex([]byte(text), -1)))
+}
+
+// escapeMDXBracesIn returns source[start:stop] with its braces turned into
+// character references, except those escaped with a backslash and those
+// within the kept ranges.
+func escapeMDXBracesIn(source []byte, start int, stop int, kept [][]int) []byte {
+	var escaped []byte
+	for i := start; i < stop; i++ {
+		c := source[i]
+		if (c != '{' && c != '}') || (i > 0 && source[i-1] == '\\') || inRanges(kept, i) {
+			escaped = append(escaped, c)
+			continue
+		}
+		if c == '{' {
+			escaped = append(escaped, "&#123;"...)
+		} else {
+			escaped = append(escaped, "&#125;"...)
+		}
+	}
+	return escaped
+}
+
+func inRanges(ranges [][]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteMarkdownSource rewrites Markdown in its source, parsed with the
+// syntax options turn on: each raw HTML node whole with rawHTML, so a tag
+// written over several lines is rewritten as one, and, if escapeBraces is
+// set, the braces of the text outside code and math.
+func rewriteMarkdownSource(markdown string, options MarkdownOptions, rawHTML func(string) string, escapeBraces bool) string {
+	options.DisableRawHTML = false
+	source := []byte(markdown)
+	document := newMarkdownRendererWithParserOptions(options, nil).Parser().Parse(text.NewReader(source))
+
+	type edit struct {
+		segment     text.Segment
+		replacement string
+	}
+	var edits []edit
+	rewriteHTML := func(first text.Segment, last text.Segment) {
+		segment := text.NewSegment(first.Start, last.Stop)
+		edits = append(edits, edit{segment, rawHTML(string(segment.Value(source)))})
+	}
+	for _, node := range rawHTMLNodes(document, source) {
+		switch n := node.(type) {
+		case *ast.RawHTML:
+			if n.Segments.Len() > 0 {
+				rewriteHTML(n.Segments.At(0), n.Segments.At(n.Segments.Len()-1))
+			}
+		case *ast.HTMLBlock:
+			lines := n.Lines()
+			if lines.Len() == 0 {
+				continue
+			}
+			last := lines.At(lines.Len() - 1)
+			if n.HasClosure() {
+				last = n.ClosureLine
+			}
+			rewriteHTML(lines.At(0), last)
+		}
+	}
+	if escapeBraces {
+		comments := mdxCommentPattern.FindAllIndex(source, -1)
+		_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			if node.Kind() == katex.KindInline || node.Kind() == katex.KindBlock {
+				return ast.WalkSkipChildren, nil
+			}
+			switch n := node.(type) {
+			case *ast.CodeSpan:
+				return ast.WalkSkipChildren, nil
+			case *ast.Text:
+				escaped := escapeMDXBracesIn(source, n.Segment.Start, n.Segment.Stop, comments)
+				edits = append(edits, edit{n.Segment, string(escaped)})
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].segment.Start < edits[j].segment.Start })
+	var sb strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.segment.Start < last {
+			continue
+		}
+		sb.Write(source[last:e.segment.Start])
+		sb.WriteString(e.replacement)
+		last = e.segment.Stop
+	}
+	sb.Write(source[last:])
+	return sb.String()
+}
+
 func codeBlockFormatOptions(context highlight.CodeBlockContext) []formatter.Option {
 	language, ok := context.Language()
 	if !ok {
@@ -7,7 +7,7 @@ import (
 )
 
 func TestMarkdownPageRendererAssignsUniqueUnicodeHeadingIDsAcrossFragments(t *testing.T) {
-	renderer := NewMarkdownPageRenderer()
+	renderer := NewMarkdownPageRenderer(MarkdownOptions{})
 
 	firstHTML := renderer.RenderFragment("## 核心链路\n\n第一段。")
 	secondHTML := renderer.RenderFragment("## 核心链路\n\n第二段。")
@@ -36,7 +36,7 @@ func TestMarkdownPageRendererAssignsUniqueUnicodeHeadingIDsAcrossFragments(t *te
 }
 
 func TestMarkdownPageRendererHighlightsBashCodeFence(t *testing.T) {
-	renderer := NewMarkdownPageRenderer()
+	renderer := NewMarkdownPageRenderer(MarkdownOptions{})
 
 	html := renderer.RenderFragment(strings.Join([]string{
 		"```bash",
@@ -56,3 +56,87 @@ func TestMarkdownPageRendererHighlightsBashCodeFence(t *testing.T) {
 		}
 	}
 }
+
+func TestMarkdownOptionsEnableOptionalFeatures(t *testing.T) {
+	options := MarkdownOptions{
+		Footnotes:       true,
+		DefinitionLists: true,
+		Typographer:     true,
+		Emoji:           true,
+		Attributes:      true,
+		HeadingAnchors:  HeadingAnchorAfter,
+	}
+
+	renderer := NewMarkdownPageRenderer(options)
+	first := renderer.RenderFragment("## Setup {.lead}\n\nIt's ready :tada: -- see[^1].\n\n[^1]: The note.")
+	second := renderer.RenderFragment("Term\n: Definition.\n\nAgain[^1].\n\n[^1]: Another note.")
+	html := first + second
+
+	for _, want := range []string{
+		`<h2 class="lead" id="setup">Setup<a href="#setup" title="Link to this heading" class="cire-heading-anchor">#</a></h2>`,
+		"It&rsquo;s ready &#x1f389; &ndash; see",
+		`href="#f1-fn:1"`,
+		`id="f2-fn:1"`,
+		"<dl>\n<dt>Term</dt>\n<dd>Definition.</dd>\n</dl>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered Markdown missing %q\nGot:\n%s", want, html)
+		}
+	}
+	if got := renderer.Headings(); len(got) != 1 || got[0].Title != "Setup" {
+		t.Fatalf("Headings() = %#v, want the heading without its anchor", got)
+	}
+	if hover := RenderMarkdownWithOptions("Done :tada:", options); !strings.Contains(hover, "&#x1f389;") {
+		t.Fatalf("hover Markdown should share the options, got %q", hover)
+	}
+	if hover := RenderMarkdown("Done :tada:"); strings.Contains(hover, "&#x1f389;") {
+		t.Fatalf("hover Markdown without options should keep the shortcode, got %q", hover)
+	}
+}
+
+func TestMarkdownOptionsDefaultsLeaveOptionalSyntaxAlone(t *testing.T) {
+	html := NewMarkdownPageRenderer(MarkdownOptions{}).RenderFragment("## Setup\n\nIt's :tada: see[^1].\n\n[^1]: The note.\n\n<kbd>Ctrl</kbd>")
+
+	for _, want := range []string{
+		`<h2 id="setup">Setup</h2>`,
+		"It's :tada: see[^1].",
+		"<kbd>Ctrl</kbd>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered Markdown missing %q\nGot:\n%s", want, html)
+		}
+	}
+}
+
+func TestMarkdownOptionsDisableRawHTML(t *testing.T) {
+	options := MarkdownOptions{DisableRawHTML: true}
+
+	doc := (&DocComment{Description: "Press <kbd>Ctrl</kbd>.", Returns: &DocReturn{Description: "nothing"}}).Markdown("class")
+	html := NewMarkdownPageRenderer(options).RenderFragment(doc + "\n\n<script>alert(1)</script>\n\n<div onclick=\"x()\">\n\nText\n\n</div>")
+
+	for _, want := range []string{
+		"<p>Press Ctrl.</p>",
+		`<div class="cire-doc-returns">`,
+		"<p>Text</p>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered Markdown missing %q\nGot:\n%s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"<kbd>", "<script>", "onclick"} {
+		if strings.Contains(html, unwanted) {
+			t.Fatalf("rendered Markdown should omit %q\nGot:\n%s", unwanted, html)
+		}
+	}
+
+	mdx := omitRawHTML("Press <Kbd>Ctrl</Kbd>.\n\n<div className=\"cire-doc-returns\">\n\nok\n\n</div>\n\n<Widget prop={1} />\n<Widget>\n  text\n</Widget>\n\nEnd.", options)
+	if want := "Press Ctrl.\n\n<div className=\"cire-doc-returns\">\n\nok\n\n</div>\n\n\nEnd."; mdx != want {
+		t.Fatalf("omitRawHTML() = %q, want %q", mdx, want)
+	}
+}
+
+func TestMarkdownOptionsValidateRejectsUnknownHeadingAnchorStyle(t *testing.T) {
+	if err := (MarkdownOptions{HeadingAnchors: "inside"}).Validate(); err == nil {
+		t.Fatal("Validate should reject an unknown heading anchor style")
+	}
+}
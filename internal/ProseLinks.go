@@ -0,0 +1,333 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// ProseLinker links symbol names mentioned in prose, as inline code such as
+// `Pipeline.AnalyzeFile` or rustdoc intra-doc links such as [`Type`], to
+// their definitions.
+//
+// Symbols come from the page's own analyzed tokens, which carry language
+// server and index definitions and their hover text, and from project-wide
+// tags definitions. Names that match several definitions are left as code and
+// reported, as are explicit intra-doc links and qualified names that match
+// none; a bare word that matches nothing is usually not a symbol and stays
+// quiet.
+type ProseLinker struct {
+	symbols  map[string][]*proseSymbol
+	byHref   map[string]*proseSymbol
+	warnings []ProseLinkWarning
+
+	// project supplies project definitions by name; their hrefs are resolved
+	// the first time the page's code or prose names them.
+	project    *ProseSymbolTable
+	sourcePath string
+	manifest   SourceRouteManifest
+	loaded     map[string]bool
+}
+
+// ProseSymbolTable holds the project definitions prose may link to, by name.
+// It is built once per export and shared by the linkers of every page.
+type ProseSymbolTable struct {
+	definitions map[string][]TagDefinition
+}
+
+type proseSymbol struct {
+	name       string
+	qualifiers []string
+	href       string
+	document   []string
+	// onPage is set for symbols the page's own code mentions, which win over
+	// project-wide definitions of the same name.
+	onPage bool
+}
+
+// ProseLinkWarning reports a name in prose that did not link.
+type ProseLinkWarning struct {
+	Name       string
+	Candidates int
+}
+
+func (w ProseLinkWarning) String() string {
+	if w.Candidates > 1 {
+		return fmt.Sprintf("%s is ambiguous: %d definitions match", w.Name, w.Candidates)
+	}
+	return fmt.Sprintf("%s matches no definition", w.Name)
+}
+
+var proseSymbolPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(?:(?:\.|::|#)[A-Za-z_$][A-Za-z0-9_$]*)*(?:\(\))?$`)
+
+func NewProseLinker() *ProseLinker {
+	return &ProseLinker{
+		symbols: make(map[string][]*proseSymbol),
+		byHref:  make(map[string]*proseSymbol),
+	}
+}
+
+// NewProseSymbolTable snapshots the definitions of a complete tags index.
+func NewProseSymbolTable(index *TagsIndex) *ProseSymbolTable {
+	table := &ProseSymbolTable{definitions: make(map[string][]TagDefinition)}
+	if index == nil {
+		return table
+	}
+	for _, definition := range index.AllDefinitions() {
+		table.definitions[definition.Name] = append(table.definitions[definition.Name], definition)
+	}
+	return table
+}
+
+// Linker returns a linker for the page at sourcePath whose project
+// definitions link through manifest.
+func (t *ProseSymbolTable) Linker(sourcePath string, manifest SourceRouteManifest) *ProseLinker {
+	linker := NewProseLinker()
+	linker.project = t
+	linker.sourcePath = sourcePath
+	linker.manifest = manifest
+	linker.loaded = make(map[string]bool)
+	return linker
+}
+
+// addProjectDefinitions adds the project definitions of name, once.
+func (l *ProseLinker) addProjectDefinitions(name string) {
+	if l.project == nil || l.loaded[name] {
+		return
+	}
+	l.loaded[name] = true
+	for _, definition := range l.project.definitions[name] {
+		location := SourceLocation{Path: definition.Path, Range: definition.Range}
+		if href, ok, _ := ResolveDefinitionHref(l.sourcePath, location, l.manifest); ok {
+			l.AddDefinition(definition, href)
+		}
+	}
+}
+
+// AddTokens adds the definitions and resolved references of the page's code,
+// with their hover text. Links within the page only add to definitions
+// AddDefinition or the project table already added, so local variables stay
+// out of the symbol set; add other definitions first.
+func (l *ProseLinker) AddTokens(sourceLines []string, tokens []TokenInfo) {
+	if l == nil {
+		return
+	}
+	for _, token := range tokens {
+		href := token.Href
+		if href == "" && token.Anchor != "" {
+			href = "#" + token.Anchor
+		}
+		if href == "" || token.InlayHintLabel != "" || token.Span.Start.Line != token.Span.End.Line {
+			continue
+		}
+		name := getSourceFromSpan(sourceLines, token.Span)
+		if !proseSymbolPattern.MatchString(name) {
+			continue
+		}
+		l.addProjectDefinitions(name)
+		if known, ok := l.byHref[href]; strings.HasPrefix(href, "#") && (!ok || known.name != name) {
+			continue
+		}
+		symbol := l.add(name, href, token.Document)
+		symbol.onPage = true
+		if qualifier := proseTokenQualifier(sourceLines, token); qualifier != "" {
+			symbol.addQualifier(qualifier)
+		}
+	}
+}
+
+// AddDefinition adds a definition from outside the page's code. Its qualifiers
+// are the enclosing type or module and the directory it lives in.
+func (l *ProseLinker) AddDefinition(definition TagDefinition, href string) {
+	if l == nil || href == "" {
+		return
+	}
+	symbol := l.add(definition.Name, href, nil)
+	if scope := strings.TrimLeft(definition.Scope, "*&"); scope != "" {
+		symbol.addQualifier(scope)
+	}
+	if dir := filepath.Base(filepath.Dir(definition.Path)); dir != "." && dir != string(filepath.Separator) {
+		symbol.addQualifier(dir)
+	}
+}
+
+func (l *ProseLinker) add(name string, href string, document []string) *proseSymbol {
+	if symbol, ok := l.byHref[href]; ok && symbol.name == name {
+		if len(symbol.document) == 0 {
+			symbol.document = document
+		}
+		return symbol
+	}
+	symbol := &proseSymbol{name: name, href: href, document: document}
+	l.byHref[href] = symbol
+	l.symbols[name] = append(l.symbols[name], symbol)
+	return symbol
+}
+
+func (s *proseSymbol) addQualifier(qualifier string) {
+	for _, existing := range s.qualifiers {
+		if existing == qualifier {
+			return
+		}
+	}
+	s.qualifiers = append(s.qualifiers, qualifier)
+}
+
+// Warnings returns the names that did not link, in the order prose mentioned
+// them.
+func (l *ProseLinker) Warnings() []ProseLinkWarning {
+	if l == nil {
+		return nil
+	}
+	return append([]ProseLinkWarning(nil), l.warnings...)
+}
+
+// resolve finds the definition text names. explicit is set for intra-doc
+// links, whose author meant a symbol.
+func (l *ProseLinker) resolve(text string, explicit bool) (*proseSymbol, bool) {
+	if !proseSymbolPattern.MatchString(text) {
+		return nil, false
+	}
+	name := strings.TrimSuffix(text, "()")
+	qualifier := ""
+	if i := strings.LastIndexAny(name, ".:#"); i >= 0 {
+		qualifier, name = name[:i], name[i+1:]
+		qualifier = strings.TrimRight(qualifier, ":")
+		if j := strings.LastIndexAny(qualifier, ".:#"); j >= 0 {
+			qualifier = qualifier[j+1:]
+		}
+	}
+
+	l.addProjectDefinitions(name)
+	candidates := l.symbols[name]
+	if qualifier != "" {
+		var qualified []*proseSymbol
+		for _, symbol := range candidates {
+			for _, q := range symbol.qualifiers {
+				if q == qualifier {
+					qualified = append(qualified, symbol)
+					break
+				}
+			}
+		}
+		candidates = qualified
+	}
+	var onPage []*proseSymbol
+	for _, symbol := range candidates {
+		if symbol.onPage {
+			onPage = append(onPage, symbol)
+		}
+	}
+	if len(onPage) > 0 {
+		candidates = onPage
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], true
+	case len(candidates) > 1:
+		l.warnings = append(l.warnings, ProseLinkWarning{Name: text, Candidates: len(candidates)})
+	case explicit || qualifier != "":
+		l.warnings = append(l.warnings, ProseLinkWarning{Name: text})
+	}
+	return nil, false
+}
+
+// linkCodeSpans turns the inline code of a parsed fragment that names a
+// symbol into a link to its definition carrying its hover text.
+func (l *ProseLinker) linkCodeSpans(document ast.Node, source []byte, options MarkdownOptions) {
+	var spans []*ast.CodeSpan
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link, *ast.AutoLink, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			spans = append(spans, n)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, span := range spans {
+		opening, closing, explicit := intraDocLinkBrackets(span, source)
+		symbol, ok := l.resolve(strings.TrimSpace(string(span.Text(source))), explicit)
+		if !ok {
+			continue
+		}
+		if explicit {
+			trimTextNode(opening, 0, 1)
+			trimTextNode(closing, 1, 0)
+		}
+
+		link := ast.NewLink()
+		link.Destination = []byte(symbol.href)
+		link.SetAttributeString("class", []byte("cire-symbol-link"))
+		if encodedHover, encodedHoverHTML, ok := encodeAstroHover(symbol.document, options); ok {
+			link.SetAttributeString("data-hover", []byte(encodedHover))
+			link.SetAttributeString("data-hover-html", []byte(encodedHoverHTML))
+		}
+		parent := span.Parent()
+		parent.ReplaceChild(parent, span, link)
+		link.AppendChild(link, span)
+	}
+}
+
+// intraDocLinkBrackets reports whether a code span is written as [`Name`]:
+// an unresolved shortcut reference link, which the parser leaves as the text
+// "[" and "]" around the span.
+func intraDocLinkBrackets(span *ast.CodeSpan, source []byte) (opening *ast.Text, closing *ast.Text, ok bool) {
+	opening, isText := span.PreviousSibling().(*ast.Text)
+	if !isText || !strings.HasSuffix(string(opening.Segment.Value(source)), "[") {
+		return nil, nil, false
+	}
+	closing, isText = span.NextSibling().(*ast.Text)
+	if !isText {
+		return nil, nil, false
+	}
+	after := string(closing.Segment.Value(source))
+	if !strings.HasPrefix(after, "]") || strings.HasPrefix(after, "](") || strings.HasPrefix(after, "][") {
+		return nil, nil, false
+	}
+	return opening, closing, true
+}
+
+func trimTextNode(node *ast.Text, head int, tail int) {
+	node.Segment.Start += head
+	node.Segment.Stop -= tail
+	if node.Segment.Len() == 0 && !node.SoftLineBreak() && !node.HardLineBreak() {
+		node.Parent().RemoveChild(node.Parent(), node)
+	}
+}
+
+// proseTokenQualifier is the name written before a token and a dot or ::, as
+// in pkg.Func or Type::new.
+func proseTokenQualifier(sourceLines []string, token TokenInfo) string {
+	line := []rune(sourceLines[token.Span.Start.Line])
+	end := int(token.Span.Start.Character)
+	if end > len(line) {
+		return ""
+	}
+	switch {
+	case end >= 1 && line[end-1] == '.':
+		end--
+	case end >= 2 && line[end-1] == ':' && line[end-2] == ':':
+		end -= 2
+	default:
+		return ""
+	}
+	start := end
+	for start > 0 && (isProseIdentRune(line[start-1])) {
+		start--
+	}
+	return string(line[start:end])
+}
+
+func isProseIdentRune(r rune) bool {
+	return r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestMarkdownPageRendererLinksSymbolsInProse(t *testing.T) {
+	linker := NewProseLinker()
+	linker.AddDefinition(TagDefinition{Name: "AnalyzeFile", Scope: "*Pipeline", Path: "/repo/cmd/pipeline.go"}, "/_source/cmd/pipeline.go.html/#L235C18")
+	linker.AddDefinition(TagDefinition{Name: "AnalyzeFile", Scope: "LSPSession", Path: "/repo/internal/lsp.go"}, "/_source/internal/lsp.go.html/#L136C24")
+	linker.AddDefinition(TagDefinition{Name: "SourceRouteManifest", Path: "/repo/internal/routes.go"}, "/_source/internal/routes.go.html/#L39C6")
+	sourceLines := []string{"var m internal.SourceRouteManifest", "fmt.Println(m)"}
+	linker.AddTokens(sourceLines, []TokenInfo{
+		{
+			Span:     scip.Range{Start: scip.Position{Line: 0, Character: 15}, End: scip.Position{Line: 0, Character: 34}},
+			Href:     "/_source/internal/routes.go.html/#L39C6",
+			Document: []string{"type SourceRouteManifest struct"},
+		},
+		{
+			Span:     scip.Range{Start: scip.Position{Line: 1, Character: 4}, End: scip.Position{Line: 1, Character: 11}},
+			Href:     "https://pkg.go.dev/fmt#Println",
+			Document: []string{"func Println(a ...any)"},
+		},
+		{
+			// A local variable links within the page and stays out.
+			Span: scip.Range{Start: scip.Position{Line: 1, Character: 12}, End: scip.Position{Line: 1, Character: 13}},
+			Href: "#L1C5",
+		},
+	})
+
+	renderer := NewMarkdownPageRenderer(MarkdownOptions{})
+	renderer.LinkSymbols(linker)
+	html := renderer.RenderFragment("Routes live in [`SourceRouteManifest`], printed by `fmt.Println` " +
+		"and read by `Pipeline.AnalyzeFile()`, not `AnalyzeFile` or `m`. See [`Missing`] and `go build`.")
+
+	for _, want := range []string{
+		`Routes live in <a href="/_source/internal/routes.go.html/#L39C6" class="cire-symbol-link" data-hover="`,
+		`"><code>SourceRouteManifest</code></a>, printed by`,
+		`<a href="https://pkg.go.dev/fmt#Println" class="cire-symbol-link" data-hover=`,
+		`<a href="/_source/cmd/pipeline.go.html/#L235C18" class="cire-symbol-link"><code>Pipeline.AnalyzeFile()</code></a>`,
+		"not <code>AnalyzeFile</code> or <code>m</code>",
+		"See [<code>Missing</code>] and <code>go build</code>.",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("rendered prose missing %q\nGot:\n%s", want, html)
+		}
+	}
+
+	got := linker.Warnings()
+	want := []ProseLinkWarning{
+		{Name: "AnalyzeFile", Candidates: 2},
+		{Name: "Missing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Warnings() = %#v, want %#v", got, want)
+	}
+}
+
+func TestProseLinkerQualifiesByPackageDirectory(t *testing.T) {
+	linker := NewProseLinker()
+	linker.AddDefinition(TagDefinition{Name: "Load", Path: "/repo/internal/config/config.go"}, "/_source/internal/config/config.go.html/#L10C6")
+	linker.AddDefinition(TagDefinition{Name: "Load", Path: "/repo/internal/theme/theme.go"}, "/_source/internal/theme/theme.go.html/#L4C6")
+
+	symbol, ok := linker.resolve("config.Load", false)
+	if !ok || symbol.href != "/_source/internal/config/config.go.html/#L10C6" {
+		t.Fatalf("resolve(config.Load) = %#v, %v", symbol, ok)
+	}
+	if _, ok := linker.resolve("other.Load", false); ok {
+		t.Fatalf("resolve(other.Load) should not link")
+	}
+	if got := linker.Warnings(); len(got) != 1 || got[0].String() != "other.Load matches no definition" {
+		t.Fatalf("Warnings() = %#v", got)
+	}
+}
+
+func TestProseSymbolTableResolvesHrefsOfNamedDefinitionsOnly(t *testing.T) {
+	root := "/repo"
+	util := filepath.Join(root, "pkg", "util.go")
+	guide := filepath.Join(root, "docs", "guide.go")
+	index := NewTagsIndex()
+	if err := index.AddFile("go", util, []byte("package pkg\n\nfunc Util() {}\n\nfunc Other() {}\n")); err != nil {
+		t.Fatalf("AddFile returned error: %v", err)
+	}
+	if err := index.AddFile("go", guide, []byte("package docs\n\nfunc Local() {}\n")); err != nil {
+		t.Fatalf("AddFile returned error: %v", err)
+	}
+	manifest := newTestSourceRouteManifest(t, root, []string{util, guide})
+
+	linker := NewProseSymbolTable(index).Linker(guide, manifest)
+
+	if symbol, ok := linker.resolve("pkg.Util", false); !ok || symbol.href != "/_source/pkg/util.go.html#L3C6" {
+		t.Fatalf("resolve(pkg.Util) = %#v, %v", symbol, ok)
+	}
+	if symbol, ok := linker.resolve("Local", false); !ok || symbol.href != "#L3C6" {
+		t.Fatalf("resolve(Local) = %#v, %v, want an anchor on the page", symbol, ok)
+	}
+	if !reflect.DeepEqual(linker.loaded, map[string]bool{"Util": true, "Local": true}) {
+		t.Fatalf("loaded = %v, want only the names prose mentioned", linker.loaded)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/cockroachdb/errors"
 	"github.com/sourcegraph/scip/bindings/go/scip"
@@ -19,8 +20,23 @@ import (
 // to extract language related file info,
 // including hover doc, go to definition info and more.
 type SCIPAnalyzer struct {
-	scipIndex *scip.Index
-	symbolMap map[string]*scip.SymbolInformation
+	scipIndex      *scip.Index
+	symbolMap      map[string]*scip.SymbolInformation
+	definedSymbols map[string]bool
+	// HighlightQueries highlights hover signatures; nil uses the built-in
+	// queries.
+	HighlightQueries *HighlightQueries
+
+	// hovers caches each symbol's hover card, whose signature is highlighted
+	// with tree-sitter, across its occurrences and the files analyzed; project
+	// exports share one analyzer between every file's pipeline.
+	hoversMu sync.Mutex
+	hovers   map[scipHoverKey]scipHover
+}
+
+type scipHoverKey struct {
+	symbol   string
+	language string // Language of the document the symbol occurs in
 }
 
 func NewSCIPAnalyzer(indexPath string) (*SCIPAnalyzer, error) {
@@ -41,9 +57,11 @@ func NewSCIPAnalyzer(indexPath string) (*SCIPAnalyzer, error) {
 	}
 
 	symbolMap := make(map[string]*scip.SymbolInformation)
+	definedSymbols := make(map[string]bool)
 	for _, doc := range scipIndex.Documents {
 		for _, sym := range doc.Symbols {
 			symbolMap[sym.Symbol] = sym
+			definedSymbols[sym.Symbol] = true
 		}
 	}
 	for _, sym := range scipIndex.ExternalSymbols {
@@ -51,20 +69,30 @@ func NewSCIPAnalyzer(indexPath string) (*SCIPAnalyzer, error) {
 	}
 
 	return &SCIPAnalyzer{
-		scipIndex: &scipIndex,
-		symbolMap: symbolMap,
+		scipIndex:      &scipIndex,
+		symbolMap:      symbolMap,
+		definedSymbols: definedSymbols,
+		hovers:         make(map[scipHoverKey]scipHover),
 	}, nil
 }
 
-func (s *SCIPAnalyzer) Analyze(sourcePath string) []TokenInfo {
-	var document *scip.Document
-
-	// Normalize project root from SCIP metadata.
-	// If it's a file:// URI, extract the path. Otherwise, treat it as a raw path.
-	projectRoot := s.scipIndex.Metadata.ProjectRoot
+// scipProjectRoot normalizes project root from SCIP metadata.
+// If it's a file:// URI, extract the path. Otherwise, treat it as a raw path.
+func scipProjectRoot(index *scip.Index) string {
+	if index.Metadata == nil {
+		return ""
+	}
+	projectRoot := index.Metadata.ProjectRoot
 	if u, err := url.Parse(projectRoot); err == nil && u.Scheme == "file" {
 		projectRoot = u.Path
 	}
+	return projectRoot
+}
+
+func (s *SCIPAnalyzer) Analyze(sourcePath string) []TokenInfo {
+	var document *scip.Document
+
+	projectRoot := scipProjectRoot(s.scipIndex)
 
 	// Iterate through documents to find a match based on absolute file paths.
 	for _, doc := range s.scipIndex.Documents {
@@ -95,30 +123,53 @@ func (s *SCIPAnalyzer) Analyze(sourcePath string) []TokenInfo {
 		isDefinition := (occ.SymbolRoles & int32(scip.SymbolRole_Definition)) != 0
 		isReference := !isDefinition
 
-		var documents []string
-		if len(occ.OverrideDocumentation) > 0 {
-			documents = append(documents, occ.OverrideDocumentation...)
-		}
+		documents := s.hover(occ.Symbol, document.Language).documents(occ.OverrideDocumentation)
 
-		if symm, ok := s.symbolMap[occ.Symbol]; ok {
-			if len(symm.Documentation) > 0 {
-				documents = append(documents, symm.Documentation...)
-			}
-		}
-
-		tokens = append(tokens, TokenInfo{
+		token := TokenInfo{
 			Symbol:         generateID(occ.Symbol),
 			IsReference:    isReference,
 			IsDefinition:   isDefinition,
 			HighlightClass: "",
 			Document:       documents,
 			Span:           span,
-		})
+			Definition:     s.externalDefinition(occ.Symbol, isReference),
+			Source:         TokenSourceSCIP,
+		}
+		if isDefinition && len(occ.EnclosingRange) > 0 {
+			enclosing := parseRange(occ.EnclosingRange)
+			token.EnclosingRange = &enclosing
+		}
+		tokens = append(tokens, token)
 	}
 
 	return tokens
 }
 
+func (s *SCIPAnalyzer) hover(symbol string, language string) scipHover {
+	key := scipHoverKey{symbol: symbol, language: language}
+	s.hoversMu.Lock()
+	defer s.hoversMu.Unlock()
+	hover, ok := s.hovers[key]
+	if !ok {
+		hover = newSCIPHover(s.HighlightQueries, s.symbolMap[symbol], language)
+		s.hovers[key] = hover
+	}
+	return hover
+}
+
+// externalDefinition describes references to symbols that no document in the index defines,
+// so link resolution can point them at the dependency's documentation.
+func (s *SCIPAnalyzer) externalDefinition(symbol string, isReference bool) *SourceLocation {
+	if !isReference || s.definedSymbols[symbol] {
+		return nil
+	}
+	pkg := ExternalPackageFromSCIPSymbol(symbol)
+	if pkg == nil {
+		return nil
+	}
+	return &SourceLocation{Package: pkg}
+}
+
 func parseRange(r []int32) scip.Range {
 	if len(r) == 3 {
 		return scip.Range{
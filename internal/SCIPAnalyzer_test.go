@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSCIPAnalyzerBuildsStructuredHover(t *testing.T) {
+	root := t.TempDir()
+	symbol := "scip-go gomod example.com/app v1.0.0 `example.com/app`/Greet()."
+	index := &scip.Index{
+		Metadata: &scip.Metadata{ProjectRoot: fileURI(root)},
+		Documents: []*scip.Document{{
+			Language:     "go",
+			RelativePath: "main.go",
+			Occurrences: []*scip.Occurrence{{
+				Range:       []int32{2, 5, 10},
+				Symbol:      symbol,
+				SymbolRoles: int32(scip.SymbolRole_Definition),
+			}},
+			Symbols: []*scip.SymbolInformation{{
+				Symbol: symbol,
+				Kind:   scip.SymbolInformation_Function,
+				SignatureDocumentation: &scip.Document{
+					Language: "go",
+					Text:     "func Greet(name string) string",
+				},
+				Documentation: []string{
+					"```go\nfunc Greet(name string) string\n```",
+					"Greet returns a greeting for name.",
+				},
+			}},
+		}},
+	}
+	indexPath := writeSCIPTestIndex(t, root, index)
+
+	analyzer, err := NewSCIPAnalyzer(indexPath)
+	if err != nil {
+		t.Fatalf("NewSCIPAnalyzer returned error: %v", err)
+	}
+	tokens := analyzer.Analyze(filepath.Join(root, "main.go"))
+	if len(tokens) != 1 {
+		t.Fatalf("tokens = %d, want 1", len(tokens))
+	}
+
+	documents := tokens[0].Document
+	if len(documents) != 3 {
+		t.Fatalf("documents = %#v, want signature, badge, docs", documents)
+	}
+	if !strings.HasPrefix(documents[0], `<pre class="cire cire-signature" data-language="go"><code>`) {
+		t.Fatalf("signature block = %q", documents[0])
+	}
+	if !strings.Contains(documents[0], `<span class="keyword">func</span>`) {
+		t.Fatalf("signature block missing tree-sitter highlight: %q", documents[0])
+	}
+	if !strings.Contains(documents[1], `data-kind="function">function</span>`) {
+		t.Fatalf("kind badge = %q", documents[1])
+	}
+	if documents[2] != "Greet returns a greeting for name." {
+		t.Fatalf("documentation = %q, want prose without duplicated signature fence", documents[2])
+	}
+
+	rendered := RenderMarkdown(strings.Join(documents, "\n"))
+	if !strings.Contains(rendered, `class="cire cire-signature"`) || !strings.Contains(rendered, "<p>Greet returns a greeting for name.</p>") {
+		t.Fatalf("rendered hover = %q", rendered)
+	}
+}
+
+func TestSCIPHoverSurvivesRawHTMLOff(t *testing.T) {
+	info := &scip.SymbolInformation{
+		Symbol: "scip-go gomod example.com/app v1.0.0 `example.com/app`/Greet().",
+		Kind:   scip.SymbolInformation_Function,
+		SignatureDocumentation: &scip.Document{
+			Language: "go",
+			Text:     "func Greet(name string) (string, error)",
+		},
+		Documentation: []string{"Greet returns a <b>greeting</b> for name."},
+	}
+	hover := strings.Join(scipHoverDocuments(info, "go", nil), "\n")
+
+	rendered := RenderMarkdownWithOptions(hover, MarkdownOptions{DisableRawHTML: true})
+
+	for _, want := range []string{
+		`<pre class="cire cire-signature" data-language="go"><code><span class="keyword`,
+		`<span class="cire-kind-badge" data-kind="function">function</span>`,
+		"Greet returns a greeting for name.",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Fatalf("rendered hover missing %q\nGot:\n%s", want, rendered)
+		}
+	}
+	if strings.Contains(rendered, "<b>") {
+		t.Fatalf("rendered hover should omit the documentation's HTML\nGot:\n%s", rendered)
+	}
+
+	forged := `<pre class="cire cire-signature"><code><img src=x onerror=alert(1)></code></pre>`
+	if rendered := RenderMarkdownWithOptions(forged, MarkdownOptions{DisableRawHTML: true}); strings.Contains(rendered, "<img") {
+		t.Fatalf("a signature block holding other HTML should be omitted\nGot:\n%s", rendered)
+	}
+}
+
+func TestSCIPAnalyzerKeepsEnclosingRangeOfDefinitions(t *testing.T) {
+	root := t.TempDir()
+	symbol := "scip-go gomod example.com/app v1.0.0 `example.com/app`/Run()."
+	index := &scip.Index{
+		Metadata: &scip.Metadata{ProjectRoot: fileURI(root)},
+		Documents: []*scip.Document{
+			{
+				Language:     "go",
+				RelativePath: "run.go",
+				Occurrences: []*scip.Occurrence{{
+					Range:          []int32{4, 5, 8},
+					Symbol:         symbol,
+					SymbolRoles:    int32(scip.SymbolRole_Definition),
+					EnclosingRange: []int32{4, 0, 9, 1},
+				}},
+				Symbols: []*scip.SymbolInformation{{Symbol: symbol}},
+			},
+			{
+				Language:     "go",
+				RelativePath: "main.go",
+				Occurrences: []*scip.Occurrence{{
+					Range:  []int32{2, 1, 4},
+					Symbol: symbol,
+				}},
+			},
+		},
+	}
+	analyzer, err := NewSCIPAnalyzer(writeSCIPTestIndex(t, root, index))
+	if err != nil {
+		t.Fatalf("NewSCIPAnalyzer returned error: %v", err)
+	}
+
+	definitions := analyzer.Analyze(filepath.Join(root, "run.go"))
+	if len(definitions) != 1 || definitions[0].EnclosingRange == nil {
+		t.Fatalf("definition tokens = %#v, want enclosing range", definitions)
+	}
+	if got := *definitions[0].EnclosingRange; got.Start.Line != 4 || got.End.Line != 9 || got.End.Character != 1 {
+		t.Fatalf("enclosing range = %#v, want lines 4-9", got)
+	}
+
+	references := analyzer.Analyze(filepath.Join(root, "main.go"))
+	if len(references) != 1 || references[0].EnclosingRange != nil {
+		t.Fatalf("reference tokens = %#v, want no enclosing range", references)
+	}
+}
+
+func TestSCIPAnalyzerBuildsHoverOncePerSymbol(t *testing.T) {
+	root := t.TempDir()
+	symbol := "scip-go gomod example.com/app v1.0.0 `example.com/app`/Greet()."
+	index := &scip.Index{
+		Metadata: &scip.Metadata{ProjectRoot: fileURI(root)},
+		Documents: []*scip.Document{{
+			Language:     "go",
+			RelativePath: "main.go",
+			Occurrences: []*scip.Occurrence{
+				{Range: []int32{2, 5, 10}, Symbol: symbol, SymbolRoles: int32(scip.SymbolRole_Definition)},
+				{Range: []int32{6, 1, 6}, Symbol: symbol},
+				{Range: []int32{7, 1, 6}, Symbol: symbol, OverrideDocumentation: []string{"override"}},
+			},
+			Symbols: []*scip.SymbolInformation{{
+				Symbol:                 symbol,
+				Kind:                   scip.SymbolInformation_Function,
+				SignatureDocumentation: &scip.Document{Language: "go", Text: "func Greet()"},
+				Documentation:          []string{"Greet greets."},
+			}},
+		}},
+	}
+	analyzer, err := NewSCIPAnalyzer(writeSCIPTestIndex(t, root, index))
+	if err != nil {
+		t.Fatalf("NewSCIPAnalyzer returned error: %v", err)
+	}
+
+	tokens := analyzer.Analyze(filepath.Join(root, "main.go"))
+	if len(tokens) != 3 {
+		t.Fatalf("tokens = %d, want 3", len(tokens))
+	}
+	if len(analyzer.hovers) != 1 {
+		t.Fatalf("hover cards built = %d, want 1", len(analyzer.hovers))
+	}
+	if strings.Join(tokens[0].Document, "|") != strings.Join(tokens[1].Document, "|") {
+		t.Fatalf("occurrences of one symbol have different hovers: %#v, %#v", tokens[0].Document, tokens[1].Document)
+	}
+	if got := tokens[2].Document; len(got) != 4 || got[2] != "override" || got[3] != "Greet greets." {
+		t.Fatalf("overridden hover = %#v, want override before documentation", got)
+	}
+}
+
+func TestSCIPHoverDocumentsWithoutSignatureKeepDocumentation(t *testing.T) {
+	info := &scip.SymbolInformation{Documentation: []string{"```go\nvar x int\n```", "docs"}}
+	got := scipHoverDocuments(info, "go", []string{"override"})
+	want := []string{"override", "```go\nvar x int\n```", "docs"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("documents = %#v, want %#v", got, want)
+	}
+}
+
+func TestRenderSignatureBlockEscapesUnknownLanguage(t *testing.T) {
+	got := renderSignatureBlock(nil, "cobol", "a < b")
+	if got != `<pre class="cire cire-signature" data-language="cobol"><code>a &lt; b</code></pre>` {
+		t.Fatalf("signature block = %q", got)
+	}
+}
+
+func TestSCIPKindLabelSplitsWords(t *testing.T) {
+	if got := scipKindLabel(scip.SymbolInformation_TypeParameter); got != "type parameter" {
+		t.Fatalf("label = %q, want type parameter", got)
+	}
+	if got := scipKindLabel(scip.SymbolInformation_UnspecifiedKind); got != "" {
+		t.Fatalf("unspecified label = %q, want empty", got)
+	}
+}
+
+func writeSCIPTestIndex(t *testing.T, dir string, index *scip.Index) string {
+	t.Helper()
+	data, err := proto.Marshal(index)
+	if err != nil {
+		t.Fatalf("marshal index: %v", err)
+	}
+	indexPath := filepath.Join(dir, "index.scip")
+	if err := os.WriteFile(indexPath, data, 0o644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	return indexPath
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// scipHoverDocuments builds the hover card for a SCIP symbol: the signature
+// highlighted with the tree-sitter grammar of its language, a kind badge, then
+// the documentation. Indexers older than SCIP 0.4 only carry documentation, in
+// which case the card is the documentation alone, as before.
+func scipHoverDocuments(info *scip.SymbolInformation, documentLanguage string, overrides []string) []string {
+	return newSCIPHover(nil, info, documentLanguage).documents(overrides)
+}
+
+// scipHover is the hover card of a symbol without the documentation that
+// occurrences override, so it is built once however often the symbol occurs.
+type scipHover struct {
+	header        []string // Signature block and kind badge
+	documentation []string
+}
+
+func newSCIPHover(queries *HighlightQueries, info *scip.SymbolInformation, documentLanguage string) scipHover {
+	var hover scipHover
+	if info == nil {
+		return hover
+	}
+
+	var signature string
+	if info.SignatureDocumentation != nil {
+		signature = strings.TrimSpace(info.SignatureDocumentation.Text)
+		if signature != "" {
+			language := info.SignatureDocumentation.Language
+			if language == "" {
+				language = documentLanguage
+			}
+			hover.header = append(hover.header, renderSignatureBlock(queries, language, signature))
+		}
+	}
+	if badge := scipKindBadge(info.Kind); badge != "" {
+		hover.header = append(hover.header, badge)
+	}
+
+	for i, doc := range info.Documentation {
+		// scip-go and scip-typescript repeat the signature as a fenced block at the
+		// top of the documentation; drop it once the signature block is shown.
+		if i == 0 && signature != "" && isSignatureFence(doc, signature) {
+			continue
+		}
+		hover.documentation = append(hover.documentation, structureHoverDocument(doc, documentLanguage))
+	}
+	return hover
+}
+
+// documents returns the card of an occurrence, with its overriding
+// documentation between the header and the symbol's own.
+func (h scipHover) documents(overrides []string) []string {
+	documents := make([]string, 0, len(h.header)+len(overrides)+len(h.documentation))
+	documents = append(documents, h.header...)
+	documents = append(documents, overrides...)
+	documents = append(documents, h.documentation...)
+	if len(documents) == 0 {
+		return nil
+	}
+	return documents
+}
+
+// renderSignatureBlock returns a raw HTML block, which markdown rendering passes
+// through unchanged, so the hover keeps tree-sitter classes instead of Chroma's.
+func renderSignatureBlock(queries *HighlightQueries, language, signature string) string {
+	canonical, err := languages.CanonicalName(strings.TrimSpace(language))
+	if err != nil {
+		canonical = normalizedCodeBlockLanguage(language)
+	}
+
+	code, ok := highlightCodeHTML(queries, canonical, signature)
+	if !ok {
+		code = escapeHTML(signature)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<pre class="cire cire-signature"`)
+	if canonical != "" {
+		fmt.Fprintf(&sb, ` data-language="%s"`, escapeHTML(canonical))
+	}
+	sb.WriteString("><code>")
+	sb.WriteString(code)
+	sb.WriteString("</code></pre>")
+	return sb.String()
+}
+
+// highlightCodeHTML highlights a standalone snippet with the language's
+// highlight query and wraps captures in spans named after the capture.
+func highlightCodeHTML(queries *HighlightQueries, language, code string) (string, bool) {
+	if language == "" {
+		return "", false
+	}
+	analyzer := NewHighlightAnalyzer(language)
+	analyzer.Queries = queries
+	tokens, err := analyzer.Analyze([]byte(code))
+	if err != nil {
+		return "", false
+	}
+	SortBySpan(tokens)
+	tokens, err = MergeSplitTokens(tokens)
+	if err != nil {
+		return "", false
+	}
+
+	// Tree-sitter columns are byte offsets, so slice lines as bytes.
+	lines := strings.Split(code, "\n")
+	offset := func(pos scip.Position) int {
+		if int(pos.Line) >= len(lines) {
+			return len(code)
+		}
+		index := 0
+		for _, line := range lines[:pos.Line] {
+			index += len(line) + 1
+		}
+		return min(index+int(pos.Character), len(code))
+	}
+
+	var sb strings.Builder
+	cursor := 0
+	for _, token := range tokens {
+		start, end := offset(token.Span.Start), offset(token.Span.End)
+		if start < cursor || end <= start {
+			continue
+		}
+		sb.WriteString(escapeHTML(code[cursor:start]))
+		if classes := token.Classes(); len(classes) > 0 {
+			fmt.Fprintf(&sb, `<span class="%s">%s</span>`, escapeHTML(strings.Join(classes, " ")), escapeHTML(code[start:end]))
+		} else {
+			sb.WriteString(escapeHTML(code[start:end]))
+		}
+		cursor = end
+	}
+	sb.WriteString(escapeHTML(code[cursor:]))
+	return sb.String(), true
+}
+
+func scipKindBadge(kind scip.SymbolInformation_Kind) string {
+	label := scipKindLabel(kind)
+	if label == "" {
+		return ""
+	}
+	// The trailing newline keeps the badge in its own paragraph once hover
+	// documents are joined line by line.
+	return fmt.Sprintf("<span class=\"cire-kind-badge\" data-kind=\"%s\">%s</span>\n",
+		strings.ReplaceAll(label, " ", "-"), label)
+}
+
+// scipKindLabel turns kind names such as TypeParameter into "type parameter".
+func scipKindLabel(kind scip.SymbolInformation_Kind) string {
+	if kind == scip.SymbolInformation_UnspecifiedKind {
+		return ""
+	}
+	name, ok := scip.SymbolInformation_Kind_name[int32(kind)]
+	if !ok {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+func isSignatureFence(doc, signature string) bool {
+	doc = strings.TrimSpace(doc)
+	if !strings.HasPrefix(doc, "```") || !strings.HasSuffix(doc, "```") {
+		return false
+	}
+	_, body, ok := strings.Cut(doc, "\n")
+	if !ok {
+		return false
+	}
+	body = strings.TrimSuffix(body, "```")
+	return strings.TrimSpace(body) == signature
+}
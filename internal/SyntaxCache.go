@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/cockroachdb/errors"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// queryKind selects which query of a language to compile.
+type queryKind int
+
+const (
+	highlightQueryKind queryKind = iota
+	commentQueryKind
+	localsQueryKind
+	tagsQueryKind
+	injectionsQueryKind
+	docsQueryKind
+)
+
+type queryCacheKey struct {
+	language string
+	kind     queryKind
+}
+
+type queryCacheEntry struct {
+	once  sync.Once
+	query *sitter.Query
+	err   error
+}
+
+// Compiled queries are immutable once created and are shared by every file of
+// a language for the life of the process; each caller runs them with its own
+// QueryCursor.
+var (
+	queryCacheMu sync.Mutex
+	queryCache   = make(map[queryCacheKey]*queryCacheEntry)
+)
+
+// Parsers are reused per language instead of pooled through sync.Pool, which
+// would drop them without freeing the C allocation.
+var (
+	parserCacheMu sync.Mutex
+	parserCache   = make(map[string][]*sitter.Parser)
+)
+
+// SourceTree is one parse of a source file, shared by the highlight, comment,
+// locals and LSP passes. A tree must not be used from two goroutines at once;
+// hand each goroutine its own Clone, which is a cheap shallow copy.
+type SourceTree struct {
+	Language string // Canonical language name
+	Content  []byte
+	Tree     *sitter.Tree
+}
+
+// ParseSource parses sourceContent with a cached parser for language.
+func ParseSource(language string, sourceContent []byte) (*SourceTree, error) {
+	canonical, err := languages.CanonicalName(language)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := languages.GetConfig(canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.HasGrammar() {
+		return nil, errors.Newf("%s has no tree-sitter grammar", canonical)
+	}
+
+	parser := acquireParser(canonical, cfg.SitterLanguage)
+	tree := parser.Parse(sourceContent, nil)
+	releaseParser(canonical, parser)
+	if tree == nil {
+		return nil, errors.Newf("failed to parse %s source", canonical)
+	}
+
+	return &SourceTree{
+		Language: canonical,
+		Content:  sourceContent,
+		Tree:     tree,
+	}, nil
+}
+
+// hasGrammar reports whether language is parsed with tree-sitter rather than
+// lexed with Chroma.
+func hasGrammar(language string) bool {
+	cfg, err := languages.GetConfig(language)
+	return err == nil && cfg.HasGrammar()
+}
+
+func (s *SourceTree) RootNode() *sitter.Node {
+	return s.Tree.RootNode()
+}
+
+// Clone returns a copy of the tree that is safe to use on another goroutine.
+func (s *SourceTree) Clone() *SourceTree {
+	return &SourceTree{
+		Language: s.Language,
+		Content:  s.Content,
+		Tree:     s.Tree.Clone(),
+	}
+}
+
+func (s *SourceTree) Close() {
+	if s != nil && s.Tree != nil {
+		s.Tree.Close()
+	}
+}
+
+func acquireParser(language string, sitterLanguage *sitter.Language) *sitter.Parser {
+	parserCacheMu.Lock()
+	idle := parserCache[language]
+	if n := len(idle); n > 0 {
+		parser := idle[n-1]
+		parserCache[language] = idle[:n-1]
+		parserCacheMu.Unlock()
+		return parser
+	}
+	parserCacheMu.Unlock()
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(sitterLanguage)
+	return parser
+}
+
+func releaseParser(language string, parser *sitter.Parser) {
+	parser.Reset()
+	parserCacheMu.Lock()
+	parserCache[language] = append(parserCache[language], parser)
+	parserCacheMu.Unlock()
+}
+
+// cachedQuery returns the query of the given kind for language, compiling it
+// on first use. Languages without a query of that kind return nil.
+func cachedQuery(language string, kind queryKind) (*sitter.Query, error) {
+	canonical, err := languages.CanonicalName(language)
+	if err != nil {
+		return nil, err
+	}
+	key := queryCacheKey{language: canonical, kind: kind}
+
+	queryCacheMu.Lock()
+	entry, ok := queryCache[key]
+	if !ok {
+		entry = &queryCacheEntry{}
+		queryCache[key] = entry
+	}
+	queryCacheMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.query, entry.err = compileQuery(canonical, kind)
+	})
+	return entry.query, entry.err
+}
+
+func compileQuery(language string, kind queryKind) (*sitter.Query, error) {
+	cfg, err := languages.GetConfig(language)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.HasGrammar() {
+		// Chroma-lexed languages have no optional queries; only asking for
+		// their highlight or comment query is a mistake.
+		switch kind {
+		case localsQueryKind, tagsQueryKind, injectionsQueryKind, docsQueryKind:
+			return nil, nil
+		}
+		return nil, errors.Newf("%s has no tree-sitter grammar", language)
+	}
+
+	var source string
+	switch kind {
+	case highlightQueryKind:
+		content, err := queryFS.ReadFile("queries/" + cfg.QueryFileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read query file %s", cfg.QueryFileName)
+		}
+		source = string(content)
+	case commentQueryKind:
+		source, err = getCommentQuery(language)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get comment query for %s", language)
+		}
+	case localsQueryKind:
+		if cfg.LocalsQueryFileName == "" {
+			return nil, nil
+		}
+		content, err := queryFS.ReadFile("queries/locals/" + cfg.LocalsQueryFileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read locals query file %s", cfg.LocalsQueryFileName)
+		}
+		source = string(content)
+	case tagsQueryKind:
+		if cfg.TagsQueryFileName == "" {
+			return nil, nil
+		}
+		content, err := queryFS.ReadFile("queries/tags/" + cfg.TagsQueryFileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read tags query file %s", cfg.TagsQueryFileName)
+		}
+		source = string(content)
+	case injectionsQueryKind:
+		if cfg.InjectionsQueryFileName == "" {
+			return nil, nil
+		}
+		content, err := queryFS.ReadFile("queries/injections/" + cfg.InjectionsQueryFileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read injections query file %s", cfg.InjectionsQueryFileName)
+		}
+		source = string(content)
+	case docsQueryKind:
+		if cfg.DocsQueryFileName == "" {
+			return nil, nil
+		}
+		content, err := queryFS.ReadFile("queries/docs/" + cfg.DocsQueryFileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read docs query file %s", cfg.DocsQueryFileName)
+		}
+		source = string(content)
+	default:
+		return nil, errors.Newf("unknown query kind %d", kind)
+	}
+
+	query, queryErr := sitter.NewQuery(cfg.SitterLanguage, source)
+	if queryErr != nil {
+		return nil, errors.Wrapf(queryErr, "failed to create query for %s", language)
+	}
+	return query, nil
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCachedQueryCompilesOncePerLanguage(t *testing.T) {
+	first, err := cachedQuery("go", highlightQueryKind)
+	if err != nil {
+		t.Fatalf("cachedQuery returned error: %v", err)
+	}
+	second, err := cachedQuery("golang", highlightQueryKind)
+	if err != nil {
+		t.Fatalf("cachedQuery returned error: %v", err)
+	}
+	if first != second {
+		t.Fatalf("cachedQuery compiled the go highlight query twice")
+	}
+
+	comments, err := cachedQuery("go", commentQueryKind)
+	if err != nil {
+		t.Fatalf("cachedQuery returned error: %v", err)
+	}
+	if comments == first {
+		t.Fatalf("comment and highlight queries share one cache entry")
+	}
+}
+
+func TestSourceTreeIsSharedAcrossPasses(t *testing.T) {
+	source := []byte("package p\n\n// Add adds.\nfunc Add(a, b int) int { return a + b }\n")
+	want, err := NewHighlightAnalyzer("go").Analyze(source)
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+
+	tree, err := ParseSource("go", source)
+	if err != nil {
+		t.Fatalf("ParseSource returned error: %v", err)
+	}
+	defer tree.Close()
+
+	var wg sync.WaitGroup
+	results := make([][]TokenInfo, 4)
+	comments := make([][]CommentInfo, 4)
+	for i := range results {
+		clone := tree.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer clone.Close()
+			results[i], _ = NewHighlightAnalyzer("go").AnalyzeTree(clone)
+			comments[i], _ = NewCommentAnalyzer("go").AnalyzeTree(clone)
+		}()
+	}
+	wg.Wait()
+
+	for i := range results {
+		if !reflect.DeepEqual(results[i], want) {
+			t.Fatalf("highlight tokens from shared tree %d = %#v, want %#v", i, results[i], want)
+		}
+		if len(comments[i]) != 1 || comments[i][0].Content != "Add adds." {
+			t.Fatalf("comments from shared tree %d = %#v", i, comments[i])
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package internal
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
+	"github.com/sourcegraph/scip/bindings/go/scip"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TagsIndex Project-wide definitions from tree-sitter tags queries
+//
+// Used as the last resort for cross-file links when neither a language server
+// nor an index resolves a reference. Queries follow the tree-sitter tags
+// conventions: @name on the identifier, with @definition.<kind> or
+// @reference.<kind> on the surrounding node. Names are matched literally and
+// narrowed by qualifier, file and directory; a reference that still matches
+// several definitions is left unlinked rather than guessed.
+type TagsIndex struct {
+	mu          sync.RWMutex
+	definitions map[string][]TagDefinition
+}
+
+// TagDefinition is a definition found by a tags query.
+type TagDefinition struct {
+	Name     string
+	Kind     string // Suffix of the @definition capture, e.g. "function" or "class"
+	Scope    string // Enclosing class, module or receiver type, if any
+	Path     string
+	Language string
+	Range    scip.Range // Name of the definition
+	Body     scip.Range // Whole definition node
+}
+
+// TagsAnalyzer links the references of one file through a TagsIndex.
+type TagsAnalyzer struct {
+	index      *TagsIndex
+	language   string
+	sourcePath string
+}
+
+type tagReference struct {
+	name      string
+	kind      string
+	qualifier string
+	span      scip.Range
+}
+
+var tagQualifierPattern = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tagTypeKinds are definition kinds preferred for @reference.type and @reference.class.
+var tagTypeKinds = map[string]bool{
+	"class":     true,
+	"interface": true,
+	"type":      true,
+	"enum":      true,
+	"mixin":     true,
+	"module":    true,
+}
+
+func NewTagsIndex() *TagsIndex {
+	return &TagsIndex{
+		definitions: make(map[string][]TagDefinition),
+	}
+}
+
+// AddFile records the definitions of one source file. Languages without a
+// tags query or a grammar are skipped.
+func (idx *TagsIndex) AddFile(language string, sourcePath string, sourceContent []byte) error {
+	canonical, err := languages.CanonicalName(language)
+	if err != nil || !hasGrammar(canonical) {
+		return nil
+	}
+	tree, err := ParseSource(canonical, sourceContent)
+	if err != nil {
+		return err
+	}
+	defer tree.Close()
+	definitions, _, err := extractTags(tree, sourcePath)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, definition := range definitions {
+		idx.definitions[definition.Name] = append(idx.definitions[definition.Name], definition)
+	}
+	return nil
+}
+
+// Definitions returns every indexed definition with the given name.
+func (idx *TagsIndex) Definitions(name string) []TagDefinition {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return append([]TagDefinition(nil), idx.definitions[name]...)
+}
+
+// AllDefinitions returns every definition in the index, ordered by path
+// and position.
+func (idx *TagsIndex) AllDefinitions() []TagDefinition {
+	idx.mu.RLock()
+	var definitions []TagDefinition
+	for _, named := range idx.definitions {
+		definitions = append(definitions, named...)
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].Path != definitions[j].Path {
+			return definitions[i].Path < definitions[j].Path
+		}
+		return definitions[i].Range.Start.Line < definitions[j].Range.Start.Line ||
+			definitions[i].Range.Start.Line == definitions[j].Range.Start.Line && definitions[i].Range.Start.Character < definitions[j].Range.Start.Character
+	})
+	return definitions
+}
+
+// Resolve picks the definition a reference in sourcePath most likely points at.
+// qualifier is the receiver or package written before the name, if any.
+func (idx *TagsIndex) Resolve(language, sourcePath, name, kind, qualifier string) (TagDefinition, bool) {
+	var candidates []TagDefinition
+	for _, definition := range idx.Definitions(name) {
+		if tagLanguageFamily(definition.Language) == tagLanguageFamily(language) {
+			candidates = append(candidates, definition)
+		}
+	}
+
+	// The kind and the qualifier rule candidates out; where the definition
+	// lives only breaks ties between the ones left.
+	if kind == "type" || kind == "class" || kind == "implementation" {
+		candidates = filterTagCandidates(candidates, func(definition TagDefinition) bool {
+			return tagTypeKinds[definition.Kind]
+		})
+	}
+	if qualifier != "" {
+		candidates = filterTagCandidates(candidates, func(definition TagDefinition) bool {
+			return tagQualifierMatches(definition, qualifier)
+		})
+	}
+	candidates = narrowTagCandidates(candidates, func(definition TagDefinition) bool {
+		return definition.Path == sourcePath
+	})
+	candidates = narrowTagCandidates(candidates, func(definition TagDefinition) bool {
+		return filepath.Dir(definition.Path) == filepath.Dir(sourcePath)
+	})
+
+	if len(candidates) != 1 {
+		return TagDefinition{}, false
+	}
+	return candidates[0], true
+}
+
+// Analyzer returns an analyzer for one file of the project.
+func (idx *TagsIndex) Analyzer(language string, sourcePath string) *TagsAnalyzer {
+	return &TagsAnalyzer{
+		index:      idx,
+		language:   language,
+		sourcePath: sourcePath,
+	}
+}
+
+func (t *TagsAnalyzer) Analyze(sourceContent []byte) ([]TokenInfo, error) {
+	tree, err := ParseSource(t.language, sourceContent)
+	if err != nil {
+		return []TokenInfo{}, nil
+	}
+	defer tree.Close()
+	return t.AnalyzeTree(tree)
+}
+
+// AnalyzeTree links the references of an already parsed file.
+func (t *TagsAnalyzer) AnalyzeTree(tree *SourceTree) ([]TokenInfo, error) {
+	definitions, references, err := extractTags(tree, t.sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	language := tree.Language
+
+	tokens := make([]TokenInfo, 0, len(definitions)+len(references))
+	for _, definition := range definitions {
+		body := definition.Body
+		tokens = append(tokens, TokenInfo{
+			Symbol:         tagSymbolID(definition),
+			IsDefinition:   true,
+			Span:           definition.Range,
+			Definition:     &SourceLocation{Path: definition.Path, Range: definition.Range},
+			EnclosingRange: &body,
+			Source:         TokenSourceTags,
+		})
+	}
+	for _, reference := range references {
+		definition, ok := t.index.Resolve(language, t.sourcePath, reference.name, reference.kind, reference.qualifier)
+		if !ok {
+			continue
+		}
+		tokens = append(tokens, TokenInfo{
+			Symbol:      tagSymbolID(definition),
+			IsReference: true,
+			Span:        reference.span,
+			Definition:  &SourceLocation{Path: definition.Path, Range: definition.Range},
+			Source:      TokenSourceTags,
+		})
+	}
+	return tokens, nil
+}
+
+// extractTags runs the language's tags query over one file. Definitions claim
+// their name nodes first, so patterns such as `(type_identifier) @reference.type`
+// do not also report the name of a type declaration as a reference.
+func extractTags(tree *SourceTree, sourcePath string) ([]TagDefinition, []tagReference, error) {
+	query, err := cachedQuery(tree.Language, tagsQueryKind)
+	if err != nil {
+		return nil, nil, err
+	}
+	if query == nil {
+		return nil, nil, nil
+	}
+	language := tree.Language
+	sourceContent := tree.Content
+
+	type tagMatch struct {
+		name sitter.Node
+		node sitter.Node
+		kind string
+	}
+	var definitionMatches, referenceMatches []tagMatch
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	matches := qc.Matches(query, tree.RootNode(), sourceContent)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var current tagMatch
+		var hasName, isDefinition, isReference bool
+		for _, capture := range match.Captures {
+			captureName := query.CaptureNames()[capture.Index]
+			switch {
+			case captureName == "name":
+				current.name = capture.Node
+				hasName = true
+			case strings.HasPrefix(captureName, "definition."):
+				current.node = capture.Node
+				current.kind = strings.TrimPrefix(captureName, "definition.")
+				isDefinition = true
+			case strings.HasPrefix(captureName, "reference."):
+				current.node = capture.Node
+				current.kind = strings.TrimPrefix(captureName, "reference.")
+				isReference = true
+			}
+		}
+		if !hasName {
+			continue
+		}
+		if isDefinition {
+			definitionMatches = append(definitionMatches, current)
+		} else if isReference {
+			referenceMatches = append(referenceMatches, current)
+		}
+	}
+
+	claimed := make(map[uintptr]bool)
+	var definitions []TagDefinition
+	var definitionNodes []sitter.Node
+	for _, match := range definitionMatches {
+		if claimed[match.name.Id()] {
+			continue
+		}
+		claimed[match.name.Id()] = true
+		definitions = append(definitions, TagDefinition{
+			Name:     match.name.Utf8Text(sourceContent),
+			Kind:     match.kind,
+			Scope:    tagReceiverType(&match.node, sourceContent),
+			Path:     sourcePath,
+			Language: language,
+			Range:    localNodeSpan(&match.name),
+			Body:     localNodeSpan(&match.node),
+		})
+		definitionNodes = append(definitionNodes, match.node)
+	}
+	for i := range definitions {
+		if definitions[i].Scope == "" {
+			definitions[i].Scope = tagEnclosingScope(definitions, definitionNodes, i)
+		}
+	}
+
+	var references []tagReference
+	for _, match := range referenceMatches {
+		if claimed[match.name.Id()] {
+			continue
+		}
+		claimed[match.name.Id()] = true
+		references = append(references, tagReference{
+			name:      match.name.Utf8Text(sourceContent),
+			kind:      match.kind,
+			qualifier: tagQualifier(&match.name, sourceContent),
+			span:      localNodeSpan(&match.name),
+		})
+	}
+
+	return definitions, references, nil
+}
+
+// tagReceiverType returns the receiver type of a Go method declaration.
+func tagReceiverType(node *sitter.Node, sourceContent []byte) string {
+	receiver := node.ChildByFieldName("receiver")
+	if receiver == nil {
+		return ""
+	}
+	var find func(n *sitter.Node) string
+	find = func(n *sitter.Node) string {
+		if n.Kind() == "type_identifier" {
+			return n.Utf8Text(sourceContent)
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			if name := find(n.NamedChild(i)); name != "" {
+				return name
+			}
+		}
+		return ""
+	}
+	return find(receiver)
+}
+
+// tagEnclosingScope names the innermost other definition whose node contains
+// definition i, such as the class around a method.
+func tagEnclosingScope(definitions []TagDefinition, nodes []sitter.Node, i int) string {
+	scope := ""
+	var scopeSize uint
+	for j := range nodes {
+		if j == i || !tagTypeKinds[definitions[j].Kind] {
+			continue
+		}
+		if nodes[j].StartByte() > nodes[i].StartByte() || nodes[j].EndByte() < nodes[i].EndByte() {
+			continue
+		}
+		size := nodes[j].EndByte() - nodes[j].StartByte()
+		if scope == "" || size < scopeSize {
+			scope = definitions[j].Name
+			scopeSize = size
+		}
+	}
+	return scope
+}
+
+// tagQualifier returns the last identifier written before the name inside its
+// parent node, e.g. "strings" for strings.Cut or "Foo" for Foo::bar.
+func tagQualifier(name *sitter.Node, sourceContent []byte) string {
+	parent := name.Parent()
+	if parent == nil || parent.StartByte() >= name.StartByte() {
+		return ""
+	}
+	prefix := string(sourceContent[parent.StartByte():name.StartByte()])
+	prefix = strings.TrimSpace(prefix)
+	for _, separator := range []string{"?.", ".", "::", "->", "\\"} {
+		if strings.HasSuffix(prefix, separator) {
+			prefix = strings.TrimSpace(strings.TrimSuffix(prefix, separator))
+			qualifier := tagQualifierPattern.FindString(prefix)
+			switch qualifier {
+			case "self", "this", "Self", "super":
+				return ""
+			}
+			return qualifier
+		}
+	}
+	return ""
+}
+
+func tagQualifierMatches(definition TagDefinition, qualifier string) bool {
+	if definition.Scope == qualifier {
+		return true
+	}
+	if filepath.Base(filepath.Dir(definition.Path)) == qualifier {
+		return true
+	}
+	stem := strings.TrimSuffix(filepath.Base(definition.Path), filepath.Ext(definition.Path))
+	return stem == qualifier
+}
+
+// filterTagCandidates keeps the candidates that satisfy keep.
+func filterTagCandidates(candidates []TagDefinition, keep func(TagDefinition) bool) []TagDefinition {
+	var filtered []TagDefinition
+	for _, candidate := range candidates {
+		if keep(candidate) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// narrowTagCandidates keeps the candidates that satisfy keep, unless none do.
+func narrowTagCandidates(candidates []TagDefinition, keep func(TagDefinition) bool) []TagDefinition {
+	if narrowed := filterTagCandidates(candidates, keep); len(narrowed) > 0 {
+		return narrowed
+	}
+	return candidates
+}
+
+// tagLanguageFamily groups languages that commonly reference each other's
+// definitions, such as headers shared by C and C++.
+func tagLanguageFamily(language string) string {
+	switch language {
+	case "javascript", "typescript":
+		return "javascript"
+	case "c", "cpp":
+		return "c"
+	default:
+		return language
+	}
+}
+
+func tagSymbolID(definition TagDefinition) string {
+	return getSymbolID(fileURIForPath(definition.Path), int(definition.Range.Start.Line), int(definition.Range.Start.Character))
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTagsQueriesCompileForEveryLanguage(t *testing.T) {
+	for _, language := range localsTestLanguages {
+		t.Run(language, func(t *testing.T) {
+			if err := NewTagsIndex().AddFile(language, "/repo/file", []byte("")); err != nil {
+				t.Fatalf("AddFile returned error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTagsIndexLinksReferencesAcrossFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		files    map[string]string
+		source   string
+		// Each link is "reference line:col -> definition path line:col", zero-based.
+		want []string
+	}{
+		{
+			name:     "go functions and types in the same package",
+			language: "go",
+			files: map[string]string{
+				"/repo/p/types.go": "package p\n\ntype Config struct{}\n\nfunc Load() Config { return Config{} }\n",
+			},
+			source: "package p\n\nfunc run() {\n\tvar c Config = Load()\n\t_ = c\n}\n",
+			want: []string{
+				"3:7 -> /repo/p/types.go 2:5",
+				"3:16 -> /repo/p/types.go 4:5",
+			},
+		},
+		{
+			name:     "go package qualifier picks the imported package",
+			language: "go",
+			files: map[string]string{
+				"/repo/a/a.go": "package a\n\nfunc Open() {}\n",
+				"/repo/b/b.go": "package b\n\nfunc Open() {}\n",
+			},
+			source: "package main\n\nfunc main() { b.Open() }\n",
+			want:   []string{"2:16 -> /repo/b/b.go 2:5"},
+		},
+		{
+			name:     "go method receiver qualifies the method",
+			language: "go",
+			files: map[string]string{
+				"/repo/p/a.go": "package p\n\ntype A struct{}\n\nfunc (a *A) Close() {}\n",
+				"/repo/q/b.go": "package q\n\ntype B struct{}\n\nfunc (b B) Close() {}\n",
+			},
+			source: "package main\n\nfunc main() { B.Close(B{}) }\n",
+			want: []string{
+				"2:16 -> /repo/q/b.go 4:11",
+				"2:22 -> /repo/q/b.go 2:5",
+			},
+		},
+		{
+			name:     "python module qualifier",
+			language: "python",
+			files: map[string]string{
+				"/repo/pkg/util.py":  "def helper():\n    pass\n",
+				"/repo/pkg/other.py": "def helper():\n    pass\n",
+			},
+			source: "import util\n\nutil.helper()\n",
+			want:   []string{"2:5 -> /repo/pkg/util.py 0:4"},
+		},
+		{
+			name:     "ambiguous names stay unlinked",
+			language: "python",
+			files: map[string]string{
+				"/repo/a/x.py": "def helper():\n    pass\n",
+				"/repo/b/y.py": "def helper():\n    pass\n",
+			},
+			source: "helper()\n",
+			want:   nil,
+		},
+		{
+			name:     "qualifier that matches no definition stays unlinked",
+			language: "go",
+			files: map[string]string{
+				"/repo/a/a.go": "package a\n\nfunc Open() {}\n",
+			},
+			source: "package main\n\nfunc main() { f.Open() }\n",
+			want:   nil,
+		},
+		{
+			name:     "type reference does not link to a function",
+			language: "go",
+			files: map[string]string{
+				"/repo/p/p.go": "package p\n\nfunc Config() {}\n",
+			},
+			source: "package main\n\nvar c Config\n",
+			want:   nil,
+		},
+		{
+			name:     "other languages are not candidates",
+			language: "python",
+			files: map[string]string{
+				"/repo/lib.go": "package lib\n\nfunc helper() {}\n",
+			},
+			source: "helper()\n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := NewTagsIndex()
+			for path, content := range tt.files {
+				language := tt.language
+				if strings.HasSuffix(path, ".go") {
+					language = "go"
+				}
+				if err := index.AddFile(language, path, []byte(content)); err != nil {
+					t.Fatalf("AddFile(%s) returned error: %v", path, err)
+				}
+			}
+
+			tokens, err := index.Analyzer(tt.language, "/repo/main/main").Analyze([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("Analyze returned error: %v", err)
+			}
+
+			var got []string
+			for _, token := range tokens {
+				if token.IsReference {
+					got = append(got, formatTagLink(token))
+				}
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Fatalf("links = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagsAnalyzerDefinitionsCarryEnclosingRange(t *testing.T) {
+	source := "class Greeter:\n    def greet(self):\n        return 1\n"
+	index := NewTagsIndex()
+	if err := index.AddFile("python", "/repo/greeter.py", []byte(source)); err != nil {
+		t.Fatalf("AddFile returned error: %v", err)
+	}
+
+	definitions := index.Definitions("greet")
+	if len(definitions) != 1 || definitions[0].Scope != "Greeter" || definitions[0].Kind != "function" {
+		t.Fatalf("definitions = %#v, want greet scoped to Greeter", definitions)
+	}
+
+	tokens, err := index.Analyzer("python", "/repo/greeter.py").Analyze([]byte(source))
+	if err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("tokens = %#v, want two definitions", tokens)
+	}
+	method := tokens[1]
+	if !method.IsDefinition || method.Definition == nil || method.Definition.Path != "/repo/greeter.py" {
+		t.Fatalf("method token = %#v", method)
+	}
+	if method.EnclosingRange == nil || method.EnclosingRange.Start.Line != 1 || method.EnclosingRange.End.Line != 2 {
+		t.Fatalf("method enclosing range = %#v, want lines 1-2", method.EnclosingRange)
+	}
+}
+
+func formatTagLink(token TokenInfo) string {
+	definition := token.Definition.Range.Start
+	return fmt.Sprintf("%d:%d -> %s %d:%d", token.Span.Start.Line, token.Span.Start.Character,
+		token.Definition.Path, definition.Line, definition.Character)
+}
@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// TokenStream is the raw output of one analyzer, named after it.
+type TokenStream struct {
+	Analyzer string
+	Tokens   []TokenInfo
+}
+
+// TokenDebugGenerator writes a standalone HTML page that shows, line by line,
+// the tokens each analyzer produced for a file next to the tokens
+// MergeSplitTokens made of them, for finding which of them a wrong hover or a
+// missing link came from.
+type TokenDebugGenerator struct {
+	sourceLines []string
+}
+
+func NewTokenDebugGenerator(sourceLines []string) *TokenDebugGenerator {
+	return &TokenDebugGenerator{sourceLines: sourceLines}
+}
+
+// mergedStreamName names the merged tokens among the streams of the page.
+const mergedStreamName = "merged"
+
+// GenerateTokenDebugHTML renders the inspector page for the file at title.
+func (g *TokenDebugGenerator) GenerateTokenDebugHTML(title string, streams []TokenStream, merged []TokenInfo) string {
+	all := append(append([]TokenStream(nil), streams...), TokenStream{Analyzer: mergedStreamName, Tokens: merged})
+
+	// Tokens are listed under the line they start on.
+	byLine := make([]map[int][]TokenInfo, len(g.sourceLines))
+	for i, stream := range all {
+		for _, token := range stream.Tokens {
+			line := int(token.Span.Start.Line)
+			if line < 0 || line >= len(g.sourceLines) {
+				continue
+			}
+			if byLine[line] == nil {
+				byLine[line] = make(map[int][]TokenInfo)
+			}
+			byLine[line][i] = append(byLine[line][i], token)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!doctype html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Tokens of %s</title>\n", escapeHTML(title))
+	sb.WriteString("<style>\n" + tokenDebugCSS + "</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<header>\n<h1>Tokens of <code>%s</code></h1>\n<form class=\"streams\">\n", escapeHTML(title))
+	for i, stream := range all {
+		fmt.Fprintf(&sb, "<label class=\"stream-%d\"><input type=\"checkbox\" data-stream=\"%d\" checked> %s <span class=\"count\">%d</span></label>\n",
+			i%len(tokenDebugPalette), i, escapeHTML(stream.Analyzer), len(stream.Tokens))
+	}
+	sb.WriteString("<label><input type=\"checkbox\" data-empty-lines> lines without tokens</label>\n</form>\n</header>\n<main>\n")
+
+	for line, source := range g.sourceLines {
+		tokens := byLine[line]
+		fmt.Fprintf(&sb, "<section class=\"line\" id=\"L%d\"", line+1)
+		if len(tokens) == 0 {
+			sb.WriteString(" data-empty")
+		}
+		fmt.Fprintf(&sb, ">\n<pre class=\"source\"><a class=\"number\" href=\"#L%d\">%d</a>%s</pre>\n", line+1, line+1, g.sourceLineHTML(source))
+		if len(tokens) == 0 {
+			sb.WriteString("</section>\n")
+			continue
+		}
+
+		sb.WriteString("<table>\n<thead><tr><th>analyzer</th><th>span</th><th>text</th><th>classes</th><th>symbol</th><th>definition</th><th>link</th><th>hover</th></tr></thead>\n<tbody>\n")
+		for i := range all {
+			for _, token := range tokens[i] {
+				g.writeTokenRow(&sb, i, all[i].Analyzer, token)
+			}
+		}
+		sb.WriteString("</tbody>\n</table>\n</section>\n")
+	}
+
+	sb.WriteString("</main>\n<script>\n" + tokenDebugScript + "</script>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// sourceLineHTML escapes a source line, one span per character so rows can
+// mark the columns their token covers.
+func (g *TokenDebugGenerator) sourceLineHTML(source string) string {
+	var sb strings.Builder
+	for column, r := range []rune(source) {
+		fmt.Fprintf(&sb, "<span data-column=\"%d\">%s</span>", column, escapeHTML(string(r)))
+	}
+	return sb.String()
+}
+
+// writeAnalyzerCell names the stream of a row. Merged tokens also list the
+// analyzers they were merged from, with the one whose link won in bold.
+func (g *TokenDebugGenerator) writeAnalyzerCell(sb *strings.Builder, analyzer string, token TokenInfo) {
+	fmt.Fprintf(sb, "<td><span class=\"analyzer\">%s</span>", escapeHTML(analyzer))
+	if analyzer == mergedStreamName && len(token.Sources) > 0 {
+		sb.WriteString(" <span class=\"sources\">")
+		for i, source := range token.Sources {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			if source == token.Source && len(token.Sources) > 1 {
+				fmt.Fprintf(sb, "<strong>%s</strong>", escapeHTML(string(source)))
+				continue
+			}
+			sb.WriteString(escapeHTML(string(source)))
+		}
+		sb.WriteString("</span>")
+	}
+	sb.WriteString("</td>")
+}
+
+func (g *TokenDebugGenerator) writeTokenRow(sb *strings.Builder, stream int, analyzer string, token TokenInfo) {
+	end := token.Span.End
+	if end.Line != token.Span.Start.Line {
+		// Mark the rest of the start line for tokens that run past it.
+		end = scip.Position{Line: token.Span.Start.Line, Character: int32(len([]rune(g.sourceLines[token.Span.Start.Line])))}
+	}
+	fmt.Fprintf(sb, "<tr class=\"stream-%d\" data-stream=\"%d\" data-line=\"%d\" data-start=\"%d\" data-end=\"%d\">",
+		stream%len(tokenDebugPalette), stream, token.Span.Start.Line+1, token.Span.Start.Character, end.Character)
+
+	g.writeAnalyzerCell(sb, analyzer, token)
+	fmt.Fprintf(sb, "<td class=\"span\">%s</td>", formatTokenDebugRange(token.Span))
+
+	text := getSourceFromSpan(g.sourceLines, token.Span)
+	if token.InlayHintLabel != "" {
+		text = token.InlayHintLabel
+		sb.WriteString("<td><code class=\"inlay\">")
+	} else {
+		sb.WriteString("<td><code>")
+	}
+	fmt.Fprintf(sb, "%s</code></td>", escapeHTML(text))
+
+	fmt.Fprintf(sb, "<td>%s</td>", escapeHTML(strings.Join(token.Classes(), " ")))
+
+	var symbol []string
+	if token.Symbol != "" {
+		symbol = append(symbol, "<code>"+escapeHTML(token.Symbol)+"</code>")
+	}
+	if token.IsDefinition {
+		symbol = append(symbol, "<span class=\"badge\">definition</span>")
+	}
+	if token.IsReference {
+		symbol = append(symbol, "<span class=\"badge\">reference</span>")
+	}
+	fmt.Fprintf(sb, "<td>%s</td>", strings.Join(symbol, " "))
+
+	fmt.Fprintf(sb, "<td>%s</td>", formatTokenDebugDefinition(token))
+
+	var link []string
+	if token.Href != "" {
+		link = append(link, "href <code>"+escapeHTML(token.Href)+"</code>")
+	}
+	if token.Anchor != "" {
+		link = append(link, "anchor <code>"+escapeHTML(token.Anchor)+"</code>")
+	}
+	fmt.Fprintf(sb, "<td>%s</td>", strings.Join(link, "<br>"))
+
+	if hover := strings.Join(token.Document, "\n\n"); hover != "" {
+		summary, _, _ := strings.Cut(strings.TrimSpace(hover), "\n")
+		fmt.Fprintf(sb, "<td><details><summary>%s</summary><pre>%s</pre></details></td>", escapeHTML(summary), escapeHTML(hover))
+	} else {
+		sb.WriteString("<td></td>")
+	}
+	sb.WriteString("</tr>\n")
+}
+
+// formatTokenDebugRange writes a range one-based, as editors show it.
+func formatTokenDebugRange(span scip.Range) string {
+	start := fmt.Sprintf("%d:%d", span.Start.Line+1, span.Start.Character+1)
+	if span.End.Line == span.Start.Line {
+		return fmt.Sprintf("%s–%d", start, span.End.Character+1)
+	}
+	return fmt.Sprintf("%s–%d:%d", start, span.End.Line+1, span.End.Character+1)
+}
+
+func formatTokenDebugDefinition(token TokenInfo) string {
+	var parts []string
+	if definition := token.Definition; definition != nil {
+		location := definition.Path
+		if location == "" {
+			location = definition.URI
+		}
+		if location != "" {
+			parts = append(parts, fmt.Sprintf("<code>%s</code> %s", escapeHTML(location), formatTokenDebugRange(definition.Range)))
+		}
+		if pkg := definition.Package; pkg != nil {
+			parts = append(parts, fmt.Sprintf("package <code>%s</code>", escapeHTML(strings.TrimSpace(pkg.Manager+" "+pkg.Module+" "+pkg.Version))))
+		}
+	}
+	if token.EnclosingRange != nil {
+		parts = append(parts, "body "+formatTokenDebugRange(*token.EnclosingRange))
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// SortTokenStreams orders each stream's tokens by span, as the page lists
+// them, leaving the analyzers' own slices alone.
+func SortTokenStreams(streams []TokenStream) []TokenStream {
+	sorted := make([]TokenStream, len(streams))
+	for i, stream := range streams {
+		tokens := append([]TokenInfo(nil), stream.Tokens...)
+		sort.SliceStable(tokens, func(a, b int) bool {
+			return scip.Position.Compare(tokens[a].Span.Start, tokens[b].Span.Start) < 0
+		})
+		sorted[i] = TokenStream{Analyzer: stream.Analyzer, Tokens: tokens}
+	}
+	return sorted
+}
+
+// tokenDebugPalette colors each stream's rows apart.
+var tokenDebugPalette = []string{"#2563eb", "#16a34a", "#d97706", "#9333ea", "#dc2626", "#0891b2", "#4b5563"}
+
+var tokenDebugCSS = func() string {
+	var sb strings.Builder
+	sb.WriteString(`body { margin: 0; font: 13px/1.45 system-ui, sans-serif; color: #111827; background: #fff; }
+header { position: sticky; top: 0; z-index: 1; padding: 8px 16px; background: #f9fafb; border-bottom: 1px solid #e5e7eb; }
+h1 { margin: 0 0 6px; font-size: 15px; }
+.streams { display: flex; flex-wrap: wrap; gap: 6px 16px; }
+.streams label { cursor: pointer; }
+.streams label[class] { border-left: 4px solid; padding-left: 6px; }
+.count { color: #6b7280; }
+main { padding: 8px 16px 48px; }
+.line { border-bottom: 1px solid #f3f4f6; padding: 2px 0; }
+.line[data-empty] { display: none; }
+body.show-empty .line[data-empty] { display: block; }
+.source { margin: 0; font: 13px/1.5 ui-monospace, monospace; white-space: pre; }
+.number { display: inline-block; width: 5ch; margin-right: 1ch; color: #9ca3af; text-align: right; text-decoration: none; }
+.source .marked { background: #fde68a; }
+table { width: 100%; margin: 2px 0 6px; border-collapse: collapse; font-size: 12px; }
+th { text-align: left; font-weight: 600; color: #6b7280; }
+th, td { padding: 1px 6px; vertical-align: top; }
+tbody tr { border-left: 4px solid; }
+tbody tr:hover { background: #f3f4f6; }
+tr.hidden { display: none; }
+code { font: 12px ui-monospace, monospace; }
+.inlay { color: #6b7280; font-style: italic; }
+.analyzer { font-weight: 600; }
+.sources { color: #6b7280; }
+.badge { padding: 0 4px; border-radius: 3px; background: #e5e7eb; }
+details pre { max-width: 60ch; margin: 4px 0; white-space: pre-wrap; }
+`)
+	for i, color := range tokenDebugPalette {
+		fmt.Fprintf(&sb, ".stream-%d { border-color: %s; }\n", i, color)
+	}
+	return sb.String()
+}()
+
+// tokenDebugScript toggles streams and empty lines, and marks the columns of
+// the token under the pointer.
+const tokenDebugScript = `document.querySelectorAll("input[data-stream]").forEach((input) => {
+  input.addEventListener("change", () => {
+    document.querySelectorAll("tr[data-stream=\"" + input.dataset.stream + "\"]").forEach((row) => {
+      row.classList.toggle("hidden", !input.checked);
+    });
+  });
+});
+document.querySelector("input[data-empty-lines]").addEventListener("change", (event) => {
+  document.body.classList.toggle("show-empty", event.target.checked);
+});
+const mark = (row, on) => {
+  const source = document.getElementById("L" + row.dataset.line).querySelector(".source");
+  const start = Number(row.dataset.start);
+  const end = Math.max(Number(row.dataset.end), start + 1);
+  source.querySelectorAll("[data-column]").forEach((column) => {
+    const at = Number(column.dataset.column);
+    column.classList.toggle("marked", on && at >= start && at < end);
+  });
+};
+document.querySelectorAll("tr[data-line]").forEach((row) => {
+  row.addEventListener("mouseenter", () => mark(row, true));
+  row.addEventListener("mouseleave", () => mark(row, false));
+});
+`
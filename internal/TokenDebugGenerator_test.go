@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+func TestGenerateTokenDebugHTML(t *testing.T) {
+	sourceLines := strings.Split("package main\n\nfunc f() { x := `<a\nb>` }", "\n")
+
+	keyword := sourcedTestToken(TokenSourceSyntax, "", "keyword", 0, 7)
+	function := sourcedTestToken(TokenSourceSCIP, "scip-go gomod main . f().", "", 5, 6)
+	function.Span.Start.Line, function.Span.End.Line = 2, 2
+	function.IsDefinition = true
+	function.Document = []string{"```go\nfunc f()\n```", "Does <nothing>."}
+	function.Definition = &SourceLocation{Path: "main.go", Range: scip.Range{Start: scip.Position{Line: 2, Character: 5}, End: scip.Position{Line: 2, Character: 6}}}
+	raw := sourcedTestToken(TokenSourceSyntax, "", "string", 16, 2)
+	raw.Span = scip.Range{Start: scip.Position{Line: 2, Character: 16}, End: scip.Position{Line: 3, Character: 2}}
+
+	streams := []TokenStream{
+		{Analyzer: "syntax", Tokens: []TokenInfo{keyword, raw}},
+		{Analyzer: "scip", Tokens: []TokenInfo{function}},
+	}
+	merged := []TokenInfo{keyword, function}
+	merged[1].Sources = []TokenSource{TokenSourceSyntax, TokenSourceSCIP}
+
+	html := NewTokenDebugGenerator(sourceLines).GenerateTokenDebugHTML("main.go", streams, merged)
+
+	for _, want := range []string{
+		`<input type="checkbox" data-stream="0" checked> syntax <span class="count">2</span>`,
+		`<input type="checkbox" data-stream="2" checked> merged <span class="count">2</span>`,
+		`<section class="line" id="L2" data-empty>`,
+		`<tr class="stream-1" data-stream="1" data-line="3" data-start="5" data-end="6"><td><span class="analyzer">scip</span></td><td class="span">3:6–7</td>`,
+		`<span class="analyzer">merged</span> <span class="sources">syntax <strong>scip</strong></span></td>`,
+		`<code>scip-go gomod main . f().</code> <span class="badge">definition</span>`,
+		`<code>main.go</code> 3:6–7`,
+		"<summary>```go</summary><pre>```go\nfunc f()\n```\n\nDoes &lt;nothing&gt;.</pre>",
+		// A token running past its line marks the rest of it and shows its end.
+		`data-line="3" data-start="16" data-end="19"`,
+		`<td class="span">3:17–4:3</td><td><code>` + "`&lt;a\nb&gt;" + `</code></td>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("token inspector does not contain %q", want)
+		}
+	}
+	if strings.Contains(html, "<nothing>") || strings.Contains(html, "`<a") {
+		t.Error("token inspector does not escape hover text and source")
+	}
+}
+
+func TestSortTokenStreamsLeavesAnalyzerTokensAlone(t *testing.T) {
+	tokens := []TokenInfo{
+		sourcedTestToken(TokenSourceSyntax, "", "b", 4, 5),
+		sourcedTestToken(TokenSourceSyntax, "", "a", 0, 1),
+	}
+
+	sorted := SortTokenStreams([]TokenStream{{Analyzer: "syntax", Tokens: tokens}})
+
+	if got := sorted[0].Tokens[0].HighlightClass; got != "a" {
+		t.Fatalf("first sorted token = %q, want a", got)
+	}
+	if tokens[0].HighlightClass != "b" {
+		t.Fatal("SortTokenStreams sorted the analyzer's own tokens")
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/cockroachdb/errors"
@@ -12,9 +13,10 @@ type WithSpan interface {
 }
 
 type SourceLocation struct {
-	URI   string
-	Path  string
-	Range scip.Range
+	URI     string
+	Path    string
+	Range   scip.Range
+	Package *ExternalPackage // Dependency that owns the definition, when known
 }
 
 // TokenInfo represents information about a symbol in code, including its position and attributes
@@ -27,13 +29,153 @@ type TokenInfo struct {
 	InlayHintLabel string     // Inlay hint text
 	Span           scip.Range // Position range of the symbol in code
 	Definition     *SourceLocation
+	EnclosingRange *scip.Range // Whole body of a definition, e.g. a function or type
 	Href           string
 	Anchor         string
+	// Source is the analyzer that produced the token. A merged segment takes
+	// the source of the token whose link it keeps, or of its last class when
+	// nothing links it, and Sources lists every analyzer of the tokens over
+	// it.
+	Source  TokenSource
+	Sources []TokenSource
+	// HighlightClasses holds every class of a merged segment, the lowest
+	// precedence first; HighlightClass is the last of them.
+	HighlightClasses []string
+}
+
+// TokenSource names the analyzer that produced a token.
+type TokenSource string
+
+const (
+	TokenSourceTags   TokenSource = "tags"
+	TokenSourceSyntax TokenSource = "syntax"
+	TokenSourceLocals TokenSource = "locals"
+	TokenSourceLSIF   TokenSource = "lsif"
+	TokenSourceSCIP   TokenSource = "scip"
+	TokenSourceLSP    TokenSource = "lsp"
+)
+
+// tokenSourcePrecedence orders analyzers from least to most trusted: syntax
+// below semantics, and a running language server above a prebuilt index.
+// Tokens without a source rank lowest.
+var tokenSourcePrecedence = []TokenSource{
+	TokenSourceTags,
+	TokenSourceSyntax,
+	TokenSourceLocals,
+	TokenSourceLSIF,
+	TokenSourceSCIP,
+	TokenSourceLSP,
+}
+
+func (s TokenSource) precedence() int {
+	return slices.Index(tokenSourcePrecedence, s) + 1
+}
+
+// Classes returns the token's highlight classes, the lowest precedence first.
+func (t TokenInfo) Classes() []string {
+	if len(t.HighlightClasses) > 0 {
+		return t.HighlightClasses
+	}
+	if t.HighlightClass != "" {
+		return []string{t.HighlightClass}
+	}
+	return nil
+}
+
+// LinkStrategy picks which of the tokens over a segment links it.
+type LinkStrategy string
+
+const (
+	// LinkByPrecedence takes the link of the most trusted analyzer, and of
+	// its tokens the innermost.
+	LinkByPrecedence LinkStrategy = "precedence"
+	// LinkByInnermost takes the link of the narrowest token, such as a
+	// method name within a qualified call, whichever analyzer produced it.
+	// Analyzer precedence breaks ties.
+	LinkByInnermost LinkStrategy = "innermost"
+)
+
+// LinkPolicy chooses the link and definition of a merged segment when several
+// analyzers link it.
+type LinkPolicy struct {
+	Strategy LinkStrategy
+	// Precedence ranks analyzers for links, the most trusted first. Empty, it
+	// is the order classes use: lsp, scip, lsif, locals, tags. Analyzers it
+	// leaves out rank below those it lists.
+	Precedence []TokenSource
+}
+
+// Validate reports a strategy or analyzer the policy does not know.
+func (policy LinkPolicy) Validate() error {
+	switch policy.Strategy {
+	case "", LinkByPrecedence, LinkByInnermost:
+	default:
+		return errors.Newf("unknown link strategy %q", policy.Strategy)
+	}
+	for i, source := range policy.Precedence {
+		if source.precedence() == 0 {
+			return errors.Newf("unknown analyzer %q in link precedence", source)
+		}
+		if slices.Contains(policy.Precedence[:i], source) {
+			return errors.Newf("analyzer %q appears twice in link precedence", source)
+		}
+	}
+	return nil
+}
+
+// linkPrecedence ranks a source for links under the policy, higher winning.
+func (p LinkPolicy) linkPrecedence(source TokenSource) int {
+	if len(p.Precedence) == 0 {
+		return source.precedence()
+	}
+	if i := slices.Index(p.Precedence, source); i >= 0 {
+		return len(p.Precedence) - i
+	}
+	return 0
+}
+
+// linksOver reports whether a's link wins over b's, of two tokens over the
+// same segment where b was opened first.
+func (p LinkPolicy) linksOver(a TokenInfo, b TokenInfo) bool {
+	rankA, rankB := p.linkPrecedence(a.Source), p.linkPrecedence(b.Source)
+	if p.Strategy == LinkByInnermost {
+		if c := compareTokenWidth(a, b); c != 0 {
+			return c < 0
+		}
+		return rankA >= rankB
+	}
+	if rankA != rankB {
+		return rankA > rankB
+	}
+	// Of one analyzer's tokens, the one opened later is inner or later in
+	// its output.
+	return true
+}
+
+// compareTokenWidth compares how tightly two tokens over one segment wrap it:
+// negative if a is the narrower.
+func compareTokenWidth(a TokenInfo, b TokenInfo) int {
+	if c := scip.Position.Compare(a.Span.Start, b.Span.Start); c != 0 {
+		return -c
+	}
+	return scip.Position.Compare(a.Span.End, b.Span.End)
+}
+
+func (t TokenInfo) hasLink() bool {
+	return t.Symbol != "" || t.Definition != nil || t.Href != "" || t.Anchor != "" || t.IsReference || t.IsDefinition
 }
 
 type CommentInfo struct {
 	Content string
 	Span    scip.Range
+	// Doc is the structured form of Content when it uses doc comment tags.
+	Doc *DocComment
+	// FrontMatter is the YAML that opened the file's leading comment, which
+	// Content no longer holds.
+	FrontMatter string
+	// RegionMarker marks a code region marker line, such as // gocire:hide,
+	// which is neither prose nor rendered code; Content holds it as written.
+	RegionMarker bool
 }
 
 func (t TokenInfo) GetSpan() scip.Range {
@@ -44,9 +186,21 @@ func (c CommentInfo) GetSpan() scip.Range {
 	return c.Span
 }
 
-// SortBySpan sorts tokens primarily by start position, then by end position
+// Prose is the Markdown to render for the comment: its structured doc when it
+// has one, its content otherwise. classAttr is "class" for HTML and
+// "className" for MDX.
+func (c CommentInfo) Prose(classAttr string) string {
+	if c.Doc != nil {
+		return c.Doc.Markdown(classAttr)
+	}
+	return c.Content
+}
+
+// SortBySpan sorts tokens primarily by start position, then by end position.
+// Tokens with equal spans keep their original order, which is how later query
+// patterns of one analyzer take precedence when merged.
 func SortBySpan[T WithSpan](tokens []T) {
-	sort.Slice(tokens, func(i, j int) bool {
+	sort.SliceStable(tokens, func(i, j int) bool {
 		s := scip.Position.Compare(tokens[i].GetSpan().Start, tokens[j].GetSpan().Start)
 		if s != 0 {
 			return s < 0
@@ -58,6 +212,12 @@ func SortBySpan[T WithSpan](tokens []T) {
 
 // MergeSplitTokens merges overlapping tokens and splits them at intersection points to eliminate overlaps
 func MergeSplitTokens(tokens []TokenInfo) ([]TokenInfo, error) {
+	return MergeSplitTokensWithPolicy(tokens, LinkPolicy{})
+}
+
+// MergeSplitTokensWithPolicy is MergeSplitTokens with the link policy a
+// project configures.
+func MergeSplitTokensWithPolicy(tokens []TokenInfo, policy LinkPolicy) ([]TokenInfo, error) {
 	if len(tokens) == 0 {
 		return []TokenInfo{}, nil
 	}
@@ -76,7 +236,7 @@ func MergeSplitTokens(tokens []TokenInfo) ([]TokenInfo, error) {
 		}
 
 		if scip.Position.Compare(curPos, nextSplit) < 0 {
-			segment := createSegment(curPos, nextSplit, activeTokens)
+			segment := createSegment(curPos, nextSplit, activeTokens, policy)
 			if segment != nil {
 				result = append(result, *segment)
 			}
@@ -160,35 +320,67 @@ func findNextEnd(activeTokens []TokenInfo) scip.Position {
 	return earliest
 }
 
-// createSegment creates a new TokenInfo segment by merging properties from active tokens within the given range
-func createSegment(start scip.Position, end scip.Position, activeTokens []TokenInfo) *TokenInfo {
+// createSegment creates a new TokenInfo segment by merging properties from
+// active tokens within the given range. The segment keeps the classes of all
+// of them in analyzer precedence, and the link of the one the link policy
+// picks.
+func createSegment(start scip.Position, end scip.Position, activeTokens []TokenInfo, policy LinkPolicy) *TokenInfo {
 	var result TokenInfo
 	if len(activeTokens) == 0 {
 		return nil
 	}
 
-	for _, token := range activeTokens {
-		if token.Symbol != "" {
-			result.Symbol = token.Symbol
-		}
-		if token.HighlightClass != "" {
-			result.HighlightClass = token.HighlightClass
-		}
+	var link *TokenInfo
+	for i, token := range activeTokens {
 		if len(token.Document) > 0 {
 			result.Document = append(result.Document, token.Document...)
 		}
-		if token.Definition != nil {
-			definition := *token.Definition
+		if token.EnclosingRange != nil {
+			enclosing := *token.EnclosingRange
+			result.EnclosingRange = &enclosing
+		}
+		if token.hasLink() && (link == nil || policy.linksOver(token, *link)) {
+			link = &activeTokens[i]
+		}
+	}
+	if link != nil {
+		result.Symbol = link.Symbol
+		if link.Definition != nil {
+			definition := *link.Definition
 			result.Definition = &definition
 		}
-		if token.Href != "" {
-			result.Href = token.Href
+		result.Href = link.Href
+		result.Anchor = link.Anchor
+		result.IsReference = link.IsReference
+		result.IsDefinition = link.IsDefinition
+	}
+
+	// Later tokens of one analyzer are inner captures or later patterns, so
+	// they stay after earlier ones.
+	byPrecedence := slices.Clone(activeTokens)
+	slices.SortStableFunc(byPrecedence, func(a, b TokenInfo) int {
+		return a.Source.precedence() - b.Source.precedence()
+	})
+	for _, token := range byPrecedence {
+		for _, class := range token.Classes() {
+			result.HighlightClasses = append(slices.DeleteFunc(result.HighlightClasses, func(existing string) bool {
+				return existing == class
+			}), class)
+			if link == nil {
+				result.Source = token.Source
+			}
 		}
-		if token.Anchor != "" {
-			result.Anchor = token.Anchor
+		for _, source := range append([]TokenSource{token.Source}, token.Sources...) {
+			if source != "" && !slices.Contains(result.Sources, source) {
+				result.Sources = append(result.Sources, source)
+			}
 		}
-		result.IsReference = result.IsReference || token.IsReference
-		result.IsDefinition = result.IsDefinition || token.IsDefinition
+	}
+	if n := len(result.HighlightClasses); n > 0 {
+		result.HighlightClass = result.HighlightClasses[n-1]
+	}
+	if link != nil {
+		result.Source = link.Source
 	}
 
 	result.Span = scip.Range{Start: start, End: end}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/sourcegraph/scip/bindings/go/scip"
@@ -361,3 +362,114 @@ func BenchmarkMergeSplitTokens(b *testing.B) {
 		}
 	}
 }
+
+func sourcedTestToken(source TokenSource, symbol string, highlightClass string, startChar, endChar int32) TokenInfo {
+	token := createTestToken(symbol, symbol != "", false, highlightClass, 0, startChar, 0, endChar)
+	token.Source = source
+	return token
+}
+
+func TestMergeSplitTokensKeepsClassesInAnalyzerPrecedence(t *testing.T) {
+	tokens := []TokenInfo{
+		sourcedTestToken(TokenSourceLSP, "", "variable.parameter", 0, 10),
+		sourcedTestToken(TokenSourceSyntax, "", "type", 0, 10),
+		sourcedTestToken(TokenSourceSyntax, "", "function.method", 4, 8),
+		sourcedTestToken(TokenSourceSyntax, "", "type", 4, 8),
+	}
+
+	result, err := MergeSplitTokens(tokens)
+	if err != nil {
+		t.Fatalf("MergeSplitTokens returned error: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected 3 segments, got %d: %#v", len(result), result)
+	}
+
+	want := [][]string{
+		{"type", "variable.parameter"},
+		{"function.method", "type", "variable.parameter"},
+		{"type", "variable.parameter"},
+	}
+	for i, segment := range result {
+		if !slices.Equal(segment.HighlightClasses, want[i]) {
+			t.Errorf("segment %d HighlightClasses = %q, want %q", i, segment.HighlightClasses, want[i])
+		}
+		if segment.HighlightClass != "variable.parameter" {
+			t.Errorf("segment %d HighlightClass = %q, want the semantic class", i, segment.HighlightClass)
+		}
+	}
+}
+
+func TestMergeSplitTokensRecordsSources(t *testing.T) {
+	tokens := []TokenInfo{
+		sourcedTestToken(TokenSourceSyntax, "", "variable", 0, 10),
+		sourcedTestToken(TokenSourceSCIP, "scip-go . pkg/x", "", 0, 4),
+		sourcedTestToken(TokenSourceLocals, "local 1", "", 0, 4),
+	}
+
+	result, err := MergeSplitTokens(tokens)
+	if err != nil {
+		t.Fatalf("MergeSplitTokens returned error: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 segments, got %d: %#v", len(result), result)
+	}
+
+	if result[0].Source != TokenSourceSCIP {
+		t.Errorf("linked segment Source = %q, want the source of its link", result[0].Source)
+	}
+	if want := []TokenSource{TokenSourceSyntax, TokenSourceLocals, TokenSourceSCIP}; !slices.Equal(result[0].Sources, want) {
+		t.Errorf("linked segment Sources = %q, want %q", result[0].Sources, want)
+	}
+	if result[1].Source != TokenSourceSyntax {
+		t.Errorf("unlinked segment Source = %q, want the source of its class", result[1].Source)
+	}
+	if want := []TokenSource{TokenSourceSyntax}; !slices.Equal(result[1].Sources, want) {
+		t.Errorf("unlinked segment Sources = %q, want %q", result[1].Sources, want)
+	}
+}
+
+func TestMergeSplitTokensPicksLinkByPolicy(t *testing.T) {
+	tokens := []TokenInfo{
+		sourcedTestToken(TokenSourceLSP, "lsp", "", 0, 10),
+		sourcedTestToken(TokenSourceSCIP, "scip", "", 0, 10),
+		sourcedTestToken(TokenSourceTags, "tags", "", 4, 8),
+	}
+
+	tests := []struct {
+		name   string
+		policy LinkPolicy
+		want   []string
+	}{
+		{"default precedence", LinkPolicy{}, []string{"lsp", "lsp", "lsp"}},
+		{"innermost", LinkPolicy{Strategy: LinkByInnermost}, []string{"lsp", "tags", "lsp"}},
+		{"configured precedence", LinkPolicy{Precedence: []TokenSource{TokenSourceSCIP, TokenSourceLSP}}, []string{"scip", "scip", "scip"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatalf("Validate returned error: %v", err)
+			}
+			result, err := MergeSplitTokensWithPolicy(slices.Clone(tokens), tt.policy)
+			if err != nil {
+				t.Fatalf("MergeSplitTokensWithPolicy returned error: %v", err)
+			}
+			var got []string
+			for _, segment := range result {
+				got = append(got, segment.Symbol)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("segment symbols = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkPolicyValidateRejectsUnknownAnalyzer(t *testing.T) {
+	if err := (LinkPolicy{Precedence: []TokenSource{"ctags"}}).Validate(); err == nil {
+		t.Fatal("Validate accepted an unknown analyzer")
+	}
+	if err := (LinkPolicy{Strategy: "outermost"}).Validate(); err == nil {
+		t.Fatal("Validate accepted an unknown strategy")
+	}
+}
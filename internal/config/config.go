@@ -6,10 +6,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Eric-Song-Nop/gocire/internal/languages"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,8 +23,12 @@ var defaultInclude = []string{
 	"**/*.rs",
 	"**/*.ts",
 	"**/*.tsx",
+	"**/*.mts",
+	"**/*.cts",
 	"**/*.js",
 	"**/*.jsx",
+	"**/*.mjs",
+	"**/*.cjs",
 	"**/*.py",
 	"**/*.cpp",
 	"**/*.cxx",
@@ -32,9 +39,27 @@ var defaultInclude = []string{
 	"**/*.hs",
 	"**/*.java",
 	"**/*.rb",
+	"**/*.rake",
+	"**/*.gemspec",
 	"**/*.cs",
 	"**/*.php",
 	"**/*.dart",
+	"**/*.sh",
+	"**/*.bash",
+	"**/*.zsh",
+	"**/*.mk",
+	"**/Makefile",
+	"**/makefile",
+	"**/GNUmakefile",
+	"**/*.proto",
+	"**/Dockerfile*",
+	"**/*.Dockerfile",
+	"**/Containerfile*",
+}
+
+var defaultScripts = []string{
+	"bin/**",
+	"scripts/**",
 }
 
 var defaultExclude = []string{
@@ -44,14 +69,18 @@ var defaultExclude = []string{
 	"dist/**",
 	"build/**",
 	".gocire/**",
+	"**/package-lock.json",
 }
 
 type ProjectConfig struct {
-	Site    SiteConfig     `yaml:"site"`
-	Project ProjectSection `yaml:"project"`
-	Content ContentConfig  `yaml:"content"`
-	Source  SourceConfig   `yaml:"source"`
-	Output  OutputConfig   `yaml:"output"`
+	Site      SiteConfig      `yaml:"site"`
+	Project   ProjectSection  `yaml:"project"`
+	Content   ContentConfig   `yaml:"content"`
+	Source    SourceConfig    `yaml:"source"`
+	Links     LinksConfig     `yaml:"links"`
+	Highlight HighlightConfig `yaml:"highlight"`
+	Markdown  MarkdownConfig  `yaml:"markdown"`
+	Output    OutputConfig    `yaml:"output"`
 }
 
 type SiteConfig struct {
@@ -82,6 +111,54 @@ type SourceConfig struct {
 	RoutePrefix string   `yaml:"routePrefix"`
 	Include     []string `yaml:"include"`
 	Exclude     []string `yaml:"exclude"`
+	// Scripts are the globs under which extensionless files no include
+	// pattern matches are read for a shebang or modeline. Elsewhere they are
+	// only recognized by name, such as Makefile.
+	Scripts []string `yaml:"scripts"`
+	// Languages maps glob patterns such as "**/*.h" to the language of the
+	// files they match, ahead of detection. The longest matching pattern wins.
+	Languages map[string]string `yaml:"languages"`
+	// Directives adds regular expressions, keyed by language, for comments
+	// that are tool directives, such as "^// @generated". They extend the
+	// language registry's list and stay in the code instead of becoming prose.
+	Directives map[string][]string `yaml:"directives"`
+}
+
+// LinksConfig maps package managers (go, npm, cargo, pip) or module path prefixes
+// to documentation URL templates for definitions outside the project root.
+// Policy and Precedence choose which analyzer links a token that several
+// link: the most trusted by Precedence, or with Policy innermost, the
+// narrowest token, such as a method name within a qualified call.
+type LinksConfig struct {
+	External   map[string]string `yaml:"external"`
+	Policy     string            `yaml:"policy"`
+	Precedence []string          `yaml:"precedence"`
+}
+
+// HighlightConfig points at a directory of tree-sitter highlight queries named
+// like the built-in ones (go.scm, typescript.scm). A file replaces the built-in
+// query of its language unless it starts with an `; inherits: go` directive.
+type HighlightConfig struct {
+	QueryDir string `yaml:"queryDir"`
+}
+
+// MarkdownConfig turns Markdown features on or off for narrative prose, hover
+// text and MDX output alike. GitHub Flavored Markdown and math are always on.
+type MarkdownConfig struct {
+	Footnotes       bool `yaml:"footnotes"`
+	DefinitionLists bool `yaml:"definitionLists"`
+	Typographer     bool `yaml:"typographer"`
+	Emoji           bool `yaml:"emoji"`
+	// Attributes enables {#id .class} attribute lists on headings and blocks.
+	Attributes bool `yaml:"attributes"`
+	// HeadingAnchors places a "#" permalink before or after each heading, or
+	// none.
+	HeadingAnchors string `yaml:"headingAnchors"`
+	// RawHTML passes HTML written in prose through; off, it is omitted.
+	RawHTML bool `yaml:"rawHTML"`
+	// Sanitize sets how much of the HTML in prose and hover text survives:
+	// off, standard or strict.
+	Sanitize string `yaml:"sanitize"`
 }
 
 type OutputConfig struct {
@@ -89,11 +166,14 @@ type OutputConfig struct {
 }
 
 type rawProjectConfig struct {
-	Site    *rawSiteConfig     `yaml:"site"`
-	Project *rawProjectSection `yaml:"project"`
-	Content *rawContentConfig  `yaml:"content"`
-	Source  *rawSourceConfig   `yaml:"source"`
-	Output  *rawOutputConfig   `yaml:"output"`
+	Site      *rawSiteConfig      `yaml:"site"`
+	Project   *rawProjectSection  `yaml:"project"`
+	Content   *rawContentConfig   `yaml:"content"`
+	Source    *rawSourceConfig    `yaml:"source"`
+	Links     *rawLinksConfig     `yaml:"links"`
+	Highlight *rawHighlightConfig `yaml:"highlight"`
+	Markdown  *rawMarkdownConfig  `yaml:"markdown"`
+	Output    *rawOutputConfig    `yaml:"output"`
 }
 
 type rawSiteConfig struct {
@@ -122,10 +202,38 @@ type rawContentMetadata struct {
 }
 
 type rawSourceConfig struct {
-	RoutePrefix      *string   `yaml:"routePrefix"`
-	RoutePrefixSnake *string   `yaml:"route_prefix"`
-	Include          *[]string `yaml:"include"`
-	Exclude          *[]string `yaml:"exclude"`
+	RoutePrefix      *string             `yaml:"routePrefix"`
+	RoutePrefixSnake *string             `yaml:"route_prefix"`
+	Include          *[]string           `yaml:"include"`
+	Exclude          *[]string           `yaml:"exclude"`
+	Scripts          *[]string           `yaml:"scripts"`
+	Languages        map[string]string   `yaml:"languages"`
+	Directives       map[string][]string `yaml:"directives"`
+}
+
+type rawLinksConfig struct {
+	External   map[string]string `yaml:"external"`
+	Policy     *string           `yaml:"policy"`
+	Precedence []string          `yaml:"precedence"`
+}
+
+type rawHighlightConfig struct {
+	QueryDir      *string `yaml:"queryDir"`
+	QueryDirSnake *string `yaml:"query_dir"`
+}
+
+type rawMarkdownConfig struct {
+	Footnotes            *bool   `yaml:"footnotes"`
+	DefinitionLists      *bool   `yaml:"definitionLists"`
+	DefinitionListsSnake *bool   `yaml:"definition_lists"`
+	Typographer          *bool   `yaml:"typographer"`
+	Emoji                *bool   `yaml:"emoji"`
+	Attributes           *bool   `yaml:"attributes"`
+	HeadingAnchors       *string `yaml:"headingAnchors"`
+	HeadingAnchorsSnake  *string `yaml:"heading_anchors"`
+	RawHTML              *bool   `yaml:"rawHTML"`
+	RawHTMLSnake         *bool   `yaml:"raw_html"`
+	Sanitize             *string `yaml:"sanitize"`
 }
 
 type rawOutputConfig struct {
@@ -146,6 +254,19 @@ func DefaultConfig() *ProjectConfig {
 			RoutePrefix: "/_source",
 			Include:     cloneStrings(defaultInclude),
 			Exclude:     cloneStrings(defaultExclude),
+			Scripts:     cloneStrings(defaultScripts),
+			Languages:   map[string]string{},
+			Directives:  map[string][]string{},
+		},
+		Links: LinksConfig{
+			External:   map[string]string{},
+			Policy:     "precedence",
+			Precedence: []string{"lsp", "scip", "lsif", "locals", "tags"},
+		},
+		Markdown: MarkdownConfig{
+			HeadingAnchors: "none",
+			RawHTML:        true,
+			Sanitize:       "standard",
 		},
 		Output: OutputConfig{
 			Dir: ".gocire/site",
@@ -291,11 +412,36 @@ func (c *ProjectConfig) Normalize(baseDir string) error {
 	if c.Content.Metadata, err = normalizeContentMetadata(c.Content.Metadata); err != nil {
 		return err
 	}
+	if c.Highlight.QueryDir, err = normalizeOptionalPath(absBaseDir, c.Highlight.QueryDir, "highlight.queryDir"); err != nil {
+		return err
+	}
 	if c.Output.Dir, err = normalizePath(absBaseDir, c.Output.Dir, "output.dir"); err != nil {
 		return err
 	}
 
 	c.Source.RoutePrefix = normalizeRoutePrefix(c.Source.RoutePrefix)
+	if c.Source.Languages, err = normalizeSourceLanguages(c.Source.Languages); err != nil {
+		return err
+	}
+	if c.Source.Directives, err = normalizeSourceDirectives(c.Source.Directives); err != nil {
+		return err
+	}
+	c.Links.External = normalizeExternalLinks(c.Links.External)
+	c.Links.Policy = strings.ToLower(strings.TrimSpace(c.Links.Policy))
+	if c.Links.Policy == "" {
+		c.Links.Policy = "precedence"
+	}
+	for i, analyzer := range c.Links.Precedence {
+		c.Links.Precedence[i] = strings.ToLower(strings.TrimSpace(analyzer))
+	}
+	c.Markdown.HeadingAnchors = strings.ToLower(strings.TrimSpace(c.Markdown.HeadingAnchors))
+	if c.Markdown.HeadingAnchors == "" {
+		c.Markdown.HeadingAnchors = "none"
+	}
+	c.Markdown.Sanitize = strings.ToLower(strings.TrimSpace(c.Markdown.Sanitize))
+	if c.Markdown.Sanitize == "" {
+		c.Markdown.Sanitize = "standard"
+	}
 
 	return c.Validate()
 }
@@ -323,9 +469,34 @@ func (c *ProjectConfig) Validate() error {
 	if err := validateContentMetadata(c.Content.Metadata); err != nil {
 		return err
 	}
+	if err := validateOptionalPath("highlight.queryDir", c.Highlight.QueryDir); err != nil {
+		return err
+	}
 	if err := validatePath("output.dir", c.Output.Dir); err != nil {
 		return err
 	}
+	if err := validateExternalLinks(c.Links.External); err != nil {
+		return err
+	}
+	if err := validateLinkPolicy(c.Links.Policy, c.Links.Precedence); err != nil {
+		return err
+	}
+	if err := validateSourceLanguages(c.Source.Languages); err != nil {
+		return err
+	}
+	if err := validateSourceDirectives(c.Source.Directives); err != nil {
+		return err
+	}
+	switch c.Markdown.HeadingAnchors {
+	case "none", "before", "after":
+	default:
+		return fmt.Errorf("markdown.headingAnchors must be none, before or after, got %q", c.Markdown.HeadingAnchors)
+	}
+	switch c.Markdown.Sanitize {
+	case "off", "standard", "strict":
+	default:
+		return fmt.Errorf("markdown.sanitize must be off, standard or strict, got %q", c.Markdown.Sanitize)
+	}
 
 	if c.Source.RoutePrefix == "" {
 		return fmt.Errorf("source.routePrefix is required")
@@ -388,12 +559,86 @@ func applyRawConfig(cfg *ProjectConfig, raw *rawProjectConfig) {
 		if raw.Source.Exclude != nil {
 			cfg.Source.Exclude = cloneStrings(*raw.Source.Exclude)
 		}
+		if raw.Source.Scripts != nil {
+			cfg.Source.Scripts = cloneStrings(*raw.Source.Scripts)
+		}
+		if raw.Source.Languages != nil {
+			cfg.Source.Languages = make(map[string]string, len(raw.Source.Languages))
+			for pattern, language := range raw.Source.Languages {
+				cfg.Source.Languages[pattern] = language
+			}
+		}
+		if raw.Source.Directives != nil {
+			cfg.Source.Directives = make(map[string][]string, len(raw.Source.Directives))
+			for language, patterns := range raw.Source.Directives {
+				cfg.Source.Directives[language] = cloneStrings(patterns)
+			}
+		}
+	}
+	if raw.Links != nil && raw.Links.External != nil {
+		cfg.Links.External = make(map[string]string, len(raw.Links.External))
+		for key, template := range raw.Links.External {
+			cfg.Links.External[key] = template
+		}
+	}
+	if raw.Links != nil && raw.Links.Policy != nil {
+		cfg.Links.Policy = *raw.Links.Policy
+	}
+	if raw.Links != nil && raw.Links.Precedence != nil {
+		cfg.Links.Precedence = append([]string(nil), raw.Links.Precedence...)
+	}
+	if raw.Highlight != nil {
+		if raw.Highlight.QueryDir != nil {
+			cfg.Highlight.QueryDir = *raw.Highlight.QueryDir
+		}
+		if raw.Highlight.QueryDirSnake != nil {
+			cfg.Highlight.QueryDir = *raw.Highlight.QueryDirSnake
+		}
+	}
+	if raw.Markdown != nil {
+		applyRawMarkdownConfig(&cfg.Markdown, raw.Markdown)
 	}
 	if raw.Output != nil && raw.Output.Dir != nil {
 		cfg.Output.Dir = *raw.Output.Dir
 	}
 }
 
+func applyRawMarkdownConfig(cfg *MarkdownConfig, raw *rawMarkdownConfig) {
+	if raw.Footnotes != nil {
+		cfg.Footnotes = *raw.Footnotes
+	}
+	if raw.DefinitionLists != nil {
+		cfg.DefinitionLists = *raw.DefinitionLists
+	}
+	if raw.DefinitionListsSnake != nil {
+		cfg.DefinitionLists = *raw.DefinitionListsSnake
+	}
+	if raw.Typographer != nil {
+		cfg.Typographer = *raw.Typographer
+	}
+	if raw.Emoji != nil {
+		cfg.Emoji = *raw.Emoji
+	}
+	if raw.Attributes != nil {
+		cfg.Attributes = *raw.Attributes
+	}
+	if raw.HeadingAnchors != nil {
+		cfg.HeadingAnchors = *raw.HeadingAnchors
+	}
+	if raw.HeadingAnchorsSnake != nil {
+		cfg.HeadingAnchors = *raw.HeadingAnchorsSnake
+	}
+	if raw.RawHTML != nil {
+		cfg.RawHTML = *raw.RawHTML
+	}
+	if raw.RawHTMLSnake != nil {
+		cfg.RawHTML = *raw.RawHTMLSnake
+	}
+	if raw.Sanitize != nil {
+		cfg.Sanitize = *raw.Sanitize
+	}
+}
+
 func contentMetadataFromRaw(raw rawContentMetadata) ContentMetadata {
 	var metadata ContentMetadata
 	if raw.Title != nil {
@@ -510,6 +755,141 @@ func validateMetadataValue(key string, metadata ContentMetadata) error {
 	return nil
 }
 
+func normalizeSourceLanguages(overrides map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(overrides))
+	for pattern, language := range overrides {
+		key := strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(pattern)), "./")
+		if _, exists := normalized[key]; exists {
+			return nil, fmt.Errorf("source.languages pattern %q normalizes to duplicate %q", pattern, key)
+		}
+		canonical, err := languages.CanonicalName(strings.TrimSpace(language))
+		if err != nil {
+			return nil, fmt.Errorf("source.languages[%q] names unsupported language %q", pattern, language)
+		}
+		normalized[key] = canonical
+	}
+	return normalized, nil
+}
+
+func validateSourceLanguages(overrides map[string]string) error {
+	for pattern, language := range overrides {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("source.languages pattern is required")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("source.languages pattern %q is invalid: %w", pattern, err)
+		}
+		if _, err := languages.CanonicalName(language); err != nil {
+			return fmt.Errorf("source.languages[%q] names unsupported language %q", pattern, language)
+		}
+	}
+	return nil
+}
+
+func normalizeSourceDirectives(directives map[string][]string) (map[string][]string, error) {
+	normalized := make(map[string][]string, len(directives))
+	for language, patterns := range directives {
+		canonical, err := languages.CanonicalName(strings.TrimSpace(language))
+		if err != nil {
+			return nil, fmt.Errorf("source.directives names unsupported language %q", language)
+		}
+		normalized[canonical] = append(normalized[canonical], patterns...)
+	}
+	return normalized, nil
+}
+
+func validateSourceDirectives(directives map[string][]string) error {
+	for language, patterns := range directives {
+		if _, err := languages.CanonicalName(language); err != nil {
+			return fmt.Errorf("source.directives names unsupported language %q", language)
+		}
+		for i, pattern := range patterns {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("source.directives[%q][%d] is required", language, i)
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("source.directives[%q][%d] is invalid: %w", language, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeExternalLinks(templates map[string]string) map[string]string {
+	normalized := make(map[string]string, len(templates))
+	for key, template := range templates {
+		normalized[strings.TrimRight(strings.TrimSpace(key), "/")] = strings.TrimSpace(template)
+	}
+	return normalized
+}
+
+func validateExternalLinks(templates map[string]string) error {
+	for key, template := range templates {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("links.external key is required")
+		}
+		if strings.TrimSpace(template) == "" {
+			return fmt.Errorf("links.external[%q] template is required", key)
+		}
+		if err := validateExternalLinkTemplate(template); err != nil {
+			return fmt.Errorf("links.external[%q] %w", key, err)
+		}
+	}
+	return nil
+}
+
+// linkAnalyzers are the analyzers links.precedence may rank.
+var linkAnalyzers = []string{"lsp", "scip", "lsif", "locals", "tags"}
+
+func validateLinkPolicy(policy string, precedence []string) error {
+	if policy != "precedence" && policy != "innermost" {
+		return fmt.Errorf("links.policy must be precedence or innermost, got %q", policy)
+	}
+	for i, analyzer := range precedence {
+		if !slices.Contains(linkAnalyzers, analyzer) {
+			return fmt.Errorf("links.precedence[%d] must be one of %s, got %q", i, strings.Join(linkAnalyzers, ", "), analyzer)
+		}
+		if slices.Contains(precedence[:i], analyzer) {
+			return fmt.Errorf("links.precedence lists %q twice", analyzer)
+		}
+	}
+	return nil
+}
+
+func validateExternalLinkTemplate(template string) error {
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return fmt.Errorf("template has an unclosed placeholder")
+		}
+		name := rest[start+1 : start+end]
+		if !isExternalLinkTemplateVariable(name) {
+			return fmt.Errorf("template uses unknown placeholder {%s}; want one of {manager}, {module}, {version}, {package}, {symbol}", name)
+		}
+		rest = rest[start+end+1:]
+	}
+
+	parsed, err := url.Parse(strings.NewReplacer("{", "", "}", "").Replace(template))
+	if err != nil || !parsed.IsAbs() || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("template must be an absolute http or https URL")
+	}
+	return nil
+}
+
+func isExternalLinkTemplateVariable(name string) bool {
+	switch name {
+	case "manager", "module", "version", "package", "symbol":
+		return true
+	default:
+		return false
+	}
+}
+
 func isWindowsAbsPath(value string) bool {
 	if len(value) >= 3 && ((value[0] >= 'A' && value[0] <= 'Z') || (value[0] >= 'a' && value[0] <= 'z')) && value[1] == ':' && (value[2] == '/' || value[2] == '\\') {
 		return true
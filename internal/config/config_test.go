@@ -77,6 +77,12 @@ func TestLoadEmptyYAMLUsesDefaults(t *testing.T) {
 	if len(cfg.Content.Metadata) != 0 {
 		t.Fatalf("content metadata = %#v, want empty", cfg.Content.Metadata)
 	}
+	if want := (MarkdownConfig{HeadingAnchors: "none", RawHTML: true, Sanitize: "standard"}); cfg.Markdown != want {
+		t.Fatalf("markdown = %#v, want %#v", cfg.Markdown, want)
+	}
+	if want := []string{"lsp", "scip", "lsif", "locals", "tags"}; cfg.Links.Policy != "precedence" || !reflect.DeepEqual(cfg.Links.Precedence, want) {
+		t.Fatalf("links policy = %q %q, want precedence %q", cfg.Links.Policy, cfg.Links.Precedence, want)
+	}
 }
 
 func TestLoadYAMLOverridesDefaults(t *testing.T) {
@@ -99,6 +105,8 @@ source:
     - src/**/*.go
   exclude:
     - tmp/**
+  scripts:
+    - tools/**
 output:
   dir: public
 `)
@@ -131,6 +139,9 @@ output:
 	if !reflect.DeepEqual(cfg.Source.Exclude, []string{"tmp/**"}) {
 		t.Fatalf("exclude = %#v, want %#v", cfg.Source.Exclude, []string{"tmp/**"})
 	}
+	if !reflect.DeepEqual(cfg.Source.Scripts, []string{"tools/**"}) {
+		t.Fatalf("scripts = %#v, want %#v", cfg.Source.Scripts, []string{"tools/**"})
+	}
 }
 
 func TestLoadSiteDescriptionAndURLFormats(t *testing.T) {
@@ -506,6 +517,8 @@ project:
 content:
   docs: ../docs
   blogs: ../writing/blogs
+highlight:
+  query_dir: ../queries
 output:
   dir: ../out/site
 `)
@@ -519,6 +532,7 @@ output:
 	assertPath(t, cfg.Site.TemplateDir, filepath.Join(configDir, "..", "theme"))
 	assertPath(t, cfg.Content.Docs, filepath.Join(configDir, "..", "docs"))
 	assertPath(t, cfg.Content.Blogs, filepath.Join(configDir, "..", "writing", "blogs"))
+	assertPath(t, cfg.Highlight.QueryDir, filepath.Join(configDir, "..", "queries"))
 	assertPath(t, cfg.Output.Dir, filepath.Join(configDir, "..", "out", "site"))
 }
 
@@ -571,6 +585,251 @@ source:
 	}
 }
 
+func TestLoadExternalLinkTemplates(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, `
+links:
+  external:
+    go: " https://pkg.go.dev/{package}@{version}#{symbol} "
+    github.com/cockroachdb/errors/: https://errors.example.com/{symbol}
+    npm: https://www.npmjs.com/package/{module}/v/{version}
+`)
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"go":                            "https://pkg.go.dev/{package}@{version}#{symbol}",
+		"github.com/cockroachdb/errors": "https://errors.example.com/{symbol}",
+		"npm":                           "https://www.npmjs.com/package/{module}/v/{version}",
+	}
+	if !reflect.DeepEqual(cfg.Links.External, want) {
+		t.Fatalf("links.external = %#v, want %#v", cfg.Links.External, want)
+	}
+}
+
+func TestLoadRejectsInvalidExternalLinkTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "unknown placeholder", template: "https://pkg.go.dev/{pkg}", wantErr: "unknown placeholder {pkg}"},
+		{name: "unclosed placeholder", template: "https://pkg.go.dev/{package", wantErr: "unclosed placeholder"},
+		{name: "relative", template: "pkg.go.dev/{package}", wantErr: "absolute http or https URL"},
+		{name: "empty", template: `""`, wantErr: "template is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, ".gocire.yml")
+			writeFile(t, configPath, `
+links:
+  external:
+    go: `+tt.template+`
+`)
+
+			_, err := Load(configPath)
+			if err == nil {
+				t.Fatal("Load returned nil error for invalid external link template")
+			}
+			if !strings.Contains(err.Error(), `links.external["go"]`) {
+				t.Fatalf("error = %q, want links.external context", err.Error())
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSourceLanguageOverrides(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, `
+source:
+  languages:
+    "**/*.h": C++
+    ./templates/*.inc: php
+`)
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"**/*.h":          "cpp",
+		"templates/*.inc": "php",
+	}
+	if !reflect.DeepEqual(cfg.Source.Languages, want) {
+		t.Fatalf("source.languages = %#v, want %#v", cfg.Source.Languages, want)
+	}
+}
+
+func TestLoadRejectsInvalidSourceLanguageOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr string
+	}{
+		{name: "unknown language", entry: `"**/*.h": cobol`, wantErr: `names unsupported language "cobol"`},
+		{name: "bad pattern", entry: `"src/[*.h": c`, wantErr: `pattern "src/[*.h" is invalid`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, ".gocire.yml")
+			writeFile(t, configPath, "source:\n  languages:\n    "+tt.entry+"\n")
+
+			_, err := Load(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSourceDirectives(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, `
+source:
+  directives:
+    golang:
+      - "^// @generated"
+    JS:
+      - "^//\\s*flow\\b"
+`)
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	want := map[string][]string{
+		"go":         {"^// @generated"},
+		"javascript": {`^//\s*flow\b`},
+	}
+	if !reflect.DeepEqual(cfg.Source.Directives, want) {
+		t.Fatalf("source.directives = %#v, want %#v", cfg.Source.Directives, want)
+	}
+}
+
+func TestLoadRejectsInvalidSourceDirectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr string
+	}{
+		{name: "unknown language", entry: `cobol: ["^\\*>"]`, wantErr: `names unsupported language "cobol"`},
+		{name: "bad pattern", entry: `go: ["^//(go"]`, wantErr: `source.directives["go"][0] is invalid`},
+		{name: "empty pattern", entry: `go: [" "]`, wantErr: `source.directives["go"][0] is required`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, ".gocire.yml")
+			writeFile(t, configPath, "source:\n  directives:\n    "+tt.entry+"\n")
+
+			_, err := Load(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMarkdownOptions(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, `
+markdown:
+  footnotes: true
+  definition_lists: true
+  emoji: true
+  headingAnchors: " After "
+  rawHTML: false
+  sanitize: Strict
+`)
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	want := MarkdownConfig{
+		Footnotes:       true,
+		DefinitionLists: true,
+		Emoji:           true,
+		HeadingAnchors:  "after",
+		Sanitize:        "strict",
+	}
+	if cfg.Markdown != want {
+		t.Fatalf("markdown = %#v, want %#v", cfg.Markdown, want)
+	}
+}
+
+func TestLoadRejectsUnknownHeadingAnchorStyle(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, "markdown:\n  headingAnchors: inside\n")
+
+	_, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), `markdown.headingAnchors must be none, before or after, got "inside"`) {
+		t.Fatalf("Load error = %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownSanitizeLevel(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, "markdown:\n  sanitize: paranoid\n")
+
+	_, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), `markdown.sanitize must be off, standard or strict, got "paranoid"`) {
+		t.Fatalf("Load error = %v", err)
+	}
+}
+
+func TestLoadLinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, `
+links:
+  policy: " Innermost "
+  precedence: [SCIP, lsp]
+`)
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Links.Policy != "innermost" {
+		t.Fatalf("links.policy = %q, want innermost", cfg.Links.Policy)
+	}
+	if want := []string{"scip", "lsp"}; !reflect.DeepEqual(cfg.Links.Precedence, want) {
+		t.Fatalf("links.precedence = %q, want %q", cfg.Links.Precedence, want)
+	}
+}
+
+func TestLoadRejectsUnknownLinkAnalyzer(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gocire.yml")
+	writeFile(t, configPath, "links:\n  precedence: [lsp, ctags]\n")
+
+	_, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), `links.precedence[1] must be one of lsp, scip, lsif, locals, tags, got "ctags"`) {
+		t.Fatalf("Load error = %v", err)
+	}
+}
+
 func TestLoadInvalidYAMLReturnsError(t *testing.T) {
 	dir := t.TempDir()
 	configPath := filepath.Join(dir, ".gocire.yml")
@@ -0,0 +1,242 @@
+package languages
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DetectionMethod records which signal identified a file's language.
+type DetectionMethod string
+
+const (
+	DetectedByOverride  DetectionMethod = "override"
+	DetectedByExtension DetectionMethod = "extension"
+	DetectedByFilename  DetectionMethod = "filename"
+	DetectedByShebang   DetectionMethod = "shebang"
+	DetectedByModeline  DetectionMethod = "modeline"
+	DetectedByHeuristic DetectionMethod = "heuristic"
+)
+
+// Detection is the language of a file and how it was found.
+type Detection struct {
+	Language string
+	Method   DetectionMethod
+}
+
+// detectionPrefixSize is how much of a file DetectFile reads; shebangs,
+// modelines and the heuristics below all look near the top.
+const detectionPrefixSize = 8 << 10
+
+// ambiguousExtensions lists extensions shared by several languages. The first
+// candidate is the one the extension alone implies.
+var ambiguousExtensions = map[string][]string{
+	".h": {"c", "cpp"},
+}
+
+var (
+	emacsModelinePattern = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*([^\s;]+)|([^\s:;]+))\s*(?:;.*?)?-\*-`)
+	vimModelinePattern   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:.*?\b(?:ft|filetype|syntax)=([^\s:]+)`)
+	versionSuffixPattern = regexp.MustCompile(`[\d.]+$`)
+)
+
+// modelineNames maps editor mode names that are not registry names or aliases.
+var modelineNames = map[string]string{
+	"c-mode":          "c",
+	"c++-mode":        "cpp",
+	"csharp-mode":     "csharp",
+	"js2":             "javascript",
+	"javascriptreact": "javascript",
+	"typescriptreact": "typescript",
+	"rustic":          "rust",
+}
+
+// contentHeuristic identifies a language from a pattern in its source.
+type contentHeuristic struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// contentHeuristics run in order, so the more distinctive patterns come first.
+var contentHeuristics = []contentHeuristic{
+	{"php", regexp.MustCompile(`\A\s*<\?php`)},
+	{"cpp", regexp.MustCompile(`(?m)^\s*(?:namespace\s+\w+|template\s*<|class\s+\w+[^;]*\{|(?:public|private|protected):)|\bstd::|#include\s*<(?:iostream|string|vector|memory|map|algorithm)>`)},
+	{"go", regexp.MustCompile(`(?m)^package\s+\w+\s*$[\s\S]*^func\s`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(?:pub\s+)?fn\s+\w+[^{]*\{|^\s*use\s+\w+::`)},
+	{"c", regexp.MustCompile(`(?m)^\s*#include\s*[<"]\w+\.h[>"]`)},
+}
+
+// DetectFile detects the language of the file at filename, reading the start
+// of it when the name alone is not enough.
+func DetectFile(filename string) (Detection, error) {
+	if detection, ok := detectFromName(filename); ok && !isAmbiguous(filename) {
+		return detection, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return Detection{}, errors.Wrapf(err, "read %s for language detection", filename)
+	}
+	defer file.Close()
+	prefix, err := io.ReadAll(io.LimitReader(file, detectionPrefixSize))
+	if err != nil {
+		return Detection{}, errors.Wrapf(err, "read %s for language detection", filename)
+	}
+	return Detect(filename, prefix)
+}
+
+// Detect determines the language of a file from its name, then its shebang
+// or an Emacs or Vim modeline, then patterns in its content. Extensions such
+// as .h that several languages share are settled by the content and fall back
+// to the language the extension implies.
+func Detect(filename string, content []byte) (Detection, error) {
+	named, hasName := detectFromName(filename)
+	if hasName && !isAmbiguous(filename) {
+		return named, nil
+	}
+
+	candidates := Names()
+	if hasName {
+		candidates = ambiguousExtensions[strings.ToLower(filepath.Ext(filename))]
+	}
+
+	if language, ok := detectShebang(content); ok && slices.Contains(candidates, language) {
+		return Detection{Language: language, Method: DetectedByShebang}, nil
+	}
+	if language, ok := detectModeline(content); ok && slices.Contains(candidates, language) {
+		return Detection{Language: language, Method: DetectedByModeline}, nil
+	}
+	for _, heuristic := range contentHeuristics {
+		if !slices.Contains(candidates, heuristic.language) {
+			continue
+		}
+		if hasName && heuristic.language == named.Language {
+			continue
+		}
+		if heuristic.pattern.Match(content) {
+			return Detection{Language: heuristic.language, Method: DetectedByHeuristic}, nil
+		}
+	}
+
+	if hasName {
+		return named, nil
+	}
+	return Detection{}, errors.Newf("could not detect language for %s", filepath.Base(filename))
+}
+
+// DetectName detects the language of filename from its extension or a
+// well-known name such as Makefile, without reading the file.
+func DetectName(filename string) (Detection, bool) {
+	if isAmbiguous(filename) {
+		return Detection{}, false
+	}
+	return detectFromName(filename)
+}
+
+func detectFromName(filename string) (Detection, bool) {
+	if language, err := DetectLanguage(filename); err == nil {
+		return Detection{Language: language, Method: DetectedByExtension}, true
+	}
+
+	base := filepath.Base(filename)
+	for _, language := range Names() {
+		for _, pattern := range registry[language].Filenames {
+			if matched, _ := path.Match(pattern, base); matched {
+				return Detection{Language: language, Method: DetectedByFilename}, true
+			}
+		}
+	}
+	return Detection{}, false
+}
+
+func isAmbiguous(filename string) bool {
+	_, ok := ambiguousExtensions[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// detectShebang maps the interpreter of a `#!` line, looking through env and
+// dropping version suffixes such as python3.12.
+func detectShebang(content []byte) (string, bool) {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return "", false
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = path.Base(field)
+			break
+		}
+	}
+	interpreter = versionSuffixPattern.ReplaceAllString(interpreter, "")
+	if interpreter == "" {
+		return "", false
+	}
+
+	for _, language := range Names() {
+		if slices.Contains(registry[language].Interpreters, interpreter) {
+			return language, true
+		}
+	}
+	return "", false
+}
+
+// detectModeline reads an Emacs modeline from the first two lines or a Vim
+// modeline from the first or last five.
+func detectModeline(content []byte) (string, bool) {
+	lines := strings.Split(string(content), "\n")
+
+	for _, line := range lines[:min(2, len(lines))] {
+		if match := emacsModelinePattern.FindStringSubmatch(line); match != nil {
+			name := match[1]
+			if name == "" {
+				name = match[2]
+			}
+			if language, ok := modelineLanguage(name); ok {
+				return language, true
+			}
+		}
+	}
+
+	candidates := lines[:min(5, len(lines))]
+	if len(lines) > 5 {
+		candidates = append(slices.Clone(candidates), lines[max(5, len(lines)-5):]...)
+	}
+	for _, line := range candidates {
+		if match := vimModelinePattern.FindStringSubmatch(line); match != nil {
+			if language, ok := modelineLanguage(match[1]); ok {
+				return language, true
+			}
+		}
+	}
+	return "", false
+}
+
+func modelineLanguage(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if mapped, ok := modelineNames[name]; ok {
+		name = mapped
+	}
+	name = strings.TrimSuffix(name, "-mode")
+	language, err := CanonicalName(name)
+	if err != nil {
+		return "", false
+	}
+	return language, true
+}
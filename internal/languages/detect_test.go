@@ -0,0 +1,156 @@
+package languages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name         string
+		filename     string
+		content      string
+		wantLanguage string
+		wantMethod   DetectionMethod
+	}{
+		{
+			name:         "extension",
+			filename:     "main.go",
+			content:      "#!/usr/bin/env python3\n",
+			wantLanguage: "go",
+			wantMethod:   DetectedByExtension,
+		},
+		{
+			name:         "filename",
+			filename:     "Rakefile",
+			wantLanguage: "ruby",
+			wantMethod:   DetectedByFilename,
+		},
+		{
+			name:         "filename glob",
+			filename:     "lib/tasks/db.rake",
+			wantLanguage: "ruby",
+			wantMethod:   DetectedByFilename,
+		},
+		{
+			name:         "env shebang with version",
+			filename:     "bin/deploy",
+			content:      "#!/usr/bin/env python3.12\nimport sys\n",
+			wantLanguage: "python",
+			wantMethod:   DetectedByShebang,
+		},
+		{
+			name:         "env shebang with flags",
+			filename:     "scripts/build",
+			content:      "#!/usr/bin/env -S node --no-warnings\nconsole.log(1)\n",
+			wantLanguage: "javascript",
+			wantMethod:   DetectedByShebang,
+		},
+		{
+			name:         "absolute shebang",
+			filename:     "bin/console",
+			content:      "#!/usr/bin/ruby\nputs 1\n",
+			wantLanguage: "ruby",
+			wantMethod:   DetectedByShebang,
+		},
+		{
+			name:         "emacs modeline",
+			filename:     "tools/gen",
+			content:      "# -*- mode: python; coding: utf-8 -*-\nprint(1)\n",
+			wantLanguage: "python",
+			wantMethod:   DetectedByModeline,
+		},
+		{
+			name:         "vim modeline at the end",
+			filename:     "tools/check",
+			content:      "a\nb\nc\nd\ne\nf\ng\n// vim: set ft=javascript ts=2:\n",
+			wantLanguage: "javascript",
+			wantMethod:   DetectedByModeline,
+		},
+		{
+			name:         "header that is really c++",
+			filename:     "include/widget.h",
+			content:      "#pragma once\nnamespace ui {\nclass Widget {\npublic:\n  void draw();\n};\n}\n",
+			wantLanguage: "cpp",
+			wantMethod:   DetectedByHeuristic,
+		},
+		{
+			name:         "header with a vim modeline",
+			filename:     "include/point.h",
+			content:      "/* vim: set ft=cpp: */\nstruct point { int x; };\n",
+			wantLanguage: "cpp",
+			wantMethod:   DetectedByModeline,
+		},
+		{
+			name:         "plain c header",
+			filename:     "include/point.h",
+			content:      "#include <stdio.h>\nstruct point { int x; };\n",
+			wantLanguage: "c",
+			wantMethod:   DetectedByExtension,
+		},
+		{
+			name:         "dockerfile variant",
+			filename:     "deploy/Dockerfile.prod",
+			wantLanguage: "dockerfile",
+			wantMethod:   DetectedByFilename,
+		},
+		{
+			name:         "shell shebang",
+			filename:     "scripts/bootstrap",
+			content:      "#!/bin/bash -e\necho hi\n",
+			wantLanguage: "shell",
+			wantMethod:   DetectedByShebang,
+		},
+		{
+			name:         "extensionless php",
+			filename:     "public/router",
+			content:      "<?php\necho 1;\n",
+			wantLanguage: "php",
+			wantMethod:   DetectedByHeuristic,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection, err := Detect(tt.filename, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("Detect returned error: %v", err)
+			}
+			if detection.Language != tt.wantLanguage || detection.Method != tt.wantMethod {
+				t.Fatalf("Detect = %+v, want %s by %s", detection, tt.wantLanguage, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestDetectRejectsUnknownFiles(t *testing.T) {
+	for _, tt := range []struct {
+		filename string
+		content  string
+	}{
+		{filename: "README.md", content: "# Title\n"},
+		{filename: "bin/run", content: "#!/usr/bin/awk -f\n{ print }\n"},
+		{filename: "LICENSE", content: "MIT License\n"},
+	} {
+		if detection, err := Detect(tt.filename, []byte(tt.content)); err == nil {
+			t.Fatalf("Detect(%s) = %+v, want error", tt.filename, detection)
+		}
+	}
+}
+
+func TestDetectFileReadsContentOnlyWhenNeeded(t *testing.T) {
+	detection, err := DetectFile(filepath.Join(t.TempDir(), "missing.rs"))
+	if err != nil || detection.Language != "rust" {
+		t.Fatalf("DetectFile(missing.rs) = %+v, %v, want rust without reading", detection, err)
+	}
+
+	script := filepath.Join(t.TempDir(), "serve")
+	if err := os.WriteFile(script, []byte("#!/usr/bin/env deno run\n"), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	detection, err = DetectFile(script)
+	if err != nil || detection.Language != "typescript" || detection.Method != DetectedByShebang {
+		t.Fatalf("DetectFile(serve) = %+v, %v, want typescript by shebang", detection, err)
+	}
+}
@@ -25,12 +25,52 @@ import (
 type LanguageConfig struct {
 	SitterLanguage            *sitter.Language
 	QueryFileName             string
+	LocalsQueryFileName       string // File under queries/locals with @local.* captures
+	TagsQueryFileName         string // File under queries/tags with @definition.* and @reference.* captures
+	InjectionsQueryFileName   string // File under queries/injections with @injection.* captures
+	DocsQueryFileName         string // File under queries/docs with @doc captures on docstrings
 	LSPCommand                string
 	LSPArgs                   []string
+	LSPWorkspaceDataArg       string // Flag passed a per-workspace data directory, such as jdtls's -data
 	LSPInitializationOptions  map[string]interface{}
 	LSPWorkspaceConfiguration map[string]interface{}
+	LSPAlternatives           []LSPServer // Tried in order when LSPCommand is not installed
 	IgnoredCaptures           []string
 	Extensions                []string
+	Filenames                 []string // Base-name globs for files the extension does not identify
+	Interpreters              []string // Shebang interpreters, without version suffixes
+	ChromaLexer               string   // Chroma lexer for languages without a tree-sitter grammar
+	CommentDirectives         []string // Patterns for comments that are tool directives, matched against the raw comment
+}
+
+// LSPServer is a language server that can stand in for a language's primary one.
+type LSPServer struct {
+	Command                string
+	Args                   []string
+	WorkspaceDataArg       string // Flag passed a per-workspace data directory
+	InitializationOptions  map[string]interface{}
+	WorkspaceConfiguration map[string]interface{}
+}
+
+// LSPServers returns the primary language server followed by its alternatives.
+func (c *LanguageConfig) LSPServers() []LSPServer {
+	if c.LSPCommand == "" {
+		return nil
+	}
+	servers := []LSPServer{{
+		Command:                c.LSPCommand,
+		Args:                   c.LSPArgs,
+		WorkspaceDataArg:       c.LSPWorkspaceDataArg,
+		InitializationOptions:  c.LSPInitializationOptions,
+		WorkspaceConfiguration: c.LSPWorkspaceConfiguration,
+	}}
+	return append(servers, c.LSPAlternatives...)
+}
+
+// HasGrammar reports whether the language is parsed with tree-sitter. Other
+// languages are highlighted with their Chroma lexer only.
+func (c *LanguageConfig) HasGrammar() bool {
+	return c.SitterLanguage != nil
 }
 
 var defaultIgnoredCaptures = []string{"punctuation", "keyword", "operator", "comment", "string"}
@@ -116,108 +156,464 @@ var rustAnalyzerWorkspaceConfiguration = map[string]interface{}{
 	"rust-analyzer": rustAnalyzerInitializationOptions,
 }
 
+// literalIgnoredCaptures also skips literals and builtins, which have no
+// definition worth a hover or definition request.
+var literalIgnoredCaptures = slices.Concat(defaultIgnoredCaptures, []string{
+	"number", "boolean", "constant.builtin", "constant.null", "type.builtin", "escape",
+})
+
+var jdtlsSettings = map[string]interface{}{
+	"java": map[string]interface{}{
+		"import": map[string]interface{}{
+			"exclusions": []string{"**/node_modules/**", "**/.metadata/**", "**/archetype-resources/**", "**/META-INF/maven/**", "**/.gocire/**"},
+		},
+		"inlayHints": map[string]interface{}{
+			"parameterNames": map[string]interface{}{
+				"enabled": "all",
+			},
+			"parameterTypes": map[string]bool{
+				"enabled": true,
+			},
+			"variableTypes": map[string]bool{
+				"enabled": true,
+			},
+		},
+	},
+}
+
+var jdtlsInitializationOptions = map[string]interface{}{
+	"settings": jdtlsSettings,
+	"extendedClientCapabilities": map[string]bool{
+		"classFileContentsSupport": true,
+	},
+}
+
+var solargraphInitializationOptions = map[string]interface{}{
+	"diagnostics": false,
+	"formatting":  false,
+	"completion":  false,
+	"hover":       true,
+	"definitions": true,
+	"references":  true,
+	"symbols":     true,
+}
+
+var solargraphWorkspaceConfiguration = map[string]interface{}{
+	"solargraph": solargraphInitializationOptions,
+}
+
+var rubyLSPInitializationOptions = map[string]interface{}{
+	"enabledFeatures": map[string]bool{
+		"definition":  true,
+		"diagnostics": false,
+		"formatting":  false,
+		"hover":       true,
+		"inlayHint":   true,
+	},
+	"featuresConfiguration": map[string]interface{}{
+		"inlayHint": map[string]bool{
+			"implicitHashValue": true,
+			"implicitRescue":    true,
+		},
+	},
+}
+
+var csharpLSWorkspaceConfiguration = map[string]interface{}{
+	"csharp": map[string]interface{}{
+		"applyFormattingOptions": false,
+	},
+}
+
+// omniSharpArgs configures OmniSharp on the command line, the only channel it
+// reads Roslyn options from besides omnisharp.json.
+var omniSharpArgs = []string{
+	"-lsp",
+	"RoslynExtensionsOptions:EnableDecompilationSupport=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:EnableForParameters=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForLiteralParameters=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForIndexerParameters=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForObjectCreationParameters=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForOtherParameters=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:EnableForTypes=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForImplicitVariableTypes=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForLambdaParameterTypes=true",
+	"RoslynExtensionsOptions:InlayHintsOptions:ForImplicitObjectCreation=true",
+}
+
+var intelephenseSettings = map[string]interface{}{
+	"diagnostics": map[string]bool{
+		"enable": false,
+	},
+	"files": map[string]interface{}{
+		"exclude": []string{"**/.git/**", "**/node_modules/**", "**/vendor/**/{Tests,tests}/**", "**/.gocire/**"},
+	},
+	"telemetry": map[string]bool{
+		"enabled": false,
+	},
+}
+
+var intelephenseInitializationOptions = map[string]interface{}{
+	"clearCache": false,
+}
+
+var intelephenseWorkspaceConfiguration = map[string]interface{}{
+	"intelephense": intelephenseSettings,
+}
+
+// phpactorInitializationOptions are flat phpactor configuration keys.
+var phpactorInitializationOptions = map[string]interface{}{
+	"indexer.exclude_patterns":                            []string{"/vendor/**/Tests/**/*", "/vendor/**/tests/**/*", "/.gocire/**/*"},
+	"language_server_worse_reflection.inlay_hints.enable": true,
+	"language_server_worse_reflection.inlay_hints.params": true,
+	"language_server_worse_reflection.inlay_hints.types":  true,
+	"language_server_php_cs_fixer.enabled":                false,
+	"language_server_phpstan.enabled":                     false,
+	"language_server_psalm.enabled":                       false,
+}
+
+var dartInitializationOptions = map[string]interface{}{
+	"closingLabels":                    false,
+	"flutterOutline":                   false,
+	"onlyAnalyzeProjectsWithOpenFiles": false,
+	"outline":                          false,
+	"suggestFromUnimportedLibraries":   false,
+}
+
+var dartWorkspaceConfiguration = map[string]interface{}{
+	"dart": map[string]interface{}{
+		"analysisExcludedFolders": []string{".gocire"},
+		"inlayHints": map[string]interface{}{
+			"parameterNames": map[string]interface{}{
+				"enabled": "all",
+			},
+			"parameterTypes": map[string]bool{
+				"enabled": true,
+			},
+			"returnTypes": map[string]bool{
+				"enabled": true,
+			},
+			"typeArguments": map[string]bool{
+				"enabled": true,
+			},
+			"variableTypes": map[string]bool{
+				"enabled": true,
+			},
+		},
+	},
+}
+
+// Comment directives address compilers, linters and formatters rather than
+// readers, so they stay in the code instead of becoming prose.
+var (
+	goCommentDirectives = []string{
+		`^//go:\S`,
+		`^//line\s`,
+		`^//export\s`,
+		`^//extern\s`,
+		`^//\s*\+build\b`,
+		`^//\s*nolint\b`,
+		`^//\s*lint:(ignore|file-ignore)\b`,
+	}
+	jsCommentDirectives = []string{
+		`^//\s*eslint-`,
+		`^/\*\s*(eslint|eslint-\S+|globals?|exported)\s`,
+		`^//\s*@ts-(ignore|expect-error|nocheck|check)\b`,
+		`^(//|/\*)\s*prettier-ignore\b`,
+		`^(//|/\*)\s*(istanbul|c8|v8)\s+ignore\b`,
+		`^//\s*biome-ignore\b`,
+		`^///\s*<(reference|amd-module|amd-dependency)\s`,
+		`^//[#@]\s*source(Mapping)?URL=`,
+		`^/\*\*?\s*@jsx(Runtime|Frag|ImportSource)?\s`,
+	}
+	pythonCommentDirectives = []string{
+		`^#\s*type:\s*ignore\b`,
+		`^#\s*noqa\b`,
+		`^#\s*(pylint|mypy|pyright|ruff|isort):`,
+		`^#\s*fmt:\s*(off|on|skip)\b`,
+		`^#\s*pragma:`,
+		`^#.*\bcoding[:=]`,
+	}
+	cCommentDirectives = []string{
+		`^(//|/\*)\s*NOLINT`,
+		`^(//|/\*)\s*clang-format\s+(on|off)\b`,
+		`^(//|/\*)\s*(LCOV|GCOVR)_EXCL_`,
+		`^(//|/\*)\s*IWYU\s+pragma:`,
+	}
+	javaCommentDirectives = []string{
+		`^(//|/\*)\s*CHECKSTYLE[:.]`,
+		`^//\s*NOSONAR\b`,
+		`^(//|/\*)\s*@formatter:(on|off)\b`,
+		`^//\s*noinspection\s`,
+	}
+	rubyCommentDirectives = []string{
+		`^#\s*(frozen_string_literal|shareable_constant_value|warn_indent|typed):`,
+		`^#\s*rubocop:`,
+		`^#.*\b(en)?coding[:=]`,
+		`^#\s*:nocov:`,
+	}
+	csharpCommentDirectives = []string{
+		`^//\s*ReSharper\s+(disable|restore|enable)\b`,
+		`^//\s*<auto-generated`,
+		`^//\s*NOSONAR\b`,
+	}
+	phpCommentDirectives = []string{
+		`^(//|/\*\*?)\s*@(phpstan|psalm)-(ignore|suppress)`,
+		`^(//|/\*)\s*phpcs:`,
+		`^//\s*@codeCoverageIgnore`,
+	}
+	dartCommentDirectives = []string{
+		`^//\s*ignore(_for_file)?:`,
+		`^//\s*@dart\s*=`,
+		`^//\s*coverage:ignore`,
+	}
+)
+
 var registry = map[string]LanguageConfig{
 	"go": {
 		SitterLanguage:            sitter.NewLanguage(golangsitter.Language()),
 		QueryFileName:             "go.scm",
+		LocalsQueryFileName:       "go.scm",
+		TagsQueryFileName:         "go.scm",
+		InjectionsQueryFileName:   "go.scm",
 		LSPCommand:                "gopls",
 		LSPArgs:                   []string{},
 		LSPInitializationOptions:  goplsInitializationOptions,
 		LSPWorkspaceConfiguration: goplsWorkspaceConfiguration,
 		IgnoredCaptures:           defaultIgnoredCaptures,
 		Extensions:                []string{".go"},
+		Interpreters:              []string{"gorun"},
+		CommentDirectives:         goCommentDirectives,
 	},
 	"python": {
-		SitterLanguage:  sitter.NewLanguage(pythonsitter.Language()),
-		QueryFileName:   "python.scm",
-		LSPCommand:      "pylsp",
-		LSPArgs:         []string{},
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".py"},
+		SitterLanguage:      sitter.NewLanguage(pythonsitter.Language()),
+		QueryFileName:       "python.scm",
+		LocalsQueryFileName: "python.scm",
+		TagsQueryFileName:   "python.scm",
+		DocsQueryFileName:   "python.scm",
+		LSPCommand:          "pylsp",
+		LSPArgs:             []string{},
+		IgnoredCaptures:     defaultIgnoredCaptures,
+		Extensions:          []string{".py"},
+		Filenames:           []string{"*.pyw", "SConstruct", "SConscript", "wscript"},
+		Interpreters:        []string{"python", "pypy", "uv"},
+		CommentDirectives:   pythonCommentDirectives,
 	},
 	"typescript": {
 		SitterLanguage:            sitter.NewLanguage(typescript.LanguageTypescript()),
 		QueryFileName:             "typescript.scm",
+		LocalsQueryFileName:       "typescript.scm",
+		TagsQueryFileName:         "typescript.scm",
+		InjectionsQueryFileName:   "typescript.scm",
 		LSPCommand:                "typescript-language-server",
 		LSPArgs:                   []string{"--stdio"},
 		LSPInitializationOptions:  typescriptInitializationOptions,
 		LSPWorkspaceConfiguration: typescriptWorkspaceConfiguration,
 		IgnoredCaptures:           defaultIgnoredCaptures,
 		Extensions:                []string{".ts", ".tsx"},
+		Filenames:                 []string{"*.mts", "*.cts"},
+		Interpreters:              []string{"ts-node", "tsx", "deno"},
+		CommentDirectives:         jsCommentDirectives,
 	},
 	"javascript": {
 		SitterLanguage:            sitter.NewLanguage(javascript.Language()),
 		QueryFileName:             "javascript.scm",
+		LocalsQueryFileName:       "javascript.scm",
+		TagsQueryFileName:         "javascript.scm",
+		InjectionsQueryFileName:   "javascript.scm",
 		LSPCommand:                "typescript-language-server",
 		LSPArgs:                   []string{"--stdio"},
 		LSPInitializationOptions:  typescriptInitializationOptions,
 		LSPWorkspaceConfiguration: typescriptWorkspaceConfiguration,
 		IgnoredCaptures:           defaultIgnoredCaptures,
 		Extensions:                []string{".js", ".jsx"},
+		Filenames:                 []string{"*.mjs", "*.cjs", "Jakefile"},
+		Interpreters:              []string{"node", "nodejs"},
+		CommentDirectives:         jsCommentDirectives,
 	},
 	"rust": {
 		SitterLanguage:            sitter.NewLanguage(rustsitter.Language()),
 		QueryFileName:             "rust.scm",
+		LocalsQueryFileName:       "rust.scm",
+		TagsQueryFileName:         "rust.scm",
+		InjectionsQueryFileName:   "rust.scm",
 		LSPCommand:                "rust-analyzer",
 		LSPArgs:                   []string{},
 		LSPInitializationOptions:  rustAnalyzerInitializationOptions,
 		LSPWorkspaceConfiguration: rustAnalyzerWorkspaceConfiguration,
 		IgnoredCaptures:           defaultIgnoredCaptures,
 		Extensions:                []string{".rs"},
+		Interpreters:              []string{"rust-script"},
 	},
 	"cpp": {
-		SitterLanguage:  sitter.NewLanguage(cppsitter.Language()),
-		QueryFileName:   "cpp.scm",
-		LSPCommand:      "clangd",
-		LSPArgs:         []string{},
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".cpp", ".cxx", ".cc", ".hpp"},
+		SitterLanguage:          sitter.NewLanguage(cppsitter.Language()),
+		QueryFileName:           "cpp.scm",
+		LocalsQueryFileName:     "cpp.scm",
+		TagsQueryFileName:       "cpp.scm",
+		InjectionsQueryFileName: "cpp.scm",
+		LSPCommand:              "clangd",
+		LSPArgs:                 []string{},
+		IgnoredCaptures:         defaultIgnoredCaptures,
+		Extensions:              []string{".cpp", ".cxx", ".cc", ".hpp"},
+		CommentDirectives:       cCommentDirectives,
 	},
 	"c": {
-		SitterLanguage:  sitter.NewLanguage(csitter.Language()),
-		QueryFileName:   "c.scm",
-		LSPCommand:      "clangd",
-		LSPArgs:         []string{},
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".c", ".h"},
+		SitterLanguage:      sitter.NewLanguage(csitter.Language()),
+		QueryFileName:       "c.scm",
+		LocalsQueryFileName: "c.scm",
+		TagsQueryFileName:   "c.scm",
+		LSPCommand:          "clangd",
+		LSPArgs:             []string{},
+		IgnoredCaptures:     defaultIgnoredCaptures,
+		Extensions:          []string{".c", ".h"},
+		CommentDirectives:   cCommentDirectives,
 	},
 	"haskell": {
-		SitterLanguage:  sitter.NewLanguage(haskellsitter.Language()),
-		QueryFileName:   "haskell.scm",
-		LSPCommand:      "haskell-language-server-wrapper",
-		LSPArgs:         []string{"--lsp"},
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".hs"},
+		SitterLanguage:          sitter.NewLanguage(haskellsitter.Language()),
+		QueryFileName:           "haskell.scm",
+		LocalsQueryFileName:     "haskell.scm",
+		TagsQueryFileName:       "haskell.scm",
+		InjectionsQueryFileName: "haskell.scm",
+		LSPCommand:              "haskell-language-server-wrapper",
+		LSPArgs:                 []string{"--lsp"},
+		IgnoredCaptures:         defaultIgnoredCaptures,
+		Extensions:              []string{".hs"},
+		Interpreters:            []string{"runhaskell", "runghc", "stack"},
+		CommentDirectives:       []string{`^\{-#`},
 	},
 	"java": {
-		SitterLanguage:  sitter.NewLanguage(javasitter.Language()),
-		QueryFileName:   "java.scm",
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".java"},
+		SitterLanguage:            sitter.NewLanguage(javasitter.Language()),
+		QueryFileName:             "java.scm",
+		LocalsQueryFileName:       "java.scm",
+		TagsQueryFileName:         "java.scm",
+		LSPCommand:                "jdtls",
+		LSPArgs:                   []string{},
+		LSPWorkspaceDataArg:       "-data",
+		LSPInitializationOptions:  jdtlsInitializationOptions,
+		LSPWorkspaceConfiguration: jdtlsSettings,
+		IgnoredCaptures:           literalIgnoredCaptures,
+		Extensions:                []string{".java"},
+		Interpreters:              []string{"java", "jbang"},
+		CommentDirectives:         javaCommentDirectives,
 	},
 	"ruby": {
-		SitterLanguage:  sitter.NewLanguage(rubysitter.Language()),
-		QueryFileName:   "ruby.scm",
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".rb"},
+		SitterLanguage:            sitter.NewLanguage(rubysitter.Language()),
+		QueryFileName:             "ruby.scm",
+		LocalsQueryFileName:       "ruby.scm",
+		TagsQueryFileName:         "ruby.scm",
+		InjectionsQueryFileName:   "ruby.scm",
+		LSPCommand:                "solargraph",
+		LSPArgs:                   []string{"stdio"},
+		LSPInitializationOptions:  solargraphInitializationOptions,
+		LSPWorkspaceConfiguration: solargraphWorkspaceConfiguration,
+		LSPAlternatives: []LSPServer{{
+			Command:               "ruby-lsp",
+			Args:                  []string{},
+			InitializationOptions: rubyLSPInitializationOptions,
+		}},
+		// self and heredoc or interpolation delimiters resolve to nothing useful.
+		IgnoredCaptures:   slices.Concat(literalIgnoredCaptures, []string{"variable.builtin", "embedded"}),
+		Extensions:        []string{".rb"},
+		Filenames:         []string{"*.rake", "*.gemspec", "*.ru", "Rakefile", "Gemfile", "Guardfile", "Vagrantfile", "Podfile", "Capfile", "Brewfile", "Fastfile"},
+		Interpreters:      []string{"ruby", "jruby", "truffleruby"},
+		CommentDirectives: rubyCommentDirectives,
 	},
 	"csharp": {
-		SitterLanguage:  sitter.NewLanguage(csharpsitter.Language()),
-		QueryFileName:   "c_sharp.scm",
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".cs"},
+		SitterLanguage:            sitter.NewLanguage(csharpsitter.Language()),
+		QueryFileName:             "c_sharp.scm",
+		LocalsQueryFileName:       "c_sharp.scm",
+		TagsQueryFileName:         "c_sharp.scm",
+		LSPCommand:                "csharp-ls",
+		LSPArgs:                   []string{},
+		LSPWorkspaceConfiguration: csharpLSWorkspaceConfiguration,
+		LSPAlternatives: []LSPServer{{
+			Command: "OmniSharp",
+			Args:    omniSharpArgs,
+		}},
+		IgnoredCaptures:   literalIgnoredCaptures,
+		Extensions:        []string{".cs"},
+		Filenames:         []string{"*.csx"},
+		Interpreters:      []string{"dotnet-script"},
+		CommentDirectives: csharpCommentDirectives,
 	},
 	"php": {
-		SitterLanguage:  sitter.NewLanguage(phpsitter.LanguagePHP()),
-		QueryFileName:   "php.scm",
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".php"},
+		SitterLanguage:            sitter.NewLanguage(phpsitter.LanguagePHP()),
+		QueryFileName:             "php.scm",
+		LocalsQueryFileName:       "php.scm",
+		TagsQueryFileName:         "php.scm",
+		InjectionsQueryFileName:   "php.scm",
+		LSPCommand:                "intelephense",
+		LSPArgs:                   []string{"--stdio"},
+		LSPInitializationOptions:  intelephenseInitializationOptions,
+		LSPWorkspaceConfiguration: intelephenseWorkspaceConfiguration,
+		LSPAlternatives: []LSPServer{{
+			Command:               "phpactor",
+			Args:                  []string{"language-server"},
+			InitializationOptions: phpactorInitializationOptions,
+		}},
+		// $this and the <?php tag resolve to nothing useful.
+		IgnoredCaptures:   slices.Concat(literalIgnoredCaptures, []string{"variable.builtin", "tag"}),
+		Extensions:        []string{".php"},
+		Filenames:         []string{"*.phtml"},
+		Interpreters:      []string{"php"},
+		CommentDirectives: phpCommentDirectives,
 	},
 	"dart": {
-		SitterLanguage:  sitter.NewLanguage(dartsitter.Language()),
-		QueryFileName:   "dart.scm",
-		IgnoredCaptures: defaultIgnoredCaptures,
-		Extensions:      []string{".dart"},
+		SitterLanguage:            sitter.NewLanguage(dartsitter.Language()),
+		QueryFileName:             "dart.scm",
+		LocalsQueryFileName:       "dart.scm",
+		TagsQueryFileName:         "dart.scm",
+		LSPCommand:                "dart",
+		LSPArgs:                   []string{"language-server", "--protocol=lsp"},
+		LSPInitializationOptions:  dartInitializationOptions,
+		LSPWorkspaceConfiguration: dartWorkspaceConfiguration,
+		// @none marks string interpolation braces.
+		IgnoredCaptures:   slices.Concat(literalIgnoredCaptures, []string{"none"}),
+		Extensions:        []string{".dart"},
+		Interpreters:      []string{"dart"},
+		CommentDirectives: dartCommentDirectives,
+	},
+
+	// Highlight-only languages are lexed by Chroma instead of a tree-sitter
+	// grammar, which gocire does not ship for them. As build and data files
+	// they have no symbols to link, so they get no locals, tags or language
+	// server either.
+	"dockerfile": {
+		ChromaLexer:       "docker",
+		Filenames:         []string{"Dockerfile", "Dockerfile.*", "*.Dockerfile", "*.dockerfile", "Containerfile", "Containerfile.*"},
+		CommentDirectives: []string{`^#\s*(syntax|escape|check)\s*=`},
+	},
+	"makefile": {
+		ChromaLexer:  "make",
+		Extensions:   []string{".mk", ".mak"},
+		Filenames:    []string{"Makefile", "makefile", "GNUmakefile", "Makefile.*"},
+		Interpreters: []string{"make"},
+	},
+	"yaml": {
+		ChromaLexer:       "yaml",
+		Extensions:        []string{".yml", ".yaml"},
+		CommentDirectives: []string{`^#\s*yaml-language-server:`, `^#\s*yamllint\s`},
+	},
+	"toml": {
+		ChromaLexer:       "toml",
+		Extensions:        []string{".toml"},
+		Filenames:         []string{"Cargo.lock", "Pipfile", "poetry.lock"},
+		CommentDirectives: []string{`^#:schema\s`},
+	},
+	"json": {
+		ChromaLexer: "json",
+		Extensions:  []string{".json"},
+		Filenames:   []string{".babelrc", ".eslintrc", "composer.lock", "*.jsonc", "*.json5"},
+	},
+	"protobuf": {
+		ChromaLexer: "protobuf",
+		Extensions:  []string{".proto"},
+	},
+	"shell": {
+		ChromaLexer:       "bash",
+		Extensions:        []string{".sh", ".bash", ".zsh"},
+		Filenames:         []string{".bashrc", ".bash_profile", ".profile", ".zshrc", "*.ksh", "PKGBUILD"},
+		Interpreters:      []string{"sh", "bash", "zsh", "dash", "ksh"},
+		CommentDirectives: []string{`^#\s*shellcheck\s`},
 	},
 }
 
@@ -231,6 +627,13 @@ var aliases = map[string]string{
 	"c#":     "csharp",
 	"cs":     "csharp",
 	"hs":     "haskell",
+	"docker": "dockerfile",
+	"make":   "makefile",
+	"yml":    "yaml",
+	"proto":  "protobuf",
+	"bash":   "shell",
+	"sh":     "shell",
+	"zsh":    "shell",
 }
 
 func GetConfig(language string) (*LanguageConfig, error) {
@@ -256,6 +659,16 @@ func CanonicalName(language string) (string, error) {
 	return lang, nil
 }
 
+// Names returns the canonical names of all registered languages, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for lang := range registry {
+		names = append(names, lang)
+	}
+	slices.Sort(names)
+	return names
+}
+
 // DetectLanguage attempts to determine the language from the file extension.
 // It returns the language name (key in registry) and an error if not found.
 func DetectLanguage(filename string) (string, error) {
@@ -1,6 +1,9 @@
 package languages
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestGoConfigEnablesGoplsInlayHints(t *testing.T) {
 	cfg, err := GetConfig("go")
@@ -145,6 +148,105 @@ func TestWorkspaceConfigurationsExposeServerSections(t *testing.T) {
 	}
 }
 
+func TestLSPServersListPrimaryThenAlternatives(t *testing.T) {
+	for language, want := range map[string][]string{
+		"java":   {"jdtls"},
+		"ruby":   {"solargraph", "ruby-lsp"},
+		"csharp": {"csharp-ls", "OmniSharp"},
+		"php":    {"intelephense", "phpactor"},
+		"dart":   {"dart"},
+	} {
+		cfg, err := GetConfig(language)
+		if err != nil {
+			t.Fatalf("GetConfig(%s) returned error: %v", language, err)
+		}
+		servers := cfg.LSPServers()
+		if len(servers) != len(want) {
+			t.Fatalf("%s servers = %#v, want %v", language, servers, want)
+		}
+		for i, command := range want {
+			if servers[i].Command != command {
+				t.Fatalf("%s server %d = %q, want %q", language, i, servers[i].Command, command)
+			}
+		}
+	}
+}
+
+func TestNewLSPLanguagesEnableInlayHints(t *testing.T) {
+	javaCfg, err := GetConfig("java")
+	if err != nil {
+		t.Fatalf("GetConfig(java) returned error: %v", err)
+	}
+	java, ok := javaCfg.LSPWorkspaceConfiguration["java"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("java workspace configuration missing java section: %#v", javaCfg.LSPWorkspaceConfiguration)
+	}
+	javaHints, ok := java["inlayHints"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("java workspace configuration missing java.inlayHints: %#v", java)
+	}
+	if got := nestedBool(t, javaHints, "variableTypes", "enabled"); !got {
+		t.Fatalf("java variable type hints are not enabled: %#v", javaHints)
+	}
+
+	rubyCfg, err := GetConfig("ruby")
+	if err != nil {
+		t.Fatalf("GetConfig(ruby) returned error: %v", err)
+	}
+	rubyLSP := rubyCfg.LSPAlternatives[0].InitializationOptions
+	if got := nestedBool(t, rubyLSP, "enabledFeatures", "inlayHint"); !got {
+		t.Fatalf("ruby-lsp inlay hints are not enabled: %#v", rubyLSP)
+	}
+
+	csharpCfg, err := GetConfig("csharp")
+	if err != nil {
+		t.Fatalf("GetConfig(csharp) returned error: %v", err)
+	}
+	if !slices.Contains(csharpCfg.LSPAlternatives[0].Args, "RoslynExtensionsOptions:InlayHintsOptions:EnableForParameters=true") {
+		t.Fatalf("OmniSharp args do not enable parameter hints: %#v", csharpCfg.LSPAlternatives[0].Args)
+	}
+
+	phpCfg, err := GetConfig("php")
+	if err != nil {
+		t.Fatalf("GetConfig(php) returned error: %v", err)
+	}
+	if phpCfg.LSPAlternatives[0].InitializationOptions["language_server_worse_reflection.inlay_hints.enable"] != true {
+		t.Fatalf("phpactor inlay hints are not enabled: %#v", phpCfg.LSPAlternatives[0].InitializationOptions)
+	}
+
+	dartCfg, err := GetConfig("dart")
+	if err != nil {
+		t.Fatalf("GetConfig(dart) returned error: %v", err)
+	}
+	dart, ok := dartCfg.LSPWorkspaceConfiguration["dart"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("dart workspace configuration missing dart section: %#v", dartCfg.LSPWorkspaceConfiguration)
+	}
+	dartHints, ok := dart["inlayHints"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("dart workspace configuration missing dart.inlayHints: %#v", dart)
+	}
+	for _, hint := range []string{"parameterTypes", "returnTypes", "typeArguments", "variableTypes"} {
+		if got := nestedBool(t, dartHints, hint, "enabled"); !got {
+			t.Fatalf("dart %s hints are not enabled: %#v", hint, dartHints)
+		}
+	}
+}
+
+func TestNewLSPLanguagesSkipLiteralCaptures(t *testing.T) {
+	for _, language := range []string{"java", "ruby", "csharp", "php", "dart"} {
+		cfg, err := GetConfig(language)
+		if err != nil {
+			t.Fatalf("GetConfig(%s) returned error: %v", language, err)
+		}
+		for _, capture := range []string{"keyword", "string", "number", "constant.builtin"} {
+			if !slices.Contains(cfg.IgnoredCaptures, capture) {
+				t.Fatalf("%s IgnoredCaptures = %v, want %s", language, cfg.IgnoredCaptures, capture)
+			}
+		}
+	}
+}
+
 func nestedBool(t *testing.T, root map[string]interface{}, path ...string) bool {
 	t.Helper()
 
@@ -502,7 +502,8 @@ func isExpectedShutdownError(err error) bool {
 	if err == nil {
 		return true
 	}
-	if errors.Is(err, os.ErrProcessDone) || errors.Is(err, io.ErrClosedPipe) {
+	// A server that exits promptly on "exit" may close the connection first.
+	if errors.Is(err, os.ErrProcessDone) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, jsonrpc2.ErrClosed) {
 		return true
 	}
 	msg := err.Error()
@@ -308,3 +308,12 @@ func TestWorkspaceConfigurationResolvesConfiguredServerSections(t *testing.T) {
 		t.Fatalf("workspaceConfiguration returned %#v, want %#v", got, want)
 	}
 }
+
+func TestShutdownTreatsClosedConnectionAsExpected(t *testing.T) {
+	if !isExpectedShutdownError(jsonrpc2.ErrClosed) {
+		t.Fatal("jsonrpc2.ErrClosed should be expected once the server exits")
+	}
+	if isExpectedShutdownError(&jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "boom"}) {
+		t.Fatal("server errors should not be expected during shutdown")
+	}
+}
@@ -20,18 +20,21 @@ const (
 )
 
 type SourceFile struct {
-	AbsPath  string
-	RelPath  string
-	Language string
-	Kind     PageKind
+	AbsPath    string
+	RelPath    string
+	Language   string
+	DetectedBy languages.DetectionMethod
+	Kind       PageKind
 }
 
 type scanConfig struct {
-	root    string
-	docs    []string
-	blogs   []string
-	include []string
-	exclude []string
+	root      string
+	docs      []string
+	blogs     []string
+	include   []string
+	scripts   []string
+	exclude   []string
+	languages map[string]string
 }
 
 func Scan(cfg config.ProjectConfig) ([]SourceFile, error) {
@@ -79,16 +82,11 @@ func Scan(cfg config.ProjectConfig) ([]SourceFile, error) {
 			return nil
 		}
 
-		included, err := isIncluded(scanCfg.include, relPath)
+		detection, ok, err := detectLanguage(scanCfg, absPath, relPath)
 		if err != nil {
 			return err
 		}
-		if !included {
-			return nil
-		}
-
-		language, err := languages.DetectLanguage(absPath)
-		if err != nil {
+		if !ok {
 			return nil
 		}
 
@@ -98,10 +96,11 @@ func Scan(cfg config.ProjectConfig) ([]SourceFile, error) {
 		}
 
 		files = append(files, SourceFile{
-			AbsPath:  absPath,
-			RelPath:  relPath,
-			Language: language,
-			Kind:     kind,
+			AbsPath:    absPath,
+			RelPath:    relPath,
+			Language:   detection.Language,
+			DetectedBy: detection.Method,
+			Kind:       kind,
 		})
 		return nil
 	})
@@ -118,15 +117,71 @@ func readScanConfig(cfg config.ProjectConfig) (scanConfig, error) {
 		return scanConfig{}, fmt.Errorf("project config missing Project.Root")
 	}
 
+	include := normalizePatterns(cfg.Source.Include)
 	return scanConfig{
-		root:    root,
-		docs:    contentGlobPatterns(root, []string{cfg.Content.Docs}, "docs"),
-		blogs:   contentGlobPatterns(root, []string{cfg.Content.Blogs}, "blogs"),
-		include: normalizePatterns(cfg.Source.Include),
-		exclude: normalizePatterns(cfg.Source.Exclude),
+		root:      root,
+		docs:      contentGlobPatterns(root, []string{cfg.Content.Docs}, "docs"),
+		blogs:     contentGlobPatterns(root, []string{cfg.Content.Blogs}, "blogs"),
+		include:   include,
+		scripts:   normalizePatterns(cfg.Source.Scripts),
+		exclude:   normalizePatterns(cfg.Source.Exclude),
+		languages: cfg.Source.Languages,
 	}, nil
 }
 
+// detectLanguage returns the language of a file the scan should keep: one an
+// include pattern matches, or an extensionless one under source.scripts. A
+// source.languages pattern decides the language of the files it matches.
+// Extensionless files are kept when their name identifies the language, or,
+// under source.scripts, their shebang or modeline does.
+func detectLanguage(scanCfg scanConfig, absPath, relPath string) (languages.Detection, bool, error) {
+	included, err := isIncluded(scanCfg.include, relPath)
+	if err != nil {
+		return languages.Detection{}, false, err
+	}
+	script := false
+	if path.Ext(relPath) == "" {
+		script, err = matchesAny(scanCfg.scripts, relPath)
+		if err != nil {
+			return languages.Detection{}, false, err
+		}
+	}
+	if !included && !script {
+		return languages.Detection{}, false, nil
+	}
+
+	language, ok, err := languageOverride(scanCfg.languages, relPath)
+	if err != nil || ok {
+		return languages.Detection{Language: language, Method: languages.DetectedByOverride}, ok, err
+	}
+
+	if path.Ext(relPath) == "" && !script {
+		detection, ok := languages.DetectName(relPath)
+		return detection, ok, nil
+	}
+	detection, err := languages.DetectFile(absPath)
+	if err != nil {
+		return languages.Detection{}, false, nil
+	}
+	return detection, true, nil
+}
+
+// languageOverride returns the language of the longest source.languages
+// pattern that matches relPath.
+func languageOverride(overrides map[string]string, relPath string) (string, bool, error) {
+	var best, language string
+	for pattern, candidate := range overrides {
+		matched, err := matchGlob(pattern, relPath)
+		if err != nil {
+			return "", false, err
+		}
+		if matched && (len(pattern) > len(best) || len(pattern) == len(best) && pattern < best) {
+			best, language = pattern, candidate
+		}
+	}
+	return language, best != "", nil
+}
+
 func contentGlobPatterns(root string, configured []string, fallback string) []string {
 	if len(configured) == 0 {
 		configured = []string{fallback}
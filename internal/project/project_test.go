@@ -156,6 +156,132 @@ func TestScanReturnsSlashRelativePaths(t *testing.T) {
 	}
 }
 
+func TestScanDetectsLanguagesByContentAndOverrides(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "main.go")
+	writeTestFileContent(t, root, "bin/deploy", "#!/usr/bin/env python3\nprint('deploy')\n")
+	writeTestFileContent(t, root, "bin/notes", "plain text\n")
+	writeTestFileContent(t, root, "tools/release", "#!/usr/bin/env python3\nprint('release')\n")
+	writeTestFileContent(t, root, "tools/Rakefile", "task :default\n")
+	writeTestFileContent(t, root, "include/widget.h", "namespace ui {\nclass Widget {};\n}\n")
+	writeTestFileContent(t, root, "legacy/point.h", "struct point { int x; };\n")
+	writeTestFileContent(t, root, "templates/page.inc", "<?php echo 1;\n")
+	writeTestFileContent(t, root, "vendor.inc", "<?php echo 2;\n")
+	writeTestFileContent(t, root, "Makefile", "all:\n")
+	writeTestFileContent(t, root, "PKGBUILD", "pkgname=app\n")
+	writeTestFileContent(t, root, ".bashrc", "alias ll='ls -l'\n")
+
+	cfg := testProjectConfig(t, root,
+		[]string{"**/*.go", "**/*.py", "**/*.h", "**/Rakefile", "templates/**"},
+		nil,
+		nil,
+		nil,
+	)
+	cfg.Source.Scripts = []string{"bin/**"}
+	cfg.Source.Languages = map[string]string{
+		"legacy/**/*.h": "cpp",
+		"**/*.h":        "c",
+		"**/*.inc":      "php",
+	}
+	files, err := Scan(cfg)
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, file := range files {
+		got[file.RelPath] = file.Language + " by " + string(file.DetectedBy)
+	}
+	want := map[string]string{
+		"main.go":            "go by extension",
+		"bin/deploy":         "python by shebang",
+		"tools/Rakefile":     "ruby by filename",
+		"include/widget.h":   "c by override",
+		"legacy/point.h":     "cpp by override",
+		"templates/page.inc": "php by override",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detections = %#v, want %#v", got, want)
+	}
+}
+
+func TestScanSettlesAmbiguousHeadersByContent(t *testing.T) {
+	root := t.TempDir()
+	writeTestFileContent(t, root, "include/widget.h", "namespace ui {\nclass Widget {};\n}\n")
+	writeTestFileContent(t, root, "include/point.h", "struct point { int x; };\n")
+
+	files, err := Scan(testProjectConfig(t, root, []string{"**/*.h"}, nil, nil, nil))
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, file := range files {
+		got[file.RelPath] = file.Language + " by " + string(file.DetectedBy)
+	}
+	want := map[string]string{
+		"include/widget.h": "cpp by heuristic",
+		"include/point.h":  "c by extension",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detections = %#v, want %#v", got, want)
+	}
+}
+
+func TestScanIncludesBuildFilesByDefaultAndDataFilesOnRequest(t *testing.T) {
+	root := t.TempDir()
+	writeTestFileContent(t, root, "Dockerfile", "FROM golang:1.25\n")
+	writeTestFileContent(t, root, "deploy/Dockerfile.prod", "FROM alpine\n")
+	writeTestFileContent(t, root, "Makefile", "all:\n\tgo build ./...\n")
+	writeTestFileContent(t, root, ".github/workflows/ci.yml", "on: push\n")
+	writeTestFileContent(t, root, "charts/app/values.yaml", "replicas: 1\n")
+	writeTestFileContent(t, root, "Cargo.toml", "[package]\n")
+	writeTestFileContent(t, root, "tsconfig.json", "{}\n")
+	writeTestFileContent(t, root, "web/package-lock.json", "{}\n")
+	writeTestFileContent(t, root, "api/user.proto", "syntax = \"proto3\";\n")
+	writeTestFileContent(t, root, "scripts/release.sh", "echo release\n")
+	writeTestFileContent(t, root, "scripts/bootstrap", "#!/usr/bin/env bash\necho hi\n")
+	writeTestFileContent(t, root, "PKGBUILD", "pkgname=app\n")
+	writeTestFileContent(t, root, ".bashrc", "alias ll='ls -l'\n")
+
+	cfg := config.DefaultConfig()
+	cfg.Project.Root = root
+	want := map[string]string{
+		"Dockerfile":             "dockerfile by filename",
+		"deploy/Dockerfile.prod": "dockerfile by filename",
+		"Makefile":               "makefile by filename",
+		"api/user.proto":         "protobuf by extension",
+		"scripts/release.sh":     "shell by extension",
+		"scripts/bootstrap":      "shell by shebang",
+	}
+	if got := scanDetections(t, *cfg); !reflect.DeepEqual(got, want) {
+		t.Fatalf("default detections = %#v, want %#v", got, want)
+	}
+
+	// YAML, TOML and JSON files come in only when asked for.
+	cfg.Source.Include = append(cfg.Source.Include, "**/*.yml", "**/*.yaml", "**/*.toml", "**/*.json")
+	want[".github/workflows/ci.yml"] = "yaml by extension"
+	want["charts/app/values.yaml"] = "yaml by extension"
+	want["Cargo.toml"] = "toml by extension"
+	want["tsconfig.json"] = "json by extension"
+	if got := scanDetections(t, *cfg); !reflect.DeepEqual(got, want) {
+		t.Fatalf("opted-in detections = %#v, want %#v", got, want)
+	}
+}
+
+func scanDetections(t *testing.T, cfg config.ProjectConfig) map[string]string {
+	t.Helper()
+	files, err := Scan(cfg)
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	got := map[string]string{}
+	for _, file := range files {
+		got[file.RelPath] = file.Language + " by " + string(file.DetectedBy)
+	}
+	return got
+}
+
 func sourceRelPaths(files []SourceFile) []string {
 	paths := make([]string, len(files))
 	for i, file := range files {
@@ -177,6 +303,18 @@ func writeTestFile(t *testing.T, root, relPath string) {
 	}
 }
 
+func writeTestFileContent(t *testing.T, root, relPath, content string) {
+	t.Helper()
+
+	absPath := filepath.Join(append([]string{root}, strings.Split(relPath, "/")...)...)
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		t.Fatalf("MkdirAll(%q): %v", filepath.Dir(absPath), err)
+	}
+	if err := os.WriteFile(absPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile(%q): %v", absPath, err)
+	}
+}
+
 func testProjectConfig(t *testing.T, root string, include, exclude, docs, blogs []string) config.ProjectConfig {
 	t.Helper()
 
@@ -0,0 +1,28 @@
+; Docstrings: a string that is the first statement of a module, class or
+; function body. Comments before a module docstring, such as a shebang or an
+; encoding line, do not stop it being one.
+
+(module
+  .
+  (comment)*
+  .
+  (expression_statement
+    .
+    (string) @doc
+    .))
+
+(class_definition
+  body: (block
+    .
+    (expression_statement
+      .
+      (string) @doc
+      .)))
+
+(function_definition
+  body: (block
+    .
+    (expression_statement
+      .
+      (string) @doc
+      .)))
@@ -0,0 +1,6 @@
+; Adapted from tree-sitter-cpp queries/injections.scm.
+; Raw string delimiters name their language: R"sql(SELECT 1)sql".
+
+(raw_string_literal
+  delimiter: (raw_string_delimiter) @injection.language
+  (raw_string_content) @injection.content)
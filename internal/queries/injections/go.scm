@@ -0,0 +1,33 @@
+; A block comment holding only a language name marks the string after it:
+;   db.Query(/* sql */ `SELECT id FROM users`)
+
+((comment) @injection.language
+  .
+  (raw_string_literal
+    (raw_string_literal_content) @injection.content)
+  (#match? @injection.language "^/\\*\\s*[A-Za-z][A-Za-z0-9_+#-]*\\s*\\*/$"))
+
+((comment) @injection.language
+  .
+  (interpreted_string_literal
+    (interpreted_string_literal_content) @injection.content)
+  (#match? @injection.language "^/\\*\\s*[A-Za-z][A-Za-z0-9_+#-]*\\s*\\*/$"))
+
+; ...and the value of a declaration or assignment after it:
+;   var q = /* sql */ `SELECT 1`
+
+((comment) @injection.language
+  .
+  (expression_list
+    .
+    (raw_string_literal
+      (raw_string_literal_content) @injection.content))
+  (#match? @injection.language "^/\\*\\s*[A-Za-z][A-Za-z0-9_+#-]*\\s*\\*/$"))
+
+((comment) @injection.language
+  .
+  (expression_list
+    .
+    (interpreted_string_literal
+      (interpreted_string_literal_content) @injection.content))
+  (#match? @injection.language "^/\\*\\s*[A-Za-z][A-Za-z0-9_+#-]*\\s*\\*/$"))
@@ -0,0 +1,23 @@
+; Adapted from tree-sitter-haskell queries/injections.scm.
+
+(quasiquote
+  (quoter) @injection.language
+  (quasiquote_body) @injection.content)
+
+(quasiquote
+  (quoter) @_name
+  (#any-of? @_name "js" "julius")
+  (quasiquote_body) @injection.content
+  (#set! injection.language "javascript"))
+
+(quasiquote
+  (quoter) @_name
+  (#any-of? @_name "tsc" "tscJSX")
+  (quasiquote_body) @injection.content
+  (#set! injection.language "typescript"))
+
+(quasiquote
+  (quoter) @_name
+  (#any-of? @_name "shamlet" "xshamlet" "hamlet" "xhamlet" "ihamlet" "hsx")
+  (quasiquote_body) @injection.content
+  (#set! injection.language "html"))
@@ -0,0 +1,12 @@
+; Adapted from tree-sitter-javascript queries/injections.scm.
+; Tagged template literals name their language: html`<p></p>`, sql`SELECT 1`.
+
+(call_expression
+  function: [
+    (identifier) @injection.language
+    (member_expression
+      property: (property_identifier) @injection.language)
+  ]
+  arguments: (template_string
+    (string_fragment) @injection.content)
+  (#set! injection.combined))
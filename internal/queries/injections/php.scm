@@ -0,0 +1,15 @@
+; Adapted from tree-sitter-php queries/injections.scm.
+
+((text) @injection.content
+  (#set! injection.language "html")
+  (#set! injection.combined))
+
+(heredoc
+  (heredoc_body) @injection.content
+  (heredoc_end) @injection.language
+  (#set! injection.include-children))
+
+(nowdoc
+  (nowdoc_body) @injection.content
+  (heredoc_end) @injection.language
+  (#set! injection.include-children))
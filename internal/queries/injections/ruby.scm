@@ -0,0 +1,5 @@
+; Heredocs name their language with the closing identifier: <<~SQL ... SQL.
+
+(heredoc_body
+  (heredoc_content) @injection.content
+  (heredoc_end) @injection.language)
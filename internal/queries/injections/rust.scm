@@ -0,0 +1,11 @@
+; Adapted from tree-sitter-rust queries/injections.scm.
+
+((macro_invocation
+  (token_tree) @injection.content)
+  (#set! injection.language "rust")
+  (#set! injection.include-children))
+
+((macro_rule
+  (token_tree) @injection.content)
+  (#set! injection.language "rust")
+  (#set! injection.include-children))
@@ -0,0 +1,51 @@
+; Scopes
+;-------
+
+[
+  (function_definition)
+  (compound_statement)
+  (for_statement)
+  (if_statement)
+  (while_statement)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameter_declaration
+  declarator: (identifier) @local.definition)
+
+(parameter_declaration
+  declarator: (pointer_declarator
+    declarator: (identifier) @local.definition))
+
+(parameter_declaration
+  declarator: (array_declarator
+    declarator: (identifier) @local.definition))
+
+(init_declarator
+  declarator: (identifier) @local.definition)
+
+(init_declarator
+  declarator: (pointer_declarator
+    declarator: (identifier) @local.definition))
+
+(init_declarator
+  declarator: (array_declarator
+    declarator: (identifier) @local.definition))
+
+(declaration
+  declarator: (identifier) @local.definition)
+
+(declaration
+  declarator: (pointer_declarator
+    declarator: (identifier) @local.definition))
+
+(declaration
+  declarator: (array_declarator
+    declarator: (identifier) @local.definition))
+
+; References
+;-----------
+
+(identifier) @local.reference
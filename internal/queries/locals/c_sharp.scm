@@ -0,0 +1,43 @@
+; Scopes
+;-------
+
+[
+  (method_declaration)
+  (constructor_declaration)
+  (local_function_statement)
+  (lambda_expression)
+  (block)
+  (for_statement)
+  (foreach_statement)
+  (using_statement)
+  (catch_clause)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameter
+  name: (identifier) @local.definition)
+
+(parameter_list
+  name: (identifier) @local.definition)
+
+(implicit_parameter) @local.definition
+
+(variable_declarator
+  name: (identifier) @local.definition)
+
+(foreach_statement
+  left: (identifier) @local.definition)
+
+(catch_declaration
+  name: (identifier) @local.definition)
+
+; Members are never local variables.
+(member_access_expression
+  name: (identifier) @_member)
+
+; References
+;-----------
+
+(identifier) @local.reference
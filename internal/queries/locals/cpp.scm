@@ -0,0 +1,56 @@
+; Scopes
+;-------
+
+[
+  (function_definition)
+  (lambda_expression)
+  (compound_statement)
+  (for_statement)
+  (for_range_loop)
+  (if_statement)
+  (while_statement)
+  (catch_clause)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameter_declaration
+  declarator: (identifier) @local.definition)
+
+(parameter_declaration
+  declarator: (pointer_declarator
+    declarator: (identifier) @local.definition))
+
+(parameter_declaration
+  declarator: (reference_declarator
+    (identifier) @local.definition))
+
+(optional_parameter_declaration
+  declarator: (identifier) @local.definition)
+
+(init_declarator
+  declarator: (identifier) @local.definition)
+
+(init_declarator
+  declarator: (pointer_declarator
+    declarator: (identifier) @local.definition))
+
+(init_declarator
+  declarator: (reference_declarator
+    (identifier) @local.definition))
+
+(declaration
+  declarator: (identifier) @local.definition)
+
+(for_range_loop
+  declarator: (identifier) @local.definition)
+
+(for_range_loop
+  declarator: (reference_declarator
+    (identifier) @local.definition))
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,39 @@
+; Scopes
+;-------
+
+; Function signatures and bodies are siblings in this grammar, so parameters
+; are defined in the scope that holds the declaration.
+[
+  (function_body)
+  (function_expression)
+  (block)
+  (for_statement)
+  (try_statement)
+] @local.scope
+
+; Definitions
+;------------
+
+(formal_parameter
+  (identifier) @local.definition)
+
+(initialized_variable_definition
+  name: (identifier) @local.definition)
+
+(for_loop_parts
+  name: (identifier) @local.definition)
+
+(catch_parameters
+  (identifier) @local.definition)
+
+; Members are never local variables.
+(unconditional_assignable_selector
+  (identifier) @_member)
+
+(conditional_assignable_selector
+  (identifier) @_member)
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,20 @@
+; Scopes
+;-------
+
+[
+  (function)
+  (bind)
+  (lambda)
+  (let_in)
+  (alternative)
+] @local.scope
+
+; Definitions
+;------------
+
+(pattern/variable) @local.definition
+
+; References
+;-----------
+
+(expression/variable) @local.reference
@@ -0,0 +1,54 @@
+; Scopes
+;-------
+
+[
+  (method_declaration)
+  (constructor_declaration)
+  (lambda_expression)
+  (block)
+  (for_statement)
+  (enhanced_for_statement)
+  (catch_clause)
+  (try_with_resources_statement)
+] @local.scope
+
+; Definitions
+;------------
+
+(formal_parameter
+  name: (identifier) @local.definition)
+
+(spread_parameter
+  (variable_declarator
+    name: (identifier) @local.definition))
+
+(catch_formal_parameter
+  name: (identifier) @local.definition)
+
+(local_variable_declaration
+  declarator: (variable_declarator
+    name: (identifier) @local.definition))
+
+(enhanced_for_statement
+  name: (identifier) @local.definition)
+
+(resource
+  name: (identifier) @local.definition)
+
+(lambda_expression
+  parameters: (identifier) @local.definition)
+
+(inferred_parameters
+  (identifier) @local.definition)
+
+; Members are never local variables.
+(field_access
+  field: (identifier) @_member)
+
+(method_invocation
+  name: (identifier) @_member)
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,29 @@
+; Scopes
+;-------
+
+[
+  (statement_block)
+  (function_expression)
+  (arrow_function)
+  (function_declaration)
+  (method_definition)
+  (for_statement)
+  (for_in_statement)
+  (catch_clause)
+] @local.scope
+
+; Definitions
+;------------
+
+(pattern/identifier) @local.definition
+
+(variable_declarator
+  name: (identifier) @local.definition)
+
+(arrow_function
+  parameter: (identifier) @local.definition)
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,50 @@
+; Scopes
+;-------
+
+; Named functions, methods and closures do not see variables of the enclosing
+; scope; closures import them explicitly with `use`.
+((function_definition) @local.scope
+  (#set! local.scope-inherits false))
+
+((method_declaration) @local.scope
+  (#set! local.scope-inherits false))
+
+((anonymous_function) @local.scope
+  (#set! local.scope-inherits false))
+
+(arrow_function) @local.scope
+
+; Definitions
+;------------
+
+(simple_parameter
+  name: (variable_name) @local.definition)
+
+(variadic_parameter
+  name: (variable_name) @local.definition)
+
+(property_promotion_parameter
+  name: (variable_name) @local.definition)
+
+(anonymous_function_use_clause
+  (variable_name) @local.definition)
+
+(assignment_expression
+  left: (variable_name) @local.definition)
+
+(foreach_statement
+  (_)
+  .
+  (variable_name) @local.definition)
+
+(foreach_statement
+  (pair
+    (variable_name) @local.definition))
+
+(catch_clause
+  name: (variable_name) @local.definition)
+
+; References
+;-----------
+
+(variable_name) @local.reference
@@ -0,0 +1,46 @@
+; Scopes
+;-------
+
+[
+  (function_item)
+  (closure_expression)
+  (block)
+  (for_expression)
+  (while_expression)
+  (if_expression)
+  (match_arm)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameter
+  pattern: (identifier) @local.definition)
+
+(parameter
+  pattern: (mut_pattern
+    (identifier) @local.definition))
+
+(closure_parameters
+  (identifier) @local.definition)
+
+(let_declaration
+  pattern: (identifier) @local.definition)
+
+(let_declaration
+  pattern: (mut_pattern
+    (identifier) @local.definition))
+
+(let_condition
+  pattern: (identifier) @local.definition)
+
+(tuple_pattern
+  (identifier) @local.definition)
+
+(for_expression
+  pattern: (identifier) @local.definition)
+
+; References
+;-----------
+
+(identifier) @local.reference
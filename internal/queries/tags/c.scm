@@ -0,0 +1,31 @@
+; Adapted from tree-sitter-c queries/tags.scm.
+
+(struct_specifier
+  name: (type_identifier) @name
+  body: (_)) @definition.class
+
+(declaration
+  type: (union_specifier
+    name: (type_identifier) @name)) @definition.class
+
+(function_definition
+  declarator: (function_declarator
+    declarator: (identifier) @name)) @definition.function
+
+(type_definition
+  declarator: (type_identifier) @name) @definition.type
+
+(enum_specifier
+  name: (type_identifier) @name
+  body: (_)) @definition.type
+
+(preproc_def
+  name: (identifier) @name) @definition.macro
+
+(preproc_function_def
+  name: (identifier) @name) @definition.macro
+
+(call_expression
+  function: (identifier) @name) @reference.call
+
+(type_identifier) @name @reference.type
@@ -0,0 +1,41 @@
+; Adapted from tree-sitter-c-sharp queries/tags.scm.
+
+(class_declaration
+  name: (identifier) @name) @definition.class
+
+(struct_declaration
+  name: (identifier) @name) @definition.class
+
+(record_declaration
+  name: (identifier) @name) @definition.class
+
+(enum_declaration
+  name: (identifier) @name) @definition.enum
+
+(interface_declaration
+  name: (identifier) @name) @definition.interface
+
+(method_declaration
+  name: (identifier) @name) @definition.method
+
+(namespace_declaration
+  name: (identifier) @name) @definition.module
+
+(base_list
+  (identifier) @name) @reference.class
+
+(object_creation_expression
+  type: (identifier) @name) @reference.class
+
+(variable_declaration
+  type: (identifier) @name) @reference.class
+
+(parameter
+  type: (identifier) @name) @reference.class
+
+(invocation_expression
+  function: [
+    (identifier) @name
+    (member_access_expression
+      name: (identifier) @name)
+  ]) @reference.call
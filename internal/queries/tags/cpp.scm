@@ -0,0 +1,52 @@
+; Adapted from tree-sitter-cpp queries/tags.scm.
+
+(struct_specifier
+  name: (type_identifier) @name
+  body: (_)) @definition.class
+
+(class_specifier
+  name: (type_identifier) @name
+  body: (_)) @definition.class
+
+(declaration
+  type: (union_specifier
+    name: (type_identifier) @name)) @definition.class
+
+(function_definition
+  declarator: (function_declarator
+    declarator: (identifier) @name)) @definition.function
+
+(function_declarator
+  declarator: (field_identifier) @name) @definition.method
+
+(function_definition
+  declarator: (function_declarator
+    declarator: (qualified_identifier
+      name: (identifier) @name))) @definition.method
+
+(type_definition
+  declarator: (type_identifier) @name) @definition.type
+
+(enum_specifier
+  name: (type_identifier) @name
+  body: (_)) @definition.type
+
+(namespace_definition
+  name: (namespace_identifier) @name) @definition.module
+
+(preproc_def
+  name: (identifier) @name) @definition.macro
+
+(preproc_function_def
+  name: (identifier) @name) @definition.macro
+
+(call_expression
+  function: [
+    (identifier) @name
+    (qualified_identifier
+      name: (identifier) @name)
+    (field_expression
+      field: (field_identifier) @name)
+  ]) @reference.call
+
+(type_identifier) @name @reference.type
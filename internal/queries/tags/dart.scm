@@ -0,0 +1,42 @@
+; Adapted from tree-sitter-dart queries/tags.scm.
+
+(class_definition
+  name: (identifier) @name) @definition.class
+
+(mixin_declaration
+  (identifier) @name) @definition.mixin
+
+(extension_declaration
+  name: (identifier) @name) @definition.extension
+
+(enum_declaration
+  name: (identifier) @name) @definition.enum
+
+(type_alias
+  (type_identifier) @name) @definition.type
+
+(method_signature
+  (function_signature
+    name: (identifier) @name)) @definition.method
+
+(method_signature
+  [
+    (getter_signature
+      name: (identifier) @name)
+    (setter_signature
+      name: (identifier) @name)
+  ]) @definition.method
+
+(program
+  (function_signature
+    name: (identifier) @name) @definition.function)
+
+(new_expression
+  (type_identifier) @name) @reference.class
+
+(type_identifier) @name @reference.type
+
+((identifier) @name @reference.call
+  .
+  (selector
+    (argument_part)))
@@ -0,0 +1,33 @@
+; Adapted from tree-sitter-go queries/tags.scm, without doc captures.
+
+(function_declaration
+  name: (identifier) @name) @definition.function
+
+(method_declaration
+  name: (field_identifier) @name) @definition.method
+
+(type_spec
+  name: (type_identifier) @name) @definition.type
+
+(source_file
+  (var_declaration
+    (var_spec
+      name: (identifier) @name) @definition.variable))
+
+(source_file
+  (const_declaration
+    (const_spec
+      name: (identifier) @name) @definition.constant))
+
+(call_expression
+  function: [
+    (identifier) @name
+    (parenthesized_expression (identifier) @name)
+    (selector_expression field: (field_identifier) @name)
+    (parenthesized_expression (selector_expression field: (field_identifier) @name))
+  ]) @reference.call
+
+(type_identifier) @name @reference.type
+
+(qualified_type
+  name: (type_identifier) @name) @reference.type
@@ -0,0 +1,26 @@
+; tree-sitter-haskell ships no tags query; top-level functions and types only.
+
+(declarations
+  (function
+    name: (variable) @name) @definition.function)
+
+(declarations
+  (signature
+    name: (variable) @name) @definition.function)
+
+(data_type
+  name: (name) @name) @definition.type
+
+(newtype
+  name: (name) @name) @definition.type
+
+(type_synomym
+  name: (name) @name) @definition.type
+
+(class
+  name: (name) @name) @definition.class
+
+(apply
+  function: (variable) @name) @reference.call
+
+(name) @name @reference.type
@@ -0,0 +1,22 @@
+; Adapted from tree-sitter-java queries/tags.scm.
+
+(class_declaration
+  name: (identifier) @name) @definition.class
+
+(record_declaration
+  name: (identifier) @name) @definition.class
+
+(enum_declaration
+  name: (identifier) @name) @definition.enum
+
+(method_declaration
+  name: (identifier) @name) @definition.method
+
+(interface_declaration
+  name: (identifier) @name) @definition.interface
+
+(method_invocation
+  name: (identifier) @name
+  arguments: (argument_list)) @reference.call
+
+(type_identifier) @name @reference.type
@@ -0,0 +1,46 @@
+; Adapted from tree-sitter-php queries/tags.scm.
+
+(namespace_definition
+  name: (namespace_name) @name) @definition.module
+
+(interface_declaration
+  name: (name) @name) @definition.interface
+
+(trait_declaration
+  name: (name) @name) @definition.interface
+
+(class_declaration
+  name: (name) @name) @definition.class
+
+(enum_declaration
+  name: (name) @name) @definition.enum
+
+(function_definition
+  name: (name) @name) @definition.function
+
+(method_declaration
+  name: (name) @name) @definition.method
+
+(class_interface_clause
+  (name) @name) @reference.implementation
+
+(base_clause
+  (name) @name) @reference.class
+
+(object_creation_expression
+  (name) @name) @reference.class
+
+(function_call_expression
+  function: [
+    (name) @name
+    (qualified_name (name) @name)
+  ]) @reference.call
+
+(scoped_call_expression
+  name: (name) @name) @reference.call
+
+(member_call_expression
+  name: (name) @name) @reference.call
+
+(named_type
+  (name) @name) @reference.type
@@ -0,0 +1,22 @@
+; Adapted from tree-sitter-python queries/tags.scm.
+
+(module
+  (expression_statement
+    (assignment
+      left: (identifier) @name) @definition.constant))
+
+(class_definition
+  name: (identifier) @name) @definition.class
+
+(function_definition
+  name: (identifier) @name) @definition.function
+
+(call
+  function: [
+    (identifier) @name
+    (attribute
+      attribute: (identifier) @name)
+  ]) @reference.call
+
+(argument_list
+  (identifier) @name) @reference.class
@@ -0,0 +1,38 @@
+; Adapted from tree-sitter-ruby queries/tags.scm, without doc captures.
+
+[
+  (method
+    name: (_) @name)
+  (singleton_method
+    name: (_) @name)
+] @definition.method
+
+(alias
+  name: (_) @name) @definition.method
+
+[
+  (class
+    name: [
+      (constant) @name
+      (scope_resolution
+        name: (_) @name)
+    ])
+  (singleton_class
+    value: [
+      (constant) @name
+      (scope_resolution
+        name: (_) @name)
+    ])
+] @definition.class
+
+(module
+  name: [
+    (constant) @name
+    (scope_resolution
+      name: (_) @name)
+  ]) @definition.module
+
+(call
+  method: (identifier) @name) @reference.call
+
+((constant) @name @reference.class)
@@ -0,0 +1,85 @@
+; Adapted from tree-sitter-javascript and tree-sitter-typescript queries/tags.scm.
+
+((method_definition
+  name: (property_identifier) @name) @definition.method
+  (#not-eq? @name "constructor"))
+
+[
+  (class
+    name: (_) @name)
+  (class_declaration
+    name: (_) @name)
+] @definition.class
+
+[
+  (function_expression
+    name: (identifier) @name)
+  (function_declaration
+    name: (identifier) @name)
+  (generator_function
+    name: (identifier) @name)
+  (generator_function_declaration
+    name: (identifier) @name)
+] @definition.function
+
+(lexical_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [(arrow_function) (function_expression)]) @definition.function)
+
+(variable_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [(arrow_function) (function_expression)]) @definition.function)
+
+(assignment_expression
+  left: [
+    (identifier) @name
+    (member_expression
+      property: (property_identifier) @name)
+  ]
+  right: [(arrow_function) (function_expression)]) @definition.function
+
+(pair
+  key: (property_identifier) @name
+  value: [(arrow_function) (function_expression)]) @definition.function
+
+((call_expression
+  function: (identifier) @name) @reference.call
+  (#not-match? @name "^(require)$"))
+
+(call_expression
+  function: (member_expression
+    property: (property_identifier) @name)) @reference.call
+
+(new_expression
+  constructor: (identifier) @name) @reference.class
+
+(function_signature
+  name: (identifier) @name) @definition.function
+
+(method_signature
+  name: (property_identifier) @name) @definition.method
+
+(abstract_method_signature
+  name: (property_identifier) @name) @definition.method
+
+(abstract_class_declaration
+  name: (type_identifier) @name) @definition.class
+
+(module
+  name: (identifier) @name) @definition.module
+
+(interface_declaration
+  name: (type_identifier) @name) @definition.interface
+
+(type_alias_declaration
+  name: (type_identifier) @name) @definition.type
+
+(enum_declaration
+  name: (identifier) @name) @definition.enum
+
+(type_identifier) @name @reference.type
+
+(extends_clause
+  value: (identifier) @name) @reference.class